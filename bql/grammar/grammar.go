@@ -55,19 +55,142 @@ func initBQL() {
 					NewTokenType(lexer.ItemQuery),
 					NewSymbol("VARS"),
 					NewTokenType(lexer.ItemFrom),
-					NewSymbol("GRAPHS"),
+					NewSymbol("FROM_GRAPHS"),
+					NewSymbol("WHERE"),
+					NewSymbol("GROUP_BY"),
+					NewSymbol("ORDER_BY"),
+					NewSymbol("HAVING"),
+					NewSymbol("GLOBAL_TIME_BOUND"),
+					NewSymbol("LIMIT"),
+					NewSymbol("OFFSET"),
+					NewSymbol("CURSOR"),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemExplain),
+					NewSymbol("EXPLAIN_ANALYZE"),
+					NewTokenType(lexer.ItemQuery),
+					NewSymbol("VARS"),
+					NewTokenType(lexer.ItemFrom),
+					NewSymbol("FROM_GRAPHS"),
 					NewSymbol("WHERE"),
 					NewSymbol("GROUP_BY"),
 					NewSymbol("ORDER_BY"),
 					NewSymbol("HAVING"),
 					NewSymbol("GLOBAL_TIME_BOUND"),
 					NewSymbol("LIMIT"),
+					NewSymbol("OFFSET"),
+					NewSymbol("CURSOR"),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemConstruct),
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("CONSTRUCT_TRIPLES"),
+					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("CONSTRUCT_INTO"),
+					NewTokenType(lexer.ItemFrom),
+					NewSymbol("FROM_GRAPHS"),
+					NewSymbol("WHERE"),
+					NewSymbol("GLOBAL_TIME_BOUND"),
+					NewSymbol("LIMIT"),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAsk),
+					NewTokenType(lexer.ItemFrom),
+					NewSymbol("FROM_GRAPHS"),
+					NewSymbol("WHERE"),
+					NewSymbol("GLOBAL_TIME_BOUND"),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDescribe),
+					NewTokenType(lexer.ItemNode),
+					NewTokenType(lexer.ItemFrom),
+					NewSymbol("FROM_GRAPHS"),
+					NewSymbol("DESCRIBE_DEPTH"),
 					NewTokenType(lexer.ItemSemicolon),
 				},
 			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemShow),
+					NewSymbol("SHOW_STATEMENT"),
+				},
+			},
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemInsert),
+					NewSymbol("INSERT_STATEMENT"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDelete),
+					NewSymbol("DELETE_STATEMENT"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemCreate),
+					NewSymbol("CREATE_GRAPHS"),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDrop),
+					NewSymbol("DROP_GRAPHS"),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBegin),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemCommit),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemRollback),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+		},
+		"SHOW_STATEMENT": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemGraphs),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemStats),
+					NewTokenType(lexer.ItemFor),
+					NewSymbol("FROM_GRAPHS"),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+		},
+		"INSERT_STATEMENT": []*Clause{
+			{
+				Elements: []Element{
 					NewTokenType(lexer.ItemData),
 					NewTokenType(lexer.ItemInto),
 					NewSymbol("GRAPHS"),
@@ -82,7 +205,19 @@ func initBQL() {
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemDelete),
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("CONSTRUCT_TRIPLES"),
+					NewTokenType(lexer.ItemRBracket),
+					NewTokenType(lexer.ItemInto),
+					NewSymbol("GRAPHS"),
+					NewSymbol("WHERE"),
+					NewTokenType(lexer.ItemSemicolon),
+				},
+			},
+		},
+		"DELETE_STATEMENT": []*Clause{
+			{
+				Elements: []Element{
 					NewTokenType(lexer.ItemData),
 					NewTokenType(lexer.ItemFrom),
 					NewSymbol("GRAPHS"),
@@ -97,18 +232,27 @@ func initBQL() {
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemCreate),
-					NewSymbol("CREATE_GRAPHS"),
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("DELETE_TEMPLATE_TRIPLES"),
+					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("UPDATE_INSERT"),
+					NewTokenType(lexer.ItemFrom),
+					NewSymbol("GRAPHS"),
+					NewSymbol("WHERE"),
 					NewTokenType(lexer.ItemSemicolon),
 				},
 			},
+		},
+		"UPDATE_INSERT": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemDrop),
-					NewSymbol("DROP_GRAPHS"),
-					NewTokenType(lexer.ItemSemicolon),
+					NewTokenType(lexer.ItemInsert),
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("CONSTRUCT_TRIPLES"),
+					NewTokenType(lexer.ItemRBracket),
 				},
 			},
+			{},
 		},
 		"CREATE_GRAPHS": []*Clause{
 			{
@@ -117,6 +261,14 @@ func initBQL() {
 					NewSymbol("GRAPHS"),
 				},
 			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemSnapshot),
+					NewTokenType(lexer.ItemString),
+					NewTokenType(lexer.ItemOf),
+					NewSymbol("GRAPHS"),
+				},
+			},
 		},
 		"DROP_GRAPHS": []*Clause{
 			{
@@ -125,6 +277,14 @@ func initBQL() {
 					NewSymbol("GRAPHS"),
 				},
 			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemSnapshot),
+					NewTokenType(lexer.ItemString),
+					NewTokenType(lexer.ItemOf),
+					NewSymbol("GRAPHS"),
+				},
+			},
 		},
 		"VARS": []*Clause{
 			{
@@ -157,541 +317,1352 @@ func initBQL() {
 					NewSymbol("MORE_VARS"),
 				},
 			},
-		},
-		"COUNT_DISTINCT": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemDistinct),
+					NewTokenType(lexer.ItemAvg),
+					NewTokenType(lexer.ItemLPar),
+					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemRPar),
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("MORE_VARS"),
 				},
 			},
-			{},
-		},
-		"VARS_AS": []*Clause{
 			{
 				Elements: []Element{
+					NewTokenType(lexer.ItemMin),
+					NewTokenType(lexer.ItemLPar),
+					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemRPar),
 					NewTokenType(lexer.ItemAs),
 					NewTokenType(lexer.ItemBinding),
+					NewSymbol("MORE_VARS"),
 				},
 			},
-			{},
-		},
-		"MORE_VARS": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemComma),
-					NewSymbol("VARS"),
+					NewTokenType(lexer.ItemMax),
+					NewTokenType(lexer.ItemLPar),
+					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemRPar),
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("MORE_VARS"),
 				},
 			},
-			{},
-		},
-		"GRAPHS": []*Clause{
 			{
 				Elements: []Element{
+					NewTokenType(lexer.ItemGroupConcat),
+					NewTokenType(lexer.ItemLPar),
 					NewTokenType(lexer.ItemBinding),
-					NewSymbol("MORE_GRAPHS"),
+					NewSymbol("GROUP_CONCAT_SEPARATOR"),
+					NewTokenType(lexer.ItemRPar),
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("MORE_VARS"),
 				},
 			},
-		},
-		"MORE_GRAPHS": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemComma),
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("SCALAR_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
+					NewSymbol("SCALAR_OPERATION"),
+					NewTokenType(lexer.ItemAs),
 					NewTokenType(lexer.ItemBinding),
-					NewSymbol("MORE_GRAPHS"),
+					NewSymbol("MORE_VARS"),
 				},
 			},
-			{},
-		},
-		"WHERE": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemWhere),
-					NewTokenType(lexer.ItemLBracket),
-					NewSymbol("CLAUSES"),
-					NewTokenType(lexer.ItemRBracket),
+					NewTokenType(lexer.ItemLower),
+					NewSymbol("SCALAR_ARGUMENTS"),
+					NewSymbol("SCALAR_OPERATION"),
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("MORE_VARS"),
 				},
 			},
-		},
-		"CLAUSES": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemNode),
-					NewSymbol("SUBJECT_EXTRACT"),
-					NewSymbol("PREDICATE"),
-					NewSymbol("OBJECT"),
-					NewSymbol("MORE_CLAUSES"),
+					NewTokenType(lexer.ItemStrlen),
+					NewSymbol("SCALAR_ARGUMENTS"),
+					NewSymbol("SCALAR_OPERATION"),
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("MORE_VARS"),
 				},
 			},
 			{
 				Elements: []Element{
+					NewTokenType(lexer.ItemSubstr),
+					NewSymbol("SCALAR_ARGUMENTS"),
+					NewSymbol("SCALAR_OPERATION"),
+					NewTokenType(lexer.ItemAs),
 					NewTokenType(lexer.ItemBinding),
-					NewSymbol("SUBJECT_EXTRACT"),
-					NewSymbol("PREDICATE"),
-					NewSymbol("OBJECT"),
-					NewSymbol("MORE_CLAUSES"),
+					NewSymbol("MORE_VARS"),
 				},
 			},
-		},
-		"SUBJECT_EXTRACT": []*Clause{
 			{
 				Elements: []Element{
+					NewTokenType(lexer.ItemConcat),
+					NewSymbol("SCALAR_ARGUMENTS"),
+					NewSymbol("SCALAR_OPERATION"),
 					NewTokenType(lexer.ItemAs),
 					NewTokenType(lexer.ItemBinding),
-					NewSymbol("SUBJECT_TYPE"),
-					NewSymbol("SUBJECT_ID"),
+					NewSymbol("MORE_VARS"),
 				},
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemType),
+					NewTokenType(lexer.ItemAbs),
+					NewSymbol("SCALAR_ARGUMENTS"),
+					NewSymbol("SCALAR_OPERATION"),
+					NewTokenType(lexer.ItemAs),
 					NewTokenType(lexer.ItemBinding),
-					NewSymbol("SUBJECT_ID"),
+					NewSymbol("MORE_VARS"),
 				},
 			},
+		},
+		"SCALAR_EXPRESSION": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemID),
 					NewTokenType(lexer.ItemBinding),
+					NewSymbol("SCALAR_OPERATION"),
 				},
 			},
-			{},
-		},
-		"SUBJECT_TYPE": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemType),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemLiteral),
+					NewSymbol("SCALAR_OPERATION"),
 				},
 			},
-			{},
-		},
-		"SUBJECT_ID": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemID),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemString),
+					NewSymbol("SCALAR_OPERATION"),
 				},
 			},
-			{},
-		},
-		"PREDICATE": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemPredicate),
-					NewSymbol("PREDICATE_AS"),
-					NewSymbol("PREDICATE_ID"),
-					NewSymbol("PREDICATE_AT"),
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("SCALAR_EXPRESSION"),
+					NewTokenType(lexer.ItemRPar),
+					NewSymbol("SCALAR_OPERATION"),
 				},
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemPredicateBound),
-					NewSymbol("PREDICATE_AS"),
-					NewSymbol("PREDICATE_ID"),
-					NewSymbol("PREDICATE_BOUND_AT"),
+					NewTokenType(lexer.ItemLower),
+					NewSymbol("SCALAR_ARGUMENTS"),
+					NewSymbol("SCALAR_OPERATION"),
 				},
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemBinding),
-					NewSymbol("PREDICATE_AS"),
-					NewSymbol("PREDICATE_ID"),
-					NewSymbol("PREDICATE_AT"),
+					NewTokenType(lexer.ItemStrlen),
+					NewSymbol("SCALAR_ARGUMENTS"),
+					NewSymbol("SCALAR_OPERATION"),
 				},
 			},
-		},
-		"PREDICATE_AS": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemAs),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemSubstr),
+					NewSymbol("SCALAR_ARGUMENTS"),
+					NewSymbol("SCALAR_OPERATION"),
 				},
 			},
-			{},
-		},
-		"PREDICATE_ID": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemID),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemConcat),
+					NewSymbol("SCALAR_ARGUMENTS"),
+					NewSymbol("SCALAR_OPERATION"),
 				},
 			},
-			{},
-		},
-		"PREDICATE_AT": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemAt),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemAbs),
+					NewSymbol("SCALAR_ARGUMENTS"),
+					NewSymbol("SCALAR_OPERATION"),
 				},
 			},
-			{},
+		},
+		"SCALAR_OPERATION": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemPlus),
+					NewSymbol("SCALAR_EXPRESSION"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemMinus),
+					NewSymbol("SCALAR_EXPRESSION"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemMul),
+					NewSymbol("SCALAR_EXPRESSION"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDiv),
+					NewSymbol("SCALAR_EXPRESSION"),
+				},
+			},
+			{},
+		},
+		"SCALAR_ARGUMENTS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("SCALAR_EXPRESSION"),
+					NewSymbol("MORE_SCALAR_ARGUMENTS"),
+					NewTokenType(lexer.ItemRPar),
+				},
+			},
+		},
+		"MORE_SCALAR_ARGUMENTS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemComma),
+					NewSymbol("SCALAR_EXPRESSION"),
+					NewSymbol("MORE_SCALAR_ARGUMENTS"),
+				},
+			},
+			{},
+		},
+		"GROUP_CONCAT_SEPARATOR": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemComma),
+					NewTokenType(lexer.ItemString),
+				},
+			},
+			{},
+		},
+		"COUNT_DISTINCT": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDistinct),
+				},
+			},
+			{},
+		},
+		"VARS_AS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"MORE_VARS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemComma),
+					NewSymbol("VARS"),
+				},
+			},
+			{},
+		},
+		"GRAPHS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("MORE_GRAPHS"),
+				},
+			},
+		},
+		"MORE_GRAPHS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemComma),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("MORE_GRAPHS"),
+				},
+			},
+			{},
+		},
+		"FROM_GRAPHS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("GRAPH_SNAPSHOT"),
+					NewSymbol("MORE_FROM_GRAPHS"),
+				},
+			},
+		},
+		"MORE_FROM_GRAPHS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemComma),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("GRAPH_SNAPSHOT"),
+					NewSymbol("MORE_FROM_GRAPHS"),
+				},
+			},
+			{},
+		},
+		"GRAPH_SNAPSHOT": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemOf),
+					NewTokenType(lexer.ItemSnapshot),
+					NewTokenType(lexer.ItemString),
+				},
+			},
+			{},
+		},
+		"WHERE": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemWhere),
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("CLAUSES"),
+					NewTokenType(lexer.ItemRBracket),
+				},
+			},
+		},
+		"CLAUSES": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemNode),
+					NewSymbol("SUBJECT_EXTRACT"),
+					NewSymbol("PREDICATE"),
+					NewSymbol("OBJECT"),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("SUBJECT_EXTRACT"),
+					NewSymbol("PREDICATE"),
+					NewSymbol("OBJECT"),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemOptional),
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("OPTIONAL_CLAUSES"),
+					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("UNION_CLAUSES"),
+					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("UNION_ALTERNATIVES"),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemFilter),
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("FILTER_CLAUSE"),
+					NewTokenType(lexer.ItemRPar),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("SUBQUERY"),
+					NewTokenType(lexer.ItemRPar),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemExists),
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("EXISTS_CLAUSES"),
+					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemNot),
+					NewTokenType(lexer.ItemExists),
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("EXISTS_CLAUSES"),
+					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("MORE_CLAUSES"),
+				},
+			},
+		},
+		"SUBQUERY": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemQuery),
+					NewSymbol("VARS"),
+					NewTokenType(lexer.ItemFrom),
+					NewSymbol("FROM_GRAPHS"),
+					NewSymbol("WHERE"),
+					NewSymbol("GROUP_BY"),
+					NewSymbol("ORDER_BY"),
+					NewSymbol("HAVING"),
+					NewSymbol("GLOBAL_TIME_BOUND"),
+					NewSymbol("LIMIT"),
+					NewSymbol("OFFSET"),
+				},
+			},
+		},
+		"OPTIONAL_CLAUSES": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemNode),
+					NewSymbol("SUBJECT_EXTRACT"),
+					NewSymbol("PREDICATE"),
+					NewSymbol("OBJECT"),
+					NewSymbol("MORE_OPTIONAL_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("SUBJECT_EXTRACT"),
+					NewSymbol("PREDICATE"),
+					NewSymbol("OBJECT"),
+					NewSymbol("MORE_OPTIONAL_CLAUSES"),
+				},
+			},
+		},
+		"EXISTS_CLAUSES": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemNode),
+					NewSymbol("SUBJECT_EXTRACT"),
+					NewSymbol("PREDICATE"),
+					NewSymbol("OBJECT"),
+					NewSymbol("MORE_EXISTS_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("SUBJECT_EXTRACT"),
+					NewSymbol("PREDICATE"),
+					NewSymbol("OBJECT"),
+					NewSymbol("MORE_EXISTS_CLAUSES"),
+				},
+			},
+		},
+		"UNION_CLAUSES": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemNode),
+					NewSymbol("SUBJECT_EXTRACT"),
+					NewSymbol("PREDICATE"),
+					NewSymbol("OBJECT"),
+					NewSymbol("MORE_UNION_CLAUSES"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("SUBJECT_EXTRACT"),
+					NewSymbol("PREDICATE"),
+					NewSymbol("OBJECT"),
+					NewSymbol("MORE_UNION_CLAUSES"),
+				},
+			},
+		},
+		"UNION_ALTERNATIVES": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemUnion),
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("UNION_CLAUSES"),
+					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("MORE_UNION_ALTERNATIVES"),
+				},
+			},
+		},
+		"MORE_UNION_ALTERNATIVES": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemUnion),
+					NewTokenType(lexer.ItemLBracket),
+					NewSymbol("UNION_CLAUSES"),
+					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("MORE_UNION_ALTERNATIVES"),
+				},
+			},
+			{},
+		},
+		"SUBJECT_EXTRACT": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("SUBJECT_TYPE"),
+					NewSymbol("SUBJECT_ID"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemType),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("SUBJECT_ID"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemID),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"SUBJECT_TYPE": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemType),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"SUBJECT_ID": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemID),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"PREDICATE": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemPredicate),
+					NewSymbol("PREDICATE_PATH"),
+					NewSymbol("PREDICATE_AS"),
+					NewSymbol("PREDICATE_ID"),
+					NewSymbol("PREDICATE_AT"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemPredicateBound),
+					NewSymbol("PREDICATE_PATH"),
+					NewSymbol("PREDICATE_AS"),
+					NewSymbol("PREDICATE_ID"),
+					NewSymbol("PREDICATE_BOUND_AT"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("PREDICATE_AS"),
+					NewSymbol("PREDICATE_ID"),
+					NewSymbol("PREDICATE_AT"),
+				},
+			},
+		},
+		"PREDICATE_PATH": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemPlus),
+					NewSymbol("PREDICATE_PATH_BINDINGS"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemMul),
+					NewSymbol("PREDICATE_PATH_BINDINGS"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemLBracket),
+					NewTokenType(lexer.ItemLiteral),
+					NewTokenType(lexer.ItemComma),
+					NewSymbol("PREDICATE_PATH_MAX"),
+					NewTokenType(lexer.ItemRBracket),
+					NewSymbol("PREDICATE_PATH_BINDINGS"),
+				},
+			},
+			{},
+		},
+		"PREDICATE_PATH_BINDINGS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemShortest),
+					NewSymbol("PREDICATE_PATH_BINDINGS"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemPath),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("PREDICATE_PATH_BINDINGS"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemHops),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("PREDICATE_PATH_BINDINGS"),
+				},
+			},
+			{},
+		},
+		"PREDICATE_PATH_MAX": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemLiteral),
+				},
+			},
+			{},
+		},
+		"PREDICATE_AS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"PREDICATE_ID": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemID),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"PREDICATE_AT": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAt),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
 		},
 		"PREDICATE_BOUND_AT": []*Clause{
 			{
 				Elements: []Element{
 					NewTokenType(lexer.ItemAt),
-					NewSymbol("PREDICATE_BOUND_AT_BINDINGS"),
+					NewSymbol("PREDICATE_BOUND_AT_BINDINGS"),
+				},
+			},
+			{},
+		},
+		"PREDICATE_BOUND_AT_BINDINGS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("PREDICATE_BOUND_AT_BINDINGS_END"),
+				},
+			},
+			{},
+		},
+		"PREDICATE_BOUND_AT_BINDINGS_END": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemComma),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"OBJECT": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemLiteral),
+					NewSymbol("OBJECT_LITERAL_AS"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemNode),
+					NewSymbol("OBJECT_SUBJECT_EXTRACT"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemPredicate),
+					NewSymbol("OBJECT_PREDICATE_AS"),
+					NewSymbol("OBJECT_PREDICATE_ID"),
+					NewSymbol("OBJECT_PREDICATE_AT"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemPredicateBound),
+					NewSymbol("OBJECT_PREDICATE_AS"),
+					NewSymbol("OBJECT_PREDICATE_ID"),
+					NewSymbol("OBJECT_PREDICATE_BOUND_AT"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("OBJECT_LITERAL_BINDING_AS"),
+					NewSymbol("OBJECT_LITERAL_BINDING_TYPE"),
+					NewSymbol("OBJECT_LITERAL_BINDING_ID"),
+					NewSymbol("OBJECT_LITERAL_BINDING_AT"),
+				},
+			},
+		},
+		"OBJECT_SUBJECT_EXTRACT": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("OBJECT_SUBJECT_TYPE"),
+					NewSymbol("OBJECT_SUBJECT_ID"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemType),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("OBJECT_SUBJECT_ID"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemID),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"OBJECT_SUBJECT_TYPE": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemType),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"OBJECT_SUBJECT_ID": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemID),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"OBJECT_PREDICATE_AS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"OBJECT_PREDICATE_ID": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemID),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"OBJECT_PREDICATE_AT": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAt),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"OBJECT_PREDICATE_BOUND_AT": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAt),
+					NewSymbol("OBJECT_PREDICATE_BOUND_AT_BINDINGS"),
+				},
+			},
+			{},
+		},
+		"OBJECT_PREDICATE_BOUND_AT_BINDINGS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("OBJECT_PREDICATE_BOUND_AT_BINDINGS_END"),
+				},
+			},
+			{},
+		},
+		"OBJECT_PREDICATE_BOUND_AT_BINDINGS_END": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemComma),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"OBJECT_LITERAL_AS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"OBJECT_LITERAL_BINDING_AS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"OBJECT_LITERAL_BINDING_TYPE": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemType),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"OBJECT_LITERAL_BINDING_ID": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemID),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"OBJECT_LITERAL_BINDING_AT": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAt),
+					NewTokenType(lexer.ItemBinding),
+				},
+			},
+			{},
+		},
+		"MORE_CLAUSES": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDot),
+					NewSymbol("CLAUSES"),
+				},
+			},
+			{},
+		},
+		"MORE_OPTIONAL_CLAUSES": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDot),
+					NewSymbol("OPTIONAL_CLAUSES"),
+				},
+			},
+			{},
+		},
+		"MORE_EXISTS_CLAUSES": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDot),
+					NewSymbol("EXISTS_CLAUSES"),
+				},
+			},
+			{},
+		},
+		"MORE_UNION_CLAUSES": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDot),
+					NewSymbol("UNION_CLAUSES"),
+				},
+			},
+			{},
+		},
+		"GROUP_BY": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemGroup),
+					NewTokenType(lexer.ItemBy),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("GROUP_BY_BINDINGS"),
+				},
+			},
+			{},
+		},
+		"GROUP_BY_BINDINGS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemComma),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("GROUP_BY_BINDINGS"),
+				},
+			},
+			{},
+		},
+		"ORDER_BY": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemOrder),
+					NewTokenType(lexer.ItemBy),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("ORDER_BY_DIRECTION"),
+					NewSymbol("ORDER_BY_BINDINGS"),
+				},
+			},
+			{},
+		},
+		"ORDER_BY_DIRECTION": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAsc),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemDesc),
+				},
+			},
+			{},
+		},
+		"ORDER_BY_BINDINGS": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemComma),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("ORDER_BY_DIRECTION"),
+					NewSymbol("ORDER_BY_BINDINGS"),
+				},
+			},
+			{},
+		},
+		"HAVING": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemHaving),
+					NewSymbol("HAVING_CLAUSE"),
+				},
+			},
+			{},
+		},
+		"HAVING_CLAUSE": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("HAVING_CLAUSE_BINARY_COMPOSITE"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemNot),
+					NewSymbol("HAVING_CLAUSE"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("HAVING_CLAUSE"),
+					NewTokenType(lexer.ItemRPar),
+					NewSymbol("HAVING_CLAUSE_BINARY_COMPOSITE"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemContains),
+					NewTokenType(lexer.ItemLPar),
+					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemComma),
+					NewTokenType(lexer.ItemString),
+					NewTokenType(lexer.ItemRPar),
+					NewSymbol("HAVING_CLAUSE_BINARY_COMPOSITE"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemStartsWith),
+					NewTokenType(lexer.ItemLPar),
+					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemComma),
+					NewTokenType(lexer.ItemString),
+					NewTokenType(lexer.ItemRPar),
+					NewSymbol("HAVING_CLAUSE_BINARY_COMPOSITE"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemRegex),
+					NewTokenType(lexer.ItemLPar),
+					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemComma),
+					NewTokenType(lexer.ItemString),
+					NewTokenType(lexer.ItemRPar),
+					NewSymbol("HAVING_CLAUSE_BINARY_COMPOSITE"),
+				},
+			},
+		},
+		"HAVING_CLAUSE_BINARY_COMPOSITE": []*Clause{
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemAnd),
+					NewSymbol("HAVING_CLAUSE"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemOr),
+					NewSymbol("HAVING_CLAUSE"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemEQ),
+					NewSymbol("HAVING_CLAUSE"),
+				},
+			},
+			{
+				Elements: []Element{
+					NewTokenType(lexer.ItemLT),
+					NewSymbol("HAVING_CLAUSE"),
 				},
 			},
-			{},
-		},
-		"PREDICATE_BOUND_AT_BINDINGS": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemBinding),
-					NewSymbol("PREDICATE_BOUND_AT_BINDINGS_END"),
+					NewTokenType(lexer.ItemGT),
+					NewSymbol("HAVING_CLAUSE"),
 				},
 			},
 			{},
 		},
-		"PREDICATE_BOUND_AT_BINDINGS_END": []*Clause{
+		"FILTER_CLAUSE": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemComma),
 					NewTokenType(lexer.ItemBinding),
+					NewSymbol("FILTER_CLAUSE_BINARY_COMPOSITE"),
 				},
 			},
-			{},
-		},
-		"OBJECT": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemLiteral),
-					NewSymbol("OBJECT_LITERAL_AS"),
+					NewTokenType(lexer.ItemNode),
+					NewSymbol("FILTER_CLAUSE_BINARY_COMPOSITE"),
 				},
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemNode),
-					NewSymbol("OBJECT_SUBJECT_EXTRACT"),
+					NewTokenType(lexer.ItemPredicate),
+					NewSymbol("FILTER_CLAUSE_BINARY_COMPOSITE"),
 				},
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemPredicate),
-					NewSymbol("OBJECT_PREDICATE_AS"),
-					NewSymbol("OBJECT_PREDICATE_ID"),
-					NewSymbol("OBJECT_PREDICATE_AT"),
+					NewTokenType(lexer.ItemLiteral),
+					NewSymbol("FILTER_CLAUSE_BINARY_COMPOSITE"),
 				},
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemPredicateBound),
-					NewSymbol("OBJECT_PREDICATE_AS"),
-					NewSymbol("OBJECT_PREDICATE_ID"),
-					NewSymbol("OBJECT_PREDICATE_BOUND_AT"),
+					NewTokenType(lexer.ItemNot),
+					NewSymbol("FILTER_CLAUSE"),
 				},
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemBinding),
-					NewSymbol("OBJECT_LITERAL_BINDING_AS"),
-					NewSymbol("OBJECT_LITERAL_BINDING_TYPE"),
-					NewSymbol("OBJECT_LITERAL_BINDING_ID"),
-					NewSymbol("OBJECT_LITERAL_BINDING_AT"),
+					NewTokenType(lexer.ItemLPar),
+					NewSymbol("FILTER_CLAUSE"),
+					NewTokenType(lexer.ItemRPar),
+					NewSymbol("FILTER_CLAUSE_BINARY_COMPOSITE"),
 				},
 			},
-		},
-		"OBJECT_SUBJECT_EXTRACT": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemAs),
+					NewTokenType(lexer.ItemContains),
+					NewTokenType(lexer.ItemLPar),
 					NewTokenType(lexer.ItemBinding),
-					NewSymbol("OBJECT_SUBJECT_TYPE"),
-					NewSymbol("OBJECT_SUBJECT_ID"),
+					NewTokenType(lexer.ItemComma),
+					NewTokenType(lexer.ItemString),
+					NewTokenType(lexer.ItemRPar),
+					NewSymbol("FILTER_CLAUSE_BINARY_COMPOSITE"),
 				},
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemType),
+					NewTokenType(lexer.ItemStartsWith),
+					NewTokenType(lexer.ItemLPar),
 					NewTokenType(lexer.ItemBinding),
-					NewSymbol("OBJECT_SUBJECT_ID"),
+					NewTokenType(lexer.ItemComma),
+					NewTokenType(lexer.ItemString),
+					NewTokenType(lexer.ItemRPar),
+					NewSymbol("FILTER_CLAUSE_BINARY_COMPOSITE"),
 				},
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemID),
+					NewTokenType(lexer.ItemRegex),
+					NewTokenType(lexer.ItemLPar),
 					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemComma),
+					NewTokenType(lexer.ItemString),
+					NewTokenType(lexer.ItemRPar),
+					NewSymbol("FILTER_CLAUSE_BINARY_COMPOSITE"),
 				},
 			},
-			{},
 		},
-		"OBJECT_SUBJECT_TYPE": []*Clause{
+		"FILTER_CLAUSE_BINARY_COMPOSITE": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemType),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemAnd),
+					NewSymbol("FILTER_CLAUSE"),
 				},
 			},
-			{},
-		},
-		"OBJECT_SUBJECT_ID": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemID),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemOr),
+					NewSymbol("FILTER_CLAUSE"),
 				},
 			},
-			{},
-		},
-		"OBJECT_PREDICATE_AS": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemAs),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemEQ),
+					NewSymbol("FILTER_CLAUSE"),
 				},
 			},
-			{},
-		},
-		"OBJECT_PREDICATE_ID": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemID),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemLT),
+					NewSymbol("FILTER_CLAUSE"),
 				},
 			},
-			{},
-		},
-		"OBJECT_PREDICATE_AT": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemAt),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemGT),
+					NewSymbol("FILTER_CLAUSE"),
 				},
 			},
 			{},
 		},
-		"OBJECT_PREDICATE_BOUND_AT": []*Clause{
+		"GLOBAL_TIME_BOUND": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemAt),
-					NewSymbol("OBJECT_PREDICATE_BOUND_AT_BINDINGS"),
+					NewTokenType(lexer.ItemBefore),
+					NewTokenType(lexer.ItemPredicate),
 				},
 			},
-			{},
-		},
-		"OBJECT_PREDICATE_BOUND_AT_BINDINGS": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemBinding),
-					NewSymbol("OBJECT_PREDICATE_BOUND_AT_BINDINGS_END"),
+					NewTokenType(lexer.ItemAfter),
+					NewTokenType(lexer.ItemPredicate),
 				},
 			},
-			{},
-		},
-		"OBJECT_PREDICATE_BOUND_AT_BINDINGS_END": []*Clause{
 			{
 				Elements: []Element{
+					NewTokenType(lexer.ItemBetween),
+					NewTokenType(lexer.ItemPredicate),
 					NewTokenType(lexer.ItemComma),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemPredicate),
 				},
 			},
 			{},
 		},
-		"OBJECT_LITERAL_AS": []*Clause{
+		"LIMIT": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemAs),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemLimit),
+					NewTokenType(lexer.ItemLiteral),
 				},
 			},
 			{},
 		},
-		"OBJECT_LITERAL_BINDING_AS": []*Clause{
+		"DESCRIBE_DEPTH": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemAs),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemDepth),
+					NewTokenType(lexer.ItemLiteral),
 				},
 			},
 			{},
 		},
-		"OBJECT_LITERAL_BINDING_TYPE": []*Clause{
+		"EXPLAIN_ANALYZE": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemType),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemAnalyze),
 				},
 			},
 			{},
 		},
-		"OBJECT_LITERAL_BINDING_ID": []*Clause{
+		"OFFSET": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemID),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemOffset),
+					NewTokenType(lexer.ItemLiteral),
 				},
 			},
 			{},
 		},
-		"OBJECT_LITERAL_BINDING_AT": []*Clause{
+		"CURSOR": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemAt),
-					NewTokenType(lexer.ItemBinding),
+					NewTokenType(lexer.ItemCursor),
+					NewTokenType(lexer.ItemString),
 				},
 			},
 			{},
 		},
-		"MORE_CLAUSES": []*Clause{
+		"CONSTRUCT_TRIPLES": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemDot),
-					NewSymbol("CLAUSES"),
+					NewTokenType(lexer.ItemNode),
+					NewSymbol("CONSTRUCT_PREDICATE"),
+					NewSymbol("CONSTRUCT_OBJECT"),
+					NewSymbol("MORE_CONSTRUCT_TRIPLES"),
 				},
 			},
-			{},
-		},
-		"GROUP_BY": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemGroup),
-					NewTokenType(lexer.ItemBy),
 					NewTokenType(lexer.ItemBinding),
-					NewSymbol("GROUP_BY_BINDINGS"),
+					NewSymbol("CONSTRUCT_PREDICATE"),
+					NewSymbol("CONSTRUCT_OBJECT"),
+					NewSymbol("MORE_CONSTRUCT_TRIPLES"),
 				},
 			},
-			{},
 		},
-		"GROUP_BY_BINDINGS": []*Clause{
+		"CONSTRUCT_PREDICATE": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemComma),
-					NewTokenType(lexer.ItemBinding),
-					NewSymbol("GROUP_BY_BINDINGS"),
+					NewTokenType(lexer.ItemPredicate),
 				},
 			},
-			{},
-		},
-		"ORDER_BY": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemOrder),
-					NewTokenType(lexer.ItemBy),
 					NewTokenType(lexer.ItemBinding),
-					NewSymbol("ORDER_BY_DIRECTION"),
-					NewSymbol("ORDER_BY_BINDINGS"),
 				},
 			},
-			{},
 		},
-		"ORDER_BY_DIRECTION": []*Clause{
+		"CONSTRUCT_OBJECT": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemAsc),
+					NewTokenType(lexer.ItemNode),
 				},
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemDesc),
+					NewTokenType(lexer.ItemPredicate),
 				},
 			},
-			{},
-		},
-		"ORDER_BY_BINDINGS": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemComma),
-					NewTokenType(lexer.ItemBinding),
-					NewSymbol("ORDER_BY_DIRECTION"),
-					NewSymbol("ORDER_BY_BINDINGS"),
+					NewTokenType(lexer.ItemLiteral),
 				},
 			},
-			{},
-		},
-		"HAVING": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemHaving),
-					NewSymbol("HAVING_CLAUSE"),
+					NewTokenType(lexer.ItemBinding),
 				},
 			},
-			{},
 		},
-		"HAVING_CLAUSE": []*Clause{
+		"MORE_CONSTRUCT_TRIPLES": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemBinding),
-					NewSymbol("HAVING_CLAUSE_BINARY_COMPOSITE"),
+					NewTokenType(lexer.ItemDot),
+					NewSymbol("CONSTRUCT_TRIPLES"),
 				},
 			},
+			{},
+		},
+		"DELETE_TEMPLATE_TRIPLES": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemNot),
-					NewSymbol("HAVING_CLAUSE"),
+					NewTokenType(lexer.ItemNode),
+					NewSymbol("DELETE_TEMPLATE_PREDICATE"),
+					NewSymbol("DELETE_TEMPLATE_OBJECT"),
+					NewSymbol("MORE_DELETE_TEMPLATE_TRIPLES"),
 				},
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemLPar),
-					NewSymbol("HAVING_CLAUSE"),
-					NewTokenType(lexer.ItemRPar),
-					NewSymbol("HAVING_CLAUSE_BINARY_COMPOSITE"),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("DELETE_TEMPLATE_PREDICATE"),
+					NewSymbol("DELETE_TEMPLATE_OBJECT"),
+					NewSymbol("MORE_DELETE_TEMPLATE_TRIPLES"),
 				},
 			},
 		},
-		"HAVING_CLAUSE_BINARY_COMPOSITE": []*Clause{
+		"DELETE_TEMPLATE_PREDICATE": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemAnd),
-					NewSymbol("HAVING_CLAUSE"),
+					NewTokenType(lexer.ItemPredicate),
 				},
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemOr),
-					NewSymbol("HAVING_CLAUSE"),
+					NewTokenType(lexer.ItemBinding),
 				},
 			},
+		},
+		"DELETE_TEMPLATE_OBJECT": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemEQ),
-					NewSymbol("HAVING_CLAUSE"),
+					NewTokenType(lexer.ItemNode),
 				},
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemLT),
-					NewSymbol("HAVING_CLAUSE"),
+					NewTokenType(lexer.ItemPredicate),
 				},
 			},
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemGT),
-					NewSymbol("HAVING_CLAUSE"),
+					NewTokenType(lexer.ItemLiteral),
 				},
 			},
-			{},
-		},
-		"GLOBAL_TIME_BOUND": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemBefore),
-					NewTokenType(lexer.ItemPredicate),
+					NewTokenType(lexer.ItemBinding),
 				},
 			},
+		},
+		"MORE_DELETE_TEMPLATE_TRIPLES": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemAfter),
-					NewTokenType(lexer.ItemPredicate),
+					NewTokenType(lexer.ItemDot),
+					NewSymbol("DELETE_TEMPLATE_TRIPLES"),
 				},
 			},
+			{},
+		},
+		"CONSTRUCT_INTO": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemBetween),
-					NewTokenType(lexer.ItemPredicate),
-					NewTokenType(lexer.ItemComma),
-					NewTokenType(lexer.ItemPredicate),
+					NewTokenType(lexer.ItemInto),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("MORE_INTO_GRAPHS"),
 				},
 			},
 			{},
 		},
-		"LIMIT": []*Clause{
+		"MORE_INTO_GRAPHS": []*Clause{
 			{
 				Elements: []Element{
-					NewTokenType(lexer.ItemLimit),
-					NewTokenType(lexer.ItemLiteral),
+					NewTokenType(lexer.ItemComma),
+					NewTokenType(lexer.ItemBinding),
+					NewSymbol("MORE_INTO_GRAPHS"),
 				},
 			},
 			{},
@@ -780,6 +1751,34 @@ func setClauseHook(symbols []semantic.Symbol, start, end semantic.ClauseHook) {
 
 type condition func(*Clause) bool
 
+// startsWith returns a condition that only accepts clauses that start with the
+// provided token type.
+func startsWith(tt lexer.TokenType) condition {
+	return func(cls *Clause) bool {
+		return len(cls.Elements) > 0 && !cls.Elements[0].isSymbol && cls.Elements[0].Token() == tt
+	}
+}
+
+func setClauseStartHook(symbols []semantic.Symbol, start semantic.ClauseHook, cnd condition) {
+	for _, sym := range symbols {
+		for _, cls := range (*semanticBQL)[sym] {
+			if cnd == nil || cnd(cls) {
+				cls.ProcessStart = start
+			}
+		}
+	}
+}
+
+func setClauseEndHook(symbols []semantic.Symbol, end semantic.ClauseHook, cnd condition) {
+	for _, sym := range symbols {
+		for _, cls := range (*semanticBQL)[sym] {
+			if cnd == nil || cnd(cls) {
+				cls.ProcessEnd = end
+			}
+		}
+	}
+}
+
 func setElementHook(symbols []semantic.Symbol, hook semantic.ElementHook, cnd condition) {
 	for _, sym := range symbols {
 		for _, cls := range (*semanticBQL)[sym] {
@@ -798,9 +1797,16 @@ func initSemanticBQL() {
 	setClauseHook([]semantic.Symbol{"CREATE_GRAPHS"}, nil, semantic.TypeBindingClauseHook(semantic.Create))
 	setClauseHook([]semantic.Symbol{"DROP_GRAPHS"}, nil, semantic.TypeBindingClauseHook(semantic.Drop))
 
+	// Create and Drop snapshot semantic hooks for type and name.
+	snapshotSymbols := []semantic.Symbol{"CREATE_GRAPHS", "DROP_GRAPHS"}
+	setElementHook(snapshotSymbols, semantic.SnapshotNameHook(), startsWith(lexer.ItemSnapshot))
+	setClauseEndHook([]semantic.Symbol{"CREATE_GRAPHS"}, semantic.TypeBindingClauseHook(semantic.CreateSnapshot), startsWith(lexer.ItemSnapshot))
+	setClauseEndHook([]semantic.Symbol{"DROP_GRAPHS"}, semantic.TypeBindingClauseHook(semantic.DropSnapshot), startsWith(lexer.ItemSnapshot))
+
 	// Add graph binding collection to GRAPHS and MORE_GRAPHS clauses.
-	graphSymbols := []semantic.Symbol{"GRAPHS", "MORE_GRAPHS"}
+	graphSymbols := []semantic.Symbol{"GRAPHS", "MORE_GRAPHS", "FROM_GRAPHS", "MORE_FROM_GRAPHS"}
 	setElementHook(graphSymbols, semantic.GraphAccumulatorHook(), nil)
+	setElementHook([]semantic.Symbol{"GRAPH_SNAPSHOT"}, semantic.GraphSnapshotHook(), nil)
 
 	// Insert and Delete semantic hooks addition.
 	insertSymbols := []semantic.Symbol{
@@ -810,24 +1816,56 @@ func initSemanticBQL() {
 	setClauseHook([]semantic.Symbol{"INSERT_OBJECT"}, nil, semantic.TypeBindingClauseHook(semantic.Insert))
 	setClauseHook([]semantic.Symbol{"DELETE_OBJECT"}, nil, semantic.TypeBindingClauseHook(semantic.Delete))
 
+	// Construct semantic hooks for template and target graphs.
+	templateSymbols := []semantic.Symbol{
+		"CONSTRUCT_TRIPLES", "CONSTRUCT_PREDICATE", "CONSTRUCT_OBJECT", "MORE_CONSTRUCT_TRIPLES",
+	}
+	setElementHook(templateSymbols, semantic.TemplateAccumulatorHook(), nil)
+	setElementHook([]semantic.Symbol{"CONSTRUCT_INTO", "MORE_INTO_GRAPHS"}, semantic.IntoGraphAccumulatorHook(), nil)
+
+	// Show semantic hooks for type.
+	setClauseStartHook([]semantic.Symbol{"SHOW_STATEMENT"}, semantic.TypeBindingClauseHook(semantic.ShowGraphs), startsWith(lexer.ItemGraphs))
+	setClauseStartHook([]semantic.Symbol{"SHOW_STATEMENT"}, semantic.TypeBindingClauseHook(semantic.ShowStats), startsWith(lexer.ItemStats))
+
+	// Update semantic hooks for type and the template of removed triples.
+	updateSymbols := []semantic.Symbol{"INSERT_STATEMENT", "DELETE_STATEMENT"}
+	setClauseStartHook(updateSymbols, semantic.TypeBindingClauseHook(semantic.Update), startsWith(lexer.ItemLBracket))
+	deleteTemplateSymbols := []semantic.Symbol{
+		"DELETE_TEMPLATE_TRIPLES", "DELETE_TEMPLATE_PREDICATE", "DELETE_TEMPLATE_OBJECT",
+		"MORE_DELETE_TEMPLATE_TRIPLES",
+	}
+	setElementHook(deleteTemplateSymbols, semantic.DeleteTemplateAccumulatorHook(), nil)
+
 	// Query semantic hooks.
 	setClauseHook([]semantic.Symbol{"WHERE"}, semantic.WhereInitWorkingClauseHook(), semantic.VarBindingsGraphChecker())
 
 	clauseSymbols := []semantic.Symbol{
-		"CLAUSES", "MORE_CLAUSES",
+		"CLAUSES", "MORE_CLAUSES", "OPTIONAL_CLAUSES", "MORE_OPTIONAL_CLAUSES",
+		"UNION_CLAUSES", "MORE_UNION_CLAUSES", "EXISTS_CLAUSES", "MORE_EXISTS_CLAUSES",
 	}
 	setClauseHook(clauseSymbols, semantic.WhereNextWorkingClauseHook(), semantic.WhereNextWorkingClauseHook())
 
 	subSymbols := []semantic.Symbol{
-		"CLAUSES", "SUBJECT_EXTRACT", "SUBJECT_TYPE", "SUBJECT_ID",
+		"CLAUSES", "OPTIONAL_CLAUSES", "UNION_CLAUSES", "EXISTS_CLAUSES", "SUBJECT_EXTRACT",
+		"SUBJECT_TYPE", "SUBJECT_ID",
 	}
 	setElementHook(subSymbols, semantic.WhereSubjectClauseHook(), nil)
+	setElementHook([]semantic.Symbol{"CLAUSES"}, semantic.OptionalGraphPatternHook(), startsWith(lexer.ItemOptional))
+	setElementHook([]semantic.Symbol{"CLAUSES"}, semantic.UnionGraphPatternHook(), startsWith(lexer.ItemLBracket))
+	setElementHook([]semantic.Symbol{"UNION_ALTERNATIVES", "MORE_UNION_ALTERNATIVES"}, semantic.UnionAlternativeHook(), nil)
+	setElementHook([]semantic.Symbol{"CLAUSES"}, semantic.FilterBuilderHook(), startsWith(lexer.ItemFilter))
+	setElementHook([]semantic.Symbol{"FILTER_CLAUSE", "FILTER_CLAUSE_BINARY_COMPOSITE"}, semantic.FilterExpressionHook(), nil)
+	setElementHook([]semantic.Symbol{"CLAUSES"}, nil, startsWith(lexer.ItemLPar))
+	setElementHook([]semantic.Symbol{"CLAUSES"}, semantic.ExistenceGraphPatternHook(), startsWith(lexer.ItemExists))
+	setElementHook([]semantic.Symbol{"CLAUSES"}, semantic.ExistenceGraphPatternHook(), startsWith(lexer.ItemNot))
+	setClauseHook([]semantic.Symbol{"SUBQUERY"}, semantic.SubqueryStartHook(), semantic.SubqueryEndHook())
 
 	predSymbols := []semantic.Symbol{
 		"PREDICATE", "PREDICATE_AS", "PREDICATE_ID", "PREDICATE_AT", "PREDICATE_BOUND_AT",
 		"PREDICATE_BOUND_AT_BINDINGS", "PREDICATE_BOUND_AT_BINDINGS_END",
 	}
 	setElementHook(predSymbols, semantic.WherePredicateClauseHook(), nil)
+	setElementHook([]semantic.Symbol{"PREDICATE_PATH", "PREDICATE_PATH_MAX", "PREDICATE_PATH_BINDINGS"}, semantic.PredicatePathHook(), nil)
 
 	objSymbols := []semantic.Symbol{
 		"OBJECT", "OBJECT_SUBJECT_EXTRACT", "OBJECT_SUBJECT_TYPE", "OBJECT_SUBJECT_ID",
@@ -844,6 +1882,16 @@ func initSemanticBQL() {
 		"VARS", "VARS_AS", "MORE_VARS", "COUNT_DISTINCT",
 	}
 	setElementHook(varSymbols, semantic.VarAccumulatorHook(), nil)
+	setElementHook([]semantic.Symbol{"GROUP_CONCAT_SEPARATOR"}, semantic.GroupConcatSeparatorHook(), nil)
+
+	// Collect and build the scalar expressions used in projections.
+	scalarSymbols := []semantic.Symbol{
+		"SCALAR_EXPRESSION", "SCALAR_OPERATION", "SCALAR_ARGUMENTS", "MORE_SCALAR_ARGUMENTS",
+	}
+	setElementHook(scalarSymbols, semantic.ScalarExpressionHook(), nil)
+	for _, tt := range []lexer.TokenType{lexer.ItemLPar, lexer.ItemLower, lexer.ItemStrlen, lexer.ItemSubstr, lexer.ItemConcat, lexer.ItemAbs} {
+		setElementHook([]semantic.Symbol{"VARS"}, semantic.ProjectionExpressionHook(), startsWith(tt))
+	}
 
 	// Collect and valiadate group by bindinds.
 	grpSymbols := []semantic.Symbol{"GROUP_BY", "GROUP_BY_BINDINGS"}
@@ -869,13 +1917,29 @@ func initSemanticBQL() {
 	limitSymbols := []semantic.Symbol{"LIMIT"}
 	setElementHook(limitSymbols, semantic.LimitCollection(), nil)
 
+	// OFFSET and CURSOR clauses semantic hook addition.
+	setElementHook([]semantic.Symbol{"OFFSET"}, semantic.OffsetCollection(), nil)
+	setElementHook([]semantic.Symbol{"CURSOR"}, semantic.CursorCollection(), nil)
+
 	// Global data accumulator hook.
-	setElementHook([]semantic.Symbol{"START"}, semantic.DataAccumulatorHook(),
-		func(cls *Clause) bool {
-			if t := cls.Elements[0].Token(); t != lexer.ItemInsert && t != lexer.ItemDelete {
-				return false
-			}
-			return true
-		})
+	setElementHook([]semantic.Symbol{"INSERT_STATEMENT", "DELETE_STATEMENT"}, semantic.DataAccumulatorHook(), startsWith(lexer.ItemData))
 	setClauseHook([]semantic.Symbol{"START"}, nil, semantic.GroupByBindingsChecker())
+
+	// Transaction statements semantic hooks for type.
+	setClauseStartHook([]semantic.Symbol{"START"}, semantic.TypeBindingClauseHook(semantic.Begin), startsWith(lexer.ItemBegin))
+	setClauseStartHook([]semantic.Symbol{"START"}, semantic.TypeBindingClauseHook(semantic.Commit), startsWith(lexer.ItemCommit))
+	setClauseStartHook([]semantic.Symbol{"START"}, semantic.TypeBindingClauseHook(semantic.Rollback), startsWith(lexer.ItemRollback))
+
+	// Construct, Ask, and Describe statements semantic hooks for type.
+	setClauseStartHook([]semantic.Symbol{"START"}, semantic.TypeBindingClauseHook(semantic.Construct), startsWith(lexer.ItemConstruct))
+	setClauseStartHook([]semantic.Symbol{"START"}, semantic.TypeBindingClauseHook(semantic.Ask), startsWith(lexer.ItemAsk))
+	setClauseStartHook([]semantic.Symbol{"START"}, semantic.TypeBindingClauseHook(semantic.Describe), startsWith(lexer.ItemDescribe))
+
+	// Describe statements semantic hooks for the node and depth.
+	setElementHook([]semantic.Symbol{"START"}, semantic.DescribeCollectionHook(), startsWith(lexer.ItemDescribe))
+	setElementHook([]semantic.Symbol{"DESCRIBE_DEPTH"}, semantic.DescribeCollectionHook(), nil)
+
+	// Explain statements semantic hooks for the explain and analyze flags.
+	setElementHook([]semantic.Symbol{"START"}, semantic.ExplainCollectionHook(), startsWith(lexer.ItemExplain))
+	setElementHook([]semantic.Symbol{"EXPLAIN_ANALYZE"}, semantic.ExplainCollectionHook(), nil)
 }
@@ -15,6 +15,7 @@
 package grammar
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/google/badwolf/bql/semantic"
@@ -31,6 +32,14 @@ func TestAcceptByParse(t *testing.T) {
 		`select ?a as ?b, ?c as ?d from ?e where{?s ?p ?o};`,
 		`select count(?a) as ?b, sum(?c) as ?d, ?e as ?f from ?g where{?s ?p ?o};`,
 		`select count(distinct ?a) as ?b from ?c where{?s ?p ?o};`,
+		`select avg(?a) as ?b, min(?a) as ?c, max(?a) as ?d from ?e where{?s ?p ?o};`,
+		`select group_concat(?a) as ?b, group_concat(?a, ", ") as ?c from ?d where{?s ?p ?o};`,
+		// Test scalar expressions.
+		`select (?a + ?b) as ?c from ?d where{?s ?p ?o};`,
+		`select (?a + ?b * "2"^^type:int64) / ?c as ?d, ?e from ?f where{?s ?p ?o};`,
+		`select lower(?a) as ?b, strlen(?a) - "1"^^type:int64 as ?c from ?d where{?s ?p ?o};`,
+		`select substr(?a, "1"^^type:int64, abs(?b)) as ?c from ?d where{?s ?p ?o};`,
+		`select concat(?a, " - ", (?b)) as ?c from ?d where{?s ?p ?o};`,
 		// Test multiple graphs are accepted.
 		`select ?a from ?b where{?s ?p ?o};`,
 		`select ?a from ?b, ?c where{?s ?p ?o};`,
@@ -82,6 +91,33 @@ func TestAcceptByParse(t *testing.T) {
 		`select ?a from ?b where {?s ?p ?o} between ""@["123"], ""@["123"];`,
 		// Test limit clause.
 		`select ?a from ?b where {?s ?p ?o} limit "10"^^type:int64;`,
+		// Test offset and cursor clauses.
+		`select ?a from ?b where {?s ?p ?o} offset "10"^^type:int64;`,
+		`select ?a from ?b where {?s ?p ?o} limit "10"^^type:int64 offset "10"^^type:int64 cursor "foo";`,
+		// Test subqueries.
+		`select ?a from ?b where {(select ?a from ?b where {?a ?p ?o})};`,
+		`select ?a from ?b where {?a ?p ?o . (select ?a from ?b where {?a ?p ?o} limit "1"^^type:int64 offset "1"^^type:int64) . ?o ?p ?a};`,
+		// Test construct statements.
+		`construct {?s ?p ?o} from ?b where {?s ?p ?o};`,
+		`construct {?o "parent_of"@[] ?s . /_<foo> "bar"@[] "yeah"^^type:text} from ?b where {?s "child_of"@[] ?o};`,
+		`construct {?s "bar"@[] ?o} into ?c, ?d from ?b where {?s ?p ?o} before ""@["123"] limit "10"^^type:int64;`,
+		// Test update statements.
+		`delete {?s ?p ?o} insert {?o ?p ?s} from ?b where {?s ?p ?o};`,
+		`delete {?s "bar"@[?t] ?o . /_<foo> "bar"@[] "yeah"^^type:text} from ?b, ?c where {?s "bar"@[?t] ?o};`,
+		`insert {?s "bar"@[?t] ?o} into ?b where {?s "foo"@[?t] ?o};`,
+		// Test ask statements.
+		`ask from ?b where {?s ?p ?o};`,
+		`ask from ?b, ?c where {/_<foo> "bar"@[] /_<foo>} before ""@["123"];`,
+		// Test show statements.
+		`show graphs;`,
+		`show stats for ?a;`,
+		`show stats for ?a, ?b as of snapshot "v1";`,
+		// Test describe statements.
+		`describe /_<foo> from ?b;`,
+		`describe /_<foo> from ?b, ?c as of snapshot "v1" depth "2"^^type:int64;`,
+		// Test explain statements.
+		`explain select ?a from ?b where {?a ?p ?o};`,
+		`explain analyze select ?a, count(?o) as ?n from ?b where {?a ?p ?o} group by ?a order by ?n limit "10"^^type:int64;`,
 		// Insert data.
 		`insert data into ?a {/_<foo> "bar"@["1234"] /_<foo>};`,
 		`insert data into ?a {/_<foo> "bar"@["1234"] "bar"@["1234"]};`,
@@ -113,6 +149,39 @@ func TestAcceptByParse(t *testing.T) {
 		                          /room<000> "connects_to"@[] /room<001>};`,
 		`delete data from ?world {/room<000> "named"@[] "Hallway"^^type:text.
 		                          /room<000> "connects_to"@[] /room<001>};`,
+		// Transactions.
+		`begin;`,
+		`commit;`,
+		`rollback;`,
+		// Snapshots.
+		`create snapshot "before load" of ?a;`,
+		`create snapshot "before load" of ?a, ?b;`,
+		`drop snapshot "before load" of ?a, ?b;`,
+		`select ?s from ?a as of snapshot "before load" where {?s ?p ?o};`,
+		`select ?s from ?a as of snapshot "v1", ?b, ?c as of snapshot "v2" where {?s ?p ?o};`,
+		// Optional graph patterns.
+		`select ?a, ?x from ?b where {?a ?p ?o . optional {?o ?q ?x}};`,
+		`select ?a, ?x from ?b where {?a ?p ?o . optional {?o ?q ?x . ?x ?r ?y} . ?a ?s ?t};`,
+		`select ?a, ?x from ?b where {optional {?o ?q ?x} . ?a ?p ?o . optional {/_<foo> ?q ?x}};`,
+		`select ?a from ?b where {{?a ?p ?o} union {?a ?q ?o}};`,
+		`select ?a from ?b where {?a ?p ?o . {?o ?q ?x . ?x ?r ?y} union {?o ?s ?x} union {/_<foo> ?t ?x} . ?a ?u ?v};`,
+		`select ?a, ?x from ?b where {{?a ?p ?o} union {?a ?q ?o} . optional {?o ?r ?x}};`,
+		`select ?a from ?b where {?a ?p ?o . exists {?o ?q ?x}};`,
+		`select ?a from ?b where {not exists {?o ?q ?x . ?x ?r ?y} . ?a ?p ?o . exists {/_<foo> ?q ?a}};`,
+		`select ?a from ?b where {?a ?p ?o . filter(?a = ?o)};`,
+		`select ?a from ?b where {filter(not(?a = /_<foo>)) . ?a ?p ?o};`,
+		`select ?a from ?b where {?a ?p ?o . filter((?o > "1"^^type:int64) and (?p = "foo"@[]))};`,
+		`select ?a from ?b where {?a ?p ?o . filter(contains(?a, "foo"))};`,
+		`select ?a from ?b where {?a ?p ?o . filter(not(starts_with(?p, "foo")) or regex(?o, "^f.*o$"))};`,
+		`select ?a from ?b where {?a ?p ?o} having contains(?a, "foo") and (?p = ?o);`,
+		// Test property paths.
+		`select ?a from ?b where {/_<foo> "bar"@[]+ ?a};`,
+		`select ?a from ?b where {?a "bar"@[]* /_<foo>};`,
+		`select ?a from ?b where {?a "bar"@[]{"1"^^type:int64, "3"^^type:int64} ?o};`,
+		`select ?a from ?b where {?a "bar"@[]{"2"^^type:int64,} ?o};`,
+		`select ?p, ?h from ?b where {/_<foo> "bar"@[]+ path ?p hops ?h ?a};`,
+		`select ?p from ?b where {?a "bar"@[]{"1"^^type:int64, "3"^^type:int64} shortest path ?p ?o};`,
+		`select ?h from ?b where {?a "bar"@[2015-07-19T13:12:04.669618843-07:00, 2016-07-19T13:12:04.669618843-07:00]* hops ?h ?o};`,
 	}
 	p, err := NewParser(BQL())
 	if err != nil {
@@ -127,6 +196,18 @@ func TestAcceptByParse(t *testing.T) {
 
 func TestRejectByParse(t *testing.T) {
 	table := []string{
+		// Reject invalid construct templates.
+		`construct {} from ?b where {?s ?p ?o};`,
+		`construct {"yeah"^^type:text ?p ?o} from ?b where {?s ?p ?o};`,
+		`construct {?s "yeah"^^type:text ?o} from ?b where {?s ?p ?o};`,
+		`construct {?s ?p ?o .} from ?b where {?s ?p ?o};`,
+		`construct {?s ?p ?o} into from ?b where {?s ?p ?o};`,
+		// Reject invalid update statements.
+		`delete {?s ?p ?o} insert {} from ?b where {?s ?p ?o};`,
+		`delete {?s ?p ?o} into ?b where {?s ?p ?o};`,
+		`insert {?s ?p ?o} from ?b where {?s ?p ?o};`,
+		`insert {?s ?p ?o} delete {?s ?p ?o} into ?b where {?s ?p ?o};`,
+		`delete {?s ?p ?o} from ?b;`,
 		// Reject missing comas on var bindings or missing bindings.
 		`select ?a ?wrong from ?b;`,
 		`select ?a , from ?b;`,
@@ -134,6 +215,13 @@ func TestRejectByParse(t *testing.T) {
 		`select ?a as ?b, from ?b;`,
 		`select count(?a as ?b, from ?b;`,
 		`select count(distinct) as ?a, from ?c;`,
+		`select avg(distinct ?a) as ?b from ?c where{?s ?p ?o};`,
+		`select min(?a) from ?c where{?s ?p ?o};`,
+		`select group_concat(?a, ?b) as ?c from ?d where{?s ?p ?o};`,
+		`select (?a + ?b) from ?d where{?s ?p ?o};`,
+		`select (?a + ) as ?c from ?d where{?s ?p ?o};`,
+		`select lower ?a as ?b from ?d where{?s ?p ?o};`,
+		`select concat() as ?b from ?d where{?s ?p ?o};`,
 		// Reject missing comas on var bindings or missing graphs.
 		`select ?a from ?b ?c;`,
 		`select ?a from ?b,;`,
@@ -181,6 +269,11 @@ func TestRejectByParse(t *testing.T) {
 		// Test limit clause.
 		`select ?a from ?b where {?s ?p ?o} limit ?b;`,
 		`select ?a from ?b where {?s ?p ?o} limit ;`,
+		`select ?a from ?b where {?s ?p ?o} offset "10"^^type:int64 limit "10"^^type:int64;`,
+		`select ?a from ?b where {?s ?p ?o} cursor ?a;`,
+		`select ?a from ?b where {(select ?a from ?b where {?a ?p ?o};)};`,
+		`select ?a from ?b where {(select ?a from ?b where {?a ?p ?o})} cursor "foo" limit "1"^^type:int64;`,
+		`select ?a from ?b where {optional {(select ?a from ?b where {?a ?p ?o})}};`,
 		// Insert incomplete data.
 		`insert data into ?a {"bar"@["1234"] /_<foo>};`,
 		`insert data into ?a {/_<foo> "bar"@["1234"]};`,
@@ -207,6 +300,65 @@ func TestRejectByParse(t *testing.T) {
 		// Drop graphs.
 		`drop graph ;`,
 		`drop graph ?a ?b, ?c;`,
+		// Transactions.
+		`begin`,
+		`begin ?a;`,
+		`commit graph ?a;`,
+		`rollback commit;`,
+		// Snapshots.
+		`create snapshot of ?a;`,
+		`create snapshot "v1" ?a;`,
+		`create snapshot "v1" of;`,
+		`drop snapshot ?a;`,
+		`select ?s from ?a as snapshot "v1" where {?s ?p ?o};`,
+		`select ?s from ?a as of snapshot where {?s ?p ?o};`,
+		`select ?s from ?a as of snapshot "v1" "v2" where {?s ?p ?o};`,
+		`insert data into ?a as of snapshot "v1" {/_<foo> "bar"@[] /_<foo>};`,
+		// Optional graph patterns.
+		`select ?a from ?b where {?a ?p ?o . optional {}};`,
+		`select ?a from ?b where {?a ?p ?o optional {?o ?q ?x}};`,
+		`select ?a from ?b where {?a ?p ?o . optional {?o ?q ?x . optional {?x ?r ?y}}};`,
+		// Existence graph patterns.
+		`select ?a from ?b where {?a ?p ?o . exists {}};`,
+		`select ?a from ?b where {?a ?p ?o . not {?o ?q ?x}};`,
+		`select ?a from ?b where {?a ?p ?o . exists {?o ?q ?x . exists {?x ?r ?y}}};`,
+		`select ?a from ?b where {?a ?p ?o . exists ?o ?q ?x};`,
+		`ask from ?b where {};`,
+		`ask from ?b;`,
+		`ask ?s from ?b where {?s ?p ?o};`,
+		// Show statements.
+		`show;`,
+		`show graphs ?a;`,
+		`show stats ?a;`,
+		`show stats for;`,
+		// Describe statements.
+		`describe ?s from ?b;`,
+		`describe /_<foo> depth "2"^^type:int64;`,
+		`describe /_<foo> from ?b depth;`,
+		`describe /_<foo> from ?b where {/_<foo> ?p ?o};`,
+		// Explain statements.
+		`explain;`,
+		`explain analyze;`,
+		`analyze select ?a from ?b where {?a ?p ?o};`,
+		`explain ask from ?b where {?s ?p ?o};`,
+		`explain insert data into ?a {/_<foo> "bar"@[] /_<foo>};`,
+		`select ?a from ?b where {?a ?p ?o . optional ?o ?q ?x};`,
+		`select ?a from ?b where {{?a ?p ?o}};`,
+		`select ?a from ?b where {?a ?p ?o . filter()};`,
+		`select ?a from ?b where {?a ?p ?o . filter(contains(?a))};`,
+		`select ?a from ?b where {?a ?p ?o . filter(contains(?a, ?p))};`,
+		`select ?a from ?b where {?a ?p ?o} having regex("foo", ?a);`,
+		`select ?a from ?b where {?a ?p+ ?o};`,
+		`select ?a from ?b where {?a "bar"@[]{} ?o};`,
+		`select ?a from ?b where {?a "bar"@[]{,"1"^^type:int64} ?o};`,
+		`select ?a from ?b where {?a "bar"@[] path ?p ?o};`,
+		`select ?a from ?b where {?a "bar"@[]+ path ?o};`,
+		`select ?a from ?b where {?a "bar"@[]+ hops "1"^^type:int64 ?o};`,
+		`select ?a from ?b where {?a ?p ?o . filter ?a = ?o};`,
+		`select ?a from ?b where {?a ?p ?o . optional {?o ?q ?x . filter(?x = ?a)}};`,
+		`select ?a from ?b where {{?a ?p ?o} union {}};`,
+		`select ?a from ?b where {{?a ?p ?o} {?a ?q ?o}};`,
+		`select ?a from ?b where {{?a ?p ?o} union {?a ?q ?o . {?a ?r ?o} union {?a ?s ?o}}};`,
 	}
 	p, err := NewParser(BQL())
 	if err != nil {
@@ -249,6 +401,9 @@ func TestAcceptOpsByParseAndSemantic(t *testing.T) {
 		{`create graph ?foo;`, 1, 0},
 		// Drop graphs.
 		{`drop graph ?foo, ?bar;`, 2, 0},
+		// Create and drop snapshots.
+		{`create snapshot "v1" of ?foo, ?bar;`, 2, 0},
+		{`drop snapshot "v1" of ?foo;`, 1, 0},
 	}
 	p, err := NewParser(SemanticBQL())
 	if err != nil {
@@ -268,6 +423,347 @@ func TestAcceptOpsByParseAndSemantic(t *testing.T) {
 	}
 }
 
+func TestStatementTypeBySemanticParse(t *testing.T) {
+	table := []struct {
+		query string
+		want  semantic.StatementType
+	}{
+		{`select ?s from ?g where{?s ?p ?o};`, semantic.Query},
+		{`insert data into ?a {/_<foo> "bar"@[] /_<foo>};`, semantic.Insert},
+		{`delete data from ?a {/_<foo> "bar"@[] /_<foo>};`, semantic.Delete},
+		{`create graph ?foo;`, semantic.Create},
+		{`drop graph ?foo;`, semantic.Drop},
+		{`begin;`, semantic.Begin},
+		{`commit;`, semantic.Commit},
+		{`rollback;`, semantic.Rollback},
+		{`create snapshot "v1" of ?foo;`, semantic.CreateSnapshot},
+		{`drop snapshot "v1" of ?foo;`, semantic.DropSnapshot},
+		{`select ?s from ?g as of snapshot "v1" where{?s ?p ?o};`, semantic.Query},
+		{`construct {?s ?p ?o} from ?g where{?s ?p ?o};`, semantic.Construct},
+		{`delete {?s ?p ?o} insert {?o ?p ?s} from ?g where{?s ?p ?o};`, semantic.Update},
+		{`delete {?s ?p ?o} from ?g where{?s ?p ?o};`, semantic.Update},
+		{`insert {?s ?p ?o} into ?g where{?s ?p ?o};`, semantic.Update},
+		{`ask from ?g where{?s ?p ?o};`, semantic.Ask},
+		{`describe /_<foo> from ?g;`, semantic.Describe},
+		{`show graphs;`, semantic.ShowGraphs},
+		{`show stats for ?g;`, semantic.ShowStats},
+		{`explain select ?s from ?g where{?s ?p ?o};`, semantic.Query},
+		{`explain analyze select ?s from ?g where{?s ?p ?o};`, semantic.Query},
+	}
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to accept entry %q with error %v", entry.query, err)
+		}
+		if got, want := st.Type(), entry.want; got != want {
+			t.Errorf("Parser.consume: wrong statement type for %q; got %v, want %v", entry.query, got, want)
+		}
+	}
+}
+
+func TestSnapshotsBySemanticParse(t *testing.T) {
+	table := []struct {
+		query     string
+		snapshot  string
+		snapshots map[string]string
+	}{
+		{`create snapshot "before load" of ?a, ?b;`, "before load", map[string]string{}},
+		{`drop snapshot "a \"quoted\" name" of ?a;`, `a "quoted" name`, map[string]string{}},
+		{`select ?s from ?a where {?s ?p ?o};`, "", map[string]string{}},
+		{`select ?s from ?a as of snapshot "v1", ?b, ?c as of snapshot "v2" where {?s ?p ?o};`, "",
+			map[string]string{"?a": "v1", "?c": "v2"}},
+	}
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to accept entry %q with error %v", entry.query, err)
+			continue
+		}
+		if got, want := st.Snapshot(), entry.snapshot; got != want {
+			t.Errorf("Parser.consume: wrong snapshot name for %q; got %q, want %q", entry.query, got, want)
+		}
+		for _, g := range st.Graphs() {
+			got, ok := st.GraphSnapshot(g)
+			if want, wok := entry.snapshots[g]; got != want || ok != wok {
+				t.Errorf("Parser.consume: wrong snapshot for graph %q in %q; got %q, want %q", g, entry.query, got, want)
+			}
+		}
+	}
+}
+
+func TestConstructBySemanticParse(t *testing.T) {
+	table := []struct {
+		query    string
+		template []string
+		graphs   []string
+		output   []string
+	}{
+		{
+			query:    `construct {?o "parent_of"@[] ?s} from ?a where {?s "child_of"@[] ?o};`,
+			template: []string{`?o "parent_of"@[] ?s`},
+			graphs:   []string{"?a"},
+		},
+		{
+			query:    `construct {?s ?p ?o . /_<foo> "bar"@[] ?s} into ?b, ?c from ?a where {?s ?p ?o};`,
+			template: []string{"?s ?p ?o", `/_<foo> "bar"@[] ?s`},
+			graphs:   []string{"?a"},
+			output:   []string{"?b", "?c"},
+		},
+	}
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to accept entry %q with error %v", entry.query, err)
+			continue
+		}
+		var got []string
+		for _, c := range st.TemplateClauses() {
+			got = append(got, c.String())
+		}
+		if want := entry.template; !reflect.DeepEqual(got, want) {
+			t.Errorf("Parser.consume: wrong template for %q; got %v, want %v", entry.query, got, want)
+		}
+		if got, want := st.Graphs(), entry.graphs; !reflect.DeepEqual(got, want) {
+			t.Errorf("Parser.consume: wrong graphs for %q; got %v, want %v", entry.query, got, want)
+		}
+		if got, want := st.OutputGraphs(), entry.output; !reflect.DeepEqual(got, want) {
+			t.Errorf("Parser.consume: wrong output graphs for %q; got %v, want %v", entry.query, got, want)
+		}
+	}
+}
+
+func TestDescribeBySemanticParse(t *testing.T) {
+	table := []struct {
+		query  string
+		node   string
+		depth  int64
+		graphs []string
+	}{
+		{`describe /_<foo> from ?a;`, "/_<foo>", 1, []string{"?a"}},
+		{`describe /u<joe> from ?a, ?b depth "3"^^type:int64;`, "/u<joe>", 3, []string{"?a", "?b"}},
+	}
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to accept entry %q with error %v", entry.query, err)
+			continue
+		}
+		if got, want := st.DescribedNode().String(), entry.node; got != want {
+			t.Errorf("Parser.consume: wrong described node for %q; got %q, want %q", entry.query, got, want)
+		}
+		if got, want := st.DescribeDepth(), entry.depth; got != want {
+			t.Errorf("Parser.consume: wrong describe depth for %q; got %d, want %d", entry.query, got, want)
+		}
+		if got, want := st.Graphs(), entry.graphs; !reflect.DeepEqual(got, want) {
+			t.Errorf("Parser.consume: wrong graphs for %q; got %v, want %v", entry.query, got, want)
+		}
+	}
+}
+
+func TestExplainBySemanticParse(t *testing.T) {
+	table := []struct {
+		query   string
+		explain bool
+		analyze bool
+	}{
+		{`select ?s from ?g where{?s ?p ?o};`, false, false},
+		{`explain select ?s from ?g where{?s ?p ?o};`, true, false},
+		{`explain analyze select ?s from ?g where{?s ?p ?o};`, true, true},
+	}
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to accept entry %q with error %v", entry.query, err)
+			continue
+		}
+		if got, want := st.Explain(), entry.explain; got != want {
+			t.Errorf("Parser.consume: wrong explain flag for %q; got %v, want %v", entry.query, got, want)
+		}
+		if got, want := st.ExplainAnalyze(), entry.analyze; got != want {
+			t.Errorf("Parser.consume: wrong explain analyze flag for %q; got %v, want %v", entry.query, got, want)
+		}
+	}
+}
+
+func TestOptionalGraphPatternsBySemanticParse(t *testing.T) {
+	table := []struct {
+		query    string
+		clauses  int
+		optional []int
+	}{
+		{`select ?a from ?b where {?a ?p ?o};`, 1, nil},
+		{`select ?a, ?x from ?b where {?a ?p ?o . optional {?o ?q ?x}};`, 1, []int{1}},
+		{`select ?a, ?x from ?b where {?a ?p ?o . optional {?o ?q ?x . ?x ?r ?y} . ?a ?s ?t . optional {?a ?u ?v}};`, 2, []int{2, 1}},
+	}
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to accept entry %q with error %v", entry.query, err)
+			continue
+		}
+		if got, want := len(st.SortedGraphPatternClauses()), entry.clauses; got != want {
+			t.Errorf("Parser.consume: wrong number of clauses for %q; got %d, want %d", entry.query, got, want)
+		}
+		var got []int
+		for _, opt := range st.SortedOptionalGraphPatternClauses() {
+			got = append(got, len(opt))
+		}
+		if !reflect.DeepEqual(got, entry.optional) {
+			t.Errorf("Parser.consume: wrong optional graph patterns for %q; got %v, want %v", entry.query, got, entry.optional)
+		}
+	}
+}
+
+func TestExistenceGraphPatternsBySemanticParse(t *testing.T) {
+	table := []struct {
+		query     string
+		clauses   int
+		existence []int
+		negated   []bool
+	}{
+		{`select ?a from ?b where {?a ?p ?o};`, 1, nil, nil},
+		{`select ?a from ?b where {?a ?p ?o . exists {?o ?q ?x}};`, 1, []int{1}, []bool{false}},
+		{`select ?a from ?b where {?a ?p ?o . not exists {?o ?q ?x . ?x ?r ?y} . ?a ?s ?t . exists {?a ?u ?v}};`, 2, []int{2, 1}, []bool{true, false}},
+	}
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to accept entry %q with error %v", entry.query, err)
+			continue
+		}
+		if got, want := len(st.SortedGraphPatternClauses()), entry.clauses; got != want {
+			t.Errorf("Parser.consume: wrong number of clauses for %q; got %d, want %d", entry.query, got, want)
+		}
+		var (
+			got     []int
+			negated []bool
+		)
+		for _, e := range st.ExistenceGraphPatterns() {
+			got = append(got, len(e.SortedClauses()))
+			negated = append(negated, e.Negated)
+		}
+		if !reflect.DeepEqual(got, entry.existence) {
+			t.Errorf("Parser.consume: wrong existence graph patterns for %q; got %v, want %v", entry.query, got, entry.existence)
+		}
+		if !reflect.DeepEqual(negated, entry.negated) {
+			t.Errorf("Parser.consume: wrong negated existence graph patterns for %q; got %v, want %v", entry.query, negated, entry.negated)
+		}
+	}
+}
+
+func TestUnionGraphPatternsBySemanticParse(t *testing.T) {
+	table := []struct {
+		query   string
+		clauses int
+		unions  [][]int
+	}{
+		{`select ?a from ?b where {?a ?p ?o};`, 1, nil},
+		{`select ?a from ?b where {{?a ?p ?o} union {?a ?q ?o}};`, 0, [][]int{{1, 1}}},
+		{`select ?a from ?b where {?a ?p ?o . {?o ?q ?x . ?x ?r ?y} union {?o ?s ?x} union {?o ?t ?x} . ?a ?u ?v . {?a ?w ?z} union {?z ?w ?a}};`, 2, [][]int{{2, 1, 1}, {1, 1}}},
+	}
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to accept entry %q with error %v", entry.query, err)
+			continue
+		}
+		if got, want := len(st.SortedGraphPatternClauses()), entry.clauses; got != want {
+			t.Errorf("Parser.consume: wrong number of clauses for %q; got %d, want %d", entry.query, got, want)
+		}
+		var got [][]int
+		for _, u := range st.SortedUnionGraphPatterns() {
+			var alts []int
+			for _, alt := range u.Alternatives {
+				alts = append(alts, len(alt))
+			}
+			got = append(got, alts)
+		}
+		if !reflect.DeepEqual(got, entry.unions) {
+			t.Errorf("Parser.consume: wrong unions of graph patterns for %q; got %v, want %v", entry.query, got, entry.unions)
+		}
+	}
+}
+
+func TestFiltersBySemanticParse(t *testing.T) {
+	table := []struct {
+		query    string
+		bindings [][]string
+	}{
+		{`select ?a from ?b where {?a ?p ?o};`, nil},
+		{`select ?a from ?b where {?a ?p ?o . filter(?a = ?o)};`, [][]string{{"?a", "?o"}}},
+		{`select ?a from ?b where {filter(not(?a = /_<foo>)) . ?a ?p ?o . filter((?o > "1"^^type:int64) or (?o = ?o))};`, [][]string{{"?a"}, {"?o"}}},
+	}
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to accept entry %q with error %v", entry.query, err)
+			continue
+		}
+		var got [][]string
+		for _, f := range st.Filters() {
+			got = append(got, f.Bindings)
+		}
+		if !reflect.DeepEqual(got, entry.bindings) {
+			t.Errorf("Parser.consume: wrong filter bindings for %q; got %v, want %v", entry.query, got, entry.bindings)
+		}
+	}
+}
+
+func TestGroupConcatSeparatorBySemanticParse(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Errorf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	q := `select ?s, group_concat(?o) as ?a, group_concat(?p, ", ") as ?b from ?g where{?s ?p ?o} group by ?s;`
+	st := &semantic.Statement{}
+	if err := p.Parse(NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to accept entry %q with error %v", q, err)
+	}
+	var got []string
+	for _, prj := range st.Projections() {
+		got = append(got, prj.Separator)
+	}
+	if want := []string{"", " ", ", "}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Parser.consume: wrong separators for %q; got %q, want %q", q, got, want)
+	}
+}
+
 func TestAcceptQueryBySemanticParse(t *testing.T) {
 	table := []string{
 		// Test well type literals are accepted.
@@ -300,6 +796,16 @@ func TestAcceptQueryBySemanticParse(t *testing.T) {
 		`select ?s from ?g where{/_<foo> as ?s  ?p "id"@[?foo, ?bar] as ?o} order by ?s;`,
 		`select ?s as ?a, ?o as ?b, ?o as ?c from ?g where{?s ?p ?o} order by ?a ASC, ?b DESC;`,
 		`select ?s as ?a, ?o as ?b, ?o as ?c from ?g where{?s ?p ?o} order by ?a ASC, ?b DESC, ?a ASC, ?b DESC, ?c;`,
+		// Test offset and cursor acceptance.
+		`select ?s from ?g where{?s ?p ?o} offset "2"^^type:int64;`,
+		`select ?s from ?g where{?s ?p ?o} order by ?s limit "10"^^type:int64 offset "2"^^type:int64;`,
+		// Test subqueries acceptance.
+		`select ?s, ?n from ?g where{(select ?s, count(?o) as ?n from ?g where{?s ?p ?o} group by ?s) . filter(?n > "1"^^type:int64)};`,
+		`select ?s, ?n from ?g where{(select ?s, count(?o) as ?n, count(?p) as ?m from ?g where{?s ?p ?o} group by ?s having ?n > ?m)};`,
+		`select ?s, ?n, ?x from ?g where{?s "foo"@[] ?x . (select ?s, count(?o) as ?n from ?g where{?s ?p ?o} group by ?s) . ?x ?p ?o};`,
+		`select ?a, ?b from ?g where{(select ?a from ?g where{?a ?p ?o}) . (select ?b from ?h where{?b ?p ?o} limit "1"^^type:int64)};`,
+		`select ?s from ?g where{?s ?p ?o . (select ?s from ?g where{?s ?p ?o . (select ?s from ?g where{?s ?p ?o})})};`,
+		`select ?s from ?g where{?s ?p ?o} order by ?s limit "10"^^type:int64 cursor "eyJiIjpbIj9zIl0sImQiOltmYWxzZV0sImsiOlsiL3U8YT4iXSwicyI6MX0";`,
 	}
 	p, err := NewParser(SemanticBQL())
 	if err != nil {
@@ -331,6 +837,60 @@ func TestRejectByParseAndSemantic(t *testing.T) {
 		`select ?s as ?a, ?o as ?b, ?o as ?c from ?g where{?s ?p ?o} order by ?a ASC, ?a DESC;`,
 		// Wrong limit literal.
 		`select ?s as ?a, ?o as ?b, ?o as ?c from ?g where{?s ?p ?o} LIMIT "true"^^type:bool;`,
+		// Templates can only use bindings of the where clause.
+		`construct {?s ?p ?x} from ?g where{?s ?p ?o};`,
+		`delete {?s ?p ?o} insert {?s "foo"@[?t] ?o} from ?g where{?s ?p ?o};`,
+		`insert {?s "foo"@[2015-07-19T13:12:04.669618843-07:00, ?t] ?o} into ?g where{?s ?p ?o};`,
+		// Only the output bindings of subqueries are available.
+		`select ?o from ?g where{(select ?s from ?g where{?s ?p ?o})};`,
+		`select ?s from ?g where{(select ?s from ?g where{?s ?p ?x})} group by ?x;`,
+		// Subqueries are checked as queries.
+		`select ?s from ?g where{(select ?s, count(?o) as ?n from ?g where{?s ?p ?o})};`,
+		`select ?s from ?g where{(select ?x from ?g where{?s ?p ?o})};`,
+		// Wrong offset literals.
+		`select ?s from ?g where{?s ?p ?o} OFFSET "true"^^type:bool;`,
+		`select ?s from ?g where{?s ?p ?o} OFFSET "-1"^^type:int64;`,
+		// Continuation tokens require an order by clause and a matching query.
+		`select ?s from ?g where{?s ?p ?o} limit "10"^^type:int64 cursor "eyJiIjpbIj9zIl0sImQiOltmYWxzZV0sImsiOlsiL3U8YT4iXSwicyI6MX0";`,
+		`select ?s from ?g where{?s ?p ?o} order by ?s DESC limit "10"^^type:int64 cursor "eyJiIjpbIj9zIl0sImQiOltmYWxzZV0sImsiOlsiL3U8YT4iXSwicyI6MX0";`,
+		`select ?s, ?o from ?g where{?s ?p ?o} order by ?s limit "10"^^type:int64 cursor "eyJiIjpbIj9zIl0sImQiOltmYWxzZV0sImsiOlsiL3U8YT4iXSwicyI6MX0";`,
+		`select ?s from ?g where{?s ?p ?o} order by ?s limit "10"^^type:int64 cursor "not a token";`,
+		// Optional graph patterns require a non optional clause.
+		`select ?x from ?g where {optional {?s ?p ?x}};`,
+		// Existence graph patterns require a non optional clause and do not bind.
+		`select ?x from ?g where {exists {?s ?p ?x}};`,
+		`select ?x from ?g where {?s ?p ?o . exists {?o ?q ?x}};`,
+		`ask from ?g where {?s ?p ?o . not exists {?o "bar"@[?t]+ ?x}};`,
+		// Filters can only use bindings of the graph pattern.
+		`select ?a from ?b where {?a ?p ?o . filter(?a = ?x)};`,
+		// Filters need valid expressions.
+		`select ?a from ?b where {?a ?p ?o . filter(?a = ?o and ?p = ?o)};`,
+		`select ?a from ?b where {?a ?p ?o . filter(/_<foo> = ?a)};`,
+		`select ?a from ?b where {?a ?p ?o . filter(regex(?a, "(foo"))};`,
+		`select ?a from ?b where {?a ?p ?o . filter(contains(?x, "foo"))};`,
+		// Property paths only walk fully specified predicates between nodes.
+		`select ?a from ?b where {?a "bar"@[?t]+ ?o};`,
+		`select ?a from ?b where {?a "bar"@[]+ "1"^^type:int64};`,
+		`select ?a from ?b where {?a "bar"@[]+ ?o as ?x};`,
+		`select ?a from ?b where {?a "bar"@[]{"3"^^type:int64, "1"^^type:int64} ?o};`,
+		`select ?a from ?b where {?a "bar"@[]{"true"^^type:bool,} ?o};`,
+		`select ?a from ?b where {?a "bar"@[2015-07-19T13:12:04.669618843-07:00, ?t]+ ?o};`,
+		`select ?a from ?b where {?a "bar"@[]+ path ?a ?o};`,
+		`select ?a from ?b where {?a "bar"@[]+ path ?p hops ?p ?o};`,
+		`select ?a from ?b where {?a "bar"@[]+ path ?p path ?q ?o};`,
+		`select ?a from ?b where {?a "bar"@[]+ shortest shortest ?o};`,
+		// Scalar expressions can only use bindings of the graph pattern.
+		`select (?s + ?x) as ?a from ?g where{?s ?p ?o};`,
+		// Scalar expressions need valid arity.
+		`select lower(?s, ?o) as ?a from ?g where{?s ?p ?o};`,
+		// Scalar expressions not grouped require an aggregation.
+		`select ?s, strlen(?o) as ?a from ?g where{?s ?p ?o} group by ?s;`,
+		// Describe depths must be positive int64 literals.
+		`describe /_<foo> from ?g depth "0"^^type:int64;`,
+		`describe /_<foo> from ?g depth "true"^^type:bool;`,
+		// Empty snapshot names.
+		`create snapshot "" of ?a;`,
+		`select ?s from ?g as of snapshot "" where{?s ?p ?o};`,
 	}
 	p, err := NewParser(SemanticBQL())
 	if err != nil {
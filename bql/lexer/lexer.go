@@ -74,6 +74,62 @@ const (
 	ItemDistinct
 	// ItemSum represents the sum function in BQL.
 	ItemSum
+	// ItemAvg represents the avg function in BQL.
+	ItemAvg
+	// ItemMin represents the min function in BQL.
+	ItemMin
+	// ItemMax represents the max function in BQL.
+	ItemMax
+	// ItemGroupConcat represents the group_concat function in BQL.
+	ItemGroupConcat
+	// ItemLower represents the lower function in BQL.
+	ItemLower
+	// ItemStrlen represents the strlen function in BQL.
+	ItemStrlen
+	// ItemSubstr represents the substr function in BQL.
+	ItemSubstr
+	// ItemConcat represents the concat function in BQL.
+	ItemConcat
+	// ItemAbs represents the abs function in BQL.
+	ItemAbs
+	// ItemContains represents the contains function in BQL.
+	ItemContains
+	// ItemStartsWith represents the starts_with function in BQL.
+	ItemStartsWith
+	// ItemRegex represents the regex function in BQL.
+	ItemRegex
+	// ItemShortest represents the shortest path modifier in BQL.
+	ItemShortest
+	// ItemPath represents the path binding modifier in BQL.
+	ItemPath
+	// ItemHops represents the hops binding modifier in BQL.
+	ItemHops
+	// ItemOffset represents the offset clause in BQL.
+	ItemOffset
+	// ItemCursor represents the cursor clause in BQL.
+	ItemCursor
+	// ItemConstruct represents the construct keyword in BQL.
+	ItemConstruct
+	// ItemAsk represents the ask keyword in BQL.
+	ItemAsk
+	// ItemExists represents the exists keyword in BQL.
+	ItemExists
+	// ItemDescribe represents the describe keyword in BQL.
+	ItemDescribe
+	// ItemDepth represents the depth clause of describe statements in BQL.
+	ItemDepth
+	// ItemShow represents the show keyword in BQL.
+	ItemShow
+	// ItemGraphs represents the graphs keyword in BQL.
+	ItemGraphs
+	// ItemStats represents the stats keyword in BQL.
+	ItemStats
+	// ItemFor represents the for keyword in BQL.
+	ItemFor
+	// ItemExplain represents the explain keyword in BQL.
+	ItemExplain
+	// ItemAnalyze represents the analyze keyword in BQL.
+	ItemAnalyze
 	// ItemGroup represents the group keyword in group by clause in BQL.
 	ItemGroup
 	// ItemBy represents the by keyword in group by clause in BQL.
@@ -88,6 +144,23 @@ const (
 	ItemDesc
 	// ItemLimit represents the limit clause in BQL.
 	ItemLimit
+	// ItemBegin represents the begin keyword that starts a transaction in BQL.
+	ItemBegin
+	// ItemCommit represents the commit keyword that ends a transaction in BQL.
+	ItemCommit
+	// ItemRollback represents the rollback keyword that discards a transaction
+	// in BQL.
+	ItemRollback
+	// ItemSnapshot represents the snapshot keyword in BQL.
+	ItemSnapshot
+	// ItemOf represents the of keyword in BQL.
+	ItemOf
+	// ItemOptional represents the optional keyword in BQL.
+	ItemOptional
+	// ItemUnion represents the union keyword in BQL.
+	ItemUnion
+	// ItemFilter represents the filter keyword in BQL.
+	ItemFilter
 
 	// ItemBinding represents a variable binding in BQL.
 	ItemBinding
@@ -100,6 +173,9 @@ const (
 	ItemPredicate
 	// ItemPredicateBound represents a BadWolf predicate bound in BQL.
 	ItemPredicateBound
+	// ItemString represents a quoted string that is neither a predicate nor a
+	// literal in BQL.
+	ItemString
 
 	// ItemLBracket represents the left opening bracket token in BQL.
 	ItemLBracket
@@ -127,6 +203,14 @@ const (
 	ItemAnd
 	// ItemOr represents keyword or in BQL.
 	ItemOr
+	// ItemPlus represents + in BQL.
+	ItemPlus
+	// ItemMinus represents - in BQL.
+	ItemMinus
+	// ItemMul represents * in BQL.
+	ItemMul
+	// ItemDiv represents / followed by a space in BQL.
+	ItemDiv
 )
 
 func (tt TokenType) String() string {
@@ -159,6 +243,62 @@ func (tt TokenType) String() string {
 		return "COUNT"
 	case ItemSum:
 		return "SUM"
+	case ItemAvg:
+		return "AVG"
+	case ItemMin:
+		return "MIN"
+	case ItemMax:
+		return "MAX"
+	case ItemGroupConcat:
+		return "GROUP_CONCAT"
+	case ItemLower:
+		return "LOWER"
+	case ItemStrlen:
+		return "STRLEN"
+	case ItemSubstr:
+		return "SUBSTR"
+	case ItemConcat:
+		return "CONCAT"
+	case ItemAbs:
+		return "ABS"
+	case ItemContains:
+		return "CONTAINS"
+	case ItemStartsWith:
+		return "STARTS_WITH"
+	case ItemRegex:
+		return "REGEX"
+	case ItemShortest:
+		return "SHORTEST"
+	case ItemPath:
+		return "PATH"
+	case ItemHops:
+		return "HOPS"
+	case ItemOffset:
+		return "OFFSET"
+	case ItemCursor:
+		return "CURSOR"
+	case ItemConstruct:
+		return "CONSTRUCT"
+	case ItemAsk:
+		return "ASK"
+	case ItemExists:
+		return "EXISTS"
+	case ItemDescribe:
+		return "DESCRIBE"
+	case ItemDepth:
+		return "DEPTH"
+	case ItemShow:
+		return "SHOW"
+	case ItemGraphs:
+		return "GRAPHS"
+	case ItemStats:
+		return "STATS"
+	case ItemFor:
+		return "FOR"
+	case ItemExplain:
+		return "EXPLAIN"
+	case ItemAnalyze:
+		return "ANALYZE"
 	case ItemGroup:
 		return "GROUP"
 	case ItemBy:
@@ -173,6 +313,22 @@ func (tt TokenType) String() string {
 		return "DESC"
 	case ItemLimit:
 		return "LIMIT"
+	case ItemBegin:
+		return "BEGIN"
+	case ItemCommit:
+		return "COMMIT"
+	case ItemRollback:
+		return "ROLLBACK"
+	case ItemSnapshot:
+		return "SNAPSHOT"
+	case ItemOf:
+		return "OF"
+	case ItemOptional:
+		return "OPTIONAL"
+	case ItemUnion:
+		return "UNION"
+	case ItemFilter:
+		return "FILTER"
 	case ItemAs:
 		return "AS"
 	case ItemBefore:
@@ -191,6 +347,8 @@ func (tt TokenType) String() string {
 		return "PREDICATE"
 	case ItemPredicateBound:
 		return "PREDICATE_BOUND"
+	case ItemString:
+		return "STRING"
 	case ItemLBracket:
 		return "LEFT_BRACKET"
 	case ItemRBracket:
@@ -217,6 +375,14 @@ func (tt TokenType) String() string {
 		return "AND"
 	case ItemOr:
 		return "OR"
+	case ItemPlus:
+		return "PLUS"
+	case ItemMinus:
+		return "MINUS"
+	case ItemMul:
+		return "MUL"
+	case ItemDiv:
+		return "DIV"
 	case ItemID:
 		return "ID"
 	case ItemType:
@@ -248,6 +414,9 @@ const (
 	lt             = rune('<')
 	gt             = rune('>')
 	eq             = rune('=')
+	plus           = rune('+')
+	minus          = rune('-')
+	mul            = rune('*')
 	quote          = rune('"')
 	hat            = rune('^')
 	at             = rune('@')
@@ -269,6 +438,34 @@ const (
 	count          = "count"
 	distinct       = "distinct"
 	sum            = "sum"
+	avg            = "avg"
+	min            = "min"
+	max            = "max"
+	groupConcat    = "group_concat"
+	lower          = "lower"
+	strlen         = "strlen"
+	substr         = "substr"
+	concat         = "concat"
+	abs            = "abs"
+	contains       = "contains"
+	startsWith     = "starts_with"
+	regex          = "regex"
+	shortest       = "shortest"
+	path           = "path"
+	hops           = "hops"
+	offset         = "offset"
+	cursor         = "cursor"
+	construct      = "construct"
+	ask            = "ask"
+	exists         = "exists"
+	describe       = "describe"
+	depth          = "depth"
+	show           = "show"
+	graphs         = "graphs"
+	stats          = "stats"
+	forKeyword     = "for"
+	explain        = "explain"
+	analyze        = "analyze"
 	group          = "group"
 	having         = "having"
 	by             = "by"
@@ -276,6 +473,14 @@ const (
 	asc            = "asc"
 	desc           = "desc"
 	limit          = "limit"
+	begin          = "begin"
+	commit         = "commit"
+	rollback       = "rollback"
+	snapshot       = "snapshot"
+	of             = "of"
+	optional       = "optional"
+	union          = "union"
+	filter         = "filter"
 	not            = "not"
 	and            = "and"
 	or             = "or"
@@ -345,6 +550,12 @@ func lexToken(l *lexer) stateFn {
 				l.next()
 				return lexBinding
 			case slash:
+				// A slash followed by a space is a division, not a node.
+				if rest := l.input[l.pos+1:]; len(rest) > 0 && unicode.IsSpace(rune(rest[0])) {
+					l.next()
+					l.emit(ItemDiv)
+					return lexSpace
+				}
 				return lexNode
 			case quote:
 				return lexPredicateOrLiteral
@@ -383,6 +594,15 @@ func lexToken(l *lexer) stateFn {
 		if state := isSingleSymbolToken(l, ItemEQ, eq); state != nil {
 			return state
 		}
+		if state := isSingleSymbolToken(l, ItemPlus, plus); state != nil {
+			return state
+		}
+		if state := isSingleSymbolToken(l, ItemMinus, minus); state != nil {
+			return state
+		}
+		if state := isSingleSymbolToken(l, ItemMul, mul); state != nil {
+			return state
+		}
 		{
 			r := l.next()
 			if unicode.IsSpace(r) {
@@ -436,7 +656,7 @@ func lexSpace(l *lexer) stateFn {
 func lexKeyword(l *lexer) stateFn {
 	input := l.input[l.pos:]
 	f := func(r rune) bool {
-		return !unicode.IsLetter(r)
+		return !unicode.IsLetter(r) && r != '_'
 	}
 	if idx := strings.IndexFunc(input, f); idx >= 0 {
 		input = input[:idx]
@@ -509,6 +729,118 @@ func lexKeyword(l *lexer) stateFn {
 		consumeKeyword(l, ItemSum)
 		return lexSpace
 	}
+	if strings.EqualFold(input, avg) {
+		consumeKeyword(l, ItemAvg)
+		return lexSpace
+	}
+	if strings.EqualFold(input, min) {
+		consumeKeyword(l, ItemMin)
+		return lexSpace
+	}
+	if strings.EqualFold(input, max) {
+		consumeKeyword(l, ItemMax)
+		return lexSpace
+	}
+	if strings.EqualFold(input, groupConcat) {
+		consumeKeyword(l, ItemGroupConcat)
+		return lexSpace
+	}
+	if strings.EqualFold(input, lower) {
+		consumeKeyword(l, ItemLower)
+		return lexSpace
+	}
+	if strings.EqualFold(input, strlen) {
+		consumeKeyword(l, ItemStrlen)
+		return lexSpace
+	}
+	if strings.EqualFold(input, substr) {
+		consumeKeyword(l, ItemSubstr)
+		return lexSpace
+	}
+	if strings.EqualFold(input, concat) {
+		consumeKeyword(l, ItemConcat)
+		return lexSpace
+	}
+	if strings.EqualFold(input, abs) {
+		consumeKeyword(l, ItemAbs)
+		return lexSpace
+	}
+	if strings.EqualFold(input, contains) {
+		consumeKeyword(l, ItemContains)
+		return lexSpace
+	}
+	if strings.EqualFold(input, startsWith) {
+		consumeKeyword(l, ItemStartsWith)
+		return lexSpace
+	}
+	if strings.EqualFold(input, regex) {
+		consumeKeyword(l, ItemRegex)
+		return lexSpace
+	}
+	if strings.EqualFold(input, shortest) {
+		consumeKeyword(l, ItemShortest)
+		return lexSpace
+	}
+	if strings.EqualFold(input, path) {
+		consumeKeyword(l, ItemPath)
+		return lexSpace
+	}
+	if strings.EqualFold(input, hops) {
+		consumeKeyword(l, ItemHops)
+		return lexSpace
+	}
+	if strings.EqualFold(input, offset) {
+		consumeKeyword(l, ItemOffset)
+		return lexSpace
+	}
+	if strings.EqualFold(input, cursor) {
+		consumeKeyword(l, ItemCursor)
+		return lexSpace
+	}
+	if strings.EqualFold(input, construct) {
+		consumeKeyword(l, ItemConstruct)
+		return lexSpace
+	}
+	if strings.EqualFold(input, ask) {
+		consumeKeyword(l, ItemAsk)
+		return lexSpace
+	}
+	if strings.EqualFold(input, exists) {
+		consumeKeyword(l, ItemExists)
+		return lexSpace
+	}
+	if strings.EqualFold(input, describe) {
+		consumeKeyword(l, ItemDescribe)
+		return lexSpace
+	}
+	if strings.EqualFold(input, depth) {
+		consumeKeyword(l, ItemDepth)
+		return lexSpace
+	}
+	if strings.EqualFold(input, show) {
+		consumeKeyword(l, ItemShow)
+		return lexSpace
+	}
+	if strings.EqualFold(input, graphs) {
+		consumeKeyword(l, ItemGraphs)
+		return lexSpace
+	}
+	if strings.EqualFold(input, stats) {
+		consumeKeyword(l, ItemStats)
+		return lexSpace
+	}
+	if strings.EqualFold(input, forKeyword) {
+		consumeKeyword(l, ItemFor)
+		return lexSpace
+	}
+	if strings.EqualFold(input, explain) {
+		consumeKeyword(l, ItemExplain)
+		return lexSpace
+	}
+	if strings.EqualFold(input, analyze) {
+		consumeKeyword(l, ItemAnalyze)
+		return lexSpace
+	}
 	if strings.EqualFold(input, group) {
 		consumeKeyword(l, ItemGroup)
 		return lexSpace
@@ -537,6 +869,38 @@ func lexKeyword(l *lexer) stateFn {
 		consumeKeyword(l, ItemLimit)
 		return lexSpace
 	}
+	if strings.EqualFold(input, begin) {
+		consumeKeyword(l, ItemBegin)
+		return lexSpace
+	}
+	if strings.EqualFold(input, commit) {
+		consumeKeyword(l, ItemCommit)
+		return lexSpace
+	}
+	if strings.EqualFold(input, rollback) {
+		consumeKeyword(l, ItemRollback)
+		return lexSpace
+	}
+	if strings.EqualFold(input, snapshot) {
+		consumeKeyword(l, ItemSnapshot)
+		return lexSpace
+	}
+	if strings.EqualFold(input, of) {
+		consumeKeyword(l, ItemOf)
+		return lexSpace
+	}
+	if strings.EqualFold(input, optional) {
+		consumeKeyword(l, ItemOptional)
+		return lexSpace
+	}
+	if strings.EqualFold(input, union) {
+		consumeKeyword(l, ItemUnion)
+		return lexSpace
+	}
+	if strings.EqualFold(input, filter) {
+		consumeKeyword(l, ItemFilter)
+		return lexSpace
+	}
 	if strings.EqualFold(input, not) {
 		consumeKeyword(l, ItemNot)
 		return lexSpace
@@ -601,6 +965,12 @@ func lexNode(l *lexer) stateFn {
 // lexPredicateOrLiteral tries to lex a predicate or a literal out of the input.
 func lexPredicateOrLiteral(l *lexer) stateFn {
 	text := l.input[l.pos:]
+	// Quoted text not followed by a time anchor or a type is a plain string.
+	if idx := closingQuote(text); idx > 0 {
+		if r := text[idx+1:]; !strings.HasPrefix(r, "@") && !strings.HasPrefix(r, "^") {
+			return lexString
+		}
+	}
 	// Fix issue 39 (https://github.com/google/badwolf/issues/39)
 	pIdx, lIdx := strings.Index(text, "\"@["), strings.Index(text, "\"^^type:")
 	if pIdx < 0 && lIdx < 0 {
@@ -613,6 +983,37 @@ func lexPredicateOrLiteral(l *lexer) stateFn {
 	return lexLiteral
 }
 
+// closingQuote returns the index of the quote that closes the one text starts
+// with, or -1 if there is none. Escaped quotes are skipped.
+func closingQuote(text string) int {
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// lexString lexes a plain quoted string out of the input.
+func lexString(l *lexer) stateFn {
+	l.next()
+	for {
+		switch r := l.next(); r {
+		case backSlash:
+			l.next()
+		case quote:
+			l.emit(ItemString)
+			return lexSpace
+		case eof:
+			l.emitError("strings need to be properly terminated; missing final \" delimiter")
+			return nil
+		}
+	}
+}
+
 // lexPredicate lexes a predicate of out of the input.
 func lexPredicate(l *lexer) stateFn {
 	l.next()
@@ -709,7 +1110,7 @@ func lexLiteral(l *lexer) stateFn {
 // consumeKeyword consume and emits a valid token
 func consumeKeyword(l *lexer, t TokenType) {
 	for {
-		if r := l.next(); !unicode.IsLetter(r) || r == eof {
+		if r := l.next(); (!unicode.IsLetter(r) && r != '_') || r == eof {
 			l.backup()
 			l.emit(t)
 			break
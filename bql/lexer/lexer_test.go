@@ -37,6 +37,14 @@ func TestIndividualTokens(t *testing.T) {
 				{Type: ItemGT, Text: ">"},
 				{Type: ItemEQ, Text: "="},
 				{Type: ItemEOF}}},
+		{"+-* / /_<foo>",
+			[]Token{
+				{Type: ItemPlus, Text: "+"},
+				{Type: ItemMinus, Text: "-"},
+				{Type: ItemMul, Text: "*"},
+				{Type: ItemDiv, Text: "/"},
+				{Type: ItemNode, Text: "/_<foo>"},
+				{Type: ItemEOF}}},
 		{"?foo ?bar ?1234 ?foo_bar ?bar_foo",
 			[]Token{
 				{Type: ItemBinding, Text: "?foo"},
@@ -47,7 +55,10 @@ func TestIndividualTokens(t *testing.T) {
 				{Type: ItemEOF}}},
 		{`SeLeCt FrOm WhErE As BeFoRe AfTeR BeTwEeN CoUnT SuM GrOuP bY HaViNg LiMiT
 		  OrDeR AsC DeSc NoT AnD Or Id TyPe At DiStInCt InSeRt DeLeTe DaTa InTo
-			CrEaTe DrOp GrApH`,
+			CrEaTe DrOp GrApH BeGiN CoMmIt RoLlBaCk SnApShOt Of OpTiOnAl UnIoN FiLtEr
+			AvG MiN MaX GrOuP_CoNcAt LoWeR StRlEn SuBsTr CoNcAt AbS
+			CoNtAiNs StArTs_WiTh ReGeX ShOrTeSt PaTh HoPs OfFsEt CuRsOr CoNsTrUcT AsK ExIsTs DeScRiBe DePtH ShOw GrApHs StAtS FoR
+			ExPlAiN AnAlYzE`,
 			[]Token{
 				{Type: ItemQuery, Text: "SeLeCt"},
 				{Type: ItemFrom, Text: "FrOm"},
@@ -79,6 +90,42 @@ func TestIndividualTokens(t *testing.T) {
 				{Type: ItemCreate, Text: "CrEaTe"},
 				{Type: ItemDrop, Text: "DrOp"},
 				{Type: ItemGraph, Text: "GrApH"},
+				{Type: ItemBegin, Text: "BeGiN"},
+				{Type: ItemCommit, Text: "CoMmIt"},
+				{Type: ItemRollback, Text: "RoLlBaCk"},
+				{Type: ItemSnapshot, Text: "SnApShOt"},
+				{Type: ItemOf, Text: "Of"},
+				{Type: ItemOptional, Text: "OpTiOnAl"},
+				{Type: ItemUnion, Text: "UnIoN"},
+				{Type: ItemFilter, Text: "FiLtEr"},
+				{Type: ItemAvg, Text: "AvG"},
+				{Type: ItemMin, Text: "MiN"},
+				{Type: ItemMax, Text: "MaX"},
+				{Type: ItemGroupConcat, Text: "GrOuP_CoNcAt"},
+				{Type: ItemLower, Text: "LoWeR"},
+				{Type: ItemStrlen, Text: "StRlEn"},
+				{Type: ItemSubstr, Text: "SuBsTr"},
+				{Type: ItemConcat, Text: "CoNcAt"},
+				{Type: ItemAbs, Text: "AbS"},
+				{Type: ItemContains, Text: "CoNtAiNs"},
+				{Type: ItemStartsWith, Text: "StArTs_WiTh"},
+				{Type: ItemRegex, Text: "ReGeX"},
+				{Type: ItemShortest, Text: "ShOrTeSt"},
+				{Type: ItemPath, Text: "PaTh"},
+				{Type: ItemHops, Text: "HoPs"},
+				{Type: ItemOffset, Text: "OfFsEt"},
+				{Type: ItemCursor, Text: "CuRsOr"},
+				{Type: ItemConstruct, Text: "CoNsTrUcT"},
+				{Type: ItemAsk, Text: "AsK"},
+				{Type: ItemExists, Text: "ExIsTs"},
+				{Type: ItemDescribe, Text: "DeScRiBe"},
+				{Type: ItemDepth, Text: "DePtH"},
+				{Type: ItemShow, Text: "ShOw"},
+				{Type: ItemGraphs, Text: "GrApHs"},
+				{Type: ItemStats, Text: "StAtS"},
+				{Type: ItemFor, Text: "FoR"},
+				{Type: ItemExplain, Text: "ExPlAiN"},
+				{Type: ItemAnalyze, Text: "AnAlYzE"},
 				{Type: ItemEOF}}},
 		{"/_<foo>/_<bar>",
 			[]Token{
@@ -145,6 +192,26 @@ func TestIndividualTokens(t *testing.T) {
 					Text:         `"p1"@[,,]`,
 					ErrorMessage: "[lexer:0:9] predicate bounds should only have one , to separate bounds"},
 				{Type: ItemEOF}}},
+		{`"before load" "a \"quoted\" name";"x"`,
+			[]Token{
+				{Type: ItemString, Text: `"before load"`},
+				{Type: ItemString, Text: `"a \"quoted\" name"`},
+				{Type: ItemSemicolon, Text: `;`},
+				{Type: ItemString, Text: `"x"`},
+				{Type: ItemEOF}}},
+		{`AS OF SNAPSHOT "v1" WHERE {?s "p"@[] ?o}`,
+			[]Token{
+				{Type: ItemAs, Text: `AS`},
+				{Type: ItemOf, Text: `OF`},
+				{Type: ItemSnapshot, Text: `SNAPSHOT`},
+				{Type: ItemString, Text: `"v1"`},
+				{Type: ItemWhere, Text: `WHERE`},
+				{Type: ItemLBracket, Text: `{`},
+				{Type: ItemBinding, Text: `?s`},
+				{Type: ItemPredicate, Text: `"p"@[]`},
+				{Type: ItemBinding, Text: `?o`},
+				{Type: ItemRBracket, Text: `}`},
+				{Type: ItemEOF}}},
 		{`/room<000> "named"@[] "Hallway"^^type:text. /room<000> "connects_to"@[] /room<001>`,
 			[]Token{
 				{Type: ItemNode, Text: `/room<000>`},
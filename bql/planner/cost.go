@@ -0,0 +1,276 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+)
+
+// costModel estimates how many rows resolving a clause produces using the
+// statistics reported by the graphs of a query. The statistics are loaded the
+// first time they are needed.
+type costModel struct {
+	gs  []storage.Graph
+	cps []storage.CardinalityProvider
+	// Set once the statistics are loaded. The model is only enabled if all the
+	// graphs provide them and contain triples.
+	loaded  bool
+	enabled bool
+	// Aggregated statistics of all the graphs.
+	triples    float64
+	subjects   float64
+	predicates float64
+	objects    float64
+}
+
+// newCostModel returns the cost model for the provided graphs.
+func newCostModel(gs []storage.Graph) *costModel {
+	return &costModel{gs: gs}
+}
+
+// load retrieves the statistics of the graphs if they were not already loaded.
+func (m *costModel) load(ctx context.Context) error {
+	if m.loaded {
+		return nil
+	}
+	var (
+		cps  []storage.CardinalityProvider
+		agg  storage.Stats
+		full = len(m.gs) > 0
+	)
+	for _, g := range m.gs {
+		sp, ok := g.(storage.StatsProvider)
+		cp, cok := g.(storage.CardinalityProvider)
+		if !ok || !cok {
+			full = false
+			break
+		}
+		st, err := sp.Stats(ctx)
+		if err != nil {
+			return err
+		}
+		agg.Triples += st.Triples
+		agg.Subjects += st.Subjects
+		agg.Predicates += st.Predicates
+		agg.Objects += st.Objects
+		cps = append(cps, cp)
+	}
+	m.loaded, m.enabled = true, full && agg.Triples > 0
+	m.cps = cps
+	m.triples, m.subjects = float64(agg.Triples), float64(agg.Subjects)
+	m.predicates, m.objects = float64(agg.Predicates), float64(agg.Objects)
+	return nil
+}
+
+// predicateCardinality returns the number of triples in all the graphs whose
+// predicate has the provided ID.
+func (m *costModel) predicateCardinality(ctx context.Context, id string) (float64, error) {
+	var c int64
+	for _, cp := range m.cps {
+		n, err := cp.PredicateCardinality(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		c += n
+	}
+	return float64(c), nil
+}
+
+// objectCardinality returns the number of triples in all the graphs with the
+// provided object.
+func (m *costModel) objectCardinality(ctx context.Context, o *triple.Object) (float64, error) {
+	var c int64
+	for _, cp := range m.cps {
+		n, err := cp.ObjectCardinality(ctx, o)
+		if err != nil {
+			return 0, err
+		}
+		c += n
+	}
+	return float64(c), nil
+}
+
+// selectivity returns the fraction of the triples of the graphs matching the
+// constant components of the clause. Components are assumed independent, and
+// a constant subject to match the average number of triples per subject.
+func (m *costModel) selectivity(ctx context.Context, cls *semantic.GraphClause) (float64, error) {
+	sel := 1.0
+	if cls.S != nil {
+		sel /= m.subjects
+	}
+	id := cls.PID
+	if cls.P != nil {
+		id = string(cls.P.ID())
+	}
+	if id != "" {
+		c, err := m.predicateCardinality(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		sel *= c / m.triples
+	}
+	if cls.O != nil {
+		c, err := m.objectCardinality(ctx, cls.O)
+		if err != nil {
+			return 0, err
+		}
+		sel *= c / m.triples
+	}
+	return sel, nil
+}
+
+// estimate returns the rows expected per row of the table when resolving a
+// clause with the provided selectivity given the bindings already bound. Bound
+// components are assumed to match the average number of triples per distinct
+// value.
+func (m *costModel) estimate(cls *semantic.GraphClause, sel float64, bound func(string) bool) float64 {
+	e := m.triples * sel
+	if cls.S == nil && (bound(cls.SBinding) || bound(cls.SAlias)) {
+		e /= m.subjects
+	}
+	if cls.P == nil && (bound(cls.PBinding) || bound(cls.PAlias)) {
+		e /= m.predicates
+	}
+	if cls.O == nil && (bound(cls.OBinding) || bound(cls.OAlias)) {
+		e /= m.objects
+	}
+	return e
+}
+
+// joins returns true if resolving the clause does not require a cartesian
+// product with the bindings already bound; that is, if the clause binds
+// nothing or shares a binding with them.
+func joins(cls *semantic.GraphClause, bound func(string) bool) bool {
+	bs := cls.Bindings()
+	if len(bs) == 0 {
+		return true
+	}
+	for _, b := range bs {
+		if bound(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// order returns the clauses in the order they should be resolved. Starting
+// from the bindings already bound, it repeatedly picks the cheapest clause
+// among the ones that join with the clauses picked before, or the cheapest
+// overall if none does. Clauses with the same cost keep their relative order.
+func (m *costModel) order(ctx context.Context, cls []*semantic.GraphClause, bound func(string) bool) ([]*semantic.GraphClause, error) {
+	sels := make([]float64, len(cls))
+	for i, c := range cls {
+		s, err := m.selectivity(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		sels[i] = s
+	}
+	var (
+		res     []*semantic.GraphClause
+		used    = make([]bool, len(cls))
+		picked  = make(map[string]bool)
+		isBound = func(b string) bool { return picked[b] || bound(b) }
+	)
+	for len(res) < len(cls) {
+		best, joined, cost := -1, false, 0.0
+		for i, c := range cls {
+			if used[i] {
+				continue
+			}
+			j, e := joins(c, isBound), m.estimate(c, sels[i], isBound)
+			if best < 0 || j && !joined || j == joined && e < cost {
+				best, joined, cost = i, j, e
+			}
+		}
+		used[best] = true
+		res = append(res, cls[best])
+		for _, b := range cls[best].Bindings() {
+			picked[b] = true
+		}
+	}
+	return res, nil
+}
+
+// costsEnabled loads the statistics of the plan graphs and returns true if
+// they are available.
+func (p *queryPlan) costsEnabled(ctx context.Context) (bool, error) {
+	if p.costs == nil {
+		return false, nil
+	}
+	if err := p.costs.load(ctx); err != nil {
+		return false, err
+	}
+	return p.costs.enabled, nil
+}
+
+// estimateRows returns the rows expected after resolving the clause given the
+// bindings already bound and the rows available. It returns the rows available
+// if the graphs do not provide statistics.
+func (p *queryPlan) estimateRows(ctx context.Context, cls *semantic.GraphClause, bound func(string) bool, rows float64) (float64, error) {
+	ok, err := p.costsEnabled(ctx)
+	if err != nil || !ok {
+		return rows, err
+	}
+	sel, err := p.costs.selectivity(ctx, cls)
+	if err != nil {
+		return 0, err
+	}
+	return rows * p.costs.estimate(cls, sel, bound), nil
+}
+
+// accessMethod returns how the clause is resolved given the bindings already
+// bound and the rows available. Partially bound clauses are resolved by
+// joining all the triples matching their constant components with the rows
+// when those triples are estimated to be fewer than the rows, since looking
+// them up for each row would take more lookups than triples retrieved. Clauses
+// whose time bounds are taken from the rows are always looked up per row.
+func (p *queryPlan) accessMethod(ctx context.Context, cls *semantic.GraphClause, bound func(string) bool, rows float64) (accessMethod, error) {
+	m := clauseAccess(cls, bound)
+	if m != specifyAccess || cls.PLowerBoundAlias != "" || cls.PUpperBoundAlias != "" {
+		return m, nil
+	}
+	ok, err := p.costsEnabled(ctx)
+	if err != nil || !ok {
+		return m, err
+	}
+	sel, err := p.costs.selectivity(ctx, cls)
+	if err != nil {
+		return m, err
+	}
+	if p.costs.triples*sel < rows {
+		return joinAccess, nil
+	}
+	return m, nil
+}
+
+// orderClauses returns the clauses in the order they are resolved given the
+// bindings already bound. Clauses are ordered by their estimated cost if all
+// the graphs provide statistics. Otherwise, they keep their order by
+// specificity.
+func (p *queryPlan) orderClauses(ctx context.Context, cls []*semantic.GraphClause, bound func(string) bool) ([]*semantic.GraphClause, error) {
+	if len(cls) < 2 {
+		return cls, nil
+	}
+	ok, err := p.costsEnabled(ctx)
+	if err != nil || !ok {
+		return cls, err
+	}
+	return p.costs.order(ctx, cls, bound)
+}
@@ -0,0 +1,169 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/storage"
+)
+
+func TestOrderClauses(t *testing.T) {
+	ctx := context.Background()
+	g, err := populateTestStore(t).Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	testTable := []struct {
+		q      string
+		graphs []storage.Graph
+		bound  []string
+		want   []string
+	}{
+		{
+			// The subject of the bought clause makes it more selective.
+			q:      `select ?x from ?test where {?x "is_a"@[] /t<car> . /u<peter> "bought"@[?t] ?x};`,
+			graphs: []storage.Graph{g},
+			want:   []string{`/u<peter> "bought"@[?t] ?x`, `?x "is_a"@[] /t<car>`},
+		},
+		{
+			// Clauses that join with the ones already picked come first.
+			q:      `select ?p, ?car from ?test where {?p "parent_of"@[] ?c . ?c "bought"@[?t] ?car . ?car "is_a"@[] /t<car>};`,
+			graphs: []storage.Graph{g},
+			want:   []string{`?car "is_a"@[] /t<car>`, `?c "bought"@[?t] ?car`, `?p "parent_of"@[] ?c`},
+		},
+		{
+			// Bindings already bound make the clauses using them cheaper.
+			q:      `select ?p, ?car from ?test where {?p "parent_of"@[] ?c . ?c "bought"@[?t] ?car . ?car "is_a"@[] /t<car>};`,
+			graphs: []storage.Graph{g},
+			bound:  []string{"?p"},
+			want:   []string{`?p "parent_of"@[] ?c`, `?c "bought"@[?t] ?car`, `?car "is_a"@[] /t<car>`},
+		},
+		{
+			// Without statistics clauses are ordered by specificity.
+			q:      `select ?x from ?test where {?x "is_a"@[] /t<car> . /u<peter> "bought"@[?t] ?x};`,
+			graphs: []storage.Graph{&unstatedGraph{g}},
+			want:   []string{`?x "is_a"@[] /t<car>`, `/u<peter> "bought"@[?t] ?x`},
+		},
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error %v", entry.q, err)
+		}
+		bound := make(map[string]bool)
+		for _, b := range entry.bound {
+			bound[b] = true
+		}
+		qp := &queryPlan{costs: newCostModel(entry.graphs)}
+		cls, err := qp.orderClauses(ctx, st.SortedGraphPatternClauses(), func(b string) bool { return bound[b] })
+		if err != nil {
+			t.Fatalf("orderClauses failed for query %q with error %v", entry.q, err)
+		}
+		var got []string
+		for _, c := range cls {
+			got = append(got, c.String())
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("orderClauses returned the wrong order for query %q; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestAccessMethod(t *testing.T) {
+	ctx := context.Background()
+	g, err := populateTestStore(t).Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	testTable := []struct {
+		q      string
+		graphs []storage.Graph
+		bound  []string
+		rows   float64
+		want   accessMethod
+	}{
+		{
+			// Unbound clauses are always fetched.
+			q:      `select ?c from ?test where {?c "bought"@[?t] ?car};`,
+			graphs: []storage.Graph{g},
+			rows:   100,
+			want:   fetchAccess,
+		},
+		{
+			// There are more bought triples than rows to look them up with.
+			q:      `select ?c from ?test where {?c "bought"@[?t] ?car};`,
+			graphs: []storage.Graph{g},
+			bound:  []string{"?car"},
+			rows:   2,
+			want:   specifyAccess,
+		},
+		{
+			// There are fewer bought triples than rows to look them up with.
+			q:      `select ?c from ?test where {?c "bought"@[?t] ?car};`,
+			graphs: []storage.Graph{g},
+			bound:  []string{"?car"},
+			rows:   100,
+			want:   joinAccess,
+		},
+		{
+			// Time bounds taken from the rows require looking them up per row.
+			q:      `select ?c from ?test where {?c "bought"@[?lo, ?hi] ?car};`,
+			graphs: []storage.Graph{g},
+			bound:  []string{"?car", "?lo", "?hi"},
+			rows:   100,
+			want:   specifyAccess,
+		},
+		{
+			// Without statistics clauses are looked up per row.
+			q:      `select ?c from ?test where {?c "bought"@[?t] ?car};`,
+			graphs: []storage.Graph{&unstatedGraph{g}},
+			bound:  []string{"?car"},
+			rows:   100,
+			want:   specifyAccess,
+		},
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error %v", entry.q, err)
+		}
+		bound := make(map[string]bool)
+		for _, b := range entry.bound {
+			bound[b] = true
+		}
+		qp := &queryPlan{costs: newCostModel(entry.graphs)}
+		got, err := qp.accessMethod(ctx, st.SortedGraphPatternClauses()[0], func(b string) bool { return bound[b] }, entry.rows)
+		if err != nil {
+			t.Fatalf("accessMethod failed for query %q with error %v", entry.q, err)
+		}
+		if got != entry.want {
+			t.Errorf("accessMethod returned the wrong method for query %q with %v rows; got %v, want %v", entry.q, entry.rows, got, entry.want)
+		}
+	}
+}
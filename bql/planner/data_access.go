@@ -91,10 +91,10 @@ func simpleExist(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 		}
 		if b {
 			unfeasible = false
-			ts := make(chan *triple.Triple, 1)
-			ts <- t
-			close(ts)
-			if err := addTriples(ts, cls, tbl); err != nil {
+			if err := addTriple(t, cls, func(r table.Row) error {
+				tbl.AddRow(r)
+				return nil
+			}); err != nil {
 				return true, nil, err
 			}
 		}
@@ -106,33 +106,43 @@ func simpleExist(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 // clause by querying the provided stora. Will return an error if it had poblems
 // retrieveing the data.
 func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphClause, lo *storage.LookupOptions, chanSize int) (*table.Table, error) {
-	s, p, o := cls.S, cls.P, cls.O
-	lo = updateTimeBounds(lo, cls)
 	tbl, err := table.New(cls.Bindings())
 	if err != nil {
 		return nil, err
 	}
+	if err := fetchRows(ctx, gs, cls, lo, chanSize, func(r table.Row) error {
+		tbl.AddRow(r)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return tbl, nil
+}
+
+// fetchRows retrieves the data specified by the graph clause by querying the
+// provided graphs, and calls add with each row as soon as it is retrieved. It
+// returns the first error returned by add, if any.
+func fetchRows(ctx context.Context, gs []storage.Graph, cls *semantic.GraphClause, lo *storage.LookupOptions, chanSize int, add func(table.Row) error) error {
+	s, p, o := cls.S, cls.P, cls.O
+	lo = updateTimeBounds(lo, cls)
 	if s != nil && p != nil && o != nil {
 		// Fully qualified triple.
 		t, err := triple.New(s, p, o)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		for _, g := range gs {
 			b, err := g.Exist(ctx, t)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			if b {
-				ts := make(chan *triple.Triple, 1)
-				ts <- t
-				close(ts)
-				if err := addTriples(ts, cls, tbl); err != nil {
-					return nil, err
+				if err := addTriple(t, cls, add); err != nil {
+					return err
 				}
 			}
 		}
-		return tbl, nil
+		return nil
 	}
 	if s != nil && p != nil && o == nil {
 		// SP request.
@@ -152,7 +162,7 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 			ts := make(chan *triple.Triple, chanSize)
 			go func() {
 				defer wg.Done()
-				aErr = addTriples(ts, cls, tbl)
+				aErr = addTriples(ts, cls, add)
 			}()
 			for o := range os {
 				if lErr != nil {
@@ -169,16 +179,16 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 			close(ts)
 			wg.Wait()
 			if oErr != nil {
-				return nil, oErr
+				return oErr
 			}
 			if aErr != nil {
-				return nil, aErr
+				return aErr
 			}
 			if lErr != nil {
-				return nil, lErr
+				return lErr
 			}
 		}
-		return tbl, nil
+		return nil
 	}
 	if s != nil && p == nil && o != nil {
 		// SO request.
@@ -198,7 +208,7 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 			ts := make(chan *triple.Triple, chanSize)
 			go func() {
 				defer wg.Done()
-				aErr = addTriples(ts, cls, tbl)
+				aErr = addTriples(ts, cls, add)
 			}()
 			for p := range ps {
 				if lErr != nil {
@@ -215,16 +225,16 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 			close(ts)
 			wg.Wait()
 			if pErr != nil {
-				return nil, pErr
+				return pErr
 			}
 			if aErr != nil {
-				return nil, aErr
+				return aErr
 			}
 			if lErr != nil {
-				return nil, lErr
+				return lErr
 			}
 		}
-		return tbl, nil
+		return nil
 	}
 	if s == nil && p != nil && o != nil {
 		// PO request.
@@ -244,7 +254,7 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 			ts := make(chan *triple.Triple, chanSize)
 			go func() {
 				defer wg.Done()
-				aErr = addTriples(ts, cls, tbl)
+				aErr = addTriples(ts, cls, add)
 			}()
 			for s := range ss {
 				if lErr != nil {
@@ -261,16 +271,16 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 			close(ts)
 			wg.Wait()
 			if pErr != nil {
-				return nil, pErr
+				return pErr
 			}
 			if aErr != nil {
-				return nil, aErr
+				return aErr
 			}
 			if lErr != nil {
-				return nil, lErr
+				return lErr
 			}
 		}
-		return tbl, nil
+		return nil
 	}
 	if s != nil && p == nil && o == nil {
 		// S request.
@@ -286,16 +296,16 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 				defer wg.Done()
 				tErr = g.TriplesForSubject(ctx, s, lo, ts)
 			}()
-			aErr = addTriples(ts, cls, tbl)
+			aErr = addTriples(ts, cls, add)
 			wg.Wait()
 			if tErr != nil {
-				return nil, tErr
+				return tErr
 			}
 			if aErr != nil {
-				return nil, aErr
+				return aErr
 			}
 		}
-		return tbl, nil
+		return nil
 	}
 	if s == nil && p != nil && o == nil {
 		// P request.
@@ -311,16 +321,16 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 				defer wg.Done()
 				tErr = g.TriplesForPredicate(ctx, p, lo, ts)
 			}()
-			aErr = addTriples(ts, cls, tbl)
+			aErr = addTriples(ts, cls, add)
 			wg.Wait()
 			if tErr != nil {
-				return nil, tErr
+				return tErr
 			}
 			if aErr != nil {
-				return nil, aErr
+				return aErr
 			}
 		}
-		return tbl, nil
+		return nil
 	}
 	if s == nil && p == nil && o != nil {
 		// O request.
@@ -335,16 +345,16 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 				defer wg.Done()
 				tErr = g.TriplesForObject(ctx, o, lo, ts)
 			}()
-			aErr := addTriples(ts, cls, tbl)
+			aErr := addTriples(ts, cls, add)
 			wg.Wait()
 			if tErr != nil {
-				return nil, tErr
+				return tErr
 			}
 			if aErr != nil {
-				return nil, aErr
+				return aErr
 			}
 		}
-		return tbl, nil
+		return nil
 	}
 	if s == nil && p == nil && o == nil {
 		// Full data request.
@@ -360,81 +370,95 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 				defer wg.Done()
 				tErr = g.Triples(ctx, ts)
 			}()
-			aErr = addTriples(ts, cls, tbl)
+			aErr = addTriples(ts, cls, add)
 			wg.Wait()
 			if tErr != nil {
-				return nil, tErr
+				return tErr
 			}
 			if aErr != nil {
-				return nil, aErr
+				return aErr
 			}
 		}
-		return tbl, nil
+		return nil
 	}
 
-	return nil, fmt.Errorf("planner.simpleFetch could not recognize request in clause %v", cls)
+	return fmt.Errorf("planner.simpleFetch could not recognize request in clause %v", cls)
 }
 
-// addTriples add all the retrieved triples from the graphs into the results
-// table. The semantic graph clause is also passed to be able to identify what
-// bindings to set.
-func addTriples(ts <-chan *triple.Triple, cls *semantic.GraphClause, tbl *table.Table) error {
+// addTriples converts all the retrieved triples from the graphs into rows and
+// calls add with them. The semantic graph clause is also passed to be able to
+// identify what bindings to set. After the first error the remaining triples
+// are drained, so the goroutines sending them are not leaked.
+func addTriples(ts <-chan *triple.Triple, cls *semantic.GraphClause, add func(table.Row) error) error {
+	var aErr error
 	for t := range ts {
-		if cls.PID != "" {
+		if aErr != nil {
+			continue
+		}
+		if err := addTriple(t, cls, add); err != nil {
+			aErr = err
+		}
+	}
+	return aErr
+}
+
+// addTriple converts the triple into a row and calls add with it, unless the
+// triple does not match the clause.
+func addTriple(t *triple.Triple, cls *semantic.GraphClause, add func(table.Row) error) error {
+	if cls.PID != "" {
+		// The triples need to be filtered.
+		if string(t.Predicate().ID()) != cls.PID {
+			return nil
+		}
+		if cls.PTemporal {
+			if t.Predicate().Type() != predicate.Temporal {
+				return nil
+			}
+			ta, err := t.Predicate().TimeAnchor()
+			if err != nil {
+				return fmt.Errorf("failed to retrieve time anchor from time predicate in triple %s with error %v", t, err)
+			}
+			// Need to check teh bounds of the triple.
+			if cls.PLowerBound != nil && cls.PLowerBound.After(*ta) {
+				return nil
+			}
+			if cls.PUpperBound != nil && cls.PUpperBound.Before(*ta) {
+				return nil
+			}
+		}
+	}
+	if cls.OID != "" {
+		if p, err := t.Object().Predicate(); err == nil {
 			// The triples need to be filtered.
-			if string(t.Predicate().ID()) != cls.PID {
-				continue
+			if string(p.ID()) != cls.OID {
+				return nil
 			}
-			if cls.PTemporal {
-				if t.Predicate().Type() != predicate.Temporal {
-					continue
+			if cls.OTemporal {
+				if p.Type() != predicate.Temporal {
+					return nil
 				}
-				ta, err := t.Predicate().TimeAnchor()
+				ta, err := p.TimeAnchor()
 				if err != nil {
 					return fmt.Errorf("failed to retrieve time anchor from time predicate in triple %s with error %v", t, err)
 				}
 				// Need to check teh bounds of the triple.
-				if cls.PLowerBound != nil && cls.PLowerBound.After(*ta) {
-					continue
-				}
-				if cls.PUpperBound != nil && cls.PUpperBound.Before(*ta) {
-					continue
-				}
-			}
-		}
-		if cls.OID != "" {
-			if p, err := t.Object().Predicate(); err == nil {
-				// The triples need to be filtered.
-				if string(p.ID()) != cls.OID {
-					continue
+				if cls.OLowerBound != nil && cls.OLowerBound.After(*ta) {
+					return nil
 				}
-				if cls.OTemporal {
-					if p.Type() != predicate.Temporal {
-						continue
-					}
-					ta, err := p.TimeAnchor()
-					if err != nil {
-						return fmt.Errorf("failed to retrieve time anchor from time predicate in triple %s with error %v", t, err)
-					}
-					// Need to check teh bounds of the triple.
-					if cls.OLowerBound != nil && cls.OLowerBound.After(*ta) {
-						continue
-					}
-					if cls.OUpperBound != nil && cls.OUpperBound.Before(*ta) {
-						continue
-					}
+				if cls.OUpperBound != nil && cls.OUpperBound.Before(*ta) {
+					return nil
 				}
 			}
 		}
-		r, err := tripleToRow(t, cls)
-		if err != nil {
-			return err
-		}
-		if r != nil {
-			tbl.AddRow(r)
-		}
 	}
-	return nil
+	r, err := tripleToRow(t, cls)
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		return nil
+	}
+	return add(r)
 }
 
 // objectToCell returns a cell containing the data boxed in the object.
@@ -228,7 +228,10 @@ func TestDataAccessAddTriples(t *testing.T) {
 	}()
 	go func() {
 		defer wg.Done()
-		if err := addTriples(ts, cls, tbl); err != nil {
+		if err := addTriples(ts, cls, func(r table.Row) error {
+			tbl.AddRow(r)
+			return nil
+		}); err != nil {
 			t.Errorf("addTriple failed with errorf %v", err)
 		}
 	}()
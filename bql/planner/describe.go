@@ -0,0 +1,145 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/node"
+)
+
+// describePlan encapsulates the sequence of instructions that need to be
+// executed in order to satisfy the execution of a valid describe BQL
+// statement.
+type describePlan struct {
+	stm      *semantic.Statement
+	store    storage.Store
+	chanSize int
+	snapshot snapshotFinder
+}
+
+// fetchTriples returns all the triples the provided lookup pushes to the
+// channel it is given.
+func fetchTriples(chanSize int, lookup func(trpls chan<- *triple.Triple) error) ([]*triple.Triple, error) {
+	var (
+		lErr error
+		wg   sync.WaitGroup
+		res  []*triple.Triple
+	)
+	ts := make(chan *triple.Triple, chanSize)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		lErr = lookup(ts)
+	}()
+	for t := range ts {
+		res = append(res, t)
+	}
+	wg.Wait()
+	return res, lErr
+}
+
+// nodeTriples returns the triples of the graph where the provided node is
+// either the subject or the object.
+func nodeTriples(ctx context.Context, g storage.Graph, n *node.Node, chanSize int) ([]*triple.Triple, error) {
+	ss, err := fetchTriples(chanSize, func(trpls chan<- *triple.Triple) error {
+		return g.TriplesForSubject(ctx, n, storage.DefaultLookup, trpls)
+	})
+	if err != nil {
+		return nil, err
+	}
+	os, err := fetchTriples(chanSize, func(trpls chan<- *triple.Triple) error {
+		return g.TriplesForObject(ctx, triple.NewNodeObject(n), storage.DefaultLookup, trpls)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(ss, os...), nil
+}
+
+// describe returns the triples the described node takes part in. When the
+// depth is larger than 1, the nodes found as objects of those triples are
+// also described, until the requested number of hops is reached. Each triple
+// is only returned once.
+func (p *describePlan) describe(ctx context.Context) ([]*triple.Triple, error) {
+	gs, err := resolveGraphs(ctx, p.store, p.stm, p.snapshot)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		ts       []*triple.Triple
+		seen     = make(map[string]bool)
+		n        = p.stm.DescribedNode()
+		visited  = map[string]bool{n.String(): true}
+		frontier = []*node.Node{n}
+	)
+	for d := int64(0); d < p.stm.DescribeDepth() && len(frontier) > 0; d++ {
+		var next []*node.Node
+		for _, n := range frontier {
+			for _, g := range gs {
+				nts, err := nodeTriples(ctx, g, n, p.chanSize)
+				if err != nil {
+					return nil, err
+				}
+				for _, t := range nts {
+					if seen[t.String()] {
+						continue
+					}
+					seen[t.String()] = true
+					ts = append(ts, t)
+					if t.Subject().String() != n.String() {
+						continue
+					}
+					if on, err := t.Object().Node(); err == nil && !visited[on.String()] {
+						visited[on.String()] = true
+						next = append(next, on)
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+	return ts, nil
+}
+
+// Execute returns the triples the described node takes part in as a table
+// with one row per triple bound to ?s, ?p, and ?o.
+func (p *describePlan) Execute(ctx context.Context) (*table.Table, error) {
+	ts, err := p.describe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return triplesTable(ts)
+}
+
+// Triples sends the triples the described node takes part in to the provided
+// channel.
+func (p *describePlan) Triples(ctx context.Context, trpls chan<- *triple.Triple) error {
+	defer close(trpls)
+	ts, err := p.describe(ctx)
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		trpls <- t
+	}
+	return nil
+}
@@ -0,0 +1,426 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// accessMethod identifies how the query plan resolves a clause.
+type accessMethod int
+
+const (
+	// pathAccess walks the property path of the clause.
+	pathAccess accessMethod = iota
+	// existAccess checks if the fully specified triple of the clause exists.
+	existAccess
+	// fetchAccess retrieves all the triples that match the clause.
+	fetchAccess
+	// specifyAccess retrieves the triples that match the clause once it is
+	// specified with the values of each row.
+	specifyAccess
+	// filterAccess removes the rows whose values do not form an existing triple.
+	filterAccess
+	// joinAccess retrieves all the triples that match the constant components
+	// of the clause and joins them with the rows.
+	joinAccess
+)
+
+// String returns the name of the function implementing the access method.
+func (m accessMethod) String() string {
+	switch m {
+	case pathAccess:
+		return "processPathClause"
+	case existAccess:
+		return "simpleExist"
+	case fetchAccess:
+		return "simpleFetch"
+	case specifyAccess:
+		return "specifyClauseWithTable"
+	case filterAccess:
+		return "filterOnExistence"
+	case joinAccess:
+		return "joinClause"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// clauseAccess returns the access method used to resolve the clause given the
+// bindings already bound.
+func clauseAccess(cls *semantic.GraphClause, bound func(string) bool) accessMethod {
+	if cls.PPath {
+		return pathAccess
+	}
+	if cls.Specificity() == 3 {
+		return existAccess
+	}
+	exist, total := 0, 0
+	for _, b := range cls.Bindings() {
+		total++
+		if bound(b) {
+			exist++
+		}
+	}
+	switch {
+	case exist == 0:
+		return fetchAccess
+	case exist < total:
+		return specifyAccess
+	default:
+		return filterAccess
+	}
+}
+
+// graphLookup returns the storage.Graph lookup used to retrieve the triples
+// given which of the subject, predicate, and object are known.
+func graphLookup(s, p, o bool) string {
+	switch {
+	case s && p && o:
+		return "Exist"
+	case s && p:
+		return "Objects"
+	case s && o:
+		return "PredicatesForSubjectAndObject"
+	case p && o:
+		return "Subjects"
+	case s:
+		return "TriplesForSubject"
+	case p:
+		return "TriplesForPredicate"
+	case o:
+		return "TriplesForObject"
+	default:
+		return "Triples"
+	}
+}
+
+// clauseLookup returns the storage.Graph lookups the access method uses to
+// resolve the clause given the bindings already bound.
+func clauseLookup(cls *semantic.GraphClause, m accessMethod, bound func(string) bool) string {
+	switch m {
+	case existAccess, filterAccess:
+		return "Exist"
+	case fetchAccess, joinAccess:
+		return graphLookup(cls.S != nil, cls.P != nil, cls.O != nil)
+	case specifyAccess:
+		return graphLookup(
+			cls.S != nil || bound(cls.SBinding) || bound(cls.SAlias),
+			cls.P != nil || bound(cls.PBinding) || bound(cls.PAlias),
+			cls.O != nil || bound(cls.OBinding) || bound(cls.OAlias))
+	}
+	// Property paths walk the graph one hop at a time.
+	forward, backward := "TriplesForSubjectAndPredicate", "TriplesForPredicateAndObject"
+	start := "TriplesForPredicate"
+	if cls.P == nil {
+		forward, backward, start = "TriplesForSubject", "TriplesForObject", "Triples"
+	}
+	switch {
+	case cls.S != nil || bound(cls.SBinding):
+		return forward
+	case cls.O != nil || bound(cls.OBinding):
+		return backward
+	default:
+		return start + ", " + forward
+	}
+}
+
+// boundsString returns a readable representation of the time bounds applied
+// to the lookups of the clause. Bounds taken from bindings are listed by name.
+// Existence checks of fully specified triples apply no bounds.
+func boundsString(lo *storage.LookupOptions, cls *semantic.GraphClause, m accessMethod) string {
+	if m == existAccess || m == filterAccess {
+		return ""
+	}
+	lo = updateTimeBounds(lo, cls)
+	lower, upper := cls.PLowerBoundAlias, cls.PUpperBoundAlias
+	if lower == "" && lo.LowerAnchor != nil {
+		lower = lo.LowerAnchor.Format(time.RFC3339Nano)
+	}
+	if upper == "" && lo.UpperAnchor != nil {
+		upper = lo.UpperAnchor.Format(time.RFC3339Nano)
+	}
+	switch {
+	case lower != "" && upper != "":
+		return fmt.Sprintf("between %s, %s", lower, upper)
+	case lower != "":
+		return "after " + lower
+	case upper != "":
+		return "before " + upper
+	default:
+		return ""
+	}
+}
+
+// subPattern returns the label of a graph pattern nested in the one labeled
+// parent.
+func subPattern(parent, format string, a ...interface{}) string {
+	l := fmt.Sprintf(format, a...)
+	if parent == "" || parent == wherePattern {
+		return l
+	}
+	return parent + ", " + l
+}
+
+// wherePattern labels the graph pattern of the where clause of a query.
+const wherePattern = "where"
+
+// explainStep describes how a clause is resolved.
+type explainStep struct {
+	pattern  string
+	clause   string
+	method   accessMethod
+	lookup   string
+	bindings string
+	bounds   string
+	// Only collected when the query is analyzed.
+	rows    int
+	elapsed time.Duration
+}
+
+// explanation collects the steps run to resolve the graph pattern of a query.
+type explanation struct {
+	steps []*explainStep
+}
+
+// record adds the step resolving the clause with the provided access method
+// given the bindings already bound, and returns the function that completes it
+// with the rows available once the clause is processed. It does nothing on a
+// nil explanation.
+func (e *explanation) record(pattern string, cls *semantic.GraphClause, m accessMethod, bound func(string) bool, lo *storage.LookupOptions) func(rows int) {
+	if e == nil {
+		return func(int) {}
+	}
+	bs := cls.Bindings()
+	sort.Strings(bs)
+	s := &explainStep{
+		pattern:  pattern,
+		clause:   cls.String(),
+		method:   m,
+		lookup:   clauseLookup(cls, m, bound),
+		bindings: strings.Join(bs, ", "),
+		bounds:   boundsString(lo, cls, m),
+	}
+	e.steps = append(e.steps, s)
+	start := time.Now()
+	return func(rows int) {
+		s.rows = rows
+		s.elapsed = time.Since(start)
+	}
+}
+
+// explainClauses adds the steps resolving the clauses in the order the plan
+// resolves them starting from the provided bound bindings, which get updated
+// with the clause bindings. As in processClauses, the clauses depending on the
+// bound bindings come first, followed by each group of independent clauses.
+func (e *explanation) explainClauses(ctx context.Context, p *queryPlan, pattern string, cls []*semantic.GraphClause, bound map[string]bool, lo *storage.LookupOptions) error {
+	dep, groups := independentClauses(cls, func(b string) bool { return bound[b] })
+	if len(groups) == 0 || len(groups) == 1 && len(dep) == 0 {
+		return e.explainGroup(ctx, p, pattern, cls, bound, lo)
+	}
+	if err := e.explainGroup(ctx, p, pattern, dep, bound, lo); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		gb := make(map[string]bool)
+		if err := e.explainGroup(ctx, p, pattern, g, gb, lo); err != nil {
+			return err
+		}
+		for b := range gb {
+			bound[b] = true
+		}
+	}
+	return nil
+}
+
+// explainGroup adds the steps resolving the clauses one at a time in the
+// order the plan resolves them starting from the provided bound bindings,
+// which get updated with the clause bindings. Since the rows available are not
+// known until the query runs, the access methods are chosen using their
+// estimated number.
+func (e *explanation) explainGroup(ctx context.Context, p *queryPlan, pattern string, cls []*semantic.GraphClause, bound map[string]bool, lo *storage.LookupOptions) error {
+	isBound := func(b string) bool { return bound[b] }
+	cls, err := p.orderClauses(ctx, cls, isBound)
+	if err != nil {
+		return err
+	}
+	rows := 1.0
+	for _, c := range cls {
+		m, err := p.accessMethod(ctx, c, isBound, rows)
+		if err != nil {
+			return err
+		}
+		e.record(pattern, c, m, isBound, lo)
+		if rows, err = p.estimateRows(ctx, c, isBound, rows); err != nil {
+			return err
+		}
+		for _, b := range c.Bindings() {
+			bound[b] = true
+		}
+	}
+	return nil
+}
+
+// explainGraphPattern adds the steps the query plan runs to resolve the graph
+// pattern of its statement, following the same order as processGraphPattern.
+func (e *explanation) explainGraphPattern(ctx context.Context, p *queryPlan, pattern string, lo *storage.LookupOptions) error {
+	bound := make(map[string]bool)
+	for i, sub := range p.stm.Subqueries() {
+		sp, err := newQueryPlan(ctx, p.store, sub, p.chanSize, p.workers, p.budget, p.snapshot)
+		if err != nil {
+			return err
+		}
+		if err := e.explainGraphPattern(ctx, sp, subPattern(pattern, "subquery %d", i+1), sub.GlobalLookupOptions()); err != nil {
+			return err
+		}
+		for _, b := range sub.OutputBindings() {
+			bound[b] = true
+		}
+	}
+	if err := e.explainClauses(ctx, p, pattern, p.cls, bound, lo); err != nil {
+		return err
+	}
+	for i, u := range p.stm.SortedUnionGraphPatterns() {
+		for j, cls := range u.Alternatives {
+			if err := e.explainClauses(ctx, p, subPattern(pattern, "union %d alternative %d", i+1, j+1), cls, make(map[string]bool), lo); err != nil {
+				return err
+			}
+		}
+		for _, b := range u.Bindings() {
+			bound[b] = true
+		}
+	}
+	for i, cls := range p.stm.SortedOptionalGraphPatternClauses() {
+		if err := e.explainClauses(ctx, p, subPattern(pattern, "optional %d", i+1), cls, make(map[string]bool), lo); err != nil {
+			return err
+		}
+		for _, c := range cls {
+			for _, b := range c.Bindings() {
+				bound[b] = true
+			}
+		}
+	}
+	for i, ex := range p.stm.ExistenceGraphPatterns() {
+		seed := make(map[string]bool)
+		for _, c := range ex.Clauses {
+			for _, b := range c.Bindings() {
+				seed[b] = bound[b]
+			}
+		}
+		if err := e.explainClauses(ctx, p, subPattern(pattern, existencePattern(ex), i+1), ex.SortedClauses(), seed, lo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// existencePattern returns the label format of an existence graph pattern.
+func existencePattern(e *semantic.ExistenceGraphPattern) string {
+	if e.Negated {
+		return "not exists %d"
+	}
+	return "exists %d"
+}
+
+// explainBindings contains the bindings of the table returned by explain
+// statements. Analyzed statements also bind ?rows and ?time.
+var explainBindings = []string{
+	"?step", "?pattern", "?clause", "?method", "?lookup", "?bindings", "?bounds",
+}
+
+// table returns the steps of the explanation as a table with one row per step.
+func (e *explanation) table(analyze bool) (*table.Table, error) {
+	bs := append([]string{}, explainBindings...)
+	if analyze {
+		bs = append(bs, "?rows", "?time")
+	}
+	t, err := table.New(bs)
+	if err != nil {
+		return nil, err
+	}
+	str := func(s string) *table.Cell {
+		return &table.Cell{S: &s}
+	}
+	for i, s := range e.steps {
+		n, err := literal.DefaultBuilder().Build(literal.Int64, int64(i+1))
+		if err != nil {
+			return nil, err
+		}
+		r := table.Row{
+			"?step":     &table.Cell{L: n},
+			"?pattern":  str(s.pattern),
+			"?clause":   str(s.clause),
+			"?method":   str(s.method.String()),
+			"?lookup":   str(s.lookup),
+			"?bindings": str(s.bindings),
+			"?bounds":   str(s.bounds),
+		}
+		if analyze {
+			rws, err := literal.DefaultBuilder().Build(literal.Int64, int64(s.rows))
+			if err != nil {
+				return nil, err
+			}
+			r["?rows"] = &table.Cell{L: rws}
+			r["?time"] = str(s.elapsed.String())
+		}
+		t.AddRow(r)
+	}
+	return t, nil
+}
+
+// explainPlan encapsulates the sequence of instructions that need to be
+// executed in order to satisfy the execution of a valid explain BQL statement.
+type explainPlan struct {
+	qp      *queryPlan
+	analyze bool
+}
+
+// newExplainPlan returns a plan that explains the provided query.
+func newExplainPlan(ctx context.Context, store storage.Store, stm *semantic.Statement, chanSize int, workers workerPool, budget memoryBudget, snapshot snapshotFinder) (*explainPlan, error) {
+	qp, err := newQueryPlan(ctx, store, stm, chanSize, workers, budget, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &explainPlan{
+		qp:      qp,
+		analyze: stm.ExplainAnalyze(),
+	}, nil
+}
+
+// Execute returns a table describing the steps run to resolve the graph
+// pattern of the query. Analyzed queries are executed to also report the rows
+// available after each step and the time spent on it.
+func (p *explainPlan) Execute(ctx context.Context) (*table.Table, error) {
+	e := &explanation{}
+	if p.analyze {
+		p.qp.explain, p.qp.pattern = e, wherePattern
+		if _, err := p.qp.Execute(ctx); err != nil {
+			return nil, err
+		}
+	} else if err := e.explainGraphPattern(ctx, p.qp, wherePattern, p.qp.stm.GlobalLookupOptions()); err != nil {
+		return nil, err
+	}
+	return e.table(p.analyze)
+}
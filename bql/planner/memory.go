@@ -0,0 +1,210 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+)
+
+// memoryBudget bounds the rows a query holds in memory.
+type memoryBudget struct {
+	// bytes is the estimated number of bytes the query can use to hold rows
+	// in memory. A budget of 0 does not limit them.
+	bytes int64
+	// dir is the directory where the rows sorted or grouped by the query are
+	// spilled. The default directory for temporary files is used if empty.
+	dir string
+}
+
+// MemoryBudgetError is returned when a query needs to hold more rows in memory
+// than its memory budget allows.
+type MemoryBudgetError struct {
+	// Rows is the number of rows that needed to be held in memory.
+	Rows int
+	// Size is the estimated size of those rows in bytes.
+	Size int64
+	// Budget is the memory budget of the query in bytes.
+	Budget int64
+}
+
+// Error returns a readable description of the error.
+func (e *MemoryBudgetError) Error() string {
+	return fmt.Sprintf("query exceeded its memory budget of %d bytes holding %d rows of about %d bytes; increase the budget or make the query more selective", e.Budget, e.Rows, e.Size)
+}
+
+// checkBudget returns an error if the rows of the table exceed the memory
+// budget of the plan.
+func (p *queryPlan) checkBudget(tbl *table.Table) error {
+	if p.budget.bytes <= 0 {
+		return nil
+	}
+	if s := tbl.Size(); s > p.budget.bytes {
+		return &MemoryBudgetError{
+			Rows:   tbl.NumRows(),
+			Size:   s,
+			Budget: p.budget.bytes,
+		}
+	}
+	return nil
+}
+
+// spills returns true if the rows the query sorts or groups are passed to an
+// external sorter as they are produced, instead of being held in memory, so
+// they can be spilled to temporary files if they exceed the memory budget.
+// Ordered queries with a cursor use the sorter even without a budget, so the
+// rows before the cursor are dropped as they are produced.
+func (p *queryPlan) spills() bool {
+	if p.explain != nil || !p.streamsGraphPattern() {
+		return false
+	}
+	ordered, grouped := len(p.stm.OrderByConfig()) > 0, len(p.stm.GroupByBindings()) > 0
+	if p.budget.bytes > 0 {
+		return ordered || grouped
+	}
+	return ordered && !grouped && p.stm.Cursor() != nil
+}
+
+// spillSort resolves the graph pattern passing the resulting rows to an
+// external sorter, and replaces the table with the sorted rows, grouped and
+// filtered by the having clause if needed. Ordered rows before the cursor are
+// dropped before they reach the sorter, and rows are held in memory only until
+// the ones required by the offset and limit clauses are found.
+func (p *queryPlan) spillSort(ctx context.Context, lo *storage.LookupOptions) error {
+	grouped, c := len(p.stm.GroupByBindings()) > 0, p.stm.Cursor()
+	cfg, bs := p.stm.TotalOrderConfig(), p.stm.OutputBindings()
+	if grouped {
+		gcfg, gbs, _, err := p.groupByConfig(nil)
+		if err != nil {
+			return err
+		}
+		cfg, bs = gcfg, gbs
+	} else {
+		// Rows may be sorted by bindings that are not returned.
+		for _, c := range cfg {
+			bs = append(bs, c.Binding)
+		}
+	}
+	s := table.NewSorter(cfg, p.budget.bytes, p.budget.dir)
+	defer s.Close()
+	var first table.Row
+	if err := p.streamGraphPattern(ctx, lo, func(r table.Row) error {
+		ok, err := p.keepRow(r)
+		if err != nil || !ok {
+			return err
+		}
+		if err := p.projectRow(r); err != nil {
+			return err
+		}
+		if !grouped && c != nil && c.Compare(r) < 0 {
+			return nil
+		}
+		if first == nil {
+			first = r
+		}
+		nr := table.Row{}
+		for _, b := range bs {
+			if c, ok := r[b]; ok {
+				nr[b] = c
+			}
+		}
+		return s.Add(nr)
+	}); err != nil {
+		return err
+	}
+	tbl, err := table.New(nil)
+	if err != nil {
+		return err
+	}
+	if grouped {
+		_, gbs, aaps, err := p.groupByConfig(first)
+		if err != nil {
+			return err
+		}
+		tbl.AddBindings(gbs)
+		if err := tbl.ReduceSorted(s, aaps); err != nil {
+			return err
+		}
+		if err := p.checkBudget(tbl); err != nil {
+			return err
+		}
+		p.tbl = tbl
+		p.after()
+		p.orderBy()
+		return p.having()
+	}
+	tbl.AddBindings(p.stm.OutputBindings())
+	p.tbl = tbl
+	return p.mergeOrdered(s)
+}
+
+// mergeOrdered adds to the table the ordered rows of the sorter that come
+// after the cursor and hold for the having clause. It stops once the table
+// holds the rows required by the offset and limit clauses, plus one more to
+// tell if a continuation token is needed.
+func (p *queryPlan) mergeOrdered(s *table.Sorter) error {
+	var (
+		c    = p.stm.Cursor()
+		skip int64
+		keep = int64(-1)
+		size int64
+	)
+	if c != nil {
+		skip = c.Skip
+	}
+	if p.stm.IsLimitSet() {
+		keep = p.stm.Limit() + 1
+		if p.stm.IsOffsetSet() {
+			keep += p.stm.Offset()
+		}
+	}
+	err := s.Merge(func(r table.Row) error {
+		if c != nil {
+			switch cmp := c.Compare(r); {
+			case cmp < 0:
+				return nil
+			case cmp == 0 && skip > 0:
+				skip--
+				return nil
+			}
+		}
+		if p.stm.HasHavingClause() {
+			ok, err := p.stm.HavingEvaluator().Evaluate(r)
+			if err != nil || !ok {
+				return err
+			}
+		}
+		p.tbl.AddRow(r)
+		if size += table.RowSize(r); p.budget.bytes > 0 && size > p.budget.bytes {
+			return &MemoryBudgetError{
+				Rows:   p.tbl.NumRows(),
+				Size:   size,
+				Budget: p.budget.bytes,
+			}
+		}
+		if keep >= 0 && int64(p.tbl.NumRows()) >= keep {
+			return errStopStream
+		}
+		return nil
+	})
+	if err == errStopStream {
+		return nil
+	}
+	return err
+}
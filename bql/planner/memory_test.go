@@ -0,0 +1,157 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/bql/semantic"
+)
+
+func TestMemoryBudgetSpills(t *testing.T) {
+	s := populateTestStore(t)
+	ss := NewSession(s)
+	queries := []string{
+		`select ?s, ?p, ?o from ?test where {?s ?p ?o} order by ?s, ?p, ?o limit "3"^^type:int64;`,
+		`select ?s, ?o from ?test where {?s "connects_to"@[] ?o} order by ?o desc limit "2"^^type:int64 offset "1"^^type:int64;`,
+		`select ?s, ?r from ?test where {?s "connects_to"@[] ?r} order by ?s having contains(?r, "Kit") limit "4"^^type:int64;`,
+		`select ?p, ?car from ?test where {?p "parent_of"@[] ?c . ?c "bought"@[?t] ?car} order by ?car desc limit "2"^^type:int64;`,
+		`select ?s, count(?o) as ?n from ?test where {?s ?p ?o} group by ?s;`,
+		`select ?s, count(?o) as ?n from ?test where {?s ?p ?o} group by ?s order by ?n desc, ?s;`,
+		`select ?grandparent, count(distinct ?name) as ?grandchildren from ?test where {/u<joe> as ?grandparent "parent_of"@[] ?offspring . ?offspring "parent_of"@[] ?name} group by ?grandparent;`,
+		`select ?s, count(?o) as ?n from ?test where {?s "no_such_predicate"@[] ?o} group by ?s;`,
+	}
+	for _, q := range queries {
+		ss.SetMemoryBudget(0)
+		want := mustRunInSession(t, ss, q)
+		// The rows sorted or grouped spill to several runs.
+		ss.SetMemoryBudget(5000)
+		got := mustRunInSession(t, ss, q)
+		if !reflect.DeepEqual(got.Bindings(), want.Bindings()) {
+			t.Errorf("query %q returned the wrong bindings with a memory budget; got %v, want %v", q, got.Bindings(), want.Bindings())
+		}
+		if got, want := got.String(), want.String(); got != want {
+			t.Errorf("query %q returned different results with a memory budget; got\n%s\nwant\n%s", q, got, want)
+		}
+		if got, want := got.ContinuationToken(), want.ContinuationToken(); got != want {
+			t.Errorf("query %q returned a different continuation token with a memory budget; got %q, want %q", q, got, want)
+		}
+	}
+}
+
+func TestMemoryBudgetSpillDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badwolf_spill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := populateTestStore(t)
+	ss := NewSession(s)
+	ss.SetMemoryBudget(5000)
+	q := `select ?s, ?p, ?o from ?test where {?s ?p ?o} order by ?s, ?p, ?o limit "3"^^type:int64;`
+
+	// Spilling fails if the directory does not exist.
+	ss.SetSpillDir(filepath.Join(dir, "missing"))
+	if _, err := runInSession(t, ss, q); err == nil {
+		t.Errorf("query %q should have failed to spill rows to a missing directory", q)
+	}
+
+	ss.SetSpillDir(dir)
+	if got, want := mustRunInSession(t, ss, q).NumRows(), 3; got != want {
+		t.Errorf("query %q returned the wrong number of rows; got %d, want %d", q, got, want)
+	}
+	fs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) != 0 {
+		t.Errorf("query %q left %d spilled files in %q", q, len(fs), dir)
+	}
+}
+
+func TestMemoryBudgetExceeded(t *testing.T) {
+	s := populateTestStore(t)
+	ss := NewSession(s)
+	ss.SetMemoryBudget(2000)
+	queries := []string{
+		// Sorted results do not fit in the budget.
+		`select ?s, ?p, ?o from ?test where {?s ?p ?o} order by ?s;`,
+		// Unsorted results do not fit in the budget.
+		`select ?s, ?p, ?o from ?test where {?s ?p ?o};`,
+		// Intermediate results do not fit in the budget.
+		`select ?s, ?k from ?test where {?s ?p ?o . ?k ?l ?m} order by ?s limit "1"^^type:int64;`,
+	}
+	for _, q := range queries {
+		_, err := runInSession(t, ss, q)
+		if _, ok := err.(*MemoryBudgetError); !ok {
+			t.Errorf("query %q should have exceeded its memory budget; got error %v", q, err)
+		}
+	}
+}
+
+func TestCursorDropsRowsBeforeSorting(t *testing.T) {
+	s, ctx := populateTestStore(t), context.Background()
+	ss := NewSession(s)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	testTable := []struct {
+		q    string
+		want bool
+	}{
+		{
+			q:    `select ?s, ?o from ?test where {?s "parent_of"@[] ?o} order by ?s desc limit "1"^^type:int64`,
+			want: true,
+		},
+		{
+			q:    `select ?s, ?o from ?test where {?s "parent_of"@[] ?o} order by ?o limit "2"^^type:int64`,
+			want: true,
+		},
+		{
+			// Grouped rows are only compared with the cursor once reduced.
+			q:    `select ?s, count(?o) as ?n from ?test where {?s ?p ?o} group by ?s order by ?s limit "1"^^type:int64`,
+			want: false,
+		},
+	}
+	for _, entry := range testTable {
+		tkn := mustRunInSession(t, ss, entry.q+";").ContinuationToken()
+		if tkn == "" {
+			t.Fatalf("query %q should have returned a continuation token", entry.q)
+		}
+		for _, q := range []string{entry.q + ";", fmt.Sprintf("%s cursor %q;", entry.q, tkn)} {
+			st := &semantic.Statement{}
+			if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+				t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+			}
+			plnr, err := ss.New(ctx, st, 0)
+			if err != nil {
+				t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+			}
+			want := entry.want && st.Cursor() != nil
+			if got := plnr.(*queryPlan).spills(); got != want {
+				t.Errorf("planner.New(%q) should pass rows to the sorter %v; got %v", q, want, got)
+			}
+		}
+	}
+}
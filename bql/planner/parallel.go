@@ -0,0 +1,161 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"runtime"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+)
+
+// DefaultWorkers is the number of goroutines a statement uses to run
+// independent lookups unless configured otherwise.
+var DefaultWorkers = runtime.NumCPU()
+
+// workerPool bounds the number of goroutines a statement uses to run
+// independent lookups concurrently. The goroutine running the statement
+// counts as one of the workers. The zero value runs everything in the calling
+// goroutine.
+type workerPool chan struct{}
+
+// newWorkerPool returns a pool of n workers.
+func newWorkerPool(n int) workerPool {
+	if n < 2 {
+		return nil
+	}
+	return make(workerPool, n-1)
+}
+
+// run calls f for the n tasks. Tasks are run on a new goroutine while there
+// are idle workers, and on the calling goroutine otherwise, so nested calls
+// never wait for a worker. It returns the error of the first failed task in
+// task order.
+func (wp workerPool) run(n int, f func(i int) error) error {
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, n)
+	)
+	for i := 0; i < n; i++ {
+		select {
+		case wp <- struct{}{}:
+			wg.Add(1)
+			go func(i int) {
+				defer func() {
+					<-wp
+					wg.Done()
+				}()
+				errs[i] = f(i)
+			}(i)
+		default:
+			errs[i] = f(i)
+		}
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// independentClauses splits the clauses into the ones depending on the
+// provided bound bindings and groups of clauses that share no bindings with
+// them nor with each other. Clauses without bindings are considered dependent,
+// as well as the ones sharing bindings with dependent clauses. Clauses keep
+// their relative order, and groups are sorted by their first clause.
+func independentClauses(cls []*semantic.GraphClause, bound func(string) bool) ([]*semantic.GraphClause, [][]*semantic.GraphClause) {
+	// Clauses are the nodes of the dependency graph, and the last node stands
+	// for the bound bindings. Connected nodes get the same root.
+	parent := make([]int, len(cls)+1)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		parent[find(i)] = find(j)
+	}
+	dep, owner := len(cls), make(map[string]int)
+	for i, c := range cls {
+		bs := c.Bindings()
+		if len(bs) == 0 {
+			union(i, dep)
+		}
+		for _, b := range bs {
+			if bound(b) {
+				union(i, dep)
+			}
+			if j, ok := owner[b]; ok {
+				union(i, j)
+			} else {
+				owner[b] = i
+			}
+		}
+	}
+	var (
+		dcls   []*semantic.GraphClause
+		groups [][]*semantic.GraphClause
+		idx    = make(map[int]int)
+	)
+	for i, c := range cls {
+		r := find(i)
+		if r == find(dep) {
+			dcls = append(dcls, c)
+			continue
+		}
+		g, ok := idx[r]
+		if !ok {
+			g = len(groups)
+			idx[r] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], c)
+	}
+	return dcls, groups
+}
+
+// fetch retrieves the triples matching the clause from the graphs of the
+// plan. Graphs are queried concurrently if there are idle workers, and their
+// triples are appended in the order the graphs are listed.
+func (p *queryPlan) fetch(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions) (*table.Table, error) {
+	if len(p.grfs) < 2 {
+		return simpleFetch(ctx, p.grfs, cls, lo, p.chanSize)
+	}
+	tbls := make([]*table.Table, len(p.grfs))
+	if err := p.workers.run(len(p.grfs), func(i int) error {
+		tbl, err := simpleFetch(ctx, p.grfs[i:i+1], cls, lo, p.chanSize)
+		tbls[i] = tbl
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	for _, tbl := range tbls[1:] {
+		if err := tbls[0].AppendTable(tbl); err != nil {
+			return nil, err
+		}
+	}
+	return tbls[0], nil
+}
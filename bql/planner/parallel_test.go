@@ -0,0 +1,173 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+)
+
+func TestWorkerPoolRun(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 8} {
+		var (
+			mu   sync.Mutex
+			done = make(map[int]bool)
+		)
+		wp := newWorkerPool(n)
+		err := wp.run(10, func(i int) error {
+			mu.Lock()
+			defer mu.Unlock()
+			done[i] = true
+			if i == 3 || i == 7 {
+				return errors.New(strconv.Itoa(i))
+			}
+			return nil
+		})
+		if err == nil || err.Error() != "3" {
+			t.Errorf("workerPool(%d).run should have returned the error of task 3; got %v", n, err)
+		}
+		if got, want := len(done), 10; got != want {
+			t.Errorf("workerPool(%d).run should have run all the tasks; got %d, want %d", n, got, want)
+		}
+		if got, want := len(wp), 0; got != want {
+			t.Errorf("workerPool(%d).run should have released all the workers; got %d busy, want %d", n, got, want)
+		}
+	}
+}
+
+func TestIndependentClauses(t *testing.T) {
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	testTable := []struct {
+		q      string
+		bound  []string
+		dep    []string
+		groups [][]string
+	}{
+		{
+			// Connected clauses form a single group.
+			q:      `select ?p, ?car from ?test where {?p "parent_of"@[] ?c . ?c "bought"@[?t] ?car};`,
+			groups: [][]string{{`?p "parent_of"@[] ?c`, `?c "bought"@[?t] ?car`}},
+		},
+		{
+			// Clauses sharing no bindings form separate groups.
+			q: `select ?p, ?car, ?r from ?test where {?p "parent_of"@[] ?c . ?r "connects_to"@[] ?x . ?c "bought"@[?t] ?car};`,
+			groups: [][]string{
+				{`?p "parent_of"@[] ?c`, `?c "bought"@[?t] ?car`},
+				{`?r "connects_to"@[] ?x`},
+			},
+		},
+		{
+			// Clauses using bound bindings, and the ones connected to them, depend
+			// on the rows available.
+			q:      `select ?p, ?car, ?r from ?test where {?p "parent_of"@[] ?c . ?r "connects_to"@[] ?x . ?c "bought"@[?t] ?car};`,
+			bound:  []string{"?car"},
+			dep:    []string{`?p "parent_of"@[] ?c`, `?c "bought"@[?t] ?car`},
+			groups: [][]string{{`?r "connects_to"@[] ?x`}},
+		},
+		{
+			// Fully specified clauses depend on the rows available.
+			q:      `select ?r from ?test where {/u<joe> "parent_of"@[] /u<mary> . ?r "connects_to"@[] ?x};`,
+			dep:    []string{`/u<joe> "parent_of"@[] /u<mary>`},
+			groups: [][]string{{`?r "connects_to"@[] ?x`}},
+		},
+	}
+	strs := func(cls []*semantic.GraphClause) []string {
+		var res []string
+		for _, c := range cls {
+			res = append(res, c.String())
+		}
+		return res
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error %v", entry.q, err)
+		}
+		bound := make(map[string]bool)
+		for _, b := range entry.bound {
+			bound[b] = true
+		}
+		dep, groups := independentClauses(st.SortedGraphPatternClauses(), func(b string) bool { return bound[b] })
+		if got, want := strs(dep), entry.dep; !reflect.DeepEqual(got, want) {
+			t.Errorf("independentClauses returned the wrong dependent clauses for query %q; got %v, want %v", entry.q, got, want)
+		}
+		var got [][]string
+		for _, g := range groups {
+			got = append(got, strs(g))
+		}
+		if !reflect.DeepEqual(got, entry.groups) {
+			t.Errorf("independentClauses returned the wrong groups for query %q; got %v, want %v", entry.q, got, entry.groups)
+		}
+	}
+}
+
+// rowStrings returns the sorted rows of the table as strings, ignoring the
+// time spent by explained steps. Rows are sorted since graphs do not return
+// triples in any particular order.
+func rowStrings(tbl *table.Table) []string {
+	bs := tbl.Bindings()
+	sort.Strings(bs)
+	var res []string
+	for _, r := range tbl.Rows() {
+		var cs []string
+		for _, b := range bs {
+			if b != "?time" {
+				cs = append(cs, r[b].String())
+			}
+		}
+		res = append(res, strings.Join(cs, "\t"))
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestPlannerWorkersDeterministic(t *testing.T) {
+	s := populateTestStore(t)
+	ss := NewSession(s)
+	mustRunInSession(t, ss, `create graph ?other;`)
+	mustRunInSession(t, ss, `insert data into ?other {/u<zoe> "parent_of"@[] /u<joe> . /u<zoe> "bought"@[2016-05-01T00:00:00-08:00] /c<mini>};`)
+	queries := []string{
+		`select ?p, ?c from ?test, ?other where {?p "parent_of"@[] ?c};`,
+		`select ?p, ?car, ?r from ?test where {?p "parent_of"@[] ?c . ?r "connects_to"@[] ?x . ?c "bought"@[?t] ?car};`,
+		`select ?p, ?car, ?r from ?test, ?other where {?p "parent_of"@[] ?c . ?r "connects_to"@[] /room<Kitchen> . ?c "bought"@[?t] ?car};`,
+		`select ?c, ?r from ?test where {?c "is_a"@[] /t<car> . ?r "connects_to"@[] /room<Kitchen> . /u<joe> "parent_of"@[] /u<mary>};`,
+		`explain analyze select ?p, ?car, ?r from ?test where {?p "parent_of"@[] ?c . ?r "connects_to"@[] ?x . ?c "bought"@[?t] ?car};`,
+	}
+	for _, q := range queries {
+		ss.SetWorkers(1)
+		want := mustRunInSession(t, ss, q)
+		for _, w := range []int{2, 8} {
+			ss.SetWorkers(w)
+			for i := 0; i < 10; i++ {
+				got := mustRunInSession(t, ss, q)
+				if !reflect.DeepEqual(rowStrings(got), rowStrings(want)) {
+					t.Fatalf("query %q returned different results with %d workers; got\n%s\nwant\n%s", q, w, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,500 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"sort"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// maxSimplePathHops is the maximum number of hops of the paths enumerated for
+// clauses that return every path and do not set a maximum number of hops.
+const maxSimplePathHops = 16
+
+// processPathClause resolves a property path clause. If the subject or the
+// object of the clause are already bound, the path is walked once per row.
+func (p *queryPlan) processPathClause(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions) error {
+	if !p.tbl.HasBinding(cls.SBinding) && !p.tbl.HasBinding(cls.OBinding) {
+		// Data is new.
+		tbl, err := p.pathTable(ctx, cls, lo, table.Row{})
+		if err != nil {
+			return err
+		}
+		if len(p.tbl.Bindings()) > 0 {
+			return p.tbl.DotProduct(tbl)
+		}
+		return p.tbl.AppendTable(tbl)
+	}
+	rws := p.tbl.Rows()
+	p.tbl.Truncate()
+	p.tbl.AddBindings(cls.Bindings())
+	for _, r := range rws {
+		tbl, err := p.pathTable(ctx, cls, lo, r)
+		if err != nil {
+			return err
+		}
+		for _, nr := range tbl.Rows() {
+			p.tbl.AddRow(table.MergeRows([]table.Row{r, nr}))
+		}
+	}
+	return nil
+}
+
+// boundNode returns the node for the provided binding in the row. It returns
+// false if the binding is bound to something else than a node.
+func boundNode(r table.Row, b string) (*node.Node, bool) {
+	if b == "" {
+		return nil, true
+	}
+	c, ok := r[b]
+	if !ok {
+		return nil, true
+	}
+	return c.N, c.N != nil
+}
+
+// pathTable returns the table of the subjects and objects connected by the
+// property path of the clause, given the values already bound in the row.
+func (p *queryPlan) pathTable(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions, r table.Row) (*table.Table, error) {
+	tbl, err := table.New(cls.Bindings())
+	if err != nil {
+		return nil, err
+	}
+	s, o := cls.S, (*node.Node)(nil)
+	if cls.O != nil {
+		if o, err = cls.O.Node(); err != nil {
+			return nil, err
+		}
+	}
+	if s == nil {
+		n, ok := boundNode(r, cls.SBinding)
+		if !ok {
+			return tbl, nil
+		}
+		s = n
+	}
+	if o == nil {
+		n, ok := boundNode(r, cls.OBinding)
+		if !ok {
+			return tbl, nil
+		}
+		o = n
+	}
+	addRow := func(sn, on *node.Node, ts []*triple.Triple) error {
+		if cls.SBinding != "" && cls.SBinding == cls.OBinding && sn.String() != on.String() {
+			return nil
+		}
+		nr := table.Row{}
+		if cls.SBinding != "" {
+			nr[cls.SBinding] = &table.Cell{N: sn}
+		}
+		if cls.OBinding != "" {
+			nr[cls.OBinding] = &table.Cell{N: on}
+		}
+		if cls.PPathBinding != "" {
+			if ts == nil {
+				ts = []*triple.Triple{}
+			}
+			nr[cls.PPathBinding] = &table.Cell{Path: ts}
+		}
+		if cls.PPathHopsBinding != "" {
+			l, err := literal.DefaultBuilder().Build(literal.Int64, int64(len(ts)))
+			if err != nil {
+				return err
+			}
+			nr[cls.PPathHopsBinding] = &table.Cell{L: l}
+		}
+		tbl.AddRow(nr)
+		return nil
+	}
+	switch {
+	case s != nil:
+		wps, err := p.walkPaths(ctx, cls, lo, s, true)
+		if err != nil {
+			return nil, err
+		}
+		for _, wp := range wps {
+			if o != nil && o.String() != wp.node.String() {
+				continue
+			}
+			if err := addRow(s, wp.node, wp.triples); err != nil {
+				return nil, err
+			}
+		}
+	case o != nil:
+		wps, err := p.walkPaths(ctx, cls, lo, o, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, wp := range wps {
+			if err := addRow(wp.node, o, wp.triples); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		starts, err := p.pathStartNodes(ctx, cls, lo)
+		if err != nil {
+			return nil, err
+		}
+		for _, sn := range starts {
+			wps, err := p.walkPaths(ctx, cls, lo, sn, true)
+			if err != nil {
+				return nil, err
+			}
+			for _, wp := range wps {
+				if err := addRow(sn, wp.node, wp.triples); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return tbl, nil
+}
+
+// walkedPath contains the node reached walking a property path, and the
+// ordered list of triples traversed to reach it when paths are returned.
+type walkedPath struct {
+	node    *node.Node
+	triples []*triple.Triple
+}
+
+// walkPaths returns the nodes reached walking the property path of the clause
+// from the provided node. Clauses returning paths return every simple path
+// found, or only the shortest path to each reachable node if requested. The
+// triples of paths walked in reverse are returned in subject to object order.
+func (p *queryPlan) walkPaths(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions, n *node.Node, forward bool) ([]walkedPath, error) {
+	var (
+		wps []walkedPath
+		err error
+	)
+	switch {
+	case !cls.ReturnsPaths():
+		ns, err := p.reachableNodes(ctx, cls, lo, n, forward)
+		if err != nil {
+			return nil, err
+		}
+		for _, rn := range ns {
+			wps = append(wps, walkedPath{node: rn})
+		}
+		return wps, nil
+	case cls.PPathShortest:
+		wps, err = p.shortestPaths(ctx, cls, lo, n, forward)
+	default:
+		wps, err = p.simplePaths(ctx, cls, lo, n, forward)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !forward {
+		for _, wp := range wps {
+			for i, j := 0, len(wp.triples)-1; i < j; i, j = i+1, j-1 {
+				wp.triples[i], wp.triples[j] = wp.triples[j], wp.triples[i]
+			}
+		}
+	}
+	return wps, nil
+}
+
+// pathState identifies a node reached while walking a property path. Once the
+// minimum number of hops is reached the number of hops no longer matters, so
+// it is capped to the minimum. This guarantees the walk ends on cycles.
+type pathState struct {
+	node string
+	hops int64
+}
+
+// reachableNodes returns the nodes reachable from the provided node walking
+// the property path of the clause. The path is walked following the direction
+// of the triples if forward is true, or in reverse otherwise.
+func (p *queryPlan) reachableNodes(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions, n *node.Node, forward bool) ([]*node.Node, error) {
+	var res []*node.Node
+	found := make(map[string]bool)
+	visited := map[pathState]bool{{n.String(), 0}: true}
+	frontier := []*node.Node{n}
+	for hops := int64(0); len(frontier) > 0 && (cls.PPathMax < 0 || hops <= cls.PPathMax); hops++ {
+		var next []*node.Node
+		for _, c := range frontier {
+			if hops >= cls.PPathMin && !found[c.String()] {
+				found[c.String()] = true
+				res = append(res, c)
+			}
+			if cls.PPathMax >= 0 && hops == cls.PPathMax {
+				continue
+			}
+			ts, err := p.pathTriples(ctx, cls, lo, c, forward)
+			if err != nil {
+				return nil, err
+			}
+			nh := hops + 1
+			if nh > cls.PPathMin {
+				nh = cls.PPathMin
+			}
+			for _, t := range ts {
+				nn := pathNextNode(t, forward)
+				st := pathState{nn.String(), nh}
+				if visited[st] {
+					continue
+				}
+				visited[st] = true
+				next = append(next, nn)
+			}
+		}
+		frontier = next
+	}
+	return res, nil
+}
+
+// pathStep records how a state of a shortest path walk was reached.
+type pathStep struct {
+	prev pathState
+	t    *triple.Triple
+}
+
+// shortestPaths returns one shortest path of at least the minimum number of
+// hops to each node reachable from the provided one. It walks the same states
+// as reachableNodes, remembering how each of them was first reached.
+func (p *queryPlan) shortestPaths(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions, n *node.Node, forward bool) ([]walkedPath, error) {
+	var res []walkedPath
+	found := make(map[string]bool)
+	start := pathState{n.String(), 0}
+	steps := map[pathState]pathStep{start: {}}
+	frontier := []*node.Node{n}
+	for hops := int64(0); len(frontier) > 0 && (cls.PPathMax < 0 || hops <= cls.PPathMax); hops++ {
+		ch := hops
+		if ch > cls.PPathMin {
+			ch = cls.PPathMin
+		}
+		var next []*node.Node
+		for _, c := range frontier {
+			cs := pathState{c.String(), ch}
+			if hops >= cls.PPathMin && !found[c.String()] {
+				found[c.String()] = true
+				var ts []*triple.Triple
+				for st := cs; st != start; st = steps[st].prev {
+					ts = append([]*triple.Triple{steps[st].t}, ts...)
+				}
+				res = append(res, walkedPath{node: c, triples: ts})
+			}
+			if cls.PPathMax >= 0 && hops == cls.PPathMax {
+				continue
+			}
+			ts, err := p.pathTriples(ctx, cls, lo, c, forward)
+			if err != nil {
+				return nil, err
+			}
+			nh := hops + 1
+			if nh > cls.PPathMin {
+				nh = cls.PPathMin
+			}
+			for _, t := range ts {
+				nn := pathNextNode(t, forward)
+				st := pathState{nn.String(), nh}
+				if _, ok := steps[st]; ok {
+					continue
+				}
+				steps[st] = pathStep{prev: cs, t: t}
+				next = append(next, nn)
+			}
+		}
+		frontier = next
+	}
+	return res, nil
+}
+
+// simplePaths returns all the paths from the provided node that do not visit
+// the same node twice and whose number of hops is within the bounds of the
+// clause. A path may only go back to its first node as its last hop. Paths
+// are at most maxSimplePathHops long, or as long as the minimum if larger, when
+// the clause sets no maximum.
+func (p *queryPlan) simplePaths(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions, n *node.Node, forward bool) ([]walkedPath, error) {
+	var (
+		res  []walkedPath
+		walk func(c *node.Node, ts []*triple.Triple) error
+	)
+	max := cls.PPathMax
+	if max < 0 {
+		max = maxSimplePathHops
+		if max < cls.PPathMin {
+			max = cls.PPathMin
+		}
+	}
+	onPath := map[string]bool{n.String(): true}
+	emit := func(c *node.Node, ts []*triple.Triple) {
+		res = append(res, walkedPath{node: c, triples: append([]*triple.Triple{}, ts...)})
+	}
+	walk = func(c *node.Node, ts []*triple.Triple) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hops := int64(len(ts))
+		if hops >= cls.PPathMin {
+			emit(c, ts)
+		}
+		if hops >= max {
+			return nil
+		}
+		nts, err := p.pathTriples(ctx, cls, lo, c, forward)
+		if err != nil {
+			return err
+		}
+		for _, t := range nts {
+			nn := pathNextNode(t, forward)
+			if nn.String() == n.String() {
+				if hops+1 >= cls.PPathMin {
+					emit(nn, append(ts, t))
+				}
+				continue
+			}
+			if onPath[nn.String()] {
+				continue
+			}
+			onPath[nn.String()] = true
+			if err := walk(nn, append(ts, t)); err != nil {
+				return err
+			}
+			delete(onPath, nn.String())
+		}
+		return nil
+	}
+	if err := walk(n, nil); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// pathNextNode returns the node reached by traversing the provided triple.
+func pathNextNode(t *triple.Triple, forward bool) *node.Node {
+	if forward {
+		n, _ := t.Object().Node()
+		return n
+	}
+	return t.Subject()
+}
+
+// pathTriples returns the triples that connect the provided node to other
+// nodes via the predicate of the clause in all the graphs of the plan, sorted
+// to make walks deterministic. Predicates with time bounds are looked up
+// within the intersection of those bounds and the ones of the lookup options.
+func (p *queryPlan) pathTriples(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions, n *node.Node, forward bool) ([]*triple.Triple, error) {
+	var res []*triple.Triple
+	nlo := updateTimeBounds(lo, cls)
+	for _, g := range p.grfs {
+		errc := make(chan error, 1)
+		ts := make(chan *triple.Triple, p.chanSize)
+		go func(g storage.Graph) {
+			switch {
+			case cls.P != nil && forward:
+				errc <- g.TriplesForSubjectAndPredicate(ctx, n, cls.P, lo, ts)
+			case cls.P != nil:
+				errc <- g.TriplesForPredicateAndObject(ctx, cls.P, triple.NewNodeObject(n), lo, ts)
+			case forward:
+				errc <- g.TriplesForSubject(ctx, n, nlo, ts)
+			default:
+				errc <- g.TriplesForObject(ctx, triple.NewNodeObject(n), nlo, ts)
+			}
+		}(g)
+		for t := range ts {
+			if _, err := t.Object().Node(); err != nil {
+				continue
+			}
+			if cls.P == nil && !boundPathTriple(cls, nlo, t) {
+				continue
+			}
+			res = append(res, t)
+		}
+		if err := <-errc; err != nil {
+			return nil, err
+		}
+	}
+	sort.Sort(byTripleString(res))
+	return res, nil
+}
+
+// byTripleString sorts triples by their string representation.
+type byTripleString []*triple.Triple
+
+func (ts byTripleString) Len() int           { return len(ts) }
+func (ts byTripleString) Swap(i, j int)      { ts[i], ts[j] = ts[j], ts[i] }
+func (ts byTripleString) Less(i, j int) bool { return ts[i].String() < ts[j].String() }
+
+// boundPathTriple returns true if the triple uses the temporal predicate of a
+// property path with time bounds, and its time anchor is within the bounds of
+// the provided lookup options.
+func boundPathTriple(cls *semantic.GraphClause, lo *storage.LookupOptions, t *triple.Triple) bool {
+	p := t.Predicate()
+	if string(p.ID()) != cls.PID || p.Type() != predicate.Temporal {
+		return false
+	}
+	ta, err := p.TimeAnchor()
+	if err != nil {
+		return false
+	}
+	if lo.LowerAnchor != nil && ta.Before(*lo.LowerAnchor) {
+		return false
+	}
+	if lo.UpperAnchor != nil && ta.After(*lo.UpperAnchor) {
+		return false
+	}
+	return true
+}
+
+// pathStartNodes returns the nodes a property path with unbound subject and
+// object can start from. Those are the subjects of the triples using the
+// predicate of the clause, and their objects too when zero hops are allowed.
+// Predicates with time bounds require scanning all the triples of the graphs.
+func (p *queryPlan) pathStartNodes(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions) ([]*node.Node, error) {
+	var ns []*node.Node
+	seen := make(map[string]bool)
+	add := func(n *node.Node) {
+		if !seen[n.String()] {
+			seen[n.String()] = true
+			ns = append(ns, n)
+		}
+	}
+	nlo := updateTimeBounds(lo, cls)
+	for _, g := range p.grfs {
+		errc := make(chan error, 1)
+		ts := make(chan *triple.Triple, p.chanSize)
+		go func(g storage.Graph) {
+			if cls.P != nil {
+				errc <- g.TriplesForPredicate(ctx, cls.P, lo, ts)
+				return
+			}
+			errc <- g.Triples(ctx, ts)
+		}(g)
+		for t := range ts {
+			if cls.P == nil && !boundPathTriple(cls, nlo, t) {
+				continue
+			}
+			add(t.Subject())
+			if on, err := t.Object().Node(); err == nil && cls.PPathMin == 0 {
+				add(on)
+			}
+		}
+		if err := <-errc; err != nil {
+			return nil, err
+		}
+	}
+	return ns, nil
+}
@@ -98,7 +98,28 @@ type insertPlan struct {
 
 type updater func(storage.Graph, []*triple.Triple) error
 
-func update(ctx context.Context, stm *semantic.Statement, store storage.Store, f updater) error {
+// update applies the updater with the provided data to all the listed graphs.
+// If the store supports transactions, the update is applied atomically to all
+// of them.
+func update(ctx context.Context, graphs []string, data []*triple.Triple, store storage.Store, f updater) error {
+	ts, ok := store.(storage.Transactional)
+	if !ok {
+		return updateGraphs(ctx, graphs, data, store, f)
+	}
+	tx, err := ts.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	if err := updateGraphs(ctx, graphs, data, tx, f); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// updateGraphs concurrently applies the updater with the provided data to all
+// the listed graphs.
+func updateGraphs(ctx context.Context, graphs []string, data []*triple.Triple, store storage.Store, f updater) error {
 	var (
 		mu   sync.Mutex
 		wg   sync.WaitGroup
@@ -110,7 +131,7 @@ func update(ctx context.Context, stm *semantic.Statement, store storage.Store, f
 		errs = append(errs, err.Error())
 	}
 
-	for _, graphBinding := range stm.Graphs() {
+	for _, graphBinding := range graphs {
 		wg.Add(1)
 		go func(graph string) {
 			defer wg.Done()
@@ -119,7 +140,7 @@ func update(ctx context.Context, stm *semantic.Statement, store storage.Store, f
 				appendError(err)
 				return
 			}
-			err = f(g, stm.Data())
+			err = f(g, data)
 			if err != nil {
 				appendError(err)
 			}
@@ -138,7 +159,7 @@ func (p *insertPlan) Execute(ctx context.Context) (*table.Table, error) {
 	if err != nil {
 		return nil, err
 	}
-	return t, update(ctx, p.stm, p.store, func(g storage.Graph, d []*triple.Triple) error {
+	return t, update(ctx, p.stm.Graphs(), p.stm.Data(), p.store, func(g storage.Graph, d []*triple.Triple) error {
 		return g.AddTriples(ctx, d)
 	})
 }
@@ -156,7 +177,7 @@ func (p *deletePlan) Execute(ctx context.Context) (*table.Table, error) {
 	if err != nil {
 		return nil, err
 	}
-	return t, update(ctx, p.stm, p.store, func(g storage.Graph, d []*triple.Triple) error {
+	return t, update(ctx, p.stm.Graphs(), p.stm.Data(), p.store, func(g storage.Graph, d []*triple.Triple) error {
 		return g.RemoveTriples(ctx, d)
 	})
 }
@@ -172,12 +193,55 @@ type queryPlan struct {
 	grfsNames []string
 	grfs      []storage.Graph
 	cls       []*semantic.GraphClause
+	filters   []*semantic.Filter
 	tbl       *table.Table
 	chanSize  int
+	workers   workerPool
+	budget    memoryBudget
+	snapshot  snapshotFinder
+	// Set when a fully specified clause could not be found in the graphs.
+	unresolvable bool
+	// Set when the steps run need to be collected, labeled with the pattern.
+	explain *explanation
+	pattern string
+	// Estimates the cost of the clauses to decide the order to resolve them.
+	costs *costModel
+}
+
+// snapshotFinder returns the graph frozen by the named snapshot of a graph.
+type snapshotFinder func(graph, name string) (storage.Graph, error)
+
+// noSnapshots is the snapshot finder used for queries run outside a session.
+func noSnapshots(graph, name string) (storage.Graph, error) {
+	return nil, fmt.Errorf("snapshot %q of graph %q can only be queried in a planner.Session", name, graph)
 }
 
-// newQueryPlan returns a new query plan ready to be executed.
-func newQueryPlan(ctx context.Context, store storage.Store, stm *semantic.Statement, chanSize int) (*queryPlan, error) {
+// resolveGraphs returns the graphs listed on the statement. Graphs listed with
+// a snapshot are resolved using the provided snapshot finder.
+func resolveGraphs(ctx context.Context, store storage.Store, stm *semantic.Statement, snapshot snapshotFinder) ([]storage.Graph, error) {
+	var gs []storage.Graph
+	for _, g := range stm.Graphs() {
+		var (
+			ng  storage.Graph
+			err error
+		)
+		if name, ok := stm.GraphSnapshot(g); ok {
+			ng, err = snapshot(g, name)
+		} else {
+			ng, err = store.Graph(ctx, g)
+		}
+		if err != nil {
+			return nil, err
+		}
+		gs = append(gs, ng)
+	}
+	return gs, nil
+}
+
+// newQueryPlan returns a new query plan ready to be executed. Independent
+// lookups are run concurrently on the provided workers. Graphs listed with a
+// snapshot are resolved using the provided snapshot finder.
+func newQueryPlan(ctx context.Context, store storage.Store, stm *semantic.Statement, chanSize int, workers workerPool, budget memoryBudget, snapshot snapshotFinder) (*queryPlan, error) {
 	bs := []string{}
 	for _, b := range stm.Bindings() {
 		bs = append(bs, b)
@@ -186,13 +250,9 @@ func newQueryPlan(ctx context.Context, store storage.Store, stm *semantic.Statem
 	if err != nil {
 		return nil, err
 	}
-	var gs []storage.Graph
-	for _, g := range stm.Graphs() {
-		ng, err := store.Graph(ctx, g)
-		if err != nil {
-			return nil, err
-		}
-		gs = append(gs, ng)
+	gs, err := resolveGraphs(ctx, store, stm, snapshot)
+	if err != nil {
+		return nil, err
 	}
 	return &queryPlan{
 		stm:       stm,
@@ -201,17 +261,24 @@ func newQueryPlan(ctx context.Context, store storage.Store, stm *semantic.Statem
 		grfs:      gs,
 		grfsNames: stm.Graphs(),
 		cls:       stm.SortedGraphPatternClauses(),
+		filters:   stm.Filters(),
 		tbl:       t,
 		chanSize:  chanSize,
+		workers:   workers,
+		budget:    budget,
+		snapshot:  snapshot,
+		costs:     newCostModel(gs),
 	}, nil
 }
 
-// processClause retrieves the triples for the provided triple given the
-// information available.
-func (p *queryPlan) processClause(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions) (bool, error) {
-	// This method decides how to process the clause based on the current
-	// list of bindings solved and data available.
-	if cls.Specificity() == 3 {
+// processClause retrieves the triples for the provided triple using the
+// provided access method, decided based on the current list of bindings solved
+// and data available.
+func (p *queryPlan) processClause(ctx context.Context, cls *semantic.GraphClause, m accessMethod, lo *storage.LookupOptions) (bool, error) {
+	switch m {
+	case pathAccess:
+		return false, p.processPathClause(ctx, cls, lo)
+	case existAccess:
 		t, err := triple.New(cls.S, cls.P, cls.O)
 		if err != nil {
 			return false, err
@@ -220,21 +287,17 @@ func (p *queryPlan) processClause(ctx context.Context, cls *semantic.GraphClause
 		if err != nil {
 			return false, err
 		}
+		if len(p.tbl.Bindings()) > 0 && len(tbl.Bindings()) == 0 {
+			// The triple adds no bindings to the rows already available.
+			return b, nil
+		}
 		if err := p.tbl.AppendTable(tbl); err != nil {
 			return b, err
 		}
 		return b, nil
-	}
-	exist, total := 0, 0
-	for _, b := range cls.Bindings() {
-		total++
-		if p.tbl.HasBinding(b) {
-			exist++
-		}
-	}
-	if exist == 0 {
+	case fetchAccess:
 		// Data is new.
-		tbl, err := simpleFetch(ctx, p.grfs, cls, lo, p.chanSize)
+		tbl, err := p.fetch(ctx, cls, lo)
 		if err != nil {
 			return false, err
 		}
@@ -242,13 +305,15 @@ func (p *queryPlan) processClause(ctx context.Context, cls *semantic.GraphClause
 			return false, p.tbl.DotProduct(tbl)
 		}
 		return false, p.tbl.AppendTable(tbl)
-	}
-	if exist > 0 && exist < total {
+	case specifyAccess:
 		// Data is partially bound, retrieve data either extends the row with the
 		// new bindings or filters it out if now new bindings are available.
 		return false, p.specifyClauseWithTable(ctx, cls, lo)
-	}
-	if exist > 0 && exist == total {
+	case joinAccess:
+		// Data is partially bound, but there are fewer triples matching the
+		// clause than rows to specify it with.
+		return false, p.joinClause(ctx, cls, lo)
+	case filterAccess:
 		// Since all bindings in the clause are already solved, the clause becomes a
 		// fully specified triple. If the triple does not exist the row will be
 		// deleted.
@@ -276,6 +341,20 @@ func getBoundValueForComponent(r table.Row, bs []string) *table.Cell {
 // addSpecifiedData specializes the clause given the row provided and attempt to
 // retrieve the corresponding clause data.
 func (p *queryPlan) addSpecifiedData(ctx context.Context, r table.Row, cls *semantic.GraphClause, lo *storage.LookupOptions) error {
+	tbl, err := p.specifiedData(ctx, r, cls, lo)
+	if err != nil {
+		return err
+	}
+	p.tbl.AddBindings(tbl.Bindings())
+	for _, nr := range tbl.Rows() {
+		p.tbl.AddRow(table.MergeRows([]table.Row{r, nr}))
+	}
+	return nil
+}
+
+// specifiedData specializes the clause given the row provided and returns the
+// corresponding clause data.
+func (p *queryPlan) specifiedData(ctx context.Context, r table.Row, cls *semantic.GraphClause, lo *storage.LookupOptions) (*table.Table, error) {
 	if cls.S == nil {
 		v := getBoundValueForComponent(r, []string{cls.SBinding, cls.SAlias})
 		if v != nil {
@@ -287,13 +366,13 @@ func (p *queryPlan) addSpecifiedData(ctx context.Context, r table.Row, cls *sema
 	if cls.P == nil {
 		v := getBoundValueForComponent(r, []string{cls.PBinding, cls.PAlias})
 		if v != nil {
-			if v.N != nil {
+			if v.P != nil {
 				cls.P = v.P
 			}
 		}
 		nlo, err := updateTimeBoundsForRow(lo, cls, r)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		lo = nlo
 	}
@@ -307,19 +386,11 @@ func (p *queryPlan) addSpecifiedData(ctx context.Context, r table.Row, cls *sema
 		}
 		nlo, err := updateTimeBoundsForRow(lo, cls, r)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		lo = nlo
 	}
-	tbl, err := simpleFetch(ctx, p.grfs, cls, lo, p.chanSize)
-	if err != nil {
-		return err
-	}
-	p.tbl.AddBindings(tbl.Bindings())
-	for _, nr := range tbl.Rows() {
-		p.tbl.AddRow(table.MergeRows([]table.Row{r, nr}))
-	}
-	return nil
+	return p.fetch(ctx, cls, lo)
 }
 
 // specifyClauseWithTable runs the clause, but it specifies it further based on
@@ -337,6 +408,17 @@ func (p *queryPlan) specifyClauseWithTable(ctx context.Context, cls *semantic.Gr
 	return nil
 }
 
+// joinClause retrieves all the triples matching the constant components of
+// the clause, and joins them with the current rows on the bindings they share.
+func (p *queryPlan) joinClause(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions) error {
+	tbl, err := p.fetch(ctx, cls, lo)
+	if err != nil {
+		return err
+	}
+	p.tbl.Join(tbl)
+	return nil
+}
+
 // cellToObject returns an object for the given cell.
 func cellToObject(c *table.Cell) (*triple.Object, error) {
 	if c == nil {
@@ -440,15 +522,11 @@ func (p *queryPlan) filterOnExistence(ctx context.Context, cls *semantic.GraphCl
 		if sbj == nil || prd == nil || obj == nil {
 			return fmt.Errorf("failed to fully specify clause %v for row %+v", cls, r)
 		}
-		for _, g := range p.stm.Graphs() {
+		for _, gph := range p.grfs {
 			t, err := triple.New(sbj, prd, obj)
 			if err != nil {
 				return err
 			}
-			gph, err := p.store.Graph(ctx, g)
-			if err != nil {
-				return err
-			}
 			b, err := gph.Exist(ctx, t)
 			if err != nil {
 				return err
@@ -463,32 +541,449 @@ func (p *queryPlan) filterOnExistence(ctx context.Context, cls *semantic.GraphCl
 	return nil
 }
 
-// processGraphPattern process the query graph pattern to retrieve the
-// data from the specified graphs.
-func (p *queryPlan) processGraphPattern(ctx context.Context, lo *storage.LookupOptions) error {
-	for _, cls := range p.cls {
-		// The current planner is based on naively executing clauses by
-		// specificity.
-		unresolvable, err := p.processClause(ctx, cls, lo)
-		if err != nil {
+// processClauses resolves the clauses of the plan to retrieve the data from
+// the specified graphs. Groups of clauses that share no bindings with the
+// rest, as split by independentClauses, are resolved on their own
+// concurrently, and their results are combined with the rows available in the
+// order of the groups.
+func (p *queryPlan) processClauses(ctx context.Context, lo *storage.LookupOptions) error {
+	cls, groups := independentClauses(p.cls, p.tbl.HasBinding)
+	if len(groups) == 0 || len(groups) == 1 && len(cls) == 0 {
+		return p.resolveClauses(ctx, p.cls, lo)
+	}
+	tbls, err := p.resolveGroups(ctx, cls, groups, lo)
+	if err != nil || p.unresolvable {
+		return err
+	}
+	for _, tbl := range tbls {
+		if len(p.tbl.Bindings()) == 0 {
+			if err := p.tbl.AppendTable(tbl); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := p.tbl.DotProduct(tbl); err != nil {
 			return err
 		}
-		if unresolvable {
+	}
+	return p.applyFilters()
+}
+
+// resolveGroups resolves the provided dependent clauses against the current
+// rows while each group of independent clauses is resolved on its own
+// concurrently. It returns the tables resolving each group, in order, unless
+// any of the clauses is unresolvable.
+func (p *queryPlan) resolveGroups(ctx context.Context, cls []*semantic.GraphClause, groups [][]*semantic.GraphClause, lo *storage.LookupOptions) ([]*table.Table, error) {
+	// Load the statistics before the groups share them.
+	if _, err := p.costsEnabled(ctx); err != nil {
+		return nil, err
+	}
+	subs := make([]*queryPlan, len(groups))
+	for i, g := range groups {
+		sp, err := p.subPlan(p.pattern, g)
+		if err != nil {
+			return nil, err
+		}
+		sp.filters = p.takeFilters(g)
+		if p.explain != nil {
+			sp.explain = &explanation{}
+		}
+		subs[i] = sp
+	}
+	err := p.workers.run(len(subs)+1, func(i int) error {
+		if i == 0 {
+			return p.resolveClauses(ctx, cls, lo)
+		}
+		sp := subs[i-1]
+		return sp.resolveClauses(ctx, sp.cls, lo)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var tbls []*table.Table
+	for _, sp := range subs {
+		if sp.explain != nil {
+			p.explain.steps = append(p.explain.steps, sp.explain.steps...)
+		}
+		if sp.unresolvable && !p.unresolvable {
+			p.unresolvable = true
 			p.tbl.Truncate()
+		}
+		tbls = append(tbls, sp.tbl)
+	}
+	if p.unresolvable {
+		return nil, nil
+	}
+	return tbls, nil
+}
+
+// takeFilters removes from the pending filters the ones whose bindings are all
+// bound by the provided clauses, and returns them.
+func (p *queryPlan) takeFilters(cls []*semantic.GraphClause) []*semantic.Filter {
+	bound := make(map[string]bool)
+	for _, c := range cls {
+		for _, b := range c.Bindings() {
+			bound[b] = true
+		}
+	}
+	var taken, pending []*semantic.Filter
+	for _, f := range p.filters {
+		ready := true
+		for _, b := range f.Bindings {
+			ready = ready && bound[b]
+		}
+		if ready {
+			taken = append(taken, f)
+		} else {
+			pending = append(pending, f)
+		}
+	}
+	p.filters = pending
+	return taken
+}
+
+// resolveClauses resolves the provided clauses one at a time against the
+// current rows in the order decided by orderClauses.
+func (p *queryPlan) resolveClauses(ctx context.Context, cls []*semantic.GraphClause, lo *storage.LookupOptions) error {
+	cls, err := p.orderClauses(ctx, cls, p.tbl.HasBinding)
+	if err != nil {
+		return err
+	}
+	for _, cls := range cls {
+		if unresolvable, err := p.resolveClause(ctx, cls, lo); err != nil || unresolvable {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveClause resolves the clause against the current rows and applies the
+// filters that become ready. It returns true if the clause is unresolvable, in
+// which case all the rows are dropped.
+func (p *queryPlan) resolveClause(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions) (bool, error) {
+	m, err := p.accessMethod(ctx, cls, p.tbl.HasBinding, float64(p.tbl.NumRows()))
+	if err != nil {
+		return false, err
+	}
+	done := p.explain.record(p.pattern, cls, m, p.tbl.HasBinding, lo)
+	unresolvable, err := p.processClause(ctx, cls, m, lo)
+	if err != nil {
+		return false, err
+	}
+	if unresolvable {
+		p.unresolvable = true
+		p.tbl.Truncate()
+		done(0)
+		return true, nil
+	}
+	if err := p.applyFilters(); err != nil {
+		return false, err
+	}
+	done(p.tbl.NumRows())
+	return false, p.checkBudget(p.tbl)
+}
+
+// filterTable removes from the table the rows for which the provided
+// evaluator does not hold.
+func filterTable(tbl *table.Table, eval semantic.Evaluator) error {
+	var eErr error
+	tbl.Filter(func(r table.Row) bool {
+		b, err := eval.Evaluate(r)
+		if err != nil {
+			eErr = err
+		}
+		return !b
+	})
+	return eErr
+}
+
+// applyFilters filters the table with the pending filters whose bindings are
+// all available in it. Applied filters are no longer pending.
+func (p *queryPlan) applyFilters() error {
+	var pending []*semantic.Filter
+	for _, f := range p.filters {
+		ready := true
+		for _, b := range f.Bindings {
+			if !p.tbl.HasBinding(b) {
+				ready = false
+				break
+			}
+		}
+		if !ready {
+			pending = append(pending, f)
+			continue
+		}
+		if err := filterTable(p.tbl, f.Evaluator); err != nil {
+			return err
+		}
+	}
+	p.filters = pending
+	return nil
+}
+
+// subPlan returns a plan that resolves the provided clauses on their own
+// against the same graphs, starting from an empty table. The pattern labels
+// the steps collected when the query is explained.
+func (p *queryPlan) subPlan(pattern string, cls []*semantic.GraphClause) (*queryPlan, error) {
+	t, err := table.New([]string{})
+	if err != nil {
+		return nil, err
+	}
+	return &queryPlan{
+		stm:       p.stm,
+		store:     p.store,
+		grfs:      p.grfs,
+		grfsNames: p.grfsNames,
+		cls:       cls,
+		tbl:       t,
+		chanSize:  p.chanSize,
+		workers:   p.workers,
+		budget:    p.budget,
+		explain:   p.explain,
+		pattern:   pattern,
+		costs:     p.costs,
+	}, nil
+}
+
+// processOptionalGraphPattern resolves the clauses of an optional graph
+// pattern on their own and left joins the result to the current table. Rows
+// that do not match the optional graph pattern are kept with null cells.
+func (p *queryPlan) processOptionalGraphPattern(ctx context.Context, pattern string, cls []*semantic.GraphClause, lo *storage.LookupOptions) error {
+	opt, err := p.subPlan(pattern, cls)
+	if err != nil {
+		return err
+	}
+	if err := opt.processClauses(ctx, lo); err != nil {
+		return err
+	}
+	// Empty results may not carry all the bindings of the pattern.
+	for _, c := range cls {
+		opt.tbl.AddBindings(c.Bindings())
+	}
+	p.tbl.LeftOptionalJoin(opt.tbl)
+	return nil
+}
+
+// processUnionGraphPattern resolves each alternative of a union on its own and
+// appends their results. Bindings missing from an alternative get null cells.
+// The union is then joined to the current table.
+func (p *queryPlan) processUnionGraphPattern(ctx context.Context, pattern string, u *semantic.UnionGraphPattern, lo *storage.LookupOptions) error {
+	bs := u.Bindings()
+	ut, err := table.New(bs)
+	if err != nil {
+		return err
+	}
+	for i, cls := range u.Alternatives {
+		alt, err := p.subPlan(fmt.Sprintf("%s alternative %d", pattern, i+1), cls)
+		if err != nil {
+			return err
+		}
+		if err := alt.processClauses(ctx, lo); err != nil {
+			return err
+		}
+		alt.tbl.PadBindings(bs)
+		if err := ut.AppendTable(alt.tbl); err != nil {
+			return err
+		}
+	}
+	if len(p.tbl.Bindings()) == 0 {
+		return p.tbl.AppendTable(ut)
+	}
+	p.tbl.Join(ut)
+	return nil
+}
+
+// existenceKey returns the key identifying the values of the provided bindings
+// in a row. It returns false if any of them is not set.
+func existenceKey(r table.Row, bs []string) (string, bool) {
+	var ks []string
+	for _, b := range bs {
+		c, ok := r[b]
+		if !ok || c.IsNull() {
+			return "", false
+		}
+		ks = append(ks, c.String())
+	}
+	return strings.Join(ks, "\x00"), true
+}
+
+// processExistenceGraphPattern removes the rows for which the existence graph
+// pattern does not hold. The clauses of the pattern are processed starting
+// from the distinct values the rows take for the bindings shared with the
+// pattern, so they are specified or filtered using the values of each row.
+func (p *queryPlan) processExistenceGraphPattern(ctx context.Context, pattern string, e *semantic.ExistenceGraphPattern, lo *storage.LookupOptions) error {
+	var (
+		bs     []string
+		shared = make(map[string]bool)
+	)
+	for _, c := range e.Clauses {
+		for _, b := range c.Bindings() {
+			if p.tbl.HasBinding(b) && !shared[b] {
+				shared[b] = true
+				bs = append(bs, b)
+			}
+		}
+	}
+	sub, err := p.subPlan(pattern, e.SortedClauses())
+	if err != nil {
+		return err
+	}
+	if len(bs) > 0 {
+		seed, err := table.New(bs)
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, r := range p.tbl.Rows() {
+			k, ok := existenceKey(r, bs)
+			if !ok || seen[k] {
+				continue
+			}
+			seen[k] = true
+			nr := table.Row{}
+			for _, b := range bs {
+				nr[b] = r[b]
+			}
+			seed.AddRow(nr)
+		}
+		sub.tbl = seed
+	}
+	if len(bs) == 0 || sub.tbl.NumRows() > 0 {
+		if err := sub.processClauses(ctx, lo); err != nil {
+			return err
+		}
+	}
+	found := make(map[string]bool)
+	for _, r := range sub.tbl.Rows() {
+		if k, ok := existenceKey(r, bs); ok {
+			found[k] = true
+		}
+	}
+	if len(sub.tbl.Bindings()) == 0 {
+		// Only fully specified clauses were processed; none of them was missing.
+		found[""] = !sub.unresolvable
+	}
+	// Filter drops the rows for which the function returns true.
+	p.tbl.Filter(func(r table.Row) bool {
+		k, ok := existenceKey(r, bs)
+		return (ok && found[k]) == e.Negated
+	})
+	return nil
+}
+
+// processSubqueries runs the queries nested in the where clause and joins
+// their results. Subqueries are resolved first, so the values they return
+// specify the clauses of the graph pattern that share bindings with them.
+func (p *queryPlan) processSubqueries(ctx context.Context) error {
+	for i, sub := range p.stm.Subqueries() {
+		sp, err := newQueryPlan(ctx, p.store, sub, p.chanSize, p.workers, p.budget, p.snapshot)
+		if err != nil {
+			return err
+		}
+		sp.explain, sp.pattern = p.explain, subPattern(p.pattern, "subquery %d", i+1)
+		res, err := sp.Execute(ctx)
+		if err != nil {
+			return err
+		}
+		// Only the output bindings of the subquery are visible to the query.
+		bs := sub.OutputBindings()
+		tbl, err := table.New(bs)
+		if err != nil {
+			return err
+		}
+		shared := false
+		for _, b := range bs {
+			shared = shared || p.tbl.HasBinding(b)
+		}
+		for _, r := range res.Rows() {
+			nr := table.Row{}
+			for _, b := range bs {
+				nr[b] = r[b]
+			}
+			tbl.AddRow(nr)
+		}
+		switch {
+		case len(p.tbl.Bindings()) == 0:
+			if err := p.tbl.AppendTable(tbl); err != nil {
+				return err
+			}
+		case shared:
+			p.tbl.Join(tbl)
+		default:
+			if err := p.tbl.DotProduct(tbl); err != nil {
+				return err
+			}
+		}
+		if err := p.applyFilters(); err != nil {
+			return err
+		}
+		if p.tbl.NumRows() == 0 {
 			return nil
 		}
 	}
 	return nil
 }
 
+// processGraphPattern process the query graph pattern to retrieve the
+// data from the specified graphs. Subqueries are resolved first, then the
+// clauses of the graph pattern, unions, and optional graph patterns last.
+func (p *queryPlan) processGraphPattern(ctx context.Context, lo *storage.LookupOptions) error {
+	if err := p.processSubqueries(ctx); err != nil {
+		return err
+	}
+	if len(p.stm.Subqueries()) > 0 && p.tbl.NumRows() == 0 {
+		return nil
+	}
+	if err := p.processClauses(ctx, lo); err != nil {
+		return err
+	}
+	if len(p.cls) > 0 && p.tbl.NumRows() == 0 {
+		return nil
+	}
+	for i, u := range p.stm.SortedUnionGraphPatterns() {
+		if err := p.processUnionGraphPattern(ctx, subPattern(p.pattern, "union %d", i+1), u, lo); err != nil {
+			return err
+		}
+		if err := p.applyFilters(); err != nil {
+			return err
+		}
+		if p.tbl.NumRows() == 0 {
+			return nil
+		}
+	}
+	for i, cls := range p.stm.SortedOptionalGraphPatternClauses() {
+		if err := p.processOptionalGraphPattern(ctx, subPattern(p.pattern, "optional %d", i+1), cls, lo); err != nil {
+			return err
+		}
+		if err := p.applyFilters(); err != nil {
+			return err
+		}
+	}
+	for i, e := range p.stm.ExistenceGraphPatterns() {
+		if p.tbl.NumRows() == 0 {
+			return nil
+		}
+		if err := p.processExistenceGraphPattern(ctx, subPattern(p.pattern, existencePattern(e), i+1), e, lo); err != nil {
+			return err
+		}
+	}
+	return p.checkBudget(p.tbl)
+}
+
 // projectAndGroupBy takes the resulting table and projects its contents and
 // groups it by if needed.
 func (p *queryPlan) projectAndGroupBy() error {
+	if err := p.evaluateExpressions(); err != nil {
+		return err
+	}
 	grp := p.stm.GroupByBindings()
 	if len(grp) == 0 { // The table only needs to be projected.
 		p.tbl.AddBindings(p.stm.OutputBindings())
 		// For each row, copy each input binding value to its appropriate alias.
 		for _, prj := range p.stm.Projections() {
+			if prj.Expression != nil {
+				continue
+			}
 			for _, row := range p.tbl.Rows() {
 				row[prj.Alias] = row[prj.Binding]
 			}
@@ -496,6 +991,24 @@ func (p *queryPlan) projectAndGroupBy() error {
 		return p.tbl.ProjectBindings(p.stm.OutputBindings())
 	}
 	// The table needs to be group reduced.
+	var first table.Row
+	if p.tbl.NumRows() > 0 {
+		first = p.tbl.Rows()[0]
+	}
+	cfg, tmpBindings, aaps, err := p.groupByConfig(first)
+	if err != nil {
+		return err
+	}
+	if err := p.tbl.ProjectBindings(tmpBindings); err != nil {
+		return err
+	}
+	return p.tbl.Reduce(cfg, aaps)
+}
+
+// groupByConfig returns the sort configuration, the bindings involved, and the
+// accumulators needed to group reduce the rows of the query. The type of the
+// values summed is taken from the provided row, if any.
+func (p *queryPlan) groupByConfig(first table.Row) (table.SortConfig, []string, []table.AliasAccPair, error) {
 	// Project only binding involved in the group operation.
 	tmpBindings := []string{}
 	mapBindings := make(map[string]bool)
@@ -503,21 +1016,26 @@ func (p *queryPlan) projectAndGroupBy() error {
 	cfg := table.SortConfig{}
 	aaps := []table.AliasAccPair{}
 	for _, prj := range p.stm.Projections() {
+		// Expression values were already stored under their alias.
+		in := prj.Binding
+		if prj.Expression != nil {
+			in = prj.Alias
+		}
 		// Only include used incoming bindings.
-		tmpBindings = append(tmpBindings, prj.Binding)
+		tmpBindings = append(tmpBindings, in)
 		// Update sorting configuration.
 		found := false
 		for _, g := range p.stm.GroupByBindings() {
-			if prj.Binding == g {
+			if in == g {
 				found = true
 			}
 		}
-		if found && !mapBindings[prj.Binding] {
-			cfg = append(cfg, table.SortConfig{{Binding: prj.Binding}}...)
-			mapBindings[prj.Binding] = true
+		if found && !mapBindings[in] {
+			cfg = append(cfg, table.SortConfig{{Binding: in}}...)
+			mapBindings[in] = true
 		}
 		aap := table.AliasAccPair{
-			InAlias: prj.Binding,
+			InAlias: in,
 		}
 		if prj.Alias == "" {
 			aap.OutAlias = prj.Binding
@@ -533,9 +1051,13 @@ func (p *queryPlan) projectAndGroupBy() error {
 				aap.Acc = table.NewCountAccumulator()
 			}
 		case lexer.ItemSum:
-			cell := p.tbl.Rows()[0][prj.Binding]
+			if first == nil {
+				aap.Acc = table.NewSumInt64LiteralAccumulator(0)
+				break
+			}
+			cell := first[prj.Binding]
 			if cell.L == nil {
-				return fmt.Errorf("cannot only sum int64 and float64 literals; found %s instead for binding %q", cell, prj.Binding)
+				return nil, nil, nil, fmt.Errorf("cannot only sum int64 and float64 literals; found %s instead for binding %q", cell, prj.Binding)
 			}
 			switch cell.L.Type() {
 			case literal.Int64:
@@ -543,67 +1065,140 @@ func (p *queryPlan) projectAndGroupBy() error {
 			case literal.Float64:
 				aap.Acc = table.NewSumFloat64LiteralAccumulator(0)
 			default:
-				return fmt.Errorf("cannot only sum int64 and float64 literals; found literal type %s instead for binding %q", cell.L.Type(), prj.Binding)
+				return nil, nil, nil, fmt.Errorf("cannot only sum int64 and float64 literals; found literal type %s instead for binding %q", cell.L.Type(), prj.Binding)
 			}
+		case lexer.ItemAvg:
+			aap.Acc = table.NewAvgAccumulator()
+		case lexer.ItemMin:
+			aap.Acc = table.NewMinAccumulator()
+		case lexer.ItemMax:
+			aap.Acc = table.NewMaxAccumulator()
+		case lexer.ItemGroupConcat:
+			aap.Acc = table.NewGroupConcatAccumulator(prj.Separator)
 		}
 		aaps = append(aaps, aap)
 	}
-	if err := p.tbl.ProjectBindings(tmpBindings); err != nil {
-		return err
+	return cfg, tmpBindings, aaps, nil
+}
+
+// evaluateExpressions computes the scalar expressions of the projections for
+// each row and stores the resulting values under their aliases.
+func (p *queryPlan) evaluateExpressions() error {
+	var bs []string
+	for _, prj := range p.stm.Projections() {
+		if prj.Expression != nil {
+			bs = append(bs, prj.Alias)
+		}
+	}
+	if len(bs) == 0 {
+		return nil
+	}
+	p.tbl.AddBindings(bs)
+	for _, row := range p.tbl.Rows() {
+		for _, prj := range p.stm.Projections() {
+			if prj.Expression == nil {
+				continue
+			}
+			c, err := prj.Expression.Evaluate(row)
+			if err != nil {
+				return err
+			}
+			row[prj.Alias] = c
+		}
 	}
-	p.tbl.Reduce(cfg, aaps)
 	return nil
 }
 
+// after drops the rows already returned to the client before the position of
+// the cursor provided in the cursor clause, if any.
+func (p *queryPlan) after() {
+	if c := p.stm.Cursor(); c != nil {
+		p.tbl.After(c)
+	}
+}
+
 // orderBy takes the resulting table and sorts its contents according to the
-// specifications of the ORDER BY clause.
+// specifications of the ORDER BY clause. Ties are broken using the remaining
+// output bindings, so the order of the rows does not change between runs.
 func (p *queryPlan) orderBy() {
-	p.tbl.Sort(p.stm.OrderByConfig())
+	if len(p.stm.OrderByConfig()) == 0 {
+		p.tbl.Sort(p.stm.OrderByConfig())
+		return
+	}
+	p.tbl.Sort(p.stm.TotalOrderConfig())
 }
 
 // having runs the filtering based on the having clause if needed.
 func (p *queryPlan) having() error {
 	if p.stm.HasHavingClause() {
-		eval := p.stm.HavingEvaluator()
-		ok := true
-		var eErr error
-		p.tbl.Filter(func(r table.Row) bool {
-			b, err := eval.Evaluate(r)
-			if err != nil {
-				ok, eErr = false, err
-			}
-			return !b
-		})
-		if !ok {
-			return eErr
-		}
+		return filterTable(p.tbl, p.stm.HavingEvaluator())
 	}
 	return nil
 }
 
-// limit truncates the table if the limit clause if available.
-func (p *queryPlan) limit() {
+// limit drops the rows skipped by the offset clause and truncates the table
+// if the limit clause if available. If an ordered query leaves rows out, it
+// returns the continuation token that allows fetching them.
+func (p *queryPlan) limit() (string, error) {
+	offset := int64(0)
+	if p.stm.IsOffsetSet() {
+		offset = p.stm.Offset()
+	}
+	tkn := ""
+	if p.stm.IsLimitSet() && len(p.stm.OrderByConfig()) > 0 {
+		rws, consumed := p.tbl.Rows(), offset+p.stm.Limit()
+		if consumed > 0 && int64(len(rws)) > consumed {
+			last := rws[consumed-1]
+			c, err := table.NewCursor(last, p.stm.TotalOrderConfig(), 0)
+			if err != nil {
+				return "", err
+			}
+			for _, r := range rws[:consumed] {
+				if c.Compare(r) == 0 {
+					c.Skip++
+				}
+			}
+			if pc := p.stm.Cursor(); pc != nil && pc.Compare(last) == 0 {
+				c.Skip += pc.Skip
+			}
+			tkn = c.Token()
+		}
+	}
+	if offset > 0 {
+		p.tbl.Offset(offset)
+	}
 	if p.stm.IsLimitSet() {
 		p.tbl.Limit(p.stm.Limit())
 	}
+	return tkn, nil
 }
 
 // Execute queries the indicated graphs.
 func (p *queryPlan) Execute(ctx context.Context) (*table.Table, error) {
 	// Retrieve the data.
 	lo := p.stm.GlobalLookupOptions()
-	if err := p.processGraphPattern(ctx, lo); err != nil {
-		return nil, err
-	}
-	if err := p.projectAndGroupBy(); err != nil {
-		return nil, err
+	if p.spills() {
+		// Sort and group the rows spilling them to temporary files if needed.
+		if err := p.spillSort(ctx, lo); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := p.processGraphPattern(ctx, lo); err != nil {
+			return nil, err
+		}
+		if err := p.projectAndGroupBy(); err != nil {
+			return nil, err
+		}
+		p.after()
+		p.orderBy()
+		if err := p.having(); err != nil {
+			return nil, err
+		}
 	}
-	p.orderBy()
-	err := p.having()
+	tkn, err := p.limit()
 	if err != nil {
 		return nil, err
 	}
-	p.limit()
 	if p.tbl.NumRows() == 0 {
 		// Correct the bindings.
 		t, err := table.New(p.stm.OutputBindings())
@@ -612,14 +1207,60 @@ func (p *queryPlan) Execute(ctx context.Context) (*table.Table, error) {
 		}
 		p.tbl = t
 	}
+	p.tbl.SetContinuationToken(tkn)
 	return p.tbl, nil
 }
 
-// New create a new executable plan given a semantic BQL statement.
+// askPlan encapsulates the sequence of instructions that need to be executed
+// in order to satisfy the execution of a valid ask BQL statement.
+type askPlan struct {
+	stm      *semantic.Statement
+	store    storage.Store
+	chanSize int
+	workers  workerPool
+	budget   memoryBudget
+	snapshot snapshotFinder
+}
+
+// Execute returns a table with a single row that binds ?ask to true if the
+// graph pattern of the statement matches the data in the indicated graphs, or
+// to false otherwise.
+func (p *askPlan) Execute(ctx context.Context) (*table.Table, error) {
+	qp, err := newQueryPlan(ctx, p.store, p.stm, p.chanSize, p.workers, p.budget, p.snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if err := qp.processGraphPattern(ctx, p.stm.GlobalLookupOptions()); err != nil {
+		return nil, err
+	}
+	found := qp.tbl.NumRows() > 0
+	if len(qp.tbl.Bindings()) == 0 {
+		// Graph patterns made only of fully specified clauses bind nothing, hence
+		// they match if all the triples were found.
+		found = len(qp.cls) > 0 && !qp.unresolvable
+	}
+	l, err := literal.DefaultBuilder().Build(literal.Bool, found)
+	if err != nil {
+		return nil, err
+	}
+	t, err := table.New([]string{"?ask"})
+	if err != nil {
+		return nil, err
+	}
+	t.AddRow(table.Row{"?ask": &table.Cell{L: l}})
+	return t, nil
+}
+
+// New create a new executable plan given a semantic BQL statement. Queries run
+// their independent lookups concurrently on up to DefaultWorkers goroutines.
 func New(ctx context.Context, store storage.Store, stm *semantic.Statement, chanSize int) (Executor, error) {
+	workers, budget := newWorkerPool(DefaultWorkers), memoryBudget{}
 	switch stm.Type() {
 	case semantic.Query:
-		return newQueryPlan(ctx, store, stm, chanSize)
+		if stm.Explain() {
+			return newExplainPlan(ctx, store, stm, chanSize, workers, budget, noSnapshots)
+		}
+		return newQueryPlan(ctx, store, stm, chanSize, workers, budget, noSnapshots)
 	case semantic.Insert:
 		return &insertPlan{
 			stm:   stm,
@@ -640,6 +1281,52 @@ func New(ctx context.Context, store storage.Store, stm *semantic.Statement, chan
 			stm:   stm,
 			store: store,
 		}, nil
+	case semantic.Construct:
+		return &constructPlan{
+			stm:      stm,
+			store:    store,
+			chanSize: chanSize,
+			workers:  workers,
+			budget:   budget,
+			snapshot: noSnapshots,
+		}, nil
+	case semantic.Ask:
+		return &askPlan{
+			stm:      stm,
+			store:    store,
+			chanSize: chanSize,
+			workers:  workers,
+			budget:   budget,
+			snapshot: noSnapshots,
+		}, nil
+	case semantic.Describe:
+		return &describePlan{
+			stm:      stm,
+			store:    store,
+			chanSize: chanSize,
+			snapshot: noSnapshots,
+		}, nil
+	case semantic.ShowGraphs:
+		return &showGraphsPlan{
+			store: store,
+		}, nil
+	case semantic.ShowStats:
+		return &showStatsPlan{
+			stm:      stm,
+			store:    store,
+			chanSize: chanSize,
+			snapshot: noSnapshots,
+		}, nil
+	case semantic.Update:
+		return &updatePlan{
+			stm:      stm,
+			store:    store,
+			chanSize: chanSize,
+			workers:  workers,
+			budget:   budget,
+		}, nil
+	case semantic.Begin, semantic.Commit, semantic.Rollback, semantic.CreateSnapshot, semantic.DropSnapshot:
+		return nil, fmt.Errorf("planner.New: %s statements can only be run in a planner.Session", stm.Type())
 	default:
 		return nil, fmt.Errorf("planner.New: unknown statement type in statement %v", stm)
 	}
@@ -16,6 +16,9 @@ package planner
 
 import (
 	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
@@ -23,6 +26,7 @@ import (
 
 	"github.com/google/badwolf/bql/grammar"
 	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
 	"github.com/google/badwolf/io"
 	"github.com/google/badwolf/storage"
 	"github.com/google/badwolf/storage/memory"
@@ -232,29 +236,50 @@ const (
 		`
 
 	testTriples = originalTriples + tripleFromIssue40
+
+	measureTriples = `/u<joe> "name"@[] "Joe Doe"^^type:text
+		/u<joe> "height"@[] "180"^^type:int64
+		/u<joe> "weight"@[] "81"^^type:float64
+		/u<mary> "name"@[] "Mary Lo"^^type:text
+		/u<mary> "height"@[] "160"^^type:int64
+		/u<mary> "weight"@[] "52"^^type:float64
+		/u<peter> "height"@[] "170"^^type:int64
+		`
+
+	pathTriples = `/u<a> "follows"@[] /u<b>
+		/u<b> "follows"@[] /u<c>
+		/u<a> "follows"@[] /u<c>
+		/u<c> "follows"@[] /u<a>
+		/u<x> "knows"@[2016-01-01T00:00:00Z] /u<y>
+		/u<y> "knows"@[2016-02-01T00:00:00Z] /u<z>
+		/u<z> "knows"@[2016-03-01T00:00:00Z] /u<w>
+		`
 )
 
-func populateTestStore(t *testing.T) storage.Store {
+// populateTestStore returns a store with the test triples, and any extra
+// triples provided, in the ?test graph.
+func populateTestStore(t *testing.T, extra ...string) storage.Store {
 	s, ctx := memory.NewStore(), context.Background()
 	g, err := s.NewGraph(ctx, "?test")
 	if err != nil {
 		t.Fatalf("memory.NewGraph failed to create \"?test\" with error %v", err)
 	}
-	b := bytes.NewBufferString(testTriples)
+	trpls := testTriples + strings.Join(extra, "")
+	b := bytes.NewBufferString(trpls)
 	if _, err := io.ReadIntoGraph(ctx, g, b, literal.DefaultBuilder()); err != nil {
 		t.Fatalf("io.ReadIntoGraph failed to read test graph with error %v", err)
 	}
-	trpls := make(chan *triple.Triple)
+	ts := make(chan *triple.Triple)
 	go func() {
-		if err := g.Triples(ctx, trpls); err != nil {
+		if err := g.Triples(ctx, ts); err != nil {
 			t.Fatal(err)
 		}
 	}()
 	cnt := 0
-	for _ = range trpls {
+	for _ = range ts {
 		cnt++
 	}
-	if got, want := cnt, len(strings.Split(testTriples, "\n"))-1; got != want {
+	if got, want := cnt, len(strings.Split(trpls, "\n"))-1; got != want {
 		t.Fatalf("Failed to import all test triples; got %v, want %v", got, want)
 	}
 	return s
@@ -303,6 +328,16 @@ func TestPlannerQuery(t *testing.T) {
 			nbs:  3,
 			nrws: len(strings.Split(testTriples, "\n")) - 1,
 		},
+		{
+			q:    `select ?s, ?p, ?o from ?test where {?s ?p /u<john> . ?s ?p ?o};`,
+			nbs:  3,
+			nrws: 2,
+		},
+		{
+			q:    `select ?car, ?x, ?y from ?test where {/u<peter> "bought"@[?t] ?car . ?car "is_a"@[] ?type . ?x "is_a"@[] ?type . ?y "bought"@[?u] ?x};`,
+			nbs:  3,
+			nrws: 16,
+		},
 		{
 			q:    `select ?p, ?o from ?test where {/u<joe> ?p ?o};`,
 			nbs:  2,
@@ -483,6 +518,151 @@ func TestPlannerQuery(t *testing.T) {
 			nbs:  2,
 			nrws: 1,
 		},
+		{
+			q:    `select ?c, ?gc from ?test where {/u<joe> "parent_of"@[] ?c . optional {?c "parent_of"@[] ?gc}};`,
+			nbs:  2,
+			nrws: 3,
+		},
+		{
+			q:    `select ?c, ?x from ?test where {/u<joe> "parent_of"@[] ?c . optional {?c "unknown"@[] ?x}};`,
+			nbs:  2,
+			nrws: 2,
+		},
+		{
+			q:    `select ?c, ?gc, ?car from ?test where {/u<joe> "parent_of"@[] ?c . optional {?c "parent_of"@[] ?gc} . optional {?c "bought"@[?t] ?car . ?car "is_a"@[] /t<car>}};`,
+			nbs:  3,
+			nrws: 9,
+		},
+		{
+			q:    `select ?c, ?gc from ?test where {optional {?c "parent_of"@[] ?gc} . /u<joe> "parent_of"@[] ?c};`,
+			nbs:  2,
+			nrws: 3,
+		},
+		{
+			q:    `select ?x from ?test where {{/u<joe> "parent_of"@[] ?x} union {/u<peter> "parent_of"@[] ?x}};`,
+			nbs:  1,
+			nrws: 4,
+		},
+		{
+			q:    `select ?c, ?car from ?test where {{/u<joe> "parent_of"@[] ?c} union {?c "bought"@[?t] ?car}};`,
+			nbs:  2,
+			nrws: 6,
+		},
+		{
+			q:    `select ?c, ?x from ?test where {/u<joe> "parent_of"@[] ?c . {?c "parent_of"@[] ?x} union {?c "bought"@[?t] ?x}};`,
+			nbs:  2,
+			nrws: 6,
+		},
+		{
+			q:    `select ?c, ?gc, ?car from ?test where {/u<joe> "parent_of"@[] ?c . {?c "parent_of"@[] ?gc} union {/u<peter> "bought"@[?t] ?car}};`,
+			nbs:  3,
+			nrws: 10,
+		},
+		{
+			q:    `select ?r, ?i from ?test where {{/room<Kitchen> "connects_to"@[] ?r} union {/room<Hallway> "connects_to"@[] ?r} . optional {?i "in"@[?t] ?r}};`,
+			nbs:  2,
+			nrws: 4,
+		},
+		{
+			q:    `select ?c, ?gc from ?test where {/u<joe> "parent_of"@[] ?c . ?c "parent_of"@[] ?gc . filter(?gc = /u<eve>)};`,
+			nbs:  2,
+			nrws: 1,
+		},
+		{
+			q:    `select ?s, ?o from ?test where {filter(not(?s = ?o)) . ?s "connects_to"@[] ?o . ?o "connects_to"@[] ?s};`,
+			nbs:  2,
+			nrws: 6,
+		},
+		{
+			q:    `select ?car, ?t from ?test where {/u<peter> "bought"@[?t] ?car . filter((?car = /c<mini>) or (?car = /c<model x>))};`,
+			nbs:  2,
+			nrws: 2,
+		},
+		{
+			q:    `select ?car from ?test where {/u<peter> "bought"@[?t] ?car . filter(?t > ""@[2016-02-01T08:00:00Z])};`,
+			nbs:  1,
+			nrws: 2,
+		},
+		{
+			q:    `select ?car from ?test where {/u<peter> "bought"@[?t] ?car . filter(?t = ""@[2016-02-01T08:00:00Z])};`,
+			nbs:  1,
+			nrws: 1,
+		},
+		{
+			q:    `select ?c, ?gc from ?test where {/u<joe> "parent_of"@[] ?c . optional {?c "parent_of"@[] ?gc} . filter(?c = /u<mary>)};`,
+			nbs:  2,
+			nrws: 1,
+		},
+		{
+			q:    `select ?car from ?test where {/u<peter> "bought"@[?t] ?car . filter(starts_with(?car, "model"))};`,
+			nbs:  1,
+			nrws: 3,
+		},
+		{
+			q:    `select ?r from ?test where {/room<Kitchen> "connects_to"@[] ?r . filter(regex(?r, "^B.*room$"))};`,
+			nbs:  1,
+			nrws: 2,
+		},
+		{
+			q:    `select ?p, ?o from ?test where {/u<peter> ?p ?o . filter(contains(?p, "ough") and not(starts_with(?o, "model")))};`,
+			nbs:  2,
+			nrws: 1,
+		},
+		{
+			q:    `select ?s, ?r from ?test where {?s "connects_to"@[] ?r} having contains(?r, "Kit");`,
+			nbs:  2,
+			nrws: 4,
+		},
+		{
+			q:    `select ?s, ?r from ?test where {?s "connects_to"@[] ?r} having contains(?r, "Kit") and starts_with(?s, "Ha");`,
+			nbs:  2,
+			nrws: 1,
+		},
+		{
+			q:    `select ?d from ?test where {/u<joe> "parent_of"@[]+ ?d};`,
+			nbs:  1,
+			nrws: 4,
+		},
+		{
+			q:    `select ?d from ?test where {/u<joe> "parent_of"@[]* ?d};`,
+			nbs:  1,
+			nrws: 5,
+		},
+		{
+			q:    `select ?d from ?test where {/u<joe> "parent_of"@[]{"2"^^type:int64, "2"^^type:int64} ?d};`,
+			nbs:  1,
+			nrws: 2,
+		},
+		{
+			q:    `select ?d from ?test where {/u<joe> "parent_of"@[]{"1"^^type:int64,} ?d};`,
+			nbs:  1,
+			nrws: 4,
+		},
+		{
+			q:    `select ?a from ?test where {?a "parent_of"@[]+ /u<eve>};`,
+			nbs:  1,
+			nrws: 2,
+		},
+		{
+			q:    `select ?a, ?d from ?test where {?a "parent_of"@[]+ ?d};`,
+			nbs:  2,
+			nrws: 6,
+		},
+		{
+			q:    `select ?r from ?test where {/room<Hallway> "connects_to"@[]+ ?r};`,
+			nbs:  1,
+			nrws: 5,
+		},
+		{
+			q:    `select ?c, ?d from ?test where {/u<joe> "parent_of"@[] ?c . ?c "parent_of"@[]* ?d};`,
+			nbs:  2,
+			nrws: 4,
+		},
+		{
+			q:    `select ?r from ?test where {?r "connects_to"@[]{"2"^^type:int64, "2"^^type:int64} ?r};`,
+			nbs:  1,
+			nrws: 4,
+		},
 	}
 
 	s := populateTestStore(t)
@@ -513,6 +693,87 @@ func TestPlannerQuery(t *testing.T) {
 	}
 }
 
+func TestPlannerOptionalKeepsRowsWithNulls(t *testing.T) {
+	ctx := context.Background()
+	q := `select ?c, ?gc from ?test where {/u<joe> "parent_of"@[] ?c . optional {?c "parent_of"@[] ?gc}};`
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+	}
+	plnr, err := New(ctx, populateTestStore(t), st, 0)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Excecute failed for query %q with error %v", q, err)
+	}
+	got := make(map[string]bool)
+	for _, r := range tbl.Rows() {
+		got[r["?c"].String()+" "+r["?gc"].String()] = true
+	}
+	want := map[string]bool{
+		"/u<mary> <NULL>":    true,
+		"/u<peter> /u<john>": true,
+		"/u<peter> /u<eve>":  true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("planner.Execute returned the wrong rows for query %q; got %v, want %v", q, got, want)
+	}
+}
+
+func TestPlannerAggregates(t *testing.T) {
+	ss := NewSession(populateTestStore(t, measureTriples))
+	testTable := []struct {
+		q    string
+		want map[string]string
+	}{
+		{
+			q: `select ?p, sum(?h) as ?sum, avg(?h) as ?avg, min(?h) as ?min, max(?h) as ?max from ?test where {?p "parent_of"@[] ?c . ?c "height"@[] ?h} group by ?p;`,
+			want: map[string]string{
+				"?p":   "/u<joe>",
+				"?sum": `"330"^^type:int64`,
+				"?avg": `"165"^^type:float64`,
+				"?min": `"160"^^type:int64`,
+				"?max": `"170"^^type:int64`,
+			},
+		},
+		{
+			q: `select ?p, min(?t) as ?first, max(?t) as ?last from ?test where {?p "bought"@[?t] ?car} group by ?p;`,
+			want: map[string]string{
+				"?p":     "/u<peter>",
+				"?first": "2016-01-01T00:00:00-08:00",
+				"?last":  "2016-04-01T00:00:00-08:00",
+			},
+		},
+		{
+			q: `select ?p, group_concat(?h, "|") as ?hs from ?test where {?p "parent_of"@[] ?c . ?c "height"@[] ?h . filter(?h > "160"^^type:int64)} group by ?p;`,
+			want: map[string]string{
+				"?p":  "/u<joe>",
+				"?hs": `"170"^^type:text`,
+			},
+		},
+	}
+	for _, entry := range testTable {
+		tbl := mustRunInSession(t, ss, entry.q)
+		if got, want := tbl.NumRows(), 1; got != want {
+			t.Errorf("planner.Excecute returned the wrong number of rows for query %q; got %d, want %d", entry.q, got, want)
+			continue
+		}
+		got := make(map[string]string)
+		for k, c := range tbl.Rows()[0] {
+			got[k] = c.String()
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Excecute returned the wrong row for query %q; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
 func TestTreeTraversalToRoot(t *testing.T) {
 	// Graph traversal data.
 	traversalTriples := `/person<Gavin Belson>  "born in"@[]    /city<Springfield>
@@ -605,3 +866,908 @@ func BenchmarkReg2(b *testing.B) {
 func BenchmarkAs2(b *testing.B) {
 	benchmarkQuery(`select ?s as ?s1, ?p as ?p1, ?o as ?o1 from ?test where {?s ?p ?o};`, b)
 }
+
+func TestPlannerPathQueries(t *testing.T) {
+	ss := NewSession(populateTestStore(t, pathTriples))
+	testTable := []struct {
+		q    string
+		bs   []string
+		want []string
+	}{
+		{
+			q:  `select ?h, ?p from ?test where {/u<a> "follows"@[]+ path ?p hops ?h /u<c>};`,
+			bs: []string{"?h", "?p"},
+			want: []string{
+				`"1"^^type:int64 [/u<a> "follows"@[] /u<c>]`,
+				`"2"^^type:int64 [/u<a> "follows"@[] /u<b>, /u<b> "follows"@[] /u<c>]`,
+			},
+		},
+		{
+			q:  `select ?p from ?test where {/u<a> "follows"@[]+ shortest path ?p /u<c>};`,
+			bs: []string{"?p"},
+			want: []string{
+				`[/u<a> "follows"@[] /u<c>]`,
+			},
+		},
+		{
+			q:  `select ?o, ?h from ?test where {/u<a> "follows"@[]* shortest hops ?h ?o};`,
+			bs: []string{"?o", "?h"},
+			want: []string{
+				`/u<a> "0"^^type:int64`,
+				`/u<b> "1"^^type:int64`,
+				`/u<c> "1"^^type:int64`,
+			},
+		},
+		{
+			q:  `select ?h, ?p from ?test where {/u<a> "follows"@[]+ path ?p hops ?h /u<a>};`,
+			bs: []string{"?h", "?p"},
+			want: []string{
+				`"2"^^type:int64 [/u<a> "follows"@[] /u<c>, /u<c> "follows"@[] /u<a>]`,
+				`"3"^^type:int64 [/u<a> "follows"@[] /u<b>, /u<b> "follows"@[] /u<c>, /u<c> "follows"@[] /u<a>]`,
+			},
+		},
+		{
+			q:  `select ?s, ?p from ?test where {?s "follows"@[]{"2"^^type:int64, "2"^^type:int64} path ?p /u<c>};`,
+			bs: []string{"?s", "?p"},
+			want: []string{
+				`/u<a> [/u<a> "follows"@[] /u<b>, /u<b> "follows"@[] /u<c>]`,
+				`/u<c> [/u<c> "follows"@[] /u<a>, /u<a> "follows"@[] /u<c>]`,
+			},
+		},
+		{
+			q:  `select ?o, ?p from ?test where {/u<x> "knows"@[2016-01-01T00:00:00Z, 2016-02-15T00:00:00Z]+ path ?p ?o};`,
+			bs: []string{"?o", "?p"},
+			want: []string{
+				`/u<y> [/u<x> "knows"@[2016-01-01T00:00:00Z] /u<y>]`,
+				`/u<z> [/u<x> "knows"@[2016-01-01T00:00:00Z] /u<y>, /u<y> "knows"@[2016-02-01T00:00:00Z] /u<z>]`,
+			},
+		},
+		{
+			q:  `select ?s, ?o, ?h from ?test where {?s "knows"@[2015-01-01T00:00:00Z, 2017-01-01T00:00:00Z]+ hops ?h ?o} after ""@[2016-01-15T00:00:00Z];`,
+			bs: []string{"?s", "?o", "?h"},
+			want: []string{
+				`/u<y> /u<w> "2"^^type:int64`,
+				`/u<y> /u<z> "1"^^type:int64`,
+				`/u<z> /u<w> "1"^^type:int64`,
+			},
+		},
+	}
+	for _, entry := range testTable {
+		tbl := mustRunInSession(t, ss, entry.q)
+		var got []string
+		for _, r := range tbl.Rows() {
+			var vs []string
+			for _, b := range entry.bs {
+				vs = append(vs, r[b].String())
+			}
+			got = append(got, strings.Join(vs, " "))
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Excecute returned the wrong rows for query %q; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerSimplePathLimits(t *testing.T) {
+	var chain []string
+	for i := 0; i < maxSimplePathHops+4; i++ {
+		chain = append(chain, fmt.Sprintf("/n<%d> \"next\"@[] /n<%d>\n", i, i+1))
+	}
+	ss := NewSession(populateTestStore(t, chain...))
+	testTable := []struct {
+		q    string
+		nrws int
+	}{
+		// Nodes are reached regardless of their distance.
+		{q: `select ?o from ?test where {/n<0> "next"@[]+ ?o};`, nrws: maxSimplePathHops + 4},
+		// Paths without a maximum are bounded.
+		{q: `select ?h from ?test where {/n<0> "next"@[]+ hops ?h ?o};`, nrws: maxSimplePathHops},
+		{q: `select ?h from ?test where {/n<0> "next"@[]+ shortest hops ?h ?o};`, nrws: maxSimplePathHops + 4},
+		{q: `select ?h from ?test where {/n<0> "next"@[]{"18"^^type:int64,} hops ?h ?o};`, nrws: 1},
+		{q: `select ?h from ?test where {/n<0> "next"@[]{"1"^^type:int64, "20"^^type:int64} hops ?h ?o};`, nrws: maxSimplePathHops + 4},
+	}
+	for _, entry := range testTable {
+		tbl := mustRunInSession(t, ss, entry.q)
+		if got, want := tbl.NumRows(), entry.nrws; got != want {
+			t.Errorf("planner.Excecute returned the wrong number of rows for query %q; got %d, want %d", entry.q, got, want)
+		}
+	}
+
+	// Enumerating paths stops once the context is canceled.
+	q := `select ?h from ?test where {/n<0> "next"@[]+ hops ?h ?o};`
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	plnr, err := ss.New(ctx, st, 0)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+	}
+	if _, err := plnr.Execute(ctx); err == nil {
+		t.Errorf("planner.Excecute should have failed for query %q with a canceled context", q)
+	}
+}
+
+func TestPlannerPagination(t *testing.T) {
+	s, ctx := populateTestStore(t), context.Background()
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	run := func(q string) *table.Table {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", q, err)
+		}
+		plnr, err := New(ctx, s, st, 0)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Excecute failed for query %q with error %v", q, err)
+		}
+		return tbl
+	}
+	rows := func(tbl *table.Table) []string {
+		var res []string
+		for _, r := range tbl.Rows() {
+			var vs []string
+			for _, b := range tbl.Bindings() {
+				vs = append(vs, r[b].String())
+			}
+			res = append(res, strings.Join(vs, " "))
+		}
+		return res
+	}
+
+	// Offsets skip the first rows of the ordered results.
+	q := `select ?s, ?o from ?test where {?s "parent_of"@[] ?o} order by ?s, ?o limit "2"^^type:int64 offset "1"^^type:int64;`
+	if got, want := rows(run(q)), []string{"/u<joe> /u<peter>", "/u<peter> /u<eve>"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("planner.Execute returned the wrong rows for query %q; got %v, want %v", q, got, want)
+	}
+
+	// Paging with continuation tokens returns all the rows once, even when
+	// some of them are equal.
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q: `select ?s, ?o from ?test where {?s "parent_of"@[] ?o} order by ?s desc limit "1"^^type:int64`,
+			want: []string{
+				"/u<peter> /u<eve>",
+				"/u<peter> /u<john>",
+				"/u<joe> /u<mary>",
+				"/u<joe> /u<peter>",
+			},
+		},
+		{
+			q: `select ?s from ?test where {?s "parent_of"@[] ?o} order by ?s limit "3"^^type:int64`,
+			want: []string{
+				"/u<joe>",
+				"/u<joe>",
+				"/u<peter>",
+				"/u<peter>",
+			},
+		},
+	}
+	for _, entry := range testTable {
+		var got []string
+		tbl := run(entry.q + ";")
+		for pages := 1; ; pages++ {
+			got = append(got, rows(tbl)...)
+			tkn := tbl.ContinuationToken()
+			if tkn == "" {
+				break
+			}
+			if pages > len(entry.want) {
+				t.Fatalf("planner.Execute returned too many pages for query %q", entry.q)
+			}
+			tbl = run(fmt.Sprintf("%s cursor %q;", entry.q, tkn))
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("paging through query %q returned the wrong rows; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerSubqueries(t *testing.T) {
+	s, ctx := populateTestStore(t), context.Background()
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	testTable := []struct {
+		q    string
+		bs   []string
+		want []string
+	}{
+		{
+			q:  `select ?p, ?n, ?car from ?test where {(select ?p, count(?c) as ?n from ?test where {?p "parent_of"@[] ?c} group by ?p) . ?p "bought"@[?t] ?car};`,
+			bs: []string{"?p", "?n", "?car"},
+			want: []string{
+				`/u<peter> "2"^^type:int64 /c<mini>`,
+				`/u<peter> "2"^^type:int64 /c<model s>`,
+				`/u<peter> "2"^^type:int64 /c<model x>`,
+				`/u<peter> "2"^^type:int64 /c<model y>`,
+			},
+		},
+		{
+			q:  `select ?s, ?n from ?test where {(select ?s, count(?o) as ?n from ?test where {?s ?p ?o} group by ?s) . filter(?n > "3"^^type:int64)};`,
+			bs: []string{"?s", "?n"},
+			want: []string{
+				`/l<barcelona> "4"^^type:int64`,
+				`/u<peter> "6"^^type:int64`,
+			},
+		},
+		{
+			// Bindings not returned by the subquery do not constrain the query.
+			q:  `select ?p, ?c from ?test where {(select ?p from ?test where {?p "bought"@[?t] ?c} group by ?p) . ?p "parent_of"@[] ?c};`,
+			bs: []string{"?p", "?c"},
+			want: []string{
+				`/u<peter> /u<eve>`,
+				`/u<peter> /u<john>`,
+			},
+		},
+		{
+			q:  `select ?o, ?x from ?test where {?o "parent_of"@[] ?x . (select ?o from ?test where {/u<joe> "parent_of"@[] ?o} order by ?o desc limit "1"^^type:int64)};`,
+			bs: []string{"?o", "?x"},
+			want: []string{
+				`/u<peter> /u<eve>`,
+				`/u<peter> /u<john>`,
+			},
+		},
+		{
+			q:  `select ?a, ?b from ?test where {(select ?a from ?test where {/u<joe> "parent_of"@[] ?a}) . (select ?b from ?test where {/u<peter> "parent_of"@[] ?b})};`,
+			bs: []string{"?a", "?b"},
+			want: []string{
+				`/u<mary> /u<eve>`,
+				`/u<mary> /u<john>`,
+				`/u<peter> /u<eve>`,
+				`/u<peter> /u<john>`,
+			},
+		},
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+			continue
+		}
+		plnr, err := New(ctx, s, st, 0)
+		if err != nil {
+			t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+			continue
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Errorf("planner.Excecute failed for query %q with error %v", entry.q, err)
+			continue
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			var vs []string
+			for _, b := range entry.bs {
+				vs = append(vs, r[b].String())
+			}
+			got = append(got, strings.Join(vs, " "))
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Excecute returned the wrong rows for query %q; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerConstruct(t *testing.T) {
+	s, ctx := populateTestStore(t), context.Background()
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q: `construct {?c "child_of"@[] ?p} from ?test where {?p "parent_of"@[] ?c};`,
+			want: []string{
+				`/u<eve> "child_of"@[] /u<peter>`,
+				`/u<john> "child_of"@[] /u<peter>`,
+				`/u<mary> "child_of"@[] /u<joe>`,
+				`/u<peter> "child_of"@[] /u<joe>`,
+			},
+		},
+		{
+			q: `construct {/t<car> "includes"@[] ?c . ?c ?p /t<vehicle>} from ?test where {?c ?p /t<car>};`,
+			want: []string{
+				`/c<mini> "is_a"@[] /t<vehicle>`,
+				`/c<model s> "is_a"@[] /t<vehicle>`,
+				`/c<model x> "is_a"@[] /t<vehicle>`,
+				`/c<model y> "is_a"@[] /t<vehicle>`,
+				`/t<car> "includes"@[] /c<mini>`,
+				`/t<car> "includes"@[] /c<model s>`,
+				`/t<car> "includes"@[] /c<model x>`,
+				`/t<car> "includes"@[] /c<model y>`,
+			},
+		},
+		{
+			// Duplicated triples are only returned once.
+			q: `construct {?p "is_parent"@[] "true"^^type:bool} from ?test where {?p "parent_of"@[] ?c};`,
+			want: []string{
+				`/u<joe> "is_parent"@[] "true"^^type:bool`,
+				`/u<peter> "is_parent"@[] "true"^^type:bool`,
+			},
+		},
+		{
+			// Predicates cannot be used as subjects.
+			q: `construct {?o "turned_at"@[] /l<barcelona>} from ?test where {/l<barcelona> "predicate"@[] ?o};`,
+		},
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+			continue
+		}
+		plnr, err := New(ctx, s, st, 0)
+		if err != nil {
+			t.Errorf("planner.New failed to create a valid construct plan with error %v", err)
+			continue
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Errorf("planner.Excecute failed for query %q with error %v", entry.q, err)
+			continue
+		}
+		if got, want := tbl.NumRows(), len(entry.want); got != want {
+			t.Errorf("planner.Excecute returned the wrong number of rows for query %q; got %d, want %d", entry.q, got, want)
+		}
+		var buffer bytes.Buffer
+		if _, err := io.WriteTriples(ctx, &buffer, plnr.(Constructor)); err != nil {
+			t.Errorf("io.WriteTriples failed for query %q with error %v", entry.q, err)
+			continue
+		}
+		var got []string
+		for _, l := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+			if l != "" {
+				got = append(got, strings.Replace(l, "\t", " ", -1))
+			}
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Triples returned the wrong triples for query %q; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerConstructInto(t *testing.T) {
+	s, ctx := populateTestStore(t), context.Background()
+	if _, err := s.NewGraph(ctx, "?derived"); err != nil {
+		t.Fatalf("memory.NewGraph failed to create \"?derived\" with error %v", err)
+	}
+	ss := NewSession(s)
+	for _, q := range []string{
+		`construct {?c "child_of"@[] ?p} into ?derived from ?test where {?p "parent_of"@[] ?c};`,
+		`construct {?c "grandchild_of"@[] ?p} into ?derived from ?test where {?p "parent_of"@[] ?x . ?x "parent_of"@[] ?c};`,
+	} {
+		if tbl := mustRunInSession(t, ss, q); tbl.NumRows() != 0 {
+			t.Errorf("construct into %q should have returned no rows; got %v", q, tbl)
+		}
+	}
+	tbl := mustRunInSession(t, ss, `select ?s, ?p, ?o from ?derived where {?s ?p ?o};`)
+	var got []string
+	for _, r := range tbl.Rows() {
+		got = append(got, fmt.Sprintf("%s %s %s", r["?s"], r["?p"], r["?o"]))
+	}
+	sort.Strings(got)
+	want := []string{
+		`/u<eve> "child_of"@[] /u<peter>`,
+		`/u<eve> "grandchild_of"@[] /u<joe>`,
+		`/u<john> "child_of"@[] /u<peter>`,
+		`/u<john> "grandchild_of"@[] /u<joe>`,
+		`/u<mary> "child_of"@[] /u<joe>`,
+		`/u<peter> "child_of"@[] /u<joe>`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("construct into returned the wrong triples; got %v, want %v", got, want)
+	}
+}
+
+func TestPlannerUpdate(t *testing.T) {
+	testTable := []struct {
+		update string
+		query  string
+		bs     []string
+		want   []string
+	}{
+		{
+			update: `delete {?s "bought"@[?t] ?c} insert {?s "purchased"@[?t] ?c} from ?test where {?s "bought"@[?t] ?c};`,
+			query:  `select ?s, ?p, ?c from ?test where {?s ?p ?c . ?c "is_a"@[] /t<car>};`,
+			bs:     []string{"?s", "?p", "?c"},
+			want: []string{
+				`/u<peter> "purchased"@[2016-01-01T00:00:00-08:00] /c<mini>`,
+				`/u<peter> "purchased"@[2016-02-01T00:00:00-08:00] /c<model s>`,
+				`/u<peter> "purchased"@[2016-03-01T00:00:00-08:00] /c<model x>`,
+				`/u<peter> "purchased"@[2016-04-01T00:00:00-08:00] /c<model y>`,
+			},
+		},
+		{
+			update: `delete {?s ?p /c<mini>} insert {?s "bought"@[2017-01-01T00:00:00-08:00] /c<mini>} from ?test where {?s ?p /c<mini>};`,
+			query:  `select ?s, ?p from ?test where {?s ?p /c<mini>};`,
+			bs:     []string{"?s", "?p"},
+			want: []string{
+				`/u<peter> "bought"@[2017-01-01T00:00:00-08:00]`,
+			},
+		},
+		{
+			update: `insert {?c "grandchild_of"@[] ?g} into ?test where {?g "parent_of"@[] ?p . ?p "parent_of"@[] ?c};`,
+			query:  `select ?c, ?g from ?test where {?c "grandchild_of"@[] ?g};`,
+			bs:     []string{"?c", "?g"},
+			want: []string{
+				`/u<eve> /u<joe>`,
+				`/u<john> /u<joe>`,
+			},
+		},
+		{
+			update: `delete {?c "is_a"@[] /t<car>} from ?test where {?c "is_a"@[] /t<car>};`,
+			query:  `select ?c from ?test where {?c "is_a"@[] ?t};`,
+			bs:     []string{"?c"},
+		},
+	}
+	ss := NewSession(populateTestStore(t))
+	for _, entry := range testTable {
+		if tbl := mustRunInSession(t, ss, entry.update); tbl.NumRows() != 0 {
+			t.Errorf("update %q should have returned no rows; got %v", entry.update, tbl)
+		}
+		var got []string
+		for _, r := range mustRunInSession(t, ss, entry.query).Rows() {
+			var vs []string
+			for _, b := range entry.bs {
+				vs = append(vs, r[b].String())
+			}
+			got = append(got, strings.Join(vs, " "))
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("update %q left the wrong data; got %v, want %v", entry.update, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerAsk(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want bool
+	}{
+		{q: `ask from ?test where {/u<joe> "parent_of"@[] /u<mary>};`, want: true},
+		{q: `ask from ?test where {/u<mary> "parent_of"@[] /u<joe>};`, want: false},
+		{q: `ask from ?test where {?s "bought"@[?t] /c<mini>};`, want: true},
+		{q: `ask from ?test where {?s "bought"@[?t] /t<car>};`, want: false},
+		{q: `ask from ?test where {?s "parent_of"@[] ?c . ?c "bought"@[?t] ?car};`, want: true},
+		{q: `ask from ?test where {?s "parent_of"@[] ?c . not exists {?c "parent_of"@[] ?x}};`, want: true},
+		{q: `ask from ?test where {?s "is_a"@[] /t<car> . not exists {?o "bought"@[?t] ?s}};`, want: false},
+	}
+	ss := NewSession(populateTestStore(t))
+	for _, entry := range testTable {
+		tbl := mustRunInSession(t, ss, entry.q)
+		if got, want := tbl.Bindings(), []string{"?ask"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("ask %q returned the wrong bindings; got %v, want %v", entry.q, got, want)
+		}
+		rws := tbl.Rows()
+		if len(rws) != 1 {
+			t.Fatalf("ask %q should have returned a single row; got %v", entry.q, tbl)
+		}
+		got, err := rws[0]["?ask"].L.Bool()
+		if err != nil {
+			t.Fatalf("ask %q should have returned a boolean; got %v", entry.q, rws[0]["?ask"])
+		}
+		if got != entry.want {
+			t.Errorf("ask %q returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerExistence(t *testing.T) {
+	testTable := []struct {
+		q    string
+		bs   []string
+		want []string
+	}{
+		{
+			q:  `select ?p, ?c from ?test where {?p "parent_of"@[] ?c . not exists {?c "parent_of"@[] ?x}};`,
+			bs: []string{"?p", "?c"},
+			want: []string{
+				`/u<joe> /u<mary>`,
+				`/u<peter> /u<eve>`,
+				`/u<peter> /u<john>`,
+			},
+		},
+		{
+			q:  `select ?p, ?c from ?test where {?p "parent_of"@[] ?c . exists {?c "parent_of"@[] ?x}};`,
+			bs: []string{"?p", "?c"},
+			want: []string{
+				`/u<joe> /u<peter>`,
+			},
+		},
+		{
+			q:  `select ?c from ?test where {?c "is_a"@[] /t<car> . exists {/u<peter> "bought"@[?t] ?c . ?c "is_a"@[] /t<car>}};`,
+			bs: []string{"?c"},
+			want: []string{
+				`/c<mini>`,
+				`/c<model s>`,
+				`/c<model x>`,
+				`/c<model y>`,
+			},
+		},
+		{
+			q:  `select ?c from ?test where {?c "is_a"@[] /t<car> . not exists {/u<joe> "bought"@[?t] ?c}};`,
+			bs: []string{"?c"},
+			want: []string{
+				`/c<mini>`,
+				`/c<model s>`,
+				`/c<model x>`,
+				`/c<model y>`,
+			},
+		},
+		{
+			q:  `select ?c from ?test where {?c "is_a"@[] /t<car> . exists {/u<mary> "parent_of"@[] ?x}};`,
+			bs: []string{"?c"},
+		},
+		{
+			q:  `select ?c from ?test where {?c "is_a"@[] /t<car> . not exists {/u<joe> "parent_of"@[] /u<mary>}};`,
+			bs: []string{"?c"},
+		},
+		{
+			q:  `select ?c from ?test where {/c<mini> "is_a"@[] ?c . exists {/u<joe> "parent_of"@[] /u<mary>}};`,
+			bs: []string{"?c"},
+			want: []string{
+				`/t<car>`,
+			},
+		},
+	}
+	ss := NewSession(populateTestStore(t))
+	for _, entry := range testTable {
+		var got []string
+		for _, r := range mustRunInSession(t, ss, entry.q).Rows() {
+			var vs []string
+			for _, b := range entry.bs {
+				vs = append(vs, r[b].String())
+			}
+			got = append(got, strings.Join(vs, " "))
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("query %q returned the wrong rows; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerDescribe(t *testing.T) {
+	s, ctx := populateTestStore(t), context.Background()
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	peter := []string{
+		`/u<joe> "parent_of"@[] /u<peter>`,
+		`/u<peter> "bought"@[2016-01-01T00:00:00-08:00] /c<mini>`,
+		`/u<peter> "bought"@[2016-02-01T00:00:00-08:00] /c<model s>`,
+		`/u<peter> "bought"@[2016-03-01T00:00:00-08:00] /c<model x>`,
+		`/u<peter> "bought"@[2016-04-01T00:00:00-08:00] /c<model y>`,
+		`/u<peter> "parent_of"@[] /u<eve>`,
+		`/u<peter> "parent_of"@[] /u<john>`,
+	}
+	cars := []string{
+		`/c<mini> "is_a"@[] /t<car>`,
+		`/c<model s> "is_a"@[] /t<car>`,
+		`/c<model x> "is_a"@[] /t<car>`,
+		`/c<model y> "is_a"@[] /t<car>`,
+	}
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `describe /u<peter> from ?test;`,
+			want: peter,
+		},
+		{
+			q:    `describe /u<peter> from ?test depth "1"^^type:int64;`,
+			want: peter,
+		},
+		{
+			q:    `describe /u<peter> from ?test depth "2"^^type:int64;`,
+			want: append(append([]string{}, cars...), peter...),
+		},
+		{
+			q:    `describe /u<peter> from ?test depth "5"^^type:int64;`,
+			want: append(append([]string{}, cars...), peter...),
+		},
+		{
+			q: `describe /u<unknown> from ?test;`,
+		},
+	}
+	for _, entry := range testTable {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to parse query %q with error %v", entry.q, err)
+			continue
+		}
+		plnr, err := New(ctx, s, st, 0)
+		if err != nil {
+			t.Errorf("planner.New failed to create a valid describe plan with error %v", err)
+			continue
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Errorf("planner.Excecute failed for query %q with error %v", entry.q, err)
+			continue
+		}
+		if got, want := tbl.NumRows(), len(entry.want); got != want {
+			t.Errorf("planner.Excecute returned the wrong number of rows for query %q; got %d, want %d", entry.q, got, want)
+		}
+		var buffer bytes.Buffer
+		if _, err := io.WriteTriples(ctx, &buffer, plnr.(Constructor)); err != nil {
+			t.Errorf("io.WriteTriples failed for query %q with error %v", entry.q, err)
+			continue
+		}
+		var got []string
+		for _, l := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+			if l != "" {
+				got = append(got, strings.Replace(l, "\t", " ", -1))
+			}
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Triples returned the wrong triples for query %q; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerShowGraphs(t *testing.T) {
+	ss := NewSession(populateTestStore(t))
+	mustRunInSession(t, ss, `create graph ?other, ?another;`)
+	var got []string
+	for _, r := range mustRunInSession(t, ss, `show graphs;`).Rows() {
+		got = append(got, r["?graph"].String())
+	}
+	if want := []string{"?another", "?other", "?test"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("show graphs returned the wrong graphs; got %v, want %v", got, want)
+	}
+}
+
+// unstatedGraph hides the statistics and cardinalities provided by the graph it
+// wraps.
+type unstatedGraph struct {
+	storage.Graph
+}
+
+func TestPlannerShowStats(t *testing.T) {
+	s, ctx := populateTestStore(t), context.Background()
+	want := storage.Stats{
+		Triples:             27,
+		Subjects:            13,
+		Predicates:          6,
+		Objects:             18,
+		TemporalPredicates:  2,
+		ImmutablePredicates: 4,
+	}
+	g, err := s.Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range []storage.Graph{g, &unstatedGraph{g}} {
+		got, err := graphStats(ctx, g, 0)
+		if err != nil {
+			t.Fatalf("graphStats failed with error %v", err)
+		}
+		if *got != want {
+			t.Errorf("graphStats returned the wrong statistics for %T; got %+v, want %+v", g, *got, want)
+		}
+	}
+
+	ss := NewSession(s)
+	mustRunInSession(t, ss, `create graph ?empty;`)
+	tbl := mustRunInSession(t, ss, `show stats for ?test, ?empty;`)
+	if got, want := tbl.Bindings(), statsBindings; !reflect.DeepEqual(got, want) {
+		t.Errorf("show stats returned the wrong bindings; got %v, want %v", got, want)
+	}
+	var got []string
+	for _, r := range tbl.Rows() {
+		var vs []string
+		for _, b := range statsBindings {
+			vs = append(vs, r[b].String())
+		}
+		got = append(got, strings.Join(vs, " "))
+	}
+	wantRows := []string{
+		`?test "27"^^type:int64 "13"^^type:int64 "6"^^type:int64 "18"^^type:int64 "2"^^type:int64 "4"^^type:int64`,
+		`?empty "0"^^type:int64 "0"^^type:int64 "0"^^type:int64 "0"^^type:int64 "0"^^type:int64 "0"^^type:int64`,
+	}
+	if !reflect.DeepEqual(got, wantRows) {
+		t.Errorf("show stats returned the wrong rows; got %v, want %v", got, wantRows)
+	}
+	if _, err := runInSession(t, ss, `show stats for ?missing;`); err == nil {
+		t.Errorf("show stats should have failed for a graph that does not exist")
+	}
+}
+
+func TestPlannerExplain(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q: `explain select ?p, ?car from ?test where {?p "parent_of"@[] ?c . ?c "bought"@[?t] ?car};`,
+			want: []string{
+				`where|simpleFetch|TriplesForPredicate|?c, ?p|`,
+				`where|specifyClauseWithTable|TriplesForSubject|?c, ?car, ?t|`,
+			},
+		},
+		{
+			q: `explain select ?s from ?test where {?s "parent_of"@[] ?o . ?o "parent_of"@[] ?s};`,
+			want: []string{
+				`where|simpleFetch|TriplesForPredicate|?o, ?s|`,
+				`where|filterOnExistence|Exist|?o, ?s|`,
+			},
+		},
+		{
+			q: `explain select ?o from ?test where {/u<joe> "parent_of"@[] /u<peter> . /u<peter> "bought"@[2015-01-01T00:00:00-08:00,2017-01-01T00:00:00-08:00] ?o} before ""@[2016-01-01T00:00:00-08:00];`,
+			want: []string{
+				`where|simpleExist|Exist||`,
+				`where|simpleFetch|TriplesForSubject|?o|between 2015-01-01T00:00:00-08:00, 2016-01-01T00:00:00-08:00`,
+			},
+		},
+		{
+			q: `explain select ?r from ?test where {/room<Hallway> "connects_to"@[]+ ?r};`,
+			want: []string{
+				`where|processPathClause|TriplesForSubjectAndPredicate|?r|`,
+			},
+		},
+		{
+			q: `explain select ?p, ?c, ?car from ?test where {?p "parent_of"@[] ?c . optional {?c "bought"@[?t] ?car} . not exists {?c "parent_of"@[] ?x}};`,
+			want: []string{
+				`where|simpleFetch|TriplesForPredicate|?c, ?p|`,
+				`optional 1|simpleFetch|Triples|?c, ?car, ?t|`,
+				`not exists 1|specifyClauseWithTable|Objects|?c, ?x|`,
+			},
+		},
+		{
+			q: `explain select ?p, ?n, ?x from ?test where {(select ?p, count(?c) as ?n from ?test where {?p "parent_of"@[] ?c} group by ?p) . {?p "bought"@[?t] ?x} union {/u<joe> "parent_of"@[] ?x}};`,
+			want: []string{
+				`subquery 1|simpleFetch|TriplesForPredicate|?c, ?p|`,
+				`union 1 alternative 1|simpleFetch|Triples|?p, ?t, ?x|`,
+				`union 1 alternative 2|simpleFetch|Objects|?x|`,
+			},
+		},
+	}
+	ss := NewSession(populateTestStore(t))
+	for _, entry := range testTable {
+		for _, analyze := range []bool{false, true} {
+			q := entry.q
+			if analyze {
+				q = strings.Replace(q, "explain", "explain analyze", 1)
+			}
+			tbl := mustRunInSession(t, ss, q)
+			if got, want := len(tbl.Bindings()), len(explainBindings); analyze {
+				if want += 2; got != want || !tbl.HasBinding("?rows") || !tbl.HasBinding("?time") {
+					t.Errorf("%q returned the wrong bindings; got %v", q, tbl.Bindings())
+				}
+			} else if got != want {
+				t.Errorf("%q returned the wrong bindings; got %v, want %v", q, tbl.Bindings(), explainBindings)
+			}
+			var got []string
+			for _, r := range tbl.Rows() {
+				var vs []string
+				for _, b := range []string{"?pattern", "?method", "?lookup", "?bindings", "?bounds"} {
+					vs = append(vs, *r[b].S)
+				}
+				got = append(got, strings.Join(vs, "|"))
+			}
+			if !reflect.DeepEqual(got, entry.want) {
+				t.Errorf("%q returned the wrong steps; got %v, want %v", q, got, entry.want)
+			}
+		}
+	}
+}
+
+func TestPlannerExplainAnalyzeRows(t *testing.T) {
+	ss := NewSession(populateTestStore(t))
+	tbl := mustRunInSession(t, ss, `explain analyze select ?p, ?car from ?test where {?p "parent_of"@[] ?c . ?c "bought"@[?t] ?car . ?car "is_a"@[] /t<car>};`)
+	var got []string
+	for _, r := range tbl.Rows() {
+		got = append(got, r["?step"].String()+" "+*r["?method"].S+" "+r["?rows"].String())
+	}
+	want := []string{
+		`"1"^^type:int64 simpleFetch "4"^^type:int64`,
+		`"2"^^type:int64 specifyClauseWithTable "4"^^type:int64`,
+		`"3"^^type:int64 specifyClauseWithTable "4"^^type:int64`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("explain analyze returned the wrong rows per step; got %v, want %v", got, want)
+	}
+	// Steps that are not run are not reported. Clauses that do not share
+	// bindings with the missing triple are resolved on their own regardless.
+	tbl = mustRunInSession(t, ss, `explain analyze select ?x from ?test where {/u<mary> "parent_of"@[] /u<joe> . /u<joe> "parent_of"@[] /u<mary> . /u<joe> "parent_of"@[] ?x};`)
+	if got, want := tbl.NumRows(), 2; got != want {
+		t.Errorf("explain analyze should have stopped after the missing triple; got %d steps, want %d", got, want)
+	}
+	// Clauses matching fewer triples than rows are joined instead of looked up
+	// per row.
+	tbl = mustRunInSession(t, ss, `explain analyze select ?car, ?x, ?y from ?test where {/u<peter> "bought"@[?t] ?car . ?car "is_a"@[] ?type . ?x "is_a"@[] ?type . ?y "bought"@[?u] ?x};`)
+	got = nil
+	for _, r := range tbl.Rows() {
+		got = append(got, r["?step"].String()+" "+*r["?method"].S+" "+r["?rows"].String())
+	}
+	want = []string{
+		`"1"^^type:int64 simpleFetch "4"^^type:int64`,
+		`"2"^^type:int64 specifyClauseWithTable "4"^^type:int64`,
+		`"3"^^type:int64 specifyClauseWithTable "16"^^type:int64`,
+		`"4"^^type:int64 joinClause "16"^^type:int64`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("explain analyze returned the wrong rows per step; got %v, want %v", got, want)
+	}
+}
+
+func TestPlannerScalarExpressions(t *testing.T) {
+	ss := NewSession(populateTestStore(t, measureTriples))
+	testTable := []struct {
+		q    string
+		want map[string]string
+	}{
+		{
+			q: `select ?n, lower(?n) as ?l, strlen(?n) as ?len, substr(?n, "1"^^type:int64, "3"^^type:int64) as ?s from ?test where {/u<joe> "name"@[] ?n};`,
+			want: map[string]string{
+				"?n":   `"Joe Doe"^^type:text`,
+				"?l":   `"joe doe"^^type:text`,
+				"?len": `"7"^^type:int64`,
+				"?s":   `"Joe"^^type:text`,
+			},
+		},
+		{
+			q: `select (?hj - ?hm) * "2"^^type:int64 as ?d, abs(?hm - ?hj) as ?a, concat(?nj, " & ", ?nm) as ?c from ?test where {/u<joe> "height"@[] ?hj . /u<joe> "name"@[] ?nj . /u<mary> "height"@[] ?hm . /u<mary> "name"@[] ?nm};`,
+			want: map[string]string{
+				"?d": `"40"^^type:int64`,
+				"?a": `"20"^^type:int64`,
+				"?c": `"Joe Doe & Mary Lo"^^type:text`,
+			},
+		},
+		{
+			q: `select strlen(?n) as ?len, sum(?w) as ?sum, count(?u) as ?cnt from ?test where {?u "name"@[] ?n . ?u "weight"@[] ?w} group by ?len;`,
+			want: map[string]string{
+				"?len": `"7"^^type:int64`,
+				"?sum": `"133"^^type:float64`,
+				"?cnt": `"2"^^type:int64`,
+			},
+		},
+	}
+	for _, entry := range testTable {
+		tbl := mustRunInSession(t, ss, entry.q)
+		if got, want := tbl.NumRows(), 1; got != want {
+			t.Errorf("planner.Excecute returned the wrong number of rows for query %q; got %d, want %d", entry.q, got, want)
+			continue
+		}
+		got := make(map[string]string)
+		for k := range entry.want {
+			got[k] = tbl.Rows()[0][k].String()
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Excecute returned the wrong row for query %q; got %v, want %v", entry.q, got, entry.want)
+		}
+	}
+	// Type errors are reported when the query is executed.
+	q := `select (?n + ?h) as ?x from ?test where {?u "name"@[] ?n . ?u "height"@[] ?h};`
+	if _, err := runInSession(t, ss, q); err == nil {
+		t.Errorf("planner.Execute should have failed to add text and int64 literals for query %q", q)
+	}
+}
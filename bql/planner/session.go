@@ -0,0 +1,300 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+)
+
+// Session keeps the state shared by a sequence of statements run against the
+// same store. BEGIN, COMMIT, and ROLLBACK statements can only be run as part
+// of a session. While a transaction is open, all other statements run inside
+// it. Named snapshots of graphs are also kept by the session, so they can be
+// queried by later statements.
+type Session struct {
+	store     storage.Store
+	mu        sync.Mutex
+	tx        storage.Tx
+	snapshots map[snapshotKey]storage.Graph
+	workers   int
+	budget    memoryBudget
+}
+
+// snapshotKey identifies a named snapshot of a graph.
+type snapshotKey struct {
+	graph string
+	name  string
+}
+
+// NewSession creates a new session for the provided store. Its statements
+// run their independent lookups on up to DefaultWorkers goroutines.
+func NewSession(store storage.Store) *Session {
+	return &Session{
+		store:     store,
+		snapshots: make(map[snapshotKey]storage.Graph),
+		workers:   DefaultWorkers,
+	}
+}
+
+// SetWorkers sets the maximum number of goroutines each statement of the
+// session uses to run independent lookups concurrently. Values lower than 2
+// run them sequentially.
+func (s *Session) SetWorkers(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workers = n
+}
+
+// SetMemoryBudget sets the estimated number of bytes each query of the session
+// can use to hold rows in memory. A budget of 0 does not limit them.
+func (s *Session) SetMemoryBudget(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.budget.bytes = n
+}
+
+// SetSpillDir sets the directory where the queries of the session spill the
+// rows they sort or group when they exceed the memory budget. The default
+// directory for temporary files is used if empty.
+func (s *Session) SetSpillDir(dir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.budget.dir = dir
+}
+
+// Store returns the store statements are currently run against. If a
+// transaction is open it returns the transaction.
+func (s *Session) Store() storage.Store {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.store
+}
+
+// InTransaction returns true if the session has an open transaction.
+func (s *Session) InTransaction() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tx != nil
+}
+
+// New creates a new executable plan for the provided statement that will run
+// as part of the session.
+func (s *Session) New(ctx context.Context, stm *semantic.Statement, chanSize int) (Executor, error) {
+	s.mu.Lock()
+	workers, budget := newWorkerPool(s.workers), s.budget
+	s.mu.Unlock()
+	switch stm.Type() {
+	case semantic.Begin:
+		return &beginPlan{s: s}, nil
+	case semantic.Commit:
+		return &endPlan{s: s, commit: true}, nil
+	case semantic.Rollback:
+		return &endPlan{s: s}, nil
+	case semantic.CreateSnapshot:
+		return &createSnapshotPlan{s: s, stm: stm}, nil
+	case semantic.DropSnapshot:
+		return &dropSnapshotPlan{s: s, stm: stm}, nil
+	case semantic.Query:
+		if stm.Explain() {
+			return newExplainPlan(ctx, s.Store(), stm, chanSize, workers, budget, s.snapshot)
+		}
+		return newQueryPlan(ctx, s.Store(), stm, chanSize, workers, budget, s.snapshot)
+	case semantic.Construct:
+		return &constructPlan{
+			stm:      stm,
+			store:    s.Store(),
+			chanSize: chanSize,
+			workers:  workers,
+			budget:   budget,
+			snapshot: s.snapshot,
+		}, nil
+	case semantic.Ask:
+		return &askPlan{
+			stm:      stm,
+			store:    s.Store(),
+			chanSize: chanSize,
+			workers:  workers,
+			budget:   budget,
+			snapshot: s.snapshot,
+		}, nil
+	case semantic.Describe:
+		return &describePlan{
+			stm:      stm,
+			store:    s.Store(),
+			chanSize: chanSize,
+			snapshot: s.snapshot,
+		}, nil
+	case semantic.ShowStats:
+		return &showStatsPlan{
+			stm:      stm,
+			store:    s.Store(),
+			chanSize: chanSize,
+			snapshot: s.snapshot,
+		}, nil
+	case semantic.Update:
+		return &updatePlan{
+			stm:      stm,
+			store:    s.Store(),
+			chanSize: chanSize,
+			workers:  workers,
+			budget:   budget,
+		}, nil
+	default:
+		return New(ctx, s.Store(), stm, chanSize)
+	}
+}
+
+// beginPlan opens a new transaction on the session.
+type beginPlan struct {
+	s *Session
+}
+
+// Execute starts a new transaction.
+func (p *beginPlan) Execute(ctx context.Context) (*table.Table, error) {
+	s := p.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tx != nil {
+		return nil, fmt.Errorf("a transaction is already in progress")
+	}
+	ts, ok := s.store.(storage.Transactional)
+	if !ok {
+		return nil, fmt.Errorf("store %q does not support transactions", s.store.Name(ctx))
+	}
+	tx, err := ts.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.tx = tx
+	return table.New([]string{})
+}
+
+// endPlan commits or rolls back the transaction open on the session.
+type endPlan struct {
+	s      *Session
+	commit bool
+}
+
+// Execute finishes the current transaction.
+func (p *endPlan) Execute(ctx context.Context) (*table.Table, error) {
+	s := p.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tx == nil {
+		return nil, fmt.Errorf("no transaction in progress")
+	}
+	tx := s.tx
+	s.tx = nil
+	var err error
+	if p.commit {
+		err = tx.Commit(ctx)
+	} else {
+		err = tx.Rollback(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return table.New([]string{})
+}
+
+// snapshot returns the named snapshot of the provided graph.
+func (s *Session) snapshot(graph, name string) (storage.Graph, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.snapshots[snapshotKey{graph, name}]
+	if !ok {
+		return nil, fmt.Errorf("snapshot %q of graph %q does not exist", name, graph)
+	}
+	return g, nil
+}
+
+// createSnapshotPlan takes a named snapshot of the graphs of a statement.
+type createSnapshotPlan struct {
+	s   *Session
+	stm *semantic.Statement
+}
+
+// Execute snapshots all the graphs listed in the statement. No snapshot is
+// registered unless all the graphs can be snapshotted. Snapshots cannot be
+// taken while a transaction is open, since they would outlive its changes if
+// it is rolled back.
+func (p *createSnapshotPlan) Execute(ctx context.Context) (*table.Table, error) {
+	s, name := p.s, p.stm.Snapshot()
+	s.mu.Lock()
+	store, inTx := s.store, s.tx != nil
+	s.mu.Unlock()
+	if inTx {
+		return nil, fmt.Errorf("cannot create snapshot %q while a transaction is in progress", name)
+	}
+	snaps := make(map[snapshotKey]storage.Graph)
+	for _, id := range p.stm.Graphs() {
+		g, err := store.Graph(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		sg, ok := g.(storage.Snapshotter)
+		if !ok {
+			return nil, fmt.Errorf("graph %q in store %q does not support snapshots", id, store.Name(ctx))
+		}
+		snap, err := sg.Snapshot(ctx)
+		if err != nil {
+			return nil, err
+		}
+		snaps[snapshotKey{id, name}] = snap
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k := range snaps {
+		if _, ok := s.snapshots[k]; ok {
+			return nil, fmt.Errorf("snapshot %q of graph %q already exists", k.name, k.graph)
+		}
+	}
+	for k, g := range snaps {
+		s.snapshots[k] = g
+	}
+	return table.New([]string{})
+}
+
+// dropSnapshotPlan releases a named snapshot of the graphs of a statement.
+type dropSnapshotPlan struct {
+	s   *Session
+	stm *semantic.Statement
+}
+
+// Execute drops the snapshots of all the graphs listed in the statement.
+func (p *dropSnapshotPlan) Execute(ctx context.Context) (*table.Table, error) {
+	s, name := p.s, p.stm.Snapshot()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range p.stm.Graphs() {
+		if _, ok := s.snapshots[snapshotKey{id, name}]; !ok {
+			return nil, fmt.Errorf("snapshot %q of graph %q does not exist", name, id)
+		}
+	}
+	for _, id := range p.stm.Graphs() {
+		delete(s.snapshots, snapshotKey{id, name})
+	}
+	return table.New([]string{})
+}
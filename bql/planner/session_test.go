@@ -0,0 +1,221 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+)
+
+// runInSession parses and runs the provided statement as part of the session.
+func runInSession(t *testing.T, s *Session, bql string) (*table.Table, error) {
+	ctx := context.Background()
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(bql, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse %q with error %v", bql, err)
+	}
+	plnr, err := s.New(ctx, st, 0)
+	if err != nil {
+		return nil, err
+	}
+	return plnr.Execute(ctx)
+}
+
+// mustRunInSession runs the statement and fails the test on error.
+func mustRunInSession(t *testing.T, s *Session, bql string) *table.Table {
+	tbl, err := runInSession(t, s, bql)
+	if err != nil {
+		t.Fatalf("failed to run %q with error %v", bql, err)
+	}
+	return tbl
+}
+
+func TestSessionTransactions(t *testing.T) {
+	const (
+		insert = `insert data into ?test, ?other {/u<zoe> "parent_of"@[] /u<joe>};`
+		query  = `select ?p from ?test where {?p "parent_of"@[] /u<joe>};`
+	)
+	s := populateTestStore(t)
+	mustRunInSession(t, NewSession(s), `create graph ?other;`)
+
+	ss := NewSession(s)
+	// Rolled back changes are only visible inside the transaction.
+	mustRunInSession(t, ss, `begin;`)
+	if !ss.InTransaction() {
+		t.Errorf("session should be in a transaction after BEGIN")
+	}
+	mustRunInSession(t, ss, insert)
+	if got, want := mustRunInSession(t, ss, query).NumRows(), 1; got != want {
+		t.Errorf("queries inside the transaction should see its writes; got %d rows, want %d", got, want)
+	}
+	if got, want := mustRunInSession(t, NewSession(s), query).NumRows(), 0; got != want {
+		t.Errorf("queries outside the transaction should not see its writes; got %d rows, want %d", got, want)
+	}
+	mustRunInSession(t, ss, `rollback;`)
+	if ss.InTransaction() {
+		t.Errorf("session should not be in a transaction after ROLLBACK")
+	}
+	if got, want := mustRunInSession(t, ss, query).NumRows(), 0; got != want {
+		t.Errorf("rolled back writes should not be visible; got %d rows, want %d", got, want)
+	}
+
+	// Committed changes are visible to everyone.
+	mustRunInSession(t, ss, `begin;`)
+	mustRunInSession(t, ss, insert)
+	mustRunInSession(t, ss, `drop graph ?other;`)
+	mustRunInSession(t, ss, `commit;`)
+	if got, want := mustRunInSession(t, NewSession(s), query).NumRows(), 1; got != want {
+		t.Errorf("committed writes should be visible; got %d rows, want %d", got, want)
+	}
+	if _, err := s.Graph(context.Background(), "?other"); err == nil {
+		t.Errorf("committed graph drop should have been applied")
+	}
+}
+
+func TestSessionRejectsInvalidTransactionSequences(t *testing.T) {
+	ss := NewSession(populateTestStore(t))
+	if _, err := runInSession(t, ss, `commit;`); err == nil {
+		t.Errorf("COMMIT should fail without an open transaction")
+	}
+	if _, err := runInSession(t, ss, `rollback;`); err == nil {
+		t.Errorf("ROLLBACK should fail without an open transaction")
+	}
+	mustRunInSession(t, ss, `begin;`)
+	if _, err := runInSession(t, ss, `begin;`); err == nil {
+		t.Errorf("BEGIN should fail with an already open transaction")
+	}
+}
+
+func TestPlannerNewRejectsTransactionStatements(t *testing.T) {
+	ctx := context.Background()
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	for _, bql := range []string{`begin;`, `commit;`, `rollback;`, `create snapshot "v1" of ?test;`, `drop snapshot "v1" of ?test;`} {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(bql, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse %q with error %v", bql, err)
+		}
+		if _, err := New(ctx, populateTestStore(t), st, 0); err == nil {
+			t.Errorf("planner.New should reject %q outside a session", bql)
+		}
+	}
+}
+
+func TestInsertIsAtomicAcrossGraphs(t *testing.T) {
+	const query = `select ?p from ?test where {?p "parent_of"@[] /u<joe>};`
+	ss := NewSession(populateTestStore(t))
+	if _, err := runInSession(t, ss, `insert data into ?test, ?missing {/u<zoe> "parent_of"@[] /u<joe>};`); err == nil {
+		t.Errorf("insert into a missing graph should fail")
+	}
+	if got, want := mustRunInSession(t, ss, query).NumRows(), 0; got != want {
+		t.Errorf("failed insert should not modify any graph; got %d rows, want %d", got, want)
+	}
+}
+
+func TestSessionSnapshots(t *testing.T) {
+	const (
+		insert = `insert data into ?test {/u<zoe> "parent_of"@[] /u<joe>};`
+		remove = `delete data from ?test {/u<joe> "parent_of"@[] /u<mary>};`
+		query  = `select ?p, ?c from ?test%s where {?p "parent_of"@[] ?c};`
+	)
+	ss := NewSession(populateTestStore(t))
+	before := mustRunInSession(t, ss, fmt.Sprintf(query, "")).NumRows()
+	mustRunInSession(t, ss, `create snapshot "before load" of ?test;`)
+	mustRunInSession(t, ss, insert)
+	mustRunInSession(t, ss, remove)
+	if got, want := mustRunInSession(t, ss, fmt.Sprintf(query, "")).NumRows(), before; got != want {
+		t.Errorf("graph should reflect the changes; got %d rows, want %d", got, want)
+	}
+	tbl := mustRunInSession(t, ss, fmt.Sprintf(query, ` as of snapshot "before load"`))
+	if got, want := tbl.NumRows(), before; got != want {
+		t.Errorf("snapshot returned the wrong number of rows; got %d, want %d", got, want)
+	}
+	found := false
+	for _, r := range tbl.Rows() {
+		if r["?p"].N.ID().String() == "zoe" {
+			t.Errorf("snapshot should not contain triples added after it was taken; got %v", r)
+		}
+		if r["?p"].N.ID().String() == "joe" && r["?c"].N.ID().String() == "mary" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("snapshot should still contain triples removed after it was taken")
+	}
+	if _, err := runInSession(t, ss, `create snapshot "before load" of ?test;`); err == nil {
+		t.Errorf("creating an existing snapshot should fail")
+	}
+	if _, err := runInSession(t, ss, fmt.Sprintf(query, ` as of snapshot "unknown"`)); err == nil {
+		t.Errorf("querying an unknown snapshot should fail")
+	}
+	if _, err := runInSession(t, ss, `create snapshot "v1" of ?test, ?missing;`); err == nil {
+		t.Errorf("snapshotting a missing graph should fail")
+	}
+	if _, err := runInSession(t, ss, fmt.Sprintf(query, ` as of snapshot "v1"`)); err == nil {
+		t.Errorf("failed snapshots should not be registered")
+	}
+	mustRunInSession(t, ss, `drop snapshot "before load" of ?test;`)
+	if _, err := runInSession(t, ss, fmt.Sprintf(query, ` as of snapshot "before load"`)); err == nil {
+		t.Errorf("querying a dropped snapshot should fail")
+	}
+	if _, err := runInSession(t, ss, `drop snapshot "before load" of ?test;`); err == nil {
+		t.Errorf("dropping a missing snapshot should fail")
+	}
+}
+
+func TestSessionRejectsSnapshotsInTransactions(t *testing.T) {
+	const query = `select ?p from ?test%s where {?p "parent_of"@[] /u<joe>};`
+	ss := NewSession(populateTestStore(t))
+	mustRunInSession(t, ss, `begin;`)
+	mustRunInSession(t, ss, `insert data into ?test {/u<zoe> "parent_of"@[] /u<joe>};`)
+	if _, err := runInSession(t, ss, `create snapshot "s1" of ?test;`); err == nil {
+		t.Errorf("CREATE SNAPSHOT should fail with an open transaction")
+	}
+	mustRunInSession(t, ss, `rollback;`)
+	if _, err := runInSession(t, ss, fmt.Sprintf(query, ` as of snapshot "s1"`)); err == nil {
+		t.Errorf("snapshots rejected inside a transaction should not be registered")
+	}
+	mustRunInSession(t, ss, `create snapshot "s1" of ?test;`)
+	if got, want := mustRunInSession(t, ss, fmt.Sprintf(query, ` as of snapshot "s1"`)).NumRows(), 0; got != want {
+		t.Errorf("snapshot should not contain rolled back writes; got %d rows, want %d", got, want)
+	}
+}
+
+func TestPlannerNewRejectsSnapshotQueries(t *testing.T) {
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	st := &semantic.Statement{}
+	bql := `select ?s from ?test as of snapshot "v1" where {?s ?p ?o};`
+	if err := p.Parse(grammar.NewLLk(bql, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse %q with error %v", bql, err)
+	}
+	if _, err := New(context.Background(), populateTestStore(t), st, 0); err == nil {
+		t.Errorf("planner.New should reject %q outside a session", bql)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"sort"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// showGraphsPlan encapsulates the sequence of instructions that need to be
+// executed in order to satisfy the execution of a valid show graphs BQL
+// statement.
+type showGraphsPlan struct {
+	store storage.Store
+}
+
+// Execute returns a table with the sorted names of the graphs available in the
+// store bound to ?graph.
+func (p *showGraphsPlan) Execute(ctx context.Context) (*table.Table, error) {
+	var (
+		gErr error
+		wg   sync.WaitGroup
+		gs   []string
+	)
+	names := make(chan string)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		gErr = p.store.GraphNames(ctx, names)
+	}()
+	for n := range names {
+		gs = append(gs, n)
+	}
+	wg.Wait()
+	if gErr != nil {
+		return nil, gErr
+	}
+	sort.Strings(gs)
+	t, err := table.New([]string{"?graph"})
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range gs {
+		n := g
+		t.AddRow(table.Row{"?graph": &table.Cell{S: &n}})
+	}
+	return t, nil
+}
+
+// showStatsPlan encapsulates the sequence of instructions that need to be
+// executed in order to satisfy the execution of a valid show stats BQL
+// statement.
+type showStatsPlan struct {
+	stm      *semantic.Statement
+	store    storage.Store
+	chanSize int
+	snapshot snapshotFinder
+}
+
+// statsBindings contains the bindings of the table returned by show stats
+// statements.
+var statsBindings = []string{
+	"?graph", "?triples", "?subjects", "?predicates", "?objects",
+	"?temporal_predicates", "?immutable_predicates",
+}
+
+// scanStats computes the statistics of a graph that does not provide them by
+// going over all its triples.
+func scanStats(ctx context.Context, g storage.Graph, chanSize int) (*storage.Stats, error) {
+	var (
+		tErr error
+		wg   sync.WaitGroup
+		st   = &storage.Stats{}
+		ss   = make(map[string]bool)
+		ps   = make(map[string]bool)
+		tps  = make(map[string]bool)
+		ips  = make(map[string]bool)
+		os   = make(map[string]bool)
+	)
+	ts := make(chan *triple.Triple, chanSize)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tErr = g.Triples(ctx, ts)
+	}()
+	for t := range ts {
+		st.Triples++
+		ss[t.Subject().String()] = true
+		os[t.Object().String()] = true
+		p := t.Predicate()
+		ps[string(p.ID())] = true
+		if p.Type() == predicate.Temporal {
+			tps[string(p.ID())] = true
+		} else {
+			ips[string(p.ID())] = true
+		}
+	}
+	wg.Wait()
+	if tErr != nil {
+		return nil, tErr
+	}
+	st.Subjects, st.Predicates, st.Objects = int64(len(ss)), int64(len(ps)), int64(len(os))
+	st.TemporalPredicates, st.ImmutablePredicates = int64(len(tps)), int64(len(ips))
+	return st, nil
+}
+
+// graphStats returns the statistics of the provided graph. They are computed
+// by scanning the graph if the driver does not implement
+// storage.StatsProvider.
+func graphStats(ctx context.Context, g storage.Graph, chanSize int) (*storage.Stats, error) {
+	if sp, ok := g.(storage.StatsProvider); ok {
+		return sp.Stats(ctx)
+	}
+	return scanStats(ctx, g, chanSize)
+}
+
+// Execute returns a table with one row per graph listed on the statement
+// containing its statistics.
+func (p *showStatsPlan) Execute(ctx context.Context) (*table.Table, error) {
+	gs, err := resolveGraphs(ctx, p.store, p.stm, p.snapshot)
+	if err != nil {
+		return nil, err
+	}
+	t, err := table.New(statsBindings)
+	if err != nil {
+		return nil, err
+	}
+	for i, g := range gs {
+		st, err := graphStats(ctx, g, p.chanSize)
+		if err != nil {
+			return nil, err
+		}
+		name := p.stm.Graphs()[i]
+		r := table.Row{"?graph": &table.Cell{S: &name}}
+		vs := []int64{
+			st.Triples, st.Subjects, st.Predicates, st.Objects,
+			st.TemporalPredicates, st.ImmutablePredicates,
+		}
+		for j, v := range vs {
+			l, err := literal.DefaultBuilder().Build(literal.Int64, v)
+			if err != nil {
+				return nil, err
+			}
+			r[statsBindings[j+1]] = &table.Cell{L: l}
+		}
+		t.AddRow(r)
+	}
+	return t, nil
+}
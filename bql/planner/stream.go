@@ -0,0 +1,306 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"errors"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+)
+
+// Streamer is implemented by the plans that can return their rows as they
+// are produced instead of materializing the whole result table first.
+type Streamer interface {
+	Executor
+
+	// Bindings returns the bindings of the rows returned by Stream.
+	Bindings() []string
+
+	// Streamable returns true if Stream sends the rows as they are produced.
+	// Otherwise, Stream executes the whole statement before sending them.
+	Streamable() bool
+
+	// Stream sends the resulting rows to the provided channel as they become
+	// available. The channel is closed once all the rows are sent, or when the
+	// context is canceled or an error is found. Rows only contain the
+	// bindings returned by Bindings.
+	Stream(ctx context.Context, rows chan<- table.Row) error
+}
+
+// errStopStream is returned by row sinks that do not need more rows.
+var errStopStream = errors.New("planner: no more rows needed")
+
+// rowSink consumes the rows produced while resolving a query.
+type rowSink func(table.Row) error
+
+// Bindings returns the output bindings of the query.
+func (p *queryPlan) Bindings() []string {
+	return p.stm.OutputBindings()
+}
+
+// Streamable returns true if the rows of the query can be returned as they
+// are produced. Sorting, grouping, and paginating with cursors require all
+// the rows, and so do the graph patterns that are combined with the rows
+// produced by the clauses.
+func (p *queryPlan) Streamable() bool {
+	s := p.stm
+	return p.explain == nil &&
+		len(s.OrderByConfig()) == 0 &&
+		len(s.GroupByBindings()) == 0 &&
+		s.Cursor() == nil &&
+		p.streamsGraphPattern()
+}
+
+// streamsGraphPattern returns true if the rows produced by the clauses of the
+// graph pattern can be consumed as they are produced; that is, if no other
+// graph pattern needs to be combined with them.
+func (p *queryPlan) streamsGraphPattern() bool {
+	s := p.stm
+	return len(s.SortedUnionGraphPatterns()) == 0 &&
+		len(s.SortedOptionalGraphPatternClauses()) == 0 &&
+		len(s.ExistenceGraphPatterns()) == 0
+}
+
+// Stream sends the rows of the query to the provided channel as they are
+// produced. Queries that cannot be streamed are executed first and their
+// rows sent once available.
+func (p *queryPlan) Stream(ctx context.Context, rows chan<- table.Row) error {
+	defer close(rows)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	bs := p.Bindings()
+	send := func(r table.Row) error {
+		nr := table.Row{}
+		for _, b := range bs {
+			if c, ok := r[b]; ok {
+				nr[b] = c
+			}
+		}
+		select {
+		case rows <- nr:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if !p.Streamable() {
+		tbl, err := p.Execute(ctx)
+		if err != nil {
+			return err
+		}
+		for _, r := range tbl.Rows() {
+			if err := send(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// Lookups still running once the limit is reached are canceled, and the
+	// errors they report because of it ignored.
+	stopped := false
+	out := p.outputSink(send)
+	sink := func(r table.Row) error {
+		err := out(r)
+		if err == errStopStream {
+			stopped = true
+			cancel()
+		}
+		return err
+	}
+	err := p.streamGraphPattern(ctx, p.stm.GlobalLookupOptions(), sink)
+	if stopped {
+		return nil
+	}
+	return err
+}
+
+// outputSink returns a sink that filters, projects, and paginates the rows
+// produced by the graph pattern before passing them to the provided one. It
+// returns errStopStream once the limit is reached.
+func (p *queryPlan) outputSink(send rowSink) rowSink {
+	offset, limit := int64(0), int64(-1)
+	if p.stm.IsOffsetSet() {
+		offset = p.stm.Offset()
+	}
+	if p.stm.IsLimitSet() {
+		limit = p.stm.Limit()
+	}
+	return func(r table.Row) error {
+		if limit == 0 {
+			return errStopStream
+		}
+		ok, err := p.keepRow(r)
+		if err != nil || !ok {
+			return err
+		}
+		if err := p.projectRow(r); err != nil {
+			return err
+		}
+		if p.stm.HasHavingClause() {
+			ok, err := p.stm.HavingEvaluator().Evaluate(r)
+			if err != nil || !ok {
+				return err
+			}
+		}
+		if offset > 0 {
+			offset--
+			return nil
+		}
+		if err := send(r); err != nil {
+			return err
+		}
+		if limit > 0 {
+			limit--
+			if limit == 0 {
+				return errStopStream
+			}
+		}
+		return nil
+	}
+}
+
+// keepRow returns true if the row holds for the pending filters. Filters whose
+// bindings are not available in the row are ignored, as they would be when
+// resolving the whole table.
+func (p *queryPlan) keepRow(r table.Row) (bool, error) {
+	for _, f := range p.filters {
+		ready := true
+		for _, b := range f.Bindings {
+			if _, ok := r[b]; !ok {
+				ready = false
+				break
+			}
+		}
+		if !ready {
+			continue
+		}
+		ok, err := f.Evaluator.Evaluate(r)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// projectRow computes the expressions of the projections for the row and
+// copies each input binding value to its alias, as projectAndGroupBy does for
+// each row of the table.
+func (p *queryPlan) projectRow(r table.Row) error {
+	for _, prj := range p.stm.Projections() {
+		if prj.Expression == nil {
+			continue
+		}
+		c, err := prj.Expression.Evaluate(r)
+		if err != nil {
+			return err
+		}
+		r[prj.Alias] = c
+	}
+	for _, prj := range p.stm.Projections() {
+		if prj.Expression == nil {
+			r[prj.Alias] = r[prj.Binding]
+		}
+	}
+	return nil
+}
+
+// streamGraphPattern resolves the graph pattern and passes the resulting rows
+// to the sink. Subqueries and all the clauses but the last one are resolved
+// as usual; the rows of the last clause are passed to the sink as they are
+// retrieved.
+func (p *queryPlan) streamGraphPattern(ctx context.Context, lo *storage.LookupOptions, sink rowSink) error {
+	if err := p.processSubqueries(ctx); err != nil {
+		return err
+	}
+	if len(p.stm.Subqueries()) > 0 && p.tbl.NumRows() == 0 {
+		return nil
+	}
+	if len(p.cls) == 0 {
+		return streamProduct(table.Row{}, []*table.Table{p.tbl}, sink)
+	}
+	cls, groups := independentClauses(p.cls, p.tbl.HasBinding)
+	if len(groups) == 0 || len(groups) == 1 && len(cls) == 0 {
+		return p.streamClauses(ctx, p.cls, lo, sink)
+	}
+	tbls, err := p.resolveGroups(ctx, cls, groups, lo)
+	if err != nil || p.unresolvable {
+		return err
+	}
+	if len(p.tbl.Bindings()) > 0 {
+		tbls = append([]*table.Table{p.tbl}, tbls...)
+	}
+	return streamProduct(table.Row{}, tbls, sink)
+}
+
+// streamClauses resolves the clauses against the current rows and passes the
+// resulting rows to the sink. The triples of the last clause are passed as
+// they are retrieved if they are looked up on their own or for each row.
+func (p *queryPlan) streamClauses(ctx context.Context, cls []*semantic.GraphClause, lo *storage.LookupOptions, sink rowSink) error {
+	cls, err := p.orderClauses(ctx, cls, p.tbl.HasBinding)
+	if err != nil {
+		return err
+	}
+	for _, c := range cls[:len(cls)-1] {
+		if unresolvable, err := p.resolveClause(ctx, c, lo); err != nil || unresolvable {
+			return err
+		}
+	}
+	last := cls[len(cls)-1]
+	m, err := p.accessMethod(ctx, last, p.tbl.HasBinding, float64(p.tbl.NumRows()))
+	if err != nil {
+		return err
+	}
+	switch {
+	case m == fetchAccess && len(p.tbl.Bindings()) == 0:
+		return fetchRows(ctx, p.grfs, last, lo, p.chanSize, sink)
+	case m == specifyAccess:
+		for _, r := range p.tbl.Rows() {
+			tmpCls := *last
+			tbl, err := p.specifiedData(ctx, r, &tmpCls, lo)
+			if err != nil {
+				return err
+			}
+			for _, nr := range tbl.Rows() {
+				if err := sink(table.MergeRows([]table.Row{r, nr})); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if unresolvable, err := p.resolveClause(ctx, last, lo); err != nil || unresolvable {
+		return err
+	}
+	return streamProduct(table.Row{}, []*table.Table{p.tbl}, sink)
+}
+
+// streamProduct passes to the sink the rows of the cartesian product of the
+// tables merged with the provided row, in the same order table.DotProduct
+// would produce them.
+func streamProduct(r table.Row, tbls []*table.Table, sink rowSink) error {
+	if len(tbls) == 0 {
+		return sink(r)
+	}
+	for _, r2 := range tbls[0].Rows() {
+		if err := streamProduct(table.MergeRows([]table.Row{r, r2}), tbls[1:], sink); err != nil {
+			return err
+		}
+	}
+	return nil
+}
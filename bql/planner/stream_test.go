@@ -0,0 +1,209 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+)
+
+// streamInSession streams the query in the session and collects the rows
+// into a table. It also returns if the rows were sent as they were produced.
+func streamInSession(t *testing.T, s *Session, bql string) (*table.Table, bool, error) {
+	ctx := context.Background()
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(bql, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse %q with error %v", bql, err)
+	}
+	plnr, err := s.New(ctx, st, 0)
+	if err != nil {
+		return nil, false, err
+	}
+	sp, ok := plnr.(Streamer)
+	if !ok {
+		t.Fatalf("planner.New should have returned a Streamer for %q; got %T", bql, plnr)
+	}
+	tbl, err := table.New(sp.Bindings())
+	if err != nil {
+		t.Fatalf("table.New failed with error %v", err)
+	}
+	rows, errc := make(chan table.Row), make(chan error, 1)
+	go func() {
+		errc <- sp.Stream(ctx, rows)
+	}()
+	for r := range rows {
+		tbl.AddRow(r)
+	}
+	return tbl, sp.Streamable(), <-errc
+}
+
+func TestStreamMatchesExecute(t *testing.T) {
+	s := populateTestStore(t)
+	ss := NewSession(s)
+	testTable := []struct {
+		q          string
+		streamable bool
+	}{
+		{
+			q:          `select ?s, ?p, ?o from ?test where {?s ?p ?o};`,
+			streamable: true,
+		},
+		{
+			q:          `select ?p as ?p1, ?o as ?o1 from ?test where {/u<joe> ?p ?o};`,
+			streamable: true,
+		},
+		{
+			q:          `select ?s, ?p, ?o from ?test where {/u<joe> as ?s "parent_of"@[] as ?p /u<mary> as ?o};`,
+			streamable: true,
+		},
+		{
+			q:          `select ?s, ?p, ?o from ?test where {/u<unknown> as ?s "parent_of"@[] as ?p /u<mary> as ?o};`,
+			streamable: true,
+		},
+		{
+			q:          `select ?o from ?test where {/l<barcelona> "predicate"@[] "turned"@[2015-01-01T00:00:00-08:00,2017-01-01T00:00:00-08:00] as ?o};`,
+			streamable: true,
+		},
+		{
+			q:          `select ?c, ?gc from ?test where {/u<joe> "parent_of"@[] ?c . ?c "parent_of"@[] ?gc . filter(?gc = /u<eve>)};`,
+			streamable: true,
+		},
+		{
+			q:          `select ?s, ?o from ?test where {filter(not(?s = ?o)) . ?s "connects_to"@[] ?o . ?o "connects_to"@[] ?s};`,
+			streamable: true,
+		},
+		{
+			q:          `select ?p, ?car, ?r from ?test where {?p "parent_of"@[] ?c . ?r "connects_to"@[] ?x . ?c "bought"@[?t] ?car};`,
+			streamable: true,
+		},
+		{
+			q:          `select ?s, ?r from ?test where {?s "connects_to"@[] ?r} having contains(?r, "Kit");`,
+			streamable: true,
+		},
+		{
+			q:          `select ?d from ?test where {/u<joe> "parent_of"@[]+ ?d};`,
+			streamable: true,
+		},
+		// Queries that cannot be streamed return the rows once executed.
+		{
+			q:          `select ?grandparent, count(?name) as ?grandchildren from ?test where {/u<joe> as ?grandparent "parent_of"@[] ?offspring . ?offspring "parent_of"@[] ?name} group by ?grandparent;`,
+			streamable: false,
+		},
+		{
+			q:          `select ?s, ?r from ?test where {?s "connects_to"@[] ?r} order by ?s, ?r limit "3"^^type:int64;`,
+			streamable: false,
+		},
+		{
+			q:          `select ?c, ?gc from ?test where {/u<joe> "parent_of"@[] ?c . optional {?c "parent_of"@[] ?gc}};`,
+			streamable: false,
+		},
+	}
+	for _, entry := range testTable {
+		q := entry.q
+		want := mustRunInSession(t, ss, q)
+		got, streamable, err := streamInSession(t, ss, q)
+		if err != nil {
+			t.Errorf("Stream failed for query %q with error %v", q, err)
+			continue
+		}
+		if streamable != entry.streamable {
+			t.Errorf("Streamable returned the wrong value for query %q; got %v, want %v", q, streamable, entry.streamable)
+		}
+		if !reflect.DeepEqual(got.Bindings(), want.Bindings()) {
+			t.Errorf("Stream returned the wrong bindings for query %q; got %v, want %v", q, got.Bindings(), want.Bindings())
+		}
+		if !reflect.DeepEqual(rowStrings(got), rowStrings(want)) {
+			t.Errorf("Stream returned the wrong rows for query %q; got\n%s\nwant\n%s", q, got, want)
+		}
+	}
+}
+
+func TestStreamLimitAndOffset(t *testing.T) {
+	s := populateTestStore(t)
+	ss := NewSession(s)
+	testTable := []struct {
+		q    string
+		nrws int
+	}{
+		{
+			q:    `select ?s, ?p, ?o from ?test where {?s ?p ?o} limit "5"^^type:int64;`,
+			nrws: 5,
+		},
+		{
+			q:    `select ?s, ?p, ?o from ?test where {?s ?p ?o} limit "0"^^type:int64;`,
+			nrws: 0,
+		},
+		{
+			q:    `select ?s, ?p, ?o from ?test where {?s ?p ?o} offset "20"^^type:int64;`,
+			nrws: 7,
+		},
+		{
+			q:    `select ?s, ?p, ?o from ?test where {?s ?p ?o} limit "5"^^type:int64 offset "25"^^type:int64;`,
+			nrws: 2,
+		},
+		{
+			q:    `select ?p, ?car from ?test where {?p "parent_of"@[] ?c . ?c "bought"@[?t] ?car} limit "2"^^type:int64;`,
+			nrws: 2,
+		},
+	}
+	for _, entry := range testTable {
+		tbl, _, err := streamInSession(t, ss, entry.q)
+		if err != nil {
+			t.Errorf("Stream failed for query %q with error %v", entry.q, err)
+			continue
+		}
+		if got, want := tbl.NumRows(), entry.nrws; got != want {
+			t.Errorf("Stream returned the wrong number of rows for query %q; got %d, want %d\n%s", entry.q, got, want, tbl)
+		}
+	}
+}
+
+func TestStreamCanceled(t *testing.T) {
+	s := populateTestStore(t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser with error %v", err)
+	}
+	q := `select ?s, ?p, ?o from ?test where {?s ?p ?o};`
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: failed to parse %q with error %v", q, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	plnr, err := New(ctx, s, st, 0)
+	if err != nil {
+		t.Fatalf("planner.New failed for query %q with error %v", q, err)
+	}
+	rows := make(chan table.Row)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- plnr.(Streamer).Stream(ctx, rows)
+	}()
+	<-rows
+	cancel()
+	if err := <-errc; err == nil {
+		t.Errorf("Stream should have failed for query %q after the context was canceled", q)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// Constructor is implemented by the plans of statements that build triples
+// instead of tables, such as construct and describe statements. The
+// constructed triples can be streamed, for instance to be serialized using
+// io.WriteTriples.
+type Constructor interface {
+	Executor
+
+	// Triples runs the plan and sends the constructed triples to the provided
+	// channel. The channel is closed once all the triples have been sent.
+	Triples(ctx context.Context, trpls chan<- *triple.Triple) error
+}
+
+// constructPlan encapsulates the sequence of instructions that need to be
+// executed in order to satisfy the execution of a valid construct BQL
+// statement.
+type constructPlan struct {
+	stm      *semantic.Statement
+	store    storage.Store
+	chanSize int
+	workers  workerPool
+	budget   memoryBudget
+	snapshot snapshotFinder
+}
+
+// templateRows runs the where clause of the statement and returns the rows
+// used to instantiate its templates.
+func templateRows(ctx context.Context, store storage.Store, stm *semantic.Statement, chanSize int, workers workerPool, budget memoryBudget, snapshot snapshotFinder) ([]table.Row, error) {
+	qp, err := newQueryPlan(ctx, store, stm, chanSize, workers, budget, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	tbl, err := qp.Execute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tbl.Rows(), nil
+}
+
+// instantiateTemplates returns the triples built by instantiating the template
+// for each of the provided rows. Triples that cannot be built, because a
+// binding is not set or its value cannot be used in its position, are dropped.
+// Duplicated triples are only returned once.
+func instantiateTemplates(rows []table.Row, tmpl []*semantic.TemplateClause) []*triple.Triple {
+	var (
+		ts   []*triple.Triple
+		seen = make(map[string]bool)
+	)
+	for _, r := range rows {
+		for _, c := range tmpl {
+			t, ok := instantiateTemplate(c, r)
+			if !ok || seen[t.String()] {
+				continue
+			}
+			seen[t.String()] = true
+			ts = append(ts, t)
+		}
+	}
+	return ts
+}
+
+// construct returns the triples built by the template of the statement.
+func (p *constructPlan) construct(ctx context.Context) ([]*triple.Triple, error) {
+	rows, err := templateRows(ctx, p.store, p.stm, p.chanSize, p.workers, p.budget, p.snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return instantiateTemplates(rows, p.stm.TemplateClauses()), nil
+}
+
+// instantiateTemplate builds the triple described by the template clause
+// using the values of the provided row. It returns false if the triple cannot
+// be built.
+func instantiateTemplate(c *semantic.TemplateClause, r table.Row) (*triple.Triple, bool) {
+	s, p, o := c.S, c.P, c.O
+	if c.SBinding != "" {
+		v, ok := r[c.SBinding]
+		if !ok || v.N == nil {
+			return nil, false
+		}
+		s = v.N
+	}
+	if c.PBinding != "" {
+		v, ok := r[c.PBinding]
+		if !ok || v.P == nil {
+			return nil, false
+		}
+		p = v.P
+	}
+	if c.PAnchorBinding != "" {
+		v, ok := r[c.PAnchorBinding]
+		if !ok || v.T == nil {
+			return nil, false
+		}
+		tp, err := predicate.NewTemporal(c.PID, *v.T)
+		if err != nil {
+			return nil, false
+		}
+		p = tp
+	}
+	if c.OBinding != "" {
+		v, ok := r[c.OBinding]
+		if !ok || v.IsNull() {
+			return nil, false
+		}
+		obj, err := cellToObject(v)
+		if err != nil {
+			return nil, false
+		}
+		o = obj
+	}
+	t, err := triple.New(s, p, o)
+	if err != nil {
+		return nil, false
+	}
+	return t, true
+}
+
+// Execute builds the triples described by the template of the statement. If
+// the statement lists graphs to write into, the triples are inserted into them
+// and an empty table is returned. Otherwise, the triples are returned as a
+// table with one row per triple bound to ?s, ?p, and ?o.
+func (p *constructPlan) Execute(ctx context.Context) (*table.Table, error) {
+	ts, err := p.construct(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if gs := p.stm.OutputGraphs(); len(gs) > 0 {
+		t, err := table.New([]string{})
+		if err != nil {
+			return nil, err
+		}
+		return t, update(ctx, gs, ts, p.store, func(g storage.Graph, d []*triple.Triple) error {
+			return g.AddTriples(ctx, d)
+		})
+	}
+	return triplesTable(ts)
+}
+
+// triplesTable returns a table with one row per provided triple bound to ?s,
+// ?p, and ?o.
+func triplesTable(ts []*triple.Triple) (*table.Table, error) {
+	t, err := table.New([]string{"?s", "?p", "?o"})
+	if err != nil {
+		return nil, err
+	}
+	for _, trpl := range ts {
+		o, err := objectToCell(trpl.Object())
+		if err != nil {
+			return nil, err
+		}
+		t.AddRow(table.Row{
+			"?s": &table.Cell{N: trpl.Subject()},
+			"?p": &table.Cell{P: trpl.Predicate()},
+			"?o": o,
+		})
+	}
+	return t, nil
+}
+
+// Triples sends the triples described by the template of the statement to
+// the provided channel. The triples are never written into the graphs the
+// statement lists to write into.
+func (p *constructPlan) Triples(ctx context.Context, trpls chan<- *triple.Triple) error {
+	defer close(trpls)
+	ts, err := p.construct(ctx)
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		trpls <- t
+	}
+	return nil
+}
+
+// updatePlan encapsulates the sequence of instructions that need to be
+// executed in order to satisfy the execution of a valid update BQL statement.
+type updatePlan struct {
+	stm      *semantic.Statement
+	store    storage.Store
+	chanSize int
+	workers  workerPool
+	budget   memoryBudget
+}
+
+// Execute runs the where clause of the statement, and then removes the
+// triples built by the delete template and adds the ones built by the insert
+// template. All the removals and additions are applied to all the graphs of
+// the statement.
+func (p *updatePlan) Execute(ctx context.Context) (*table.Table, error) {
+	rows, err := templateRows(ctx, p.store, p.stm, p.chanSize, p.workers, p.budget, noSnapshots)
+	if err != nil {
+		return nil, err
+	}
+	del := instantiateTemplates(rows, p.stm.DeleteTemplateClauses())
+	ins := instantiateTemplates(rows, p.stm.TemplateClauses())
+	t, err := table.New([]string{})
+	if err != nil {
+		return nil, err
+	}
+	return t, update(ctx, p.stm.Graphs(), nil, p.store, func(g storage.Graph, _ []*triple.Triple) error {
+		if err := g.RemoveTriples(ctx, del); err != nil {
+			return err
+		}
+		return g.AddTriples(ctx, ins)
+	})
+}
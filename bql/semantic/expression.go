@@ -18,10 +18,15 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/google/badwolf/bql/lexer"
 	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
 )
 
 // Evaluator interface computes the evaluation of a boolean expression.
@@ -58,6 +63,12 @@ const (
 	AND
 	// OR represents 'or'
 	OR
+	// CONTAINS represents 'contains'
+	CONTAINS
+	// STARTSWITH represents 'starts_with'
+	STARTSWITH
+	// REGEX represents 'regex'
+	REGEX
 )
 
 // String returns a readable string of the operation.
@@ -75,6 +86,12 @@ func (o OP) String() string {
 		return "and"
 	case OR:
 		return "or"
+	case CONTAINS:
+		return "contains"
+	case STARTSWITH:
+		return "starts_with"
+	case REGEX:
+		return "regex"
 	default:
 		return "@UNKNOWN@"
 	}
@@ -106,6 +123,28 @@ func (e *evaluationNode) Evaluate(r table.Row) (bool, error) {
 		return eL, eR, nil
 	}
 
+	eL, eR, err := eval()
+	if err != nil {
+		return false, err
+	}
+	return compareCells(e.op, eL, eR)
+}
+
+// compareCells applies the comparison operation to the provided cells. Cells
+// holding times are compared as time values.
+func compareCells(op OP, eL, eR *table.Cell) (bool, error) {
+	if tL, ok := cellTime(eL); ok {
+		if tR, ok := cellTime(eR); ok {
+			switch op {
+			case EQ:
+				return tL.Equal(*tR), nil
+			case LT:
+				return tL.Before(*tR), nil
+			case GT:
+				return tL.After(*tR), nil
+			}
+		}
+	}
 	cs := func(c *table.Cell) string {
 		if c.L != nil {
 			return strings.TrimSpace(c.L.ToComparableString())
@@ -113,21 +152,49 @@ func (e *evaluationNode) Evaluate(r table.Row) (bool, error) {
 		return strings.TrimSpace(c.String())
 	}
 
-	eL, eR, err := eval()
-	if err != nil {
-		return false, err
-	}
 	csEL, csER := cs(eL), cs(eR)
-	switch e.op {
+	switch op {
 	case EQ:
 		return reflect.DeepEqual(csEL, csER), nil
 	case LT:
-		return cs(eL) < cs(eR), nil
+		return csEL < csER, nil
 	case GT:
 		return csEL > csER, nil
 	default:
-		return false, fmt.Errorf("boolean evaluation require a boolen operation; found %q instead", e.op)
+		return false, fmt.Errorf("boolean evaluation require a boolen operation; found %q instead", op)
+	}
+}
+
+// cellTime returns the time held by the cell, if any. Bound time anchors hold
+// a time, and time constants written as ""@[time] hold it as the anchor of a
+// temporal predicate with an empty ID.
+func cellTime(c *table.Cell) (*time.Time, bool) {
+	if c.T != nil {
+		return c.T, true
+	}
+	if c.P != nil && c.P.ID() == "" && c.P.Type() == predicate.Temporal {
+		if t, err := c.P.TimeAnchor(); err == nil {
+			return t, true
+		}
 	}
+	return nil, false
+}
+
+// constantEvaluationNode represents the internal representation of one
+// expression comparing a binding against a constant value.
+type constantEvaluationNode struct {
+	op OP
+	lB string
+	rC *table.Cell
+}
+
+// Evaluate the expression.
+func (e *constantEvaluationNode) Evaluate(r table.Row) (bool, error) {
+	eL, ok := r[e.lB]
+	if !ok {
+		return false, fmt.Errorf("comparison operations require the binding value for %q for row %q to exist", e.lB, r)
+	}
+	return compareCells(e.op, eL, e.rC)
 }
 
 // NewEvaluationExpression creates a new evaluator for two bindings in a row.
@@ -148,6 +215,119 @@ func NewEvaluationExpression(op OP, lB, rB string) (Evaluator, error) {
 	}
 }
 
+// NewConstantEvaluationExpression creates a new evaluator that compares a
+// binding in a row against a constant value.
+func NewConstantEvaluationExpression(op OP, lB string, c *table.Cell) (Evaluator, error) {
+	l := strings.TrimSpace(lB)
+	if l == "" || c == nil {
+		return nil, fmt.Errorf("binding and constant cannot be empty; got %q, %v", l, c)
+	}
+	switch op {
+	case EQ, LT, GT:
+		return &constantEvaluationNode{
+			op: op,
+			lB: lB,
+			rC: c,
+		}, nil
+	default:
+		return nil, errors.New("evaluation expressions require the operation to be one for the follwing '=', '<', '>'")
+	}
+}
+
+// constantCell returns the cell holding the value of a node, predicate, or
+// literal token.
+func constantCell(tkn *lexer.Token) (*table.Cell, error) {
+	switch tkn.Type {
+	case lexer.ItemNode:
+		n, err := node.Parse(tkn.Text)
+		if err != nil {
+			return nil, err
+		}
+		return &table.Cell{N: n}, nil
+	case lexer.ItemPredicate:
+		p, err := predicate.Parse(tkn.Text)
+		if err != nil {
+			return nil, err
+		}
+		return &table.Cell{P: p}, nil
+	case lexer.ItemLiteral:
+		l, err := literal.DefaultBuilder().Parse(tkn.Text)
+		if err != nil {
+			return nil, err
+		}
+		return &table.Cell{L: l}, nil
+	default:
+		return nil, fmt.Errorf("cannot use %v as a constant", tkn)
+	}
+}
+
+// matchEvaluationNode represents the internal representation of one
+// expression matching the value of a binding against a string.
+type matchEvaluationNode struct {
+	op OP
+	lB string
+	s  string
+	re *regexp.Regexp
+}
+
+// Evaluate the expression.
+func (e *matchEvaluationNode) Evaluate(r table.Row) (bool, error) {
+	c, ok := r[e.lB]
+	if !ok {
+		return false, fmt.Errorf("%s requires the binding value for %q for row %q to exist", e.op, e.lB, r)
+	}
+	var v string
+	switch {
+	case c.IsNull():
+		return false, nil
+	case c.N != nil:
+		v = c.N.ID().String()
+	case c.P != nil:
+		v = string(c.P.ID())
+	case c.L != nil && c.L.Type() == literal.Text:
+		v, _ = c.L.Text()
+	default:
+		return false, fmt.Errorf("%s can only be applied to text literals, nodes, and predicates; found %s instead for binding %q", e.op, c, e.lB)
+	}
+	switch e.op {
+	case CONTAINS:
+		return strings.Contains(v, e.s), nil
+	case STARTSWITH:
+		return strings.HasPrefix(v, e.s), nil
+	case REGEX:
+		return e.re.MatchString(v), nil
+	default:
+		return false, fmt.Errorf("match evaluation require a match operation; found %q instead", e.op)
+	}
+}
+
+// NewMatchEvaluationExpression creates a new evaluator that matches the value
+// of a binding in a row against the provided string. Nodes and predicates are
+// matched using their IDs.
+func NewMatchEvaluationExpression(op OP, lB, s string) (Evaluator, error) {
+	l := strings.TrimSpace(lB)
+	if l == "" {
+		return nil, errors.New("binding cannot be empty")
+	}
+	e := &matchEvaluationNode{
+		op: op,
+		lB: lB,
+		s:  s,
+	}
+	switch op {
+	case CONTAINS, STARTSWITH:
+	case REGEX:
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q; %v", s, err)
+		}
+		e.re = re
+	default:
+		return nil, errors.New("match expressions require the operation to be one for the follwing 'contains', 'starts_with', 'regex'")
+	}
+	return e, nil
+}
+
 // booleanNode represents the internal representation of one expression.
 type booleanNode struct {
 	op OP
@@ -290,20 +470,51 @@ func internalNewEvaluator(ce []ConsumedElement) (Evaluator, []ConsumedElement, e
 		default:
 			return nil, nil, fmt.Errorf("cannot create a binary evaluation operand for %v", opTkn)
 		}
-		if bndTkn.Type == lexer.ItemBinding {
+		var res []ConsumedElement
+		if len(tail) > 2 {
+			res = tail[2:]
+		}
+		switch bndTkn.Type {
+		case lexer.ItemBinding:
 			e, err := NewEvaluationExpression(op, tkn.Text, bndTkn.Text)
 			if err != nil {
 				return nil, nil, err
 			}
-			var res []ConsumedElement
-			if len(tail) > 2 {
-				res = tail[2:]
+			return e, res, nil
+		case lexer.ItemNode, lexer.ItemPredicate, lexer.ItemLiteral:
+			c, err := constantCell(bndTkn)
+			if err != nil {
+				return nil, nil, err
+			}
+			e, err := NewConstantEvaluationExpression(op, tkn.Text, c)
+			if err != nil {
+				return nil, nil, err
 			}
 			return e, res, nil
 		}
 		return nil, nil, fmt.Errorf("cannot build a binary evaluation operand with right operant %v", bndTkn)
 	}
 
+	// Match function tokens
+	if op, ok := matchOPs[tkn.Type]; ok {
+		if len(tail) < 5 {
+			return nil, nil, fmt.Errorf("incomplete %s expression %v", op, ce)
+		}
+		lp, bnd, cm, str, rp := tail[0].Token(), tail[1].Token(), tail[2].Token(), tail[3].Token(), tail[4].Token()
+		if lp.Type != lexer.ItemLPar || bnd.Type != lexer.ItemBinding || cm.Type != lexer.ItemComma || str.Type != lexer.ItemString || rp.Type != lexer.ItemRPar {
+			return nil, nil, fmt.Errorf("%s expressions require a binding and a string; found %v instead", op, tail[:5])
+		}
+		s, err := unquoteString(str)
+		if err != nil {
+			return nil, nil, err
+		}
+		e, err := NewMatchEvaluationExpression(op, bnd.Text, s)
+		if err != nil {
+			return nil, nil, err
+		}
+		return binaryBooleanTail(e, tail[5:])
+	}
+
 	// LPar Token
 	if tkn.Type == lexer.ItemLPar {
 		tailEval, ce, err := internalNewEvaluator(tail)
@@ -317,29 +528,7 @@ func internalNewEvaluator(ce []ConsumedElement) (Evaluator, []ConsumedElement, e
 		if head.Token().Type != lexer.ItemRPar {
 			return nil, nil, fmt.Errorf("missing right parentesis in expression; found %v instead", head)
 		}
-		if len(tail) > 1 {
-			// Binary boolean expression.
-			opTkn := tail[0].Token()
-			var op OP
-			switch opTkn.Type {
-			case lexer.ItemAnd:
-				op = AND
-			case lexer.ItemOr:
-				op = OR
-			default:
-				return nil, nil, fmt.Errorf("cannot create a binary boolean evaluation operand for %v", opTkn)
-			}
-			rTailEval, ceResTail, err := internalNewEvaluator(tail[1:])
-			if err != nil {
-				return nil, nil, err
-			}
-			ev, err := NewBinaryBooleanExpression(op, tailEval, rTailEval)
-			if err != nil {
-				return nil, nil, err
-			}
-			return ev, ceResTail, nil
-		}
-		return tailEval, tail, nil
+		return binaryBooleanTail(tailEval, tail)
 	}
 
 	var tkns []string
@@ -348,3 +537,38 @@ func internalNewEvaluator(ce []ConsumedElement) (Evaluator, []ConsumedElement, e
 	}
 	return nil, nil, fmt.Errorf("could not create an evaluator for condition {%s}", strings.Join(tkns, ","))
 }
+
+// matchOPs maps the tokens of the match functions to their operations.
+var matchOPs = map[lexer.TokenType]OP{
+	lexer.ItemContains:   CONTAINS,
+	lexer.ItemStartsWith: STARTSWITH,
+	lexer.ItemRegex:      REGEX,
+}
+
+// binaryBooleanTail combines the provided evaluator with the rest of the
+// expression if the remaining tokens start with a binary boolean operation.
+func binaryBooleanTail(lE Evaluator, tail []ConsumedElement) (Evaluator, []ConsumedElement, error) {
+	if len(tail) <= 1 {
+		return lE, tail, nil
+	}
+	// Binary boolean expression.
+	opTkn := tail[0].Token()
+	var op OP
+	switch opTkn.Type {
+	case lexer.ItemAnd:
+		op = AND
+	case lexer.ItemOr:
+		op = OR
+	default:
+		return nil, nil, fmt.Errorf("cannot create a binary boolean evaluation operand for %v", opTkn)
+	}
+	rTailEval, ceResTail, err := internalNewEvaluator(tail[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+	ev, err := NewBinaryBooleanExpression(op, lE, rTailEval)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ev, ceResTail, nil
+}
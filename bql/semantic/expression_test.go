@@ -16,9 +16,13 @@ package semantic
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/badwolf/bql/lexer"
 	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
 )
 
 func TestEvaluationNode(t *testing.T) {
@@ -186,6 +190,118 @@ func TestBooleanEvaluationNode(t *testing.T) {
 	}
 }
 
+func testLiteral(t *testing.T, s string) *literal.Literal {
+	l, err := literal.DefaultBuilder().Parse(s)
+	if err != nil {
+		t.Fatalf("literal.Parse(%q) failed with error %v", s, err)
+	}
+	return l
+}
+
+func TestMatchEvaluationNode(t *testing.T) {
+	n, err := node.Parse("/u<joe doe>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := predicate.Parse(`"parent_of"@[]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := table.Row{
+		"?n":    &table.Cell{N: n},
+		"?p":    &table.Cell{P: p},
+		"?t":    &table.Cell{L: testLiteral(t, `"Hello World"^^type:text`)},
+		"?i":    &table.Cell{L: testLiteral(t, `"1"^^type:int64`)},
+		"?null": &table.Cell{},
+	}
+	testTable := []struct {
+		op   OP
+		b    string
+		s    string
+		want bool
+		err  bool
+	}{
+		{op: CONTAINS, b: "?n", s: "doe", want: true},
+		{op: CONTAINS, b: "?n", s: "/u", want: false},
+		{op: STARTSWITH, b: "?n", s: "joe", want: true},
+		{op: STARTSWITH, b: "?p", s: "parent", want: true},
+		{op: CONTAINS, b: "?p", s: "child", want: false},
+		{op: REGEX, b: "?t", s: "^Hello W.*d$", want: true},
+		{op: REGEX, b: "?t", s: "^hello", want: false},
+		{op: CONTAINS, b: "?null", s: "", want: false},
+		{op: CONTAINS, b: "?i", s: "1", err: true},
+		{op: CONTAINS, b: "?missing", s: "1", err: true},
+	}
+	for _, entry := range testTable {
+		e, err := NewMatchEvaluationExpression(entry.op, entry.b, entry.s)
+		if err != nil {
+			t.Errorf("NewMatchEvaluationExpression(%s, %q, %q) failed with error %v", entry.op, entry.b, entry.s, err)
+			continue
+		}
+		got, err := e.Evaluate(r)
+		if !entry.err && err != nil {
+			t.Errorf("%s(%q, %q).Evaluate(%v) failed with error %v", entry.op, entry.b, entry.s, r, err)
+		}
+		if entry.err && err == nil {
+			t.Errorf("%s(%q, %q).Evaluate(%v) should have failed", entry.op, entry.b, entry.s, r)
+		}
+		if got != entry.want {
+			t.Errorf("%s(%q, %q).Evaluate(%v) returned the wrong value; got %v, want %v", entry.op, entry.b, entry.s, r, got, entry.want)
+		}
+	}
+	if _, err := NewMatchEvaluationExpression(REGEX, "?t", "(foo"); err == nil {
+		t.Errorf("NewMatchEvaluationExpression should have failed to compile an invalid regular expression")
+	}
+	if _, err := NewMatchEvaluationExpression(EQ, "?t", "foo"); err == nil {
+		t.Errorf("NewMatchEvaluationExpression should have failed for a non match operation")
+	}
+}
+
+func TestConstantEvaluationNodeTimes(t *testing.T) {
+	pst := time.FixedZone("PST", -8*60*60)
+	tm := time.Date(2016, 2, 1, 0, 0, 0, 0, pst)
+	p, err := predicate.Parse(`"bought"@[2016-02-01T00:00:00-08:00]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := table.Row{
+		"?t": &table.Cell{T: &tm},
+		"?p": &table.Cell{P: p},
+	}
+	testTable := []struct {
+		op   OP
+		b    string
+		c    string
+		want bool
+	}{
+		{op: EQ, b: "?t", c: `""@[2016-02-01T08:00:00Z]`, want: true},
+		{op: LT, b: "?t", c: `""@[2016-02-01T08:00:00Z]`, want: false},
+		{op: GT, b: "?t", c: `""@[2016-02-01T08:00:00Z]`, want: false},
+		{op: LT, b: "?t", c: `""@[2016-01-31T23:00:00-10:00]`, want: true},
+		{op: GT, b: "?t", c: `""@[2016-01-31T12:00:00Z]`, want: true},
+		{op: GT, b: "?t", c: `""@[2016-02-01T12:00:00+09:00]`, want: true},
+		// Predicates with an ID are not time constants.
+		{op: EQ, b: "?p", c: `""@[2016-02-01T08:00:00Z]`, want: false},
+	}
+	for _, entry := range testTable {
+		c, err := predicate.Parse(entry.c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := NewConstantEvaluationExpression(entry.op, entry.b, &table.Cell{P: c})
+		if err != nil {
+			t.Fatalf("NewConstantEvaluationExpression(%s, %q, %q) failed with error %v", entry.op, entry.b, entry.c, err)
+		}
+		got, err := e.Evaluate(r)
+		if err != nil {
+			t.Errorf("%q %s %q failed with error %v", entry.b, entry.op, entry.c, err)
+		}
+		if got != entry.want {
+			t.Errorf("%q %s %q returned the wrong value; got %v, want %v", entry.b, entry.op, entry.c, got, entry.want)
+		}
+	}
+}
+
 func TestNewEvaluator(t *testing.T) {
 	testTable := []struct {
 		id   string
@@ -387,6 +503,48 @@ func TestNewEvaluator(t *testing.T) {
 			err:  false,
 			want: false,
 		},
+		{
+			id: "?foo = /u<peter>",
+			in: []ConsumedElement{
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?foo",
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemEQ,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemNode,
+					Text: "/u<peter>",
+				}),
+			},
+			r: table.Row{
+				"?foo": &table.Cell{S: table.CellString("/u<peter>")},
+			},
+			err:  false,
+			want: true,
+		},
+		{
+			id: "?foo > \"10\"^^type:int64",
+			in: []ConsumedElement{
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?foo",
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemGT,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemLiteral,
+					Text: `"10"^^type:int64`,
+				}),
+			},
+			r: table.Row{
+				"?foo": &table.Cell{L: testLiteral(t, `"9"^^type:int64`)},
+			},
+			err:  false,
+			want: false,
+		},
 	}
 	for _, entry := range testTable {
 		eval, err := NewEvaluator(entry.in)
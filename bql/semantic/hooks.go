@@ -17,6 +17,7 @@ package semantic
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -164,14 +165,95 @@ var (
 	// limit clause expression.
 	licl ElementHook
 
+	// ofcl contains the element hook to collect the offset value of the offset
+	// clause.
+	ofcl ElementHook
+
+	// cucl contains the element hook that decodes the continuation token of
+	// the cursor clause.
+	cucl ElementHook
+
+	// sqsh contains the clause hook that starts parsing a subquery.
+	sqsh ClauseHook
+
+	// sqeh contains the clause hook that checks and stores a parsed subquery.
+	sqeh ClauseHook
+
 	// gbcl contains the element hook that collects the global time bounds
 	// to apply to the graph clause when temporal predicates are present.
 	gbcl ElementHook
+
+	// snch contains the element hook that collects the name of the snapshot
+	// created or dropped by a statement.
+	snch ElementHook
+
+	// gsch contains the element hook that collects the snapshot to use for
+	// the last graph listed in a statement.
+	gsch ElementHook
+
+	// opch contains the element hook that opens and closes optional graph
+	// patterns.
+	opch ElementHook
+
+	// ugch contains the element hook that opens a union of graph patterns and
+	// its first alternative.
+	ugch ElementHook
+
+	// egch contains the element hook that opens and closes existence graph
+	// patterns.
+	egch ElementHook
+
+	// uach contains the element hook that opens and closes the alternatives of
+	// a union of graph patterns.
+	uach ElementHook
+
+	// fech contains the element hook that collects the tokens of a filter
+	// expression.
+	fech ElementHook
+
+	// fbch contains the element hook that builds a filter once its expression
+	// has been collected.
+	fbch ElementHook
+
+	// gcsh contains the element hook that collects the separator used by
+	// GROUP_CONCAT.
+	gcsh ElementHook
+
+	// sech contains the element hook that collects the tokens of a scalar
+	// expression.
+	sech ElementHook
+
+	// pech contains the element hook that builds the scalar expression of a
+	// projection and sets its alias.
+	pech ElementHook
+
+	// ppch contains the element hook that collects the property path modifiers
+	// and bindings of a predicate.
+	ppch ElementHook
+
+	// tach contains the element hook that collects the clauses of a template.
+	tach ElementHook
+
+	// dtch contains the element hook that collects the clauses of the template
+	// of the triples to remove.
+	dtch ElementHook
+
+	// igch contains the element hook that collects the graphs a statement
+	// writes its results into.
+	igch ElementHook
+
+	// dsch contains the element hook that collects the node and the depth of
+	// describe statements.
+	dsch ElementHook
+
+	// exch contains the element hook that collects the explain and analyze
+	// flags of explained queries.
+	exch ElementHook
 )
 
 func init() {
 	dach = dataAccumulator(literal.DefaultBuilder())
-	gach = graphAccumulator()
+	gach = graphAccumulator((*Statement).AddGraph)
 	wnch = whereNextWorkingClause()
 	wich = whereInitWorkingClause()
 	wsch = whereSubjectClause()
@@ -186,7 +268,28 @@ func init() {
 	hech = havingExpression()
 	hebl = havingExpressionBuilder()
 	licl = limitCollection()
+	ofcl = offsetCollection()
+	cucl = cursorCollection()
+	sqsh = subqueryStart()
+	sqeh = subqueryEnd()
 	gbcl = collectGlobalBounds()
+	snch = snapshotName()
+	gsch = graphSnapshot()
+	opch = optionalGraphPattern()
+	ugch = unionGraphPattern()
+	uach = unionAlternative()
+	egch = existenceGraphPattern()
+	fech = filterExpression()
+	fbch = filterBuilder()
+	gcsh = groupConcatSeparator()
+	sech = scalarExpression()
+	pech = projectionExpression()
+	ppch = predicatePath()
+	tach = templateAccumulator(literal.DefaultBuilder(), (*Statement).AddWorkingTemplateClause)
+	dtch = templateAccumulator(literal.DefaultBuilder(), (*Statement).AddWorkingDeleteTemplateClause)
+	igch = graphAccumulator((*Statement).AddOutputGraph)
+	dsch = describeCollection()
+	exch = explainCollection()
 
 	predicateRegexp = regexp.MustCompile(`^"(.+)"@\["?([^\]"]*)"?\]$`)
 	boundRegexp = regexp.MustCompile(`^"(.+)"@\["?([^\]"]*)"?,"?([^\]"]*)"?\]$`)
@@ -283,14 +386,135 @@ func LimitCollection() ElementHook {
 	return licl
 }
 
+// OffsetCollection returns the offset collection hook.
+func OffsetCollection() ElementHook {
+	return ofcl
+}
+
+// CursorCollection returns the cursor collection hook.
+func CursorCollection() ElementHook {
+	return cucl
+}
+
+// SubqueryStartHook returns the singleton to start parsing subqueries.
+func SubqueryStartHook() ClauseHook {
+	return sqsh
+}
+
+// SubqueryEndHook returns the singleton to check and store parsed subqueries.
+func SubqueryEndHook() ClauseHook {
+	return sqeh
+}
+
 // CollectGlobalBounds returns the global temporary bounds hook.
 func CollectGlobalBounds() ElementHook {
 	return gbcl
 }
 
+// SnapshotNameHook returns the singleton for collecting the name of the
+// snapshot created or dropped by a statement.
+func SnapshotNameHook() ElementHook {
+	return snch
+}
+
+// GraphSnapshotHook returns the singleton for collecting the snapshot to use
+// for a graph.
+func GraphSnapshotHook() ElementHook {
+	return gsch
+}
+
+// OptionalGraphPatternHook returns the singleton for opening and closing
+// optional graph patterns.
+func OptionalGraphPatternHook() ElementHook {
+	return opch
+}
+
+// ExistenceGraphPatternHook returns the singleton for opening and closing
+// existence graph patterns.
+func ExistenceGraphPatternHook() ElementHook {
+	return egch
+}
+
+// UnionGraphPatternHook returns the singleton for opening unions of graph
+// patterns.
+func UnionGraphPatternHook() ElementHook {
+	return ugch
+}
+
+// UnionAlternativeHook returns the singleton for opening and closing the
+// alternatives of a union of graph patterns.
+func UnionAlternativeHook() ElementHook {
+	return uach
+}
+
+// FilterExpressionHook returns the singleton for collecting filter
+// expressions.
+func FilterExpressionHook() ElementHook {
+	return fech
+}
+
+// FilterBuilderHook returns the singleton for building filters.
+func FilterBuilderHook() ElementHook {
+	return fbch
+}
+
+// GroupConcatSeparatorHook returns the singleton for collecting the separator
+// used by GROUP_CONCAT.
+func GroupConcatSeparatorHook() ElementHook {
+	return gcsh
+}
+
+// ScalarExpressionHook returns the singleton for collecting scalar
+// expressions.
+func ScalarExpressionHook() ElementHook {
+	return sech
+}
+
+// ProjectionExpressionHook returns the singleton for building the scalar
+// expressions used in projections.
+func ProjectionExpressionHook() ElementHook {
+	return pech
+}
+
+// PredicatePathHook returns the singleton for collecting property path
+// modifiers.
+func PredicatePathHook() ElementHook {
+	return ppch
+}
+
+// TemplateAccumulatorHook returns the singleton for collecting the clauses of
+// a template.
+func TemplateAccumulatorHook() ElementHook {
+	return tach
+}
+
+// DeleteTemplateAccumulatorHook returns the singleton for collecting the
+// clauses of the template of the triples to remove.
+func DeleteTemplateAccumulatorHook() ElementHook {
+	return dtch
+}
+
+// IntoGraphAccumulatorHook returns the singleton for collecting the graphs a
+// statement writes its results into.
+func IntoGraphAccumulatorHook() ElementHook {
+	return igch
+}
+
+// DescribeCollectionHook returns the singleton for collecting the node and the
+// depth of describe statements.
+func DescribeCollectionHook() ElementHook {
+	return dsch
+}
+
+// ExplainCollectionHook returns the singleton for collecting the explain and
+// analyze flags of explained queries.
+func ExplainCollectionHook() ElementHook {
+	return exch
+}
+
 // graphAccumulator returns an element hook that keeps track of the graphs
-// listed in a statement.
-func graphAccumulator() ElementHook {
+// listed in a statement using the provided function to add them.
+func graphAccumulator(add func(*Statement, string)) ElementHook {
 	var hook ElementHook
 	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
 		if ce.IsSymbol() {
@@ -298,10 +522,10 @@ func graphAccumulator() ElementHook {
 		}
 		tkn := ce.Token()
 		switch tkn.Type {
-		case lexer.ItemComma:
+		case lexer.ItemComma, lexer.ItemInto:
 			return hook, nil
 		case lexer.ItemBinding:
-			st.AddGraph(strings.TrimSpace(tkn.Text))
+			add(st, strings.TrimSpace(tkn.Text))
 			return hook, nil
 		default:
 			return nil, fmt.Errorf("hook.GrapAccumulator requires a binding to refer to a graph, got %v instead", tkn)
@@ -310,6 +534,263 @@ func graphAccumulator() ElementHook {
 	return hook
 }
 
+// templateAccumulator returns an element hook that collects the nodes,
+// predicates, literals, and bindings of the clauses of a template. Each clause
+// is added to the statement using the provided function once its object has
+// been collected.
+func templateAccumulator(b literal.Builder, add func(*Statement)) ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		tkn := ce.Token()
+		switch tkn.Type {
+		case lexer.ItemNode, lexer.ItemPredicate, lexer.ItemLiteral, lexer.ItemBinding:
+		default:
+			return hook, nil
+		}
+		c := st.WorkingTemplateClause()
+		switch {
+		case c.S == nil && c.SBinding == "":
+			switch tkn.Type {
+			case lexer.ItemBinding:
+				c.SBinding = strings.TrimSpace(tkn.Text)
+			case lexer.ItemNode:
+				n, err := node.Parse(tkn.Text)
+				if err != nil {
+					return nil, err
+				}
+				c.S = n
+			default:
+				return nil, fmt.Errorf("hook.TemplateAccumulator requires a node or a binding as subject, got %v instead", tkn)
+			}
+		case c.P == nil && c.PBinding == "" && c.PAnchorBinding == "":
+			switch tkn.Type {
+			case lexer.ItemBinding:
+				c.PBinding = strings.TrimSpace(tkn.Text)
+			case lexer.ItemPredicate:
+				if p, err := predicate.Parse(tkn.Text); err == nil {
+					c.P = p
+					break
+				}
+				cmps := predicateRegexp.FindAllStringSubmatch(tkn.Text, 2)
+				if len(cmps) != 1 || len(cmps[0]) != 3 || !strings.HasPrefix(cmps[0][2], "?") {
+					return nil, fmt.Errorf("hook.TemplateAccumulator requires a predicate or a predicate with a bound time anchor, got %v instead", tkn)
+				}
+				c.PID, c.PAnchorBinding = cmps[0][1], cmps[0][2]
+			default:
+				return nil, fmt.Errorf("hook.TemplateAccumulator requires a predicate or a binding as predicate, got %v instead", tkn)
+			}
+		default:
+			if tkn.Type == lexer.ItemBinding {
+				c.OBinding = strings.TrimSpace(tkn.Text)
+			} else {
+				o, err := triple.ParseObject(tkn.Text, b)
+				if err != nil {
+					return nil, err
+				}
+				c.O = o
+			}
+			add(st)
+		}
+		return hook, nil
+	}
+	return hook
+}
+
+// parseSnapshotName returns the snapshot name in the provided quoted string
+// token.
+func parseSnapshotName(tkn *lexer.Token) (string, error) {
+	s, err := unquoteString(tkn)
+	if err != nil {
+		return "", err
+	}
+	if s == "" {
+		return "", fmt.Errorf("snapshot names cannot be empty")
+	}
+	return s, nil
+}
+
+// unquoteString returns the contents of a quoted string token.
+func unquoteString(tkn *lexer.Token) (string, error) {
+	if tkn.Type != lexer.ItemString {
+		return "", fmt.Errorf("expected a quoted string, got %v instead", tkn)
+	}
+	s, err := strconv.Unquote(tkn.Text)
+	if err != nil {
+		return "", fmt.Errorf("invalid quoted string %s with error %v", tkn.Text, err)
+	}
+	return s, nil
+}
+
+// snapshotName returns an element hook that collects the name of the snapshot
+// created or dropped by a statement.
+func snapshotName() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		tkn := ce.Token()
+		switch tkn.Type {
+		case lexer.ItemSnapshot, lexer.ItemOf:
+			return hook, nil
+		default:
+			name, err := parseSnapshotName(tkn)
+			if err != nil {
+				return nil, err
+			}
+			st.BindSnapshot(name)
+			return hook, nil
+		}
+	}
+	return hook
+}
+
+// graphSnapshot returns an element hook that sets the snapshot to use for the
+// last graph listed in a statement.
+func graphSnapshot() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		tkn := ce.Token()
+		switch tkn.Type {
+		case lexer.ItemAs, lexer.ItemOf, lexer.ItemSnapshot:
+			return hook, nil
+		default:
+			name, err := parseSnapshotName(tkn)
+			if err != nil {
+				return nil, err
+			}
+			gs := st.Graphs()
+			if len(gs) == 0 {
+				return nil, fmt.Errorf("snapshot %q does not refer to any graph", name)
+			}
+			st.SetGraphSnapshot(gs[len(gs)-1], name)
+			return hook, nil
+		}
+	}
+	return hook
+}
+
+// optionalGraphPattern returns an element hook that opens an optional graph
+// pattern when the OPTIONAL keyword is found and closes it with the matching
+// right bracket.
+func optionalGraphPattern() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		switch ce.Token().Type {
+		case lexer.ItemOptional:
+			st.StartOptionalGraphPattern()
+		case lexer.ItemRBracket:
+			st.EndOptionalGraphPattern()
+		}
+		return hook, nil
+	}
+	return hook
+}
+
+// existenceGraphPattern returns an element hook that opens an existence graph
+// pattern when the EXISTS keyword is found, and closes it at the end of the
+// pattern. A preceding NOT keyword opens a negated one instead.
+func existenceGraphPattern() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		switch ce.Token().Type {
+		case lexer.ItemNot:
+			st.StartExistenceGraphPattern(true)
+		case lexer.ItemExists:
+			if !st.InExistenceGraphPattern() {
+				st.StartExistenceGraphPattern(false)
+			}
+		case lexer.ItemRBracket:
+			st.EndExistenceGraphPattern()
+		}
+		return hook, nil
+	}
+	return hook
+}
+
+// unionGraphPattern returns an element hook that opens a new union of graph
+// patterns and its first alternative when the left bracket is found, and
+// closes the alternative with the matching right bracket.
+func unionGraphPattern() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		switch ce.Token().Type {
+		case lexer.ItemLBracket:
+			st.StartUnionGraphPattern()
+			st.StartUnionAlternative()
+		case lexer.ItemRBracket:
+			st.EndUnionAlternative()
+		}
+		return hook, nil
+	}
+	return hook
+}
+
+// unionAlternative returns an element hook that opens a new alternative of the
+// current union of graph patterns when the UNION keyword is found and closes
+// it with the matching right bracket.
+func unionAlternative() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		switch ce.Token().Type {
+		case lexer.ItemUnion:
+			st.StartUnionAlternative()
+		case lexer.ItemRBracket:
+			st.EndUnionAlternative()
+		}
+		return hook, nil
+	}
+	return hook
+}
+
+// filterExpression returns an element hook that collects the tokens of the
+// filter expression being parsed.
+func filterExpression() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		st.AddWorkingFilterElement(ce)
+		return hook, nil
+	}
+	return hook
+}
+
+// filterBuilder returns an element hook that builds the filter being parsed
+// when the right parenthesis closing its expression is found.
+func filterBuilder() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() || ce.Token().Type != lexer.ItemRPar {
+			return hook, nil
+		}
+		if err := st.AddWorkingFilter(); err != nil {
+			return nil, err
+		}
+		return hook, nil
+	}
+	return hook
+}
+
 // whereNextWorkingClause returns a clause hook to close the current graphs
 // clause and starts a new working one.
 func whereNextWorkingClause() ClauseHook {
@@ -550,6 +1031,72 @@ func wherePredicateClause() ElementHook {
 	return f
 }
 
+// predicatePath returns an element hook that turns the predicate of the
+// working graph clause into a property path. '+' walks one or more hops, '*'
+// zero or more, and {min, max} the provided number of hops. The shortest, path
+// and hops modifiers that may follow turn it into a path returning clause.
+func predicatePath() ElementHook {
+	var (
+		f            ElementHook
+		lastNopToken *lexer.Token
+	)
+	f = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return f, nil
+		}
+		tkn := ce.Token()
+		c := st.WorkingClause()
+		switch tkn.Type {
+		case lexer.ItemPlus:
+			c.PPath, c.PPathMin, c.PPathMax = true, 1, -1
+		case lexer.ItemMul:
+			c.PPath, c.PPathMin, c.PPathMax = true, 0, -1
+		case lexer.ItemLBracket:
+			c.PPath, c.PPathMin, c.PPathMax = true, 0, -1
+		case lexer.ItemLiteral:
+			l, err := literal.DefaultBuilder().Parse(tkn.Text)
+			if err != nil {
+				return nil, err
+			}
+			v, err := l.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("property path hop bounds require int64 literals; found %s instead", tkn.Text)
+			}
+			if lastNopToken != nil && lastNopToken.Type == lexer.ItemComma {
+				c.PPathMax = v
+			} else {
+				c.PPathMin = v
+			}
+		case lexer.ItemShortest:
+			if c.PPathShortest {
+				return nil, fmt.Errorf("shortest can only be specified once per property path")
+			}
+			c.PPathShortest = true
+		case lexer.ItemBinding:
+			if lastNopToken == nil {
+				return nil, fmt.Errorf("invalid property path binding %s", tkn.Text)
+			}
+			switch lastNopToken.Type {
+			case lexer.ItemPath:
+				if c.PPathBinding != "" {
+					return nil, fmt.Errorf("property path already bound to %s; cannot also bind it to %s", c.PPathBinding, tkn.Text)
+				}
+				c.PPathBinding = tkn.Text
+			case lexer.ItemHops:
+				if c.PPathHopsBinding != "" {
+					return nil, fmt.Errorf("property path hops already bound to %s; cannot also bind them to %s", c.PPathHopsBinding, tkn.Text)
+				}
+				c.PPathHopsBinding = tkn.Text
+			default:
+				return nil, fmt.Errorf("invalid property path binding %s", tkn.Text)
+			}
+		}
+		lastNopToken = tkn
+		return f, nil
+	}
+	return f
+}
+
 // whereObjectClause returns an element hook that updates the object
 // modifiers on the working graph clause.
 func whereObjectClause() ElementHook {
@@ -674,8 +1221,11 @@ func varAccumulator() ElementHook {
 			}
 		case lexer.ItemAs:
 			lastNopToken = tkn
-		case lexer.ItemSum, lexer.ItemCount:
+		case lexer.ItemSum, lexer.ItemCount, lexer.ItemAvg, lexer.ItemMin, lexer.ItemMax:
+			p.OP = tkn.Type
+		case lexer.ItemGroupConcat:
 			p.OP = tkn.Type
+			p.Separator = " "
 		case lexer.ItemDistinct:
 			p.Modifier = tkn.Type
 		case lexer.ItemComma:
@@ -688,6 +1238,67 @@ func varAccumulator() ElementHook {
 	return f
 }
 
+// scalarExpression returns an element hook that collects the tokens of the
+// scalar expression being parsed.
+func scalarExpression() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		st.workingExpression = append(st.workingExpression, ce)
+		return hook, nil
+	}
+	return hook
+}
+
+// projectionExpression returns an element hook that collects the tokens of a
+// projected scalar expression. Once the alias binding is found, it builds the
+// expression and adds the projection.
+func projectionExpression() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		switch tkn := ce.Token(); tkn.Type {
+		case lexer.ItemAs:
+		case lexer.ItemBinding:
+			ces := st.workingExpression
+			st.workingExpression = nil
+			e, err := NewScalarExpression(ces)
+			if err != nil {
+				return nil, err
+			}
+			p := st.WorkingProjection()
+			p.Expression, p.Alias = e, tkn.Text
+			st.AddWorkingProjection()
+		default:
+			st.workingExpression = append(st.workingExpression, ce)
+		}
+		return hook, nil
+	}
+	return hook
+}
+
+// groupConcatSeparator returns an element hook that sets the separator of the
+// working projection.
+func groupConcatSeparator() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() || ce.Token().Type != lexer.ItemString {
+			return hook, nil
+		}
+		sep, err := unquoteString(ce.Token())
+		if err != nil {
+			return nil, err
+		}
+		st.WorkingProjection().Separator = sep
+		return hook, nil
+	}
+	return hook
+}
+
 // bindingsGraphChecker validate that all input bindings are provided by the
 // graph pattern.
 func bindingsGraphChecker() ClauseHook {
@@ -695,12 +1306,47 @@ func bindingsGraphChecker() ClauseHook {
 	f = func(s *Statement, _ Symbol) (ClauseHook, error) {
 		// Force working projection flush.
 		s.AddWorkingProjection()
+		if len(s.OptionalGraphPatternClauses()) > 0 && len(s.SortedGraphPatternClauses()) == 0 && len(s.UnionGraphPatterns()) == 0 && len(s.Subqueries()) == 0 {
+			return nil, fmt.Errorf("optional graph patterns require at least one non optional clause, union, or subquery in the where clause")
+		}
+		if len(s.ExistenceGraphPatterns()) > 0 && len(s.SortedGraphPatternClauses()) == 0 && len(s.UnionGraphPatterns()) == 0 && len(s.Subqueries()) == 0 {
+			return nil, fmt.Errorf("existence graph patterns require at least one non optional clause, union, or subquery in the where clause")
+		}
 		bs := s.BindingsMap()
 		for _, b := range s.InputBindings() {
 			if _, ok := bs[b]; !ok {
 				return nil, fmt.Errorf("specified binding %s not found in where clause, only %v bindings are available", b, s.Bindings())
 			}
 		}
+		cls := s.allGraphClauses()
+		for _, e := range s.ExistenceGraphPatterns() {
+			cls = append(cls, e.Clauses...)
+		}
+		for _, c := range cls {
+			if c == nil {
+				continue
+			}
+			if err := c.ValidatePath(); err != nil {
+				return nil, err
+			}
+		}
+		for _, p := range s.Projections() {
+			if p.Expression == nil {
+				continue
+			}
+			for _, b := range p.Expression.Bindings() {
+				if _, ok := bs[b]; !ok {
+					return nil, fmt.Errorf("binding %s used in the expression of %s not found in where clause, only %v bindings are available", b, p.Alias, s.Bindings())
+				}
+			}
+		}
+		for _, f := range s.Filters() {
+			for _, b := range f.Bindings {
+				if _, ok := bs[b]; !ok {
+					return nil, fmt.Errorf("filter binding %s not found in where clause, only %v bindings are available", b, s.Bindings())
+				}
+			}
+		}
 		return f, nil
 	}
 	return f
@@ -750,7 +1396,11 @@ func groupByBindingsChecker() ClauseHook {
 				continue
 			}
 			if len(s.groupBy) > 0 && prj.OP == lexer.ItemError {
-				return nil, fmt.Errorf("Binding %q not listed on GROUP BY requires an aggregation function", prj.Binding)
+				s := prj.Binding
+				if s == "" {
+					s = prj.Alias
+				}
+				return nil, fmt.Errorf("Binding %q not listed on GROUP BY requires an aggregation function", s)
 			}
 			if len(s.groupBy) == 0 && prj.OP != lexer.ItemError {
 				s := prj.Alias
@@ -886,6 +1536,143 @@ func limitCollection() ElementHook {
 	return f
 }
 
+// describeCollection returns an element hook that collects the node to
+// describe and the optional depth of describe statements.
+func describeCollection() ElementHook {
+	var f func(st *Statement, ce ConsumedElement) (ElementHook, error)
+	f = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return f, nil
+		}
+		switch ce.token.Type {
+		case lexer.ItemNode:
+			n, err := ToNode(ce)
+			if err != nil {
+				return nil, err
+			}
+			st.BindDescribedNode(n)
+		case lexer.ItemLiteral:
+			l, err := literal.DefaultBuilder().Parse(ce.token.Text)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse depth literal %q with error %v", ce.token.Text, err)
+			}
+			d, err := l.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("depth required an int64 value; found %s instead", l)
+			}
+			if d < 1 {
+				return nil, fmt.Errorf("depth must be at least 1; found %d instead", d)
+			}
+			st.SetDescribeDepth(d)
+		}
+		return f, nil
+	}
+	return f
+}
+
+// explainCollection returns an element hook that flags the statement to be
+// explained, and whether the explanation needs to be analyzed.
+func explainCollection() ElementHook {
+	var f func(st *Statement, ce ConsumedElement) (ElementHook, error)
+	f = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return f, nil
+		}
+		switch ce.token.Type {
+		case lexer.ItemExplain:
+			st.BindExplain(false)
+		case lexer.ItemAnalyze:
+			st.BindExplain(true)
+		}
+		return f, nil
+	}
+	return f
+}
+
+// subqueryStart returns a clause hook that saves the state of the statement
+// being parsed, so the hooks of the nested query populate a clean one.
+func subqueryStart() ClauseHook {
+	var f ClauseHook
+	f = func(s *Statement, _ Symbol) (ClauseHook, error) {
+		s.StartSubquery()
+		return f, nil
+	}
+	return f
+}
+
+// subqueryEnd returns a clause hook that checks the group by clause of the
+// parsed subquery, and adds it to the enclosing statement.
+func subqueryEnd() ClauseHook {
+	var f ClauseHook
+	f = func(s *Statement, sym Symbol) (ClauseHook, error) {
+		if _, err := gcch(s, sym); err != nil {
+			return nil, err
+		}
+		if err := s.EndSubquery(); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	return f
+}
+
+// offsetCollection collects the number of rows to skip listed in the offset
+// clause.
+func offsetCollection() ElementHook {
+	var f func(st *Statement, ce ConsumedElement) (ElementHook, error)
+	f = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() || ce.token.Type == lexer.ItemOffset {
+			return f, nil
+		}
+		if ce.token.Type != lexer.ItemLiteral {
+			return nil, fmt.Errorf("offset clause required an int64 literal; found %v instead", ce.token)
+		}
+		l, err := literal.DefaultBuilder().Parse(ce.token.Text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse offset literal %q with error %v", ce.token.Text, err)
+		}
+		ov, err := l.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("offset required an int64 value; found %s instead", l)
+		}
+		if ov < 0 {
+			return nil, fmt.Errorf("offset cannot be negative; found %d instead", ov)
+		}
+		st.offsetSet, st.offset = true, ov
+		return f, nil
+	}
+	return f
+}
+
+// cursorCollection decodes the continuation token of the cursor clause. The
+// token must have been returned by the same query, since it is only valid for
+// the sort configuration it was created for.
+func cursorCollection() ElementHook {
+	var f func(st *Statement, ce ConsumedElement) (ElementHook, error)
+	f = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() || ce.token.Type == lexer.ItemCursor {
+			return f, nil
+		}
+		tkn, err := unquoteString(ce.token)
+		if err != nil {
+			return nil, err
+		}
+		if len(st.orderBy) == 0 {
+			return nil, fmt.Errorf("continuation tokens can only be used by queries with an order by clause")
+		}
+		c, err := table.ParseCursor(tkn)
+		if err != nil {
+			return nil, err
+		}
+		if !c.Matches(st.TotalOrderConfig()) {
+			return nil, fmt.Errorf("continuation token %s was not created for this query", ce.token.Text)
+		}
+		st.cursor = c
+		return f, nil
+	}
+	return f
+}
+
 // collectGlobalBounds collects the global time bounds that should be applied
 // to all temporal predicates.
 func collectGlobalBounds() ElementHook {
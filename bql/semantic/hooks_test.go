@@ -91,6 +91,102 @@ func TestDataAccumulatorHook(t *testing.T) {
 	}
 }
 
+func TestTemplateAccumulatorHook(t *testing.T) {
+	st := &Statement{}
+	ces := []ConsumedElement{
+		NewConsumedSymbol("FOO"),
+		NewConsumedToken(&lexer.Token{
+			Type: lexer.ItemBinding,
+			Text: "?s",
+		}),
+		NewConsumedToken(&lexer.Token{
+			Type: lexer.ItemPredicate,
+			Text: `"p"@[]`,
+		}),
+		NewConsumedToken(&lexer.Token{
+			Type: lexer.ItemBinding,
+			Text: "?o",
+		}),
+		NewConsumedToken(&lexer.Token{
+			Type: lexer.ItemDot,
+			Text: ".",
+		}),
+		NewConsumedToken(&lexer.Token{
+			Type: lexer.ItemNode,
+			Text: "/_<s>",
+		}),
+		NewConsumedToken(&lexer.Token{
+			Type: lexer.ItemBinding,
+			Text: "?p",
+		}),
+		NewConsumedToken(&lexer.Token{
+			Type: lexer.ItemBinding,
+			Text: "?s",
+		}),
+		NewConsumedToken(&lexer.Token{
+			Type: lexer.ItemDot,
+			Text: ".",
+		}),
+		NewConsumedToken(&lexer.Token{
+			Type: lexer.ItemBinding,
+			Text: "?o",
+		}),
+		NewConsumedToken(&lexer.Token{
+			Type: lexer.ItemPredicate,
+			Text: `"p"@[?t]`,
+		}),
+		NewConsumedToken(&lexer.Token{
+			Type: lexer.ItemBinding,
+			Text: "?s",
+		}),
+	}
+	var (
+		hook ElementHook
+		err  error
+	)
+	hook = templateAccumulator(literal.DefaultBuilder(), (*Statement).AddWorkingTemplateClause)
+	for _, ce := range ces {
+		hook, err = hook(st, ce)
+		if err != nil {
+			t.Fatalf("semantic.TemplateAccumulator hook should have never failed for %v with error %v", ce, err)
+		}
+	}
+	var got []string
+	for _, c := range st.TemplateClauses() {
+		got = append(got, c.String())
+	}
+	if want := []string{`?s "p"@[] ?o`, "/_<s> ?p ?s", `?o "p"@[?t] ?s`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("semantic.TemplateAccumulator hook returned the wrong template; got %v, want %v", got, want)
+	}
+	if got, want := st.InputBindings(), []string{"?s", "?o", "?p", "?t"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("semantic.TemplateAccumulator hook projected the wrong bindings; got %v, want %v", got, want)
+	}
+
+	st = &Statement{}
+	hook = templateAccumulator(literal.DefaultBuilder(), (*Statement).AddWorkingDeleteTemplateClause)
+	for _, ce := range ces[:4] {
+		hook, err = hook(st, ce)
+		if err != nil {
+			t.Fatalf("semantic.TemplateAccumulator hook should have never failed for %v with error %v", ce, err)
+		}
+	}
+	if got, want := len(st.DeleteTemplateClauses()), 1; got != want {
+		t.Errorf("semantic.TemplateAccumulator hook returned the wrong number of delete template clauses; got %d, want %d", got, want)
+	}
+	if got, want := len(st.TemplateClauses()), 0; got != want {
+		t.Errorf("semantic.TemplateAccumulator hook returned the wrong number of template clauses; got %d, want %d", got, want)
+	}
+
+	hook = templateAccumulator(literal.DefaultBuilder(), (*Statement).AddWorkingTemplateClause)
+	ce := NewConsumedToken(&lexer.Token{
+		Type: lexer.ItemLiteral,
+		Text: `"foo"^^type:text`,
+	})
+	if _, err := hook(&Statement{}, ce); err == nil {
+		t.Errorf("semantic.TemplateAccumulator hook should have failed to use a literal as subject")
+	}
+}
+
 func TestSemanticAcceptInsertDelete(t *testing.T) {
 	st := &Statement{}
 	ces := []ConsumedElement{
@@ -113,7 +209,7 @@ func TestSemanticAcceptInsertDelete(t *testing.T) {
 		hook ElementHook
 		err  error
 	)
-	hook = graphAccumulator()
+	hook = graphAccumulator((*Statement).AddGraph)
 	for _, ce := range ces {
 		hook, err = hook(st, ce)
 		if err != nil {
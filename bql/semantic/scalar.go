@@ -0,0 +1,502 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semantic
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/badwolf/bql/lexer"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// ScalarExpression computes a value for each row of a results table.
+type ScalarExpression interface {
+	// Evaluate computes the value of the expression for the provided row. It
+	// returns an error if it could not be evaluated for the provided row.
+	Evaluate(r table.Row) (*table.Cell, error)
+
+	// Bindings returns the bindings the expression needs to be evaluated.
+	Bindings() []string
+}
+
+// bindingScalar returns the value of a binding.
+type bindingScalar struct {
+	b string
+}
+
+// Evaluate returns the cell of the binding in the provided row.
+func (s *bindingScalar) Evaluate(r table.Row) (*table.Cell, error) {
+	c, ok := r[s.b]
+	if !ok {
+		return nil, fmt.Errorf("scalar expressions require the binding value for %q for row %q to exist", s.b, r)
+	}
+	return c, nil
+}
+
+// Bindings returns the binding.
+func (s *bindingScalar) Bindings() []string {
+	return []string{s.b}
+}
+
+// constantScalar always returns the same value.
+type constantScalar struct {
+	c *table.Cell
+}
+
+// Evaluate returns the constant value.
+func (s *constantScalar) Evaluate(r table.Row) (*table.Cell, error) {
+	return s.c, nil
+}
+
+// Bindings returns no bindings since constants do not depend on the row.
+func (s *constantScalar) Bindings() []string {
+	return nil
+}
+
+// arithmeticScalar applies an arithmetic operation to two expressions.
+type arithmeticScalar struct {
+	op   lexer.TokenType
+	l, r ScalarExpression
+}
+
+// Evaluate computes the arithmetic operation for the provided row.
+func (s *arithmeticScalar) Evaluate(r table.Row) (*table.Cell, error) {
+	ls, err := evaluateLiterals(operatorName(s.op), r, s.l, s.r)
+	if err != nil || ls == nil {
+		return &table.Cell{}, err
+	}
+	l, err := arithmetic(s.op, ls[0], ls[1])
+	if err != nil {
+		return nil, err
+	}
+	return &table.Cell{L: l}, nil
+}
+
+// Bindings returns the bindings used by both operands.
+func (s *arithmeticScalar) Bindings() []string {
+	return append(s.l.Bindings(), s.r.Bindings()...)
+}
+
+// functionScalar applies a function to a list of expressions.
+type functionScalar struct {
+	fn   lexer.TokenType
+	args []ScalarExpression
+}
+
+// Evaluate computes the value of the function for the provided row.
+func (s *functionScalar) Evaluate(r table.Row) (*table.Cell, error) {
+	ls, err := evaluateLiterals(s.fn.String(), r, s.args...)
+	if err != nil || ls == nil {
+		return &table.Cell{}, err
+	}
+	l, err := function(s.fn, ls)
+	if err != nil {
+		return nil, err
+	}
+	return &table.Cell{L: l}, nil
+}
+
+// Bindings returns the bindings used by all the arguments.
+func (s *functionScalar) Bindings() []string {
+	var bs []string
+	for _, a := range s.args {
+		bs = append(bs, a.Bindings()...)
+	}
+	return bs
+}
+
+// evaluateLiterals evaluates the provided expressions and returns the literals
+// they hold. It returns no literals if any of them is null, and an error if
+// any of them is not a literal.
+func evaluateLiterals(name string, r table.Row, es ...ScalarExpression) ([]*literal.Literal, error) {
+	var ls []*literal.Literal
+	for _, e := range es {
+		c, err := e.Evaluate(r)
+		if err != nil {
+			return nil, err
+		}
+		if c.IsNull() {
+			return nil, nil
+		}
+		if c.L == nil {
+			return nil, fmt.Errorf("%s can only be applied to literals; found %s instead", name, c)
+		}
+		ls = append(ls, c.L)
+	}
+	return ls, nil
+}
+
+// operatorName returns the symbol of an arithmetic operation.
+func operatorName(op lexer.TokenType) string {
+	switch op {
+	case lexer.ItemPlus:
+		return "+"
+	case lexer.ItemMinus:
+		return "-"
+	case lexer.ItemMul:
+		return "*"
+	case lexer.ItemDiv:
+		return "/"
+	default:
+		return op.String()
+	}
+}
+
+// isNumeric returns true if the literal holds an int64 or a float64.
+func isNumeric(l *literal.Literal) bool {
+	return l.Type() == literal.Int64 || l.Type() == literal.Float64
+}
+
+// toFloat64 returns the value of a numeric literal as a float64.
+func toFloat64(l *literal.Literal) float64 {
+	if l.Type() == literal.Int64 {
+		i, _ := l.Int64()
+		return float64(i)
+	}
+	f, _ := l.Float64()
+	return f
+}
+
+// arithmetic applies the arithmetic operation to the provided literals. The
+// result is an int64 if both literals are int64, and a float64 otherwise. It
+// returns an error if the result of an int64 operation overflows.
+func arithmetic(op lexer.TokenType, l, r *literal.Literal) (*literal.Literal, error) {
+	if !isNumeric(l) || !isNumeric(r) {
+		return nil, fmt.Errorf("cannot apply %s to literals of type %s and %s; only int64 and float64 literals are supported", operatorName(op), l.Type(), r.Type())
+	}
+	if l.Type() == literal.Int64 && r.Type() == literal.Int64 {
+		li, _ := l.Int64()
+		ri, _ := r.Int64()
+		var (
+			v        int64
+			overflow bool
+		)
+		switch op {
+		case lexer.ItemPlus:
+			v = li + ri
+			overflow = (ri > 0 && v < li) || (ri < 0 && v > li)
+		case lexer.ItemMinus:
+			v = li - ri
+			overflow = (ri > 0 && v > li) || (ri < 0 && v < li)
+		case lexer.ItemMul:
+			v = li * ri
+			overflow = li != 0 && (v/li != ri || (li == -1 && ri == math.MinInt64))
+		case lexer.ItemDiv:
+			if ri == 0 {
+				return nil, errors.New("division by zero")
+			}
+			v = li / ri
+			overflow = li == math.MinInt64 && ri == -1
+		default:
+			return nil, fmt.Errorf("unknown arithmetic operation %s", op)
+		}
+		if overflow {
+			return nil, fmt.Errorf("int64 overflow applying %s to %d and %d", operatorName(op), li, ri)
+		}
+		return literal.DefaultBuilder().Build(literal.Int64, v)
+	}
+	lf, rf := toFloat64(l), toFloat64(r)
+	var v float64
+	switch op {
+	case lexer.ItemPlus:
+		v = lf + rf
+	case lexer.ItemMinus:
+		v = lf - rf
+	case lexer.ItemMul:
+		v = lf * rf
+	case lexer.ItemDiv:
+		if rf == 0 {
+			return nil, errors.New("division by zero")
+		}
+		v = lf / rf
+	default:
+		return nil, fmt.Errorf("unknown arithmetic operation %s", op)
+	}
+	return literal.DefaultBuilder().Build(literal.Float64, v)
+}
+
+// textArgument returns the value of a text literal passed to a function.
+func textArgument(fn lexer.TokenType, l *literal.Literal) (string, error) {
+	if l.Type() != literal.Text {
+		return "", fmt.Errorf("%s requires a text literal; found a literal of type %s instead", fn, l.Type())
+	}
+	return l.Text()
+}
+
+// int64Argument returns the value of an int64 literal passed to a function.
+func int64Argument(fn lexer.TokenType, l *literal.Literal) (int64, error) {
+	if l.Type() != literal.Int64 {
+		return 0, fmt.Errorf("%s requires an int64 literal; found a literal of type %s instead", fn, l.Type())
+	}
+	return l.Int64()
+}
+
+// function applies the function to the provided literals.
+func function(fn lexer.TokenType, args []*literal.Literal) (*literal.Literal, error) {
+	b := literal.DefaultBuilder()
+	switch fn {
+	case lexer.ItemLower:
+		s, err := textArgument(fn, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return b.Build(literal.Text, strings.ToLower(s))
+	case lexer.ItemStrlen:
+		s, err := textArgument(fn, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return b.Build(literal.Int64, int64(utf8.RuneCountInString(s)))
+	case lexer.ItemSubstr:
+		s, err := textArgument(fn, args[0])
+		if err != nil {
+			return nil, err
+		}
+		start, err := int64Argument(fn, args[1])
+		if err != nil {
+			return nil, err
+		}
+		rs := []rune(s)
+		// The bounds are clamped to the text before being added, so large
+		// values cannot overflow.
+		l, from := int64(len(rs)), int64(0)
+		if start > 1 {
+			from = start - 1
+		}
+		if from > l {
+			from = l
+		}
+		to := l
+		if len(args) == 3 {
+			n, err := int64Argument(fn, args[2])
+			if err != nil {
+				return nil, err
+			}
+			if n < 0 {
+				return nil, fmt.Errorf("%s requires a non negative length; found %d instead", fn, n)
+			}
+			if start < 1 {
+				// The positions before the first character count towards the
+				// length.
+				if n += start; n > 0 {
+					n--
+				} else {
+					n = 0
+				}
+			}
+			if n < to-from {
+				to = from + n
+			}
+		}
+		return b.Build(literal.Text, string(rs[from:to]))
+	case lexer.ItemConcat:
+		var ss []string
+		for _, a := range args {
+			s, err := textArgument(fn, a)
+			if err != nil {
+				return nil, err
+			}
+			ss = append(ss, s)
+		}
+		return b.Build(literal.Text, strings.Join(ss, ""))
+	case lexer.ItemAbs:
+		switch args[0].Type() {
+		case literal.Int64:
+			i, _ := args[0].Int64()
+			if i == math.MinInt64 {
+				return nil, fmt.Errorf("int64 overflow applying %s to %d", fn, i)
+			}
+			if i < 0 {
+				i = -i
+			}
+			return b.Build(literal.Int64, i)
+		case literal.Float64:
+			f, _ := args[0].Float64()
+			if f < 0 {
+				f = -f
+			}
+			return b.Build(literal.Float64, f)
+		default:
+			return nil, fmt.Errorf("%s requires an int64 or float64 literal; found a literal of type %s instead", fn, args[0].Type())
+		}
+	default:
+		return nil, fmt.Errorf("unknown function %s", fn)
+	}
+}
+
+// functionArity contains the minimum and maximum number of arguments of each
+// function. A negative maximum means there is no maximum.
+var functionArity = map[lexer.TokenType][2]int{
+	lexer.ItemLower:  {1, 1},
+	lexer.ItemStrlen: {1, 1},
+	lexer.ItemSubstr: {2, 3},
+	lexer.ItemConcat: {1, -1},
+	lexer.ItemAbs:    {1, 1},
+}
+
+// NewScalarExpression builds a scalar expression given a sequence of tokens.
+// Multiplication and division take precedence over addition and subtraction.
+// It will return a descriptive error if it could not build it.
+func NewScalarExpression(ces []ConsumedElement) (ScalarExpression, error) {
+	p := &scalarParser{ces: ces}
+	e, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(ces) {
+		return nil, fmt.Errorf("failed to consume all tokens; left over %v", ces[p.pos:])
+	}
+	return e, nil
+}
+
+// scalarParser keeps track of the tokens consumed while building a scalar
+// expression.
+type scalarParser struct {
+	ces []ConsumedElement
+	pos int
+}
+
+// peek returns the type of the next token without consuming it.
+func (p *scalarParser) peek() lexer.TokenType {
+	if p.pos >= len(p.ces) {
+		return lexer.ItemEOF
+	}
+	return p.ces[p.pos].Token().Type
+}
+
+// next consumes the next token.
+func (p *scalarParser) next() (*lexer.Token, error) {
+	if p.pos >= len(p.ces) {
+		return nil, errors.New("incomplete scalar expression")
+	}
+	tkn := p.ces[p.pos].Token()
+	p.pos++
+	return tkn, nil
+}
+
+// expect consumes the next token and fails if it is not of the provided type.
+func (p *scalarParser) expect(tt lexer.TokenType) error {
+	tkn, err := p.next()
+	if err != nil {
+		return err
+	}
+	if tkn.Type != tt {
+		return fmt.Errorf("expected %s in scalar expression; found %s instead", tt, tkn.Type)
+	}
+	return nil
+}
+
+// expression builds a sequence of additions and subtractions.
+func (p *scalarParser) expression() (ScalarExpression, error) {
+	e, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == lexer.ItemPlus || op == lexer.ItemMinus; op = p.peek() {
+		p.pos++
+		r, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		e = &arithmeticScalar{op: op, l: e, r: r}
+	}
+	return e, nil
+}
+
+// term builds a sequence of multiplications and divisions.
+func (p *scalarParser) term() (ScalarExpression, error) {
+	e, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == lexer.ItemMul || op == lexer.ItemDiv; op = p.peek() {
+		p.pos++
+		r, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		e = &arithmeticScalar{op: op, l: e, r: r}
+	}
+	return e, nil
+}
+
+// factor builds a binding, a constant, a function call, or a parenthesized
+// expression.
+func (p *scalarParser) factor() (ScalarExpression, error) {
+	tkn, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch tkn.Type {
+	case lexer.ItemBinding:
+		return &bindingScalar{b: tkn.Text}, nil
+	case lexer.ItemLiteral:
+		c, err := constantCell(tkn)
+		if err != nil {
+			return nil, err
+		}
+		return &constantScalar{c: c}, nil
+	case lexer.ItemString:
+		s, err := unquoteString(tkn)
+		if err != nil {
+			return nil, err
+		}
+		l, err := literal.DefaultBuilder().Build(literal.Text, s)
+		if err != nil {
+			return nil, err
+		}
+		return &constantScalar{c: &table.Cell{L: l}}, nil
+	case lexer.ItemLPar:
+		e, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(lexer.ItemRPar); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+	arity, ok := functionArity[tkn.Type]
+	if !ok {
+		return nil, fmt.Errorf("cannot use %s in a scalar expression", tkn.Type)
+	}
+	if err := p.expect(lexer.ItemLPar); err != nil {
+		return nil, err
+	}
+	f := &functionScalar{fn: tkn.Type}
+	for {
+		a, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		f.args = append(f.args, a)
+		if p.peek() != lexer.ItemComma {
+			break
+		}
+		p.pos++
+	}
+	if err := p.expect(lexer.ItemRPar); err != nil {
+		return nil, err
+	}
+	if n := len(f.args); n < arity[0] || (arity[1] >= 0 && n > arity[1]) {
+		return nil, fmt.Errorf("%s does not accept %d arguments", tkn.Type, n)
+	}
+	return f, nil
+}
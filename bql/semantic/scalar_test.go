@@ -0,0 +1,180 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semantic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/bql/lexer"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/triple/node"
+)
+
+// scalarTokens returns the consumed tokens of the provided expression.
+func scalarTokens(t *testing.T, s string) []ConsumedElement {
+	var ces []ConsumedElement
+	for tkn := range lexer.New(s, 0) {
+		switch tkn.Type {
+		case lexer.ItemEOF:
+			return ces
+		case lexer.ItemError:
+			t.Fatalf("lexer.New(%q) failed with error %v", s, tkn.ErrorMessage)
+		}
+		cp := tkn
+		ces = append(ces, NewConsumedToken(&cp))
+	}
+	return ces
+}
+
+func TestScalarExpressionEvaluate(t *testing.T) {
+	r := table.Row{
+		"?a":    &table.Cell{L: testLiteral(t, `"2"^^type:int64`)},
+		"?b":    &table.Cell{L: testLiteral(t, `"3"^^type:int64`)},
+		"?one":  &table.Cell{L: testLiteral(t, `"1"^^type:int64`)},
+		"?max":  &table.Cell{L: testLiteral(t, `"9223372036854775807"^^type:int64`)},
+		"?min":  &table.Cell{L: testLiteral(t, `"-9223372036854775808"^^type:int64`)},
+		"?f":    &table.Cell{L: testLiteral(t, `"-1.5"^^type:float64`)},
+		"?name": &table.Cell{L: testLiteral(t, `"Joe Doe"^^type:text`)},
+		"?null": &table.Cell{},
+	}
+	testTable := []struct {
+		expr string
+		want string
+	}{
+		{`?a`, `"2"^^type:int64`},
+		{`?a + ?b`, `"5"^^type:int64`},
+		{`?a + ?b * ?b`, `"11"^^type:int64`},
+		{`(?a + ?b) * ?b`, `"15"^^type:int64`},
+		{`?b - ?a - ?a`, `"-1"^^type:int64`},
+		{`?b / ?a`, `"1"^^type:int64`},
+		{`?a * ?f`, `"-3"^^type:float64`},
+		{`?a + "1"^^type:int64`, `"3"^^type:int64`},
+		{`abs(?f)`, `"1.5"^^type:float64`},
+		{`abs(?a - ?b)`, `"1"^^type:int64`},
+		{`lower(?name)`, `"joe doe"^^type:text`},
+		{`strlen(?name) + ?a`, `"9"^^type:int64`},
+		{`substr(?name, "5"^^type:int64)`, `"Doe"^^type:text`},
+		{`substr(?name, "1"^^type:int64, "3"^^type:int64)`, `"Joe"^^type:text`},
+		{`substr(?name, "6"^^type:int64, "10"^^type:int64)`, `"oe"^^type:text`},
+		{`substr(?name, "0"^^type:int64, "2"^^type:int64)`, `"J"^^type:text`},
+		{`substr(?name, "-2"^^type:int64, "5"^^type:int64)`, `"Jo"^^type:text`},
+		{`substr(?name, "-2"^^type:int64, "2"^^type:int64)`, `""^^type:text`},
+		{`substr(?name, "9"^^type:int64, "1"^^type:int64)`, `""^^type:text`},
+		{`substr(?name, "2"^^type:int64, "9223372036854775807"^^type:int64)`, `"oe Doe"^^type:text`},
+		{`substr(?name, "9223372036854775807"^^type:int64, "9223372036854775807"^^type:int64)`, `""^^type:text`},
+		{`substr(?name, "-9223372036854775808"^^type:int64, "1"^^type:int64)`, `""^^type:text`},
+		{`substr(?name, "-9223372036854775808"^^type:int64, "0"^^type:int64)`, `""^^type:text`},
+		{`substr(?name, "-9223372036854775808"^^type:int64, "9223372036854775807"^^type:int64)`, `""^^type:text`},
+		{`substr(?name, "-9223372036854775808"^^type:int64)`, `"Joe Doe"^^type:text`},
+		{`concat(lower(?name), ", ", "Jr")`, `"joe doe, Jr"^^type:text`},
+		{`?null + ?a`, `<NULL>`},
+		{`?max - ?a + ?a`, `"9223372036854775807"^^type:int64`},
+		{`?min + ?max`, `"-1"^^type:int64`},
+		{`?min / ?b`, `"-3074457345618258602"^^type:int64`},
+		{`?min * ?one`, `"-9223372036854775808"^^type:int64`},
+		{`?one - ?max`, `"-9223372036854775806"^^type:int64`},
+	}
+	for _, entry := range testTable {
+		e, err := NewScalarExpression(scalarTokens(t, entry.expr))
+		if err != nil {
+			t.Errorf("NewScalarExpression(%q) failed with error %v", entry.expr, err)
+			continue
+		}
+		c, err := e.Evaluate(r)
+		if err != nil {
+			t.Errorf("%q.Evaluate(%v) failed with error %v", entry.expr, r, err)
+			continue
+		}
+		if got := c.String(); got != entry.want {
+			t.Errorf("%q.Evaluate(%v) returned the wrong value; got %s, want %s", entry.expr, r, got, entry.want)
+		}
+	}
+}
+
+func TestScalarExpressionEvaluateErrors(t *testing.T) {
+	n, err := node.Parse("/u<joe>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := table.Row{
+		"?a":     &table.Cell{L: testLiteral(t, `"2"^^type:int64`)},
+		"?zero":  &table.Cell{L: testLiteral(t, `"0"^^type:int64`)},
+		"?minus": &table.Cell{L: testLiteral(t, `"-1"^^type:int64`)},
+		"?max":   &table.Cell{L: testLiteral(t, `"9223372036854775807"^^type:int64`)},
+		"?min":   &table.Cell{L: testLiteral(t, `"-9223372036854775808"^^type:int64`)},
+		"?name":  &table.Cell{L: testLiteral(t, `"Joe"^^type:text`)},
+		"?n":     &table.Cell{N: n},
+	}
+	testTable := []struct {
+		expr string
+		want string
+	}{
+		{`?name + ?a`, `cannot apply + to literals of type text and int64`},
+		{`?a / ?zero`, `division by zero`},
+		{`?max + ?a`, `int64 overflow applying + to 9223372036854775807 and 2`},
+		{`?min + ?min`, `int64 overflow applying +`},
+		{`?min - ?a`, `int64 overflow applying -`},
+		{`?a - ?min`, `int64 overflow applying -`},
+		{`?max * ?a`, `int64 overflow applying *`},
+		{`?min * ?minus`, `int64 overflow applying *`},
+		{`?minus * ?min`, `int64 overflow applying *`},
+		{`?min / ?minus`, `int64 overflow applying /`},
+		{`abs(?min)`, `int64 overflow applying ABS`},
+		{`lower(?a)`, `LOWER requires a text literal; found a literal of type int64`},
+		{`abs(?name)`, `ABS requires an int64 or float64 literal`},
+		{`substr(?name, ?name)`, `SUBSTR requires an int64 literal`},
+		{`?n + ?a`, `+ can only be applied to literals`},
+		{`?missing + ?a`, `?missing`},
+	}
+	for _, entry := range testTable {
+		e, err := NewScalarExpression(scalarTokens(t, entry.expr))
+		if err != nil {
+			t.Errorf("NewScalarExpression(%q) failed with error %v", entry.expr, err)
+			continue
+		}
+		if _, err := e.Evaluate(r); err == nil || !strings.Contains(err.Error(), entry.want) {
+			t.Errorf("%q.Evaluate(%v) returned the wrong error; got %v, want it to contain %q", entry.expr, r, err, entry.want)
+		}
+	}
+}
+
+func TestNewScalarExpressionErrors(t *testing.T) {
+	testTable := []string{
+		`?a +`,
+		`(?a + ?b`,
+		`?a ?b`,
+		`lower(?a, ?b)`,
+		`substr(?a)`,
+		`strlen ?a`,
+		`/u<joe>`,
+	}
+	for _, entry := range testTable {
+		if _, err := NewScalarExpression(scalarTokens(t, entry)); err == nil {
+			t.Errorf("NewScalarExpression(%q) should have failed", entry)
+		}
+	}
+}
+
+func TestScalarExpressionBindings(t *testing.T) {
+	e, err := NewScalarExpression(scalarTokens(t, `concat(?a, substr(?b, ?c)) + "1"^^type:int64`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := e.Bindings(), []string{"?a", "?b", "?c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Bindings returned the wrong bindings; got %v, want %v", got, want)
+	}
+}
@@ -19,6 +19,7 @@
 package semantic
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"sort"
@@ -46,6 +47,28 @@ const (
 	Create
 	// Drop statement.
 	Drop
+	// Begin statement.
+	Begin
+	// Commit statement.
+	Commit
+	// Rollback statement.
+	Rollback
+	// CreateSnapshot statement.
+	CreateSnapshot
+	// DropSnapshot statement.
+	DropSnapshot
+	// Construct statement.
+	Construct
+	// Update statement.
+	Update
+	// Ask statement.
+	Ask
+	// Describe statement.
+	Describe
+	// ShowGraphs statement.
+	ShowGraphs
+	// ShowStats statement.
+	ShowStats
 )
 
 // String provides a readable version of the StatementType.
@@ -61,6 +84,28 @@ func (t StatementType) String() string {
 		return "CREATE"
 	case Drop:
 		return "DROP"
+	case Begin:
+		return "BEGIN"
+	case Commit:
+		return "COMMIT"
+	case Rollback:
+		return "ROLLBACK"
+	case CreateSnapshot:
+		return "CREATE SNAPSHOT"
+	case DropSnapshot:
+		return "DROP SNAPSHOT"
+	case Construct:
+		return "CONSTRUCT"
+	case Update:
+		return "UPDATE"
+	case Ask:
+		return "ASK"
+	case Describe:
+		return "DESCRIBE"
+	case ShowGraphs:
+		return "SHOW GRAPHS"
+	case ShowStats:
+		return "SHOW STATS"
 	default:
 		return "UNKNOWN"
 	}
@@ -70,18 +115,42 @@ func (t StatementType) String() string {
 type Statement struct {
 	sType                     StatementType
 	graphs                    []string
+	outputGraphs              []string
+	graphSnapshots            map[string]string
+	snapshot                  string
+	describedNode             *node.Node
+	describeDepth             int64
+	explain                   bool
+	explainAnalyze            bool
 	data                      []*triple.Triple
+	template                  []*TemplateClause
+	deleteTemplate            []*TemplateClause
+	workingTemplate           *TemplateClause
 	pattern                   []*GraphClause
+	optional                  [][]*GraphClause
+	inOptional                bool
+	existence                 []*ExistenceGraphPattern
+	inExistence               bool
+	unions                    []*UnionGraphPattern
+	inUnion                   bool
+	filters                   []*Filter
+	workingFilter             []ConsumedElement
 	workingClause             *GraphClause
 	projection                []*Projection
 	workingProjection         *Projection
+	workingExpression         []ConsumedElement
 	groupBy                   []string
 	orderBy                   table.SortConfig
 	havingExpression          []ConsumedElement
 	havingExpressionEvaluator Evaluator
 	limitSet                  bool
 	limit                     int64
+	offsetSet                 bool
+	offset                    int64
+	cursor                    *table.Cursor
 	lookupOptions             storage.LookupOptions
+	subqueries                []*Statement
+	outer                     *Statement
 }
 
 // GraphClause represents a clause of a graph pattern in a where clause.
@@ -104,6 +173,12 @@ type GraphClause struct {
 	PLowerBoundAlias string
 	PUpperBoundAlias string
 	PTemporal        bool
+	PPath            bool
+	PPathMin         int64
+	PPathMax         int64
+	PPathShortest    bool
+	PPathBinding     string
+	PPathHopsBinding string
 
 	O                *triple.Object
 	OBinding         string
@@ -150,6 +225,8 @@ func (c *GraphClause) BindingsMap() map[string]int {
 	addToBindings(bm, c.PUpperBoundAlias)
 	addToBindings(bm, c.PIDAlias)
 	addToBindings(bm, c.PAnchorAlias)
+	addToBindings(bm, c.PPathBinding)
+	addToBindings(bm, c.PPathHopsBinding)
 	addToBindings(bm, c.OBinding)
 	addToBindings(bm, c.OAlias)
 	addToBindings(bm, c.OTypeAlias)
@@ -171,11 +248,147 @@ func (c *GraphClause) Bindings() []string {
 	return bs
 }
 
+// boundString returns a readable representation of a time bound given either
+// its value or the binding it is taken from.
+func boundString(t *time.Time, alias string) string {
+	if t != nil {
+		return t.Format(time.RFC3339Nano)
+	}
+	return alias
+}
+
+// String returns a readable representation of the clause.
+func (c *GraphClause) String() string {
+	s, p, o := c.SBinding, c.PBinding, c.OBinding
+	if c.S != nil {
+		s = c.S.String()
+	}
+	switch {
+	case c.P != nil:
+		p = c.P.String()
+	case c.PAnchorBinding != "":
+		p = fmt.Sprintf("%q@[%s]", c.PID, c.PAnchorBinding)
+	case c.PID != "":
+		p = fmt.Sprintf("%q@[%s, %s]", c.PID, boundString(c.PLowerBound, c.PLowerBoundAlias), boundString(c.PUpperBound, c.PUpperBoundAlias))
+	}
+	if c.PPath {
+		switch {
+		case c.PPathMax >= 0:
+			p += fmt.Sprintf("{%d, %d}", c.PPathMin, c.PPathMax)
+		case c.PPathMin == 1:
+			p += "+"
+		default:
+			p += "*"
+		}
+		if c.PPathShortest {
+			p += " shortest"
+		}
+	}
+	switch {
+	case c.O != nil:
+		o = c.O.String()
+	case c.OAnchorBinding != "":
+		o = fmt.Sprintf("%q@[%s]", c.OID, c.OAnchorBinding)
+	case c.OID != "":
+		o = fmt.Sprintf("%q@[%s, %s]", c.OID, boundString(c.OLowerBound, c.OLowerBoundAlias), boundString(c.OUpperBound, c.OUpperBoundAlias))
+	}
+	return fmt.Sprintf("%s %s %s", s, p, o)
+}
+
+// ValidatePath checks that a property path clause walks a fully specified
+// predicate, or a predicate with fixed time bounds, between nodes. Subjects and
+// objects of property paths can only be nodes or bindings.
+func (c *GraphClause) ValidatePath() error {
+	if !c.PPath {
+		return nil
+	}
+	if c.P == nil && (c.PID == "" || c.PAnchorBinding != "" || c.PLowerBoundAlias != "" || c.PUpperBoundAlias != "") {
+		return errors.New("property paths require a fully specified predicate or a predicate with fixed time bounds")
+	}
+	if c.PPathMin < 0 || (c.PPathMax >= 0 && c.PPathMax < c.PPathMin) {
+		return fmt.Errorf("invalid property path hop bounds {%d, %d}", c.PPathMin, c.PPathMax)
+	}
+	if c.SAlias != "" || c.STypeAlias != "" || c.SIDAlias != "" ||
+		c.PAlias != "" || c.PIDAlias != "" || c.PAnchorAlias != "" ||
+		c.OAlias != "" || c.OTypeAlias != "" || c.OIDAlias != "" || c.OAnchorBinding != "" || c.OAnchorAlias != "" ||
+		c.OLowerBoundAlias != "" || c.OUpperBoundAlias != "" {
+		return fmt.Errorf("property path for predicate %q cannot use aliases; only nodes and bindings are allowed as subject and object", c.PID)
+	}
+	if c.O != nil {
+		if _, err := c.O.Node(); err != nil {
+			return fmt.Errorf("property path for predicate %q requires a node object; found %s instead", c.PID, c.O)
+		}
+	}
+	bs := map[string]bool{c.SBinding: true, c.OBinding: true}
+	for _, b := range []string{c.PPathBinding, c.PPathHopsBinding} {
+		if b == "" {
+			continue
+		}
+		if bs[b] {
+			return fmt.Errorf("property path for predicate %q binds %s more than once", c.PID, b)
+		}
+		bs[b] = true
+	}
+	return nil
+}
+
+// ReturnsPaths returns true if the property path clause produces one row per
+// walked path instead of one row per pair of connected nodes.
+func (c *GraphClause) ReturnsPaths() bool {
+	return c.PPath && (c.PPathShortest || c.PPathBinding != "" || c.PPathHopsBinding != "")
+}
+
 // IsEmpty will return true if the are no set values in the clause.
 func (c *GraphClause) IsEmpty() bool {
 	return reflect.DeepEqual(c, &GraphClause{})
 }
 
+// TemplateClause represents a triple of a template used to build new triples
+// out of the rows returned by a where clause. Each component is either a
+// fixed value or a binding. Predicates can also be built out of a fixed ID and
+// a binding holding their time anchor.
+type TemplateClause struct {
+	S        *node.Node
+	SBinding string
+
+	P              *predicate.Predicate
+	PBinding       string
+	PID            string
+	PAnchorBinding string
+
+	O        *triple.Object
+	OBinding string
+}
+
+// Bindings returns the list of bindings used by the template clause.
+func (c *TemplateClause) Bindings() []string {
+	var bs []string
+	for _, b := range []string{c.SBinding, c.PBinding, c.PAnchorBinding, c.OBinding} {
+		if b != "" {
+			bs = append(bs, b)
+		}
+	}
+	return bs
+}
+
+// String returns a readable form of the template clause.
+func (c *TemplateClause) String() string {
+	s, p, o := c.SBinding, c.PBinding, c.OBinding
+	if c.S != nil {
+		s = c.S.String()
+	}
+	if c.P != nil {
+		p = c.P.String()
+	}
+	if c.PAnchorBinding != "" {
+		p = fmt.Sprintf("%q@[%s]", c.PID, c.PAnchorBinding)
+	}
+	if c.O != nil {
+		o = c.O.String()
+	}
+	return fmt.Sprintf("%s %s %s", s, p, o)
+}
+
 // BindType sets the type of a statement.
 func (s *Statement) BindType(st StatementType) {
 	s.sType = st
@@ -196,6 +409,89 @@ func (s *Statement) Graphs() []string {
 	return s.graphs
 }
 
+// AddOutputGraph adds a graph where the statement will write its results.
+func (s *Statement) AddOutputGraph(g string) {
+	s.outputGraphs = append(s.outputGraphs, g)
+}
+
+// OutputGraphs returns the list of graphs the statement writes its results
+// into.
+func (s *Statement) OutputGraphs() []string {
+	return s.outputGraphs
+}
+
+// SetGraphSnapshot sets the name of the snapshot to use for the provided graph.
+func (s *Statement) SetGraphSnapshot(g, name string) {
+	if s.graphSnapshots == nil {
+		s.graphSnapshots = make(map[string]string)
+	}
+	s.graphSnapshots[g] = name
+}
+
+// GraphSnapshot returns the name of the snapshot to use for the provided
+// graph, if any.
+func (s *Statement) GraphSnapshot(g string) (string, bool) {
+	name, ok := s.graphSnapshots[g]
+	return name, ok
+}
+
+// BindSnapshot sets the name of the snapshot created or dropped by the
+// statement.
+func (s *Statement) BindSnapshot(name string) {
+	s.snapshot = name
+}
+
+// Snapshot returns the name of the snapshot created or dropped by the
+// statement.
+func (s *Statement) Snapshot() string {
+	return s.snapshot
+}
+
+// BindDescribedNode sets the node described by the statement.
+func (s *Statement) BindDescribedNode(n *node.Node) {
+	s.describedNode = n
+}
+
+// DescribedNode returns the node described by the statement.
+func (s *Statement) DescribedNode() *node.Node {
+	return s.describedNode
+}
+
+// SetDescribeDepth sets how many hops of node objects the statement follows
+// from the described node.
+func (s *Statement) SetDescribeDepth(d int64) {
+	s.describeDepth = d
+}
+
+// DescribeDepth returns how many hops of node objects the statement follows
+// from the described node. It defaults to 1, which only returns the triples
+// the node takes part in.
+func (s *Statement) DescribeDepth() int64 {
+	if s.describeDepth < 1 {
+		return 1
+	}
+	return s.describeDepth
+}
+
+// BindExplain marks the statement to be explained instead of executed. If
+// analyze is true, the statement is also executed to collect the rows and
+// time spent on each step.
+func (s *Statement) BindExplain(analyze bool) {
+	s.explain = true
+	s.explainAnalyze = s.explainAnalyze || analyze
+}
+
+// Explain returns true if the statement needs to be explained.
+func (s *Statement) Explain() bool {
+	return s.explain
+}
+
+// ExplainAnalyze returns true if the explanation of the statement needs to
+// include the rows and time spent on each step.
+func (s *Statement) ExplainAnalyze() bool {
+	return s.explainAnalyze
+}
+
 // AddData adds a triple to a given statement's data.
 func (s *Statement) AddData(d *triple.Triple) {
 	s.data = append(s.data, d)
@@ -206,6 +502,59 @@ func (s *Statement) Data() []*triple.Triple {
 	return s.data
 }
 
+// WorkingTemplateClause returns the template clause currently being parsed.
+func (s *Statement) WorkingTemplateClause() *TemplateClause {
+	if s.workingTemplate == nil {
+		s.workingTemplate = &TemplateClause{}
+	}
+	return s.workingTemplate
+}
+
+// AddWorkingTemplateClause adds the current working template clause to the
+// template of the statement. The bindings used by the clause are also added
+// to the projections of the statement, so the where clause returns them.
+func (s *Statement) AddWorkingTemplateClause() {
+	s.template = append(s.template, s.flushWorkingTemplateClause())
+}
+
+// AddWorkingDeleteTemplateClause adds the current working template clause to
+// the template of the triples removed by the statement. As with
+// AddWorkingTemplateClause, the bindings used are added to the projections.
+func (s *Statement) AddWorkingDeleteTemplateClause() {
+	s.deleteTemplate = append(s.deleteTemplate, s.flushWorkingTemplateClause())
+}
+
+// flushWorkingTemplateClause projects the bindings used by the working
+// template clause, resets it, and returns it.
+func (s *Statement) flushWorkingTemplateClause() *TemplateClause {
+	c := s.WorkingTemplateClause()
+	for _, b := range c.Bindings() {
+		found := false
+		for _, p := range s.projection {
+			if p.Binding == b {
+				found = true
+				break
+			}
+		}
+		if !found {
+			s.projection = append(s.projection, &Projection{Binding: b})
+		}
+	}
+	s.workingTemplate = nil
+	return c
+}
+
+// TemplateClauses returns the template used to build new triples.
+func (s *Statement) TemplateClauses() []*TemplateClause {
+	return s.template
+}
+
+// DeleteTemplateClauses returns the template used to build the triples
+// removed by the statement.
+func (s *Statement) DeleteTemplateClauses() []*TemplateClause {
+	return s.deleteTemplate
+}
+
 // GraphPatternClauses returns the list of graph pattern clauses
 func (s *Statement) GraphPatternClauses() []*GraphClause {
 	return s.pattern
@@ -222,14 +571,213 @@ func (s *Statement) WorkingClause() *GraphClause {
 }
 
 // AddWorkingGraphClause adds the current working graph clause to the set of
-// clauses that form the graph pattern.
+// clauses that form the graph pattern. If an optional graph pattern, an
+// existence graph pattern, or an alternative of a union is open, the clause is
+// added to it instead.
 func (s *Statement) AddWorkingGraphClause() {
 	if s.workingClause != nil || !s.workingClause.IsEmpty() {
-		s.pattern = append(s.pattern, s.workingClause)
+		switch {
+		case s.inOptional:
+			last := len(s.optional) - 1
+			s.optional[last] = append(s.optional[last], s.workingClause)
+		case s.inExistence:
+			e := s.existence[len(s.existence)-1]
+			e.Clauses = append(e.Clauses, s.workingClause)
+		case s.inUnion:
+			u := s.unions[len(s.unions)-1]
+			last := len(u.Alternatives) - 1
+			u.Alternatives[last] = append(u.Alternatives[last], s.workingClause)
+		default:
+			s.pattern = append(s.pattern, s.workingClause)
+		}
 	}
 	s.ResetWorkingGraphClause()
 }
 
+// StartSubquery saves the state of the statement and resets it, so the nested
+// query that follows can be parsed using the same hooks.
+func (s *Statement) StartSubquery() {
+	outer := *s
+	*s = Statement{
+		sType: Query,
+		outer: &outer,
+	}
+}
+
+// EndSubquery adds the nested query parsed since the matching StartSubquery
+// call to the subqueries of the statement, and restores the statement state.
+func (s *Statement) EndSubquery() error {
+	if s.outer == nil {
+		return errors.New("cannot end a subquery that was never started")
+	}
+	sub := *s
+	*s = *sub.outer
+	sub.outer = nil
+	s.subqueries = append(s.subqueries, &sub)
+	return nil
+}
+
+// Subqueries returns the queries nested in the where clause of the statement.
+func (s *Statement) Subqueries() []*Statement {
+	return s.subqueries
+}
+
+// StartOptionalGraphPattern opens a new optional graph pattern. All clauses
+// added until it is closed become part of it.
+func (s *Statement) StartOptionalGraphPattern() {
+	s.optional = append(s.optional, nil)
+	s.inOptional = true
+}
+
+// EndOptionalGraphPattern closes the current optional graph pattern.
+func (s *Statement) EndOptionalGraphPattern() {
+	s.inOptional = false
+}
+
+// OptionalGraphPatternClauses returns the clauses of each optional graph
+// pattern.
+func (s *Statement) OptionalGraphPatternClauses() [][]*GraphClause {
+	return s.optional
+}
+
+// ExistenceGraphPattern contains the clauses of an EXISTS or NOT EXISTS filter.
+// Rows are only kept if the clauses match, or do not match when negated, once
+// the bindings they share with the rest of the graph pattern are set to the
+// values of the row. The bindings only used by the clauses are not available
+// outside of the pattern.
+type ExistenceGraphPattern struct {
+	Clauses []*GraphClause
+	Negated bool
+}
+
+// SortedClauses returns the clauses of the pattern sorted by specificity.
+func (e *ExistenceGraphPattern) SortedClauses() []*GraphClause {
+	return sortClauses(e.Clauses)
+}
+
+// StartExistenceGraphPattern opens a new existence graph pattern. All clauses
+// added until it is closed become part of it.
+func (s *Statement) StartExistenceGraphPattern(negated bool) {
+	s.existence = append(s.existence, &ExistenceGraphPattern{Negated: negated})
+	s.inExistence = true
+}
+
+// InExistenceGraphPattern returns true if an existence graph pattern is open.
+func (s *Statement) InExistenceGraphPattern() bool {
+	return s.inExistence
+}
+
+// EndExistenceGraphPattern closes the current existence graph pattern.
+func (s *Statement) EndExistenceGraphPattern() {
+	s.inExistence = false
+}
+
+// ExistenceGraphPatterns returns the existence graph patterns of the
+// statement.
+func (s *Statement) ExistenceGraphPatterns() []*ExistenceGraphPattern {
+	return s.existence
+}
+
+// UnionGraphPattern contains the alternative sets of clauses of a union of
+// graph patterns. Rows matching any of the alternatives are part of the union.
+type UnionGraphPattern struct {
+	Alternatives [][]*GraphClause
+}
+
+// Bindings returns the bindings of all the alternatives of the union.
+func (u *UnionGraphPattern) Bindings() []string {
+	var bs []string
+	seen := make(map[string]bool)
+	for _, alt := range u.Alternatives {
+		for _, cls := range alt {
+			for _, b := range cls.Bindings() {
+				if !seen[b] {
+					seen[b] = true
+					bs = append(bs, b)
+				}
+			}
+		}
+	}
+	sort.Strings(bs)
+	return bs
+}
+
+// StartUnionGraphPattern opens a new union of graph patterns.
+func (s *Statement) StartUnionGraphPattern() {
+	s.unions = append(s.unions, &UnionGraphPattern{})
+}
+
+// StartUnionAlternative opens a new alternative on the current union of graph
+// patterns. All clauses added until it is closed become part of it.
+func (s *Statement) StartUnionAlternative() {
+	u := s.unions[len(s.unions)-1]
+	u.Alternatives = append(u.Alternatives, nil)
+	s.inUnion = true
+}
+
+// EndUnionAlternative closes the current alternative of the union.
+func (s *Statement) EndUnionAlternative() {
+	s.inUnion = false
+}
+
+// UnionGraphPatterns returns the unions of graph patterns of the statement.
+func (s *Statement) UnionGraphPatterns() []*UnionGraphPattern {
+	return s.unions
+}
+
+// SortedUnionGraphPatterns returns the unions of graph patterns with the
+// clauses of each alternative sorted by specificity.
+func (s *Statement) SortedUnionGraphPatterns() []*UnionGraphPattern {
+	var us []*UnionGraphPattern
+	for _, u := range s.unions {
+		su := &UnionGraphPattern{}
+		for _, alt := range u.Alternatives {
+			su.Alternatives = append(su.Alternatives, sortClauses(alt))
+		}
+		us = append(us, su)
+	}
+	return us
+}
+
+// Filter contains a boolean expression used to filter the rows matched by the
+// graph pattern, and the bindings it needs to be evaluated.
+type Filter struct {
+	Evaluator Evaluator
+	Bindings  []string
+}
+
+// AddWorkingFilterElement adds an element to the expression of the filter
+// being parsed.
+func (s *Statement) AddWorkingFilterElement(ce ConsumedElement) {
+	s.workingFilter = append(s.workingFilter, ce)
+}
+
+// AddWorkingFilter builds the filter being parsed and adds it to the set of
+// filters of the graph pattern.
+func (s *Statement) AddWorkingFilter() error {
+	ces := s.workingFilter
+	s.workingFilter = nil
+	eval, err := NewEvaluator(ces)
+	if err != nil {
+		return err
+	}
+	f := &Filter{Evaluator: eval}
+	seen := make(map[string]bool)
+	for _, ce := range ces {
+		if tkn := ce.Token(); tkn.Type == lexer.ItemBinding && !seen[tkn.Text] {
+			seen[tkn.Text] = true
+			f.Bindings = append(f.Bindings, tkn.Text)
+		}
+	}
+	s.filters = append(s.filters, f)
+	return nil
+}
+
+// Filters returns the filters of the graph pattern.
+func (s *Statement) Filters() []*Filter {
+	return s.filters
+}
+
 // addToBindings adds the binding if not empty.
 func addToBindings(bs map[string]int, b string) {
 	if b != "" {
@@ -237,12 +785,27 @@ func addToBindings(bs map[string]int, b string) {
 	}
 }
 
+// allGraphClauses returns all the clauses of the graph pattern, including
+// the ones in optional graph patterns and unions.
+func (s *Statement) allGraphClauses() []*GraphClause {
+	clauses := append([]*GraphClause{}, s.pattern...)
+	for _, opt := range s.optional {
+		clauses = append(clauses, opt...)
+	}
+	for _, u := range s.unions {
+		for _, alt := range u.Alternatives {
+			clauses = append(clauses, alt...)
+		}
+	}
+	return clauses
+}
+
 // BindingsMap returns the set of bindings available on the graph clauses for he
 // statement.
 func (s *Statement) BindingsMap() map[string]int {
 	bm := make(map[string]int)
 
-	for _, cls := range s.pattern {
+	for _, cls := range s.allGraphClauses() {
 		if cls != nil {
 			addToBindings(bm, cls.SBinding)
 			addToBindings(bm, cls.SAlias)
@@ -255,6 +818,8 @@ func (s *Statement) BindingsMap() map[string]int {
 			addToBindings(bm, cls.PUpperBoundAlias)
 			addToBindings(bm, cls.PIDAlias)
 			addToBindings(bm, cls.PAnchorAlias)
+			addToBindings(bm, cls.PPathBinding)
+			addToBindings(bm, cls.PPathHopsBinding)
 			addToBindings(bm, cls.OBinding)
 			addToBindings(bm, cls.OAlias)
 			addToBindings(bm, cls.OTypeAlias)
@@ -265,6 +830,11 @@ func (s *Statement) BindingsMap() map[string]int {
 			addToBindings(bm, cls.OUpperBoundAlias)
 		}
 	}
+	for _, sub := range s.subqueries {
+		for _, b := range sub.OutputBindings() {
+			addToBindings(bm, b)
+		}
+	}
 	return bm
 }
 
@@ -296,11 +866,11 @@ func (s bySpecificity) Less(i, j int) bool {
 	return s[i].Specificity() > s[j].Specificity()
 }
 
-// SortedGraphPatternClauses return the list of graph pattern clauses
-func (s *Statement) SortedGraphPatternClauses() []*GraphClause {
+// sortClauses returns the non empty clauses sorted by specificity.
+func sortClauses(clauses []*GraphClause) []*GraphClause {
 	var ptrns []*GraphClause
 	// Filter empty clauses.
-	for _, cls := range s.pattern {
+	for _, cls := range clauses {
 		if cls != nil && !cls.IsEmpty() {
 			ptrns = append(ptrns, cls)
 		}
@@ -309,14 +879,31 @@ func (s *Statement) SortedGraphPatternClauses() []*GraphClause {
 	return ptrns
 }
 
+// SortedGraphPatternClauses return the list of graph pattern clauses
+func (s *Statement) SortedGraphPatternClauses() []*GraphClause {
+	return sortClauses(s.pattern)
+}
+
+// SortedOptionalGraphPatternClauses returns the clauses of each optional graph
+// pattern sorted by specificity.
+func (s *Statement) SortedOptionalGraphPatternClauses() [][]*GraphClause {
+	var ptrns [][]*GraphClause
+	for _, opt := range s.optional {
+		ptrns = append(ptrns, sortClauses(opt))
+	}
+	return ptrns
+}
+
 // Projection contains the information required to project the outcome of
 // querying with GraphClauses. It also contains the information of what
 // aggregation function should be used.
 type Projection struct {
-	Binding  string
-	Alias    string
-	OP       lexer.TokenType // The information about what function to use.
-	Modifier lexer.TokenType // The modifier for the selected op.
+	Binding    string
+	Alias      string
+	OP         lexer.TokenType  // The information about what function to use.
+	Modifier   lexer.TokenType  // The modifier for the selected op.
+	Separator  string           // The separator used by GROUP_CONCAT.
+	Expression ScalarExpression // The scalar expression computing the value.
 }
 
 // String returns a readable form of the projection.
@@ -326,7 +913,7 @@ func (p *Projection) String() string {
 
 // IsEmpty checks if the given projection is empty.
 func (p *Projection) IsEmpty() bool {
-	return p.Binding == "" && p.Alias == "" && p.OP == lexer.ItemError && p.Modifier == lexer.ItemError
+	return p.Binding == "" && p.Alias == "" && p.OP == lexer.ItemError && p.Modifier == lexer.ItemError && p.Expression == nil
 }
 
 // ResetProjection resets the current working variable projection.
@@ -393,6 +980,25 @@ func (s *Statement) OrderByConfig() table.SortConfig {
 	return s.orderBy
 }
 
+// TotalOrderConfig returns the sort configuration specified by the order by
+// statement, followed by the remaining output bindings in ascending order.
+// Sorting by it makes the order of the rows deterministic, which paging
+// through the results with continuation tokens requires.
+func (s *Statement) TotalOrderConfig() table.SortConfig {
+	cfg := append(table.SortConfig{}, s.orderBy...)
+	used := make(map[string]bool)
+	for _, sc := range s.orderBy {
+		used[sc.Binding] = true
+	}
+	for _, b := range s.OutputBindings() {
+		if !used[b] {
+			used[b] = true
+			cfg = append(cfg, table.SortConfig{{Binding: b}}...)
+		}
+	}
+	return cfg
+}
+
 // HasHavingClause returns true if there is a having clause.
 func (s *Statement) HasHavingClause() bool {
 	return len(s.havingExpression) > 0
@@ -414,6 +1020,22 @@ func (s *Statement) Limit() int64 {
 	return s.limit
 }
 
+// IsOffsetSet returns true if the offset is set.
+func (s *Statement) IsOffsetSet() bool {
+	return s.offsetSet
+}
+
+// Offset returns the offset value set in the offset clause.
+func (s *Statement) Offset() int64 {
+	return s.offset
+}
+
+// Cursor returns the cursor decoded from the continuation token provided in
+// the cursor clause, or nil if none was provided.
+func (s *Statement) Cursor() *table.Cursor {
+	return s.cursor
+}
+
 // GlobalLookupOptions returns the global lookup options available in the
 // statement.
 func (s *Statement) GlobalLookupOptions() *storage.LookupOptions {
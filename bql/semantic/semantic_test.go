@@ -71,6 +71,34 @@ func TestGraphClauseSpecificity(t *testing.T) {
 	}
 }
 
+func TestGraphClauseString(t *testing.T) {
+	n, err := node.Parse("/u<joe>")
+	if err != nil {
+		t.Fatalf("node.Parse failed with error %v", err)
+	}
+	p, err := predicate.Parse(`"parent_of"@[]`)
+	if err != nil {
+		t.Fatalf("predicate.Parse failed with error %v", err)
+	}
+	table := []struct {
+		gc   *GraphClause
+		want string
+	}{
+		{&GraphClause{S: n, P: p, OBinding: "?o"}, `/u<joe> "parent_of"@[] ?o`},
+		{&GraphClause{SBinding: "?s", PBinding: "?p", O: triple.NewNodeObject(n)}, `?s ?p /u<joe>`},
+		{&GraphClause{SBinding: "?s", PID: "bought", PAnchorBinding: "?t", OBinding: "?o"}, `?s "bought"@[?t] ?o`},
+		{&GraphClause{SBinding: "?s", PID: "bought", PUpperBoundAlias: "?u", OBinding: "?o"}, `?s "bought"@[, ?u] ?o`},
+		{&GraphClause{S: n, P: p, PPath: true, PPathMin: 1, PPathMax: -1, OBinding: "?o"}, `/u<joe> "parent_of"@[]+ ?o`},
+		{&GraphClause{S: n, P: p, PPath: true, PPathMin: 0, PPathMax: -1, PPathShortest: true, OBinding: "?o"}, `/u<joe> "parent_of"@[]* shortest ?o`},
+		{&GraphClause{S: n, P: p, PPath: true, PPathMin: 2, PPathMax: 3, OBinding: "?o"}, `/u<joe> "parent_of"@[]{2, 3} ?o`},
+	}
+	for _, entry := range table {
+		if got, want := entry.gc.String(), entry.want; got != want {
+			t.Errorf("semantic.GraphClause.String returned the wrong value; got %q, want %q", got, want)
+		}
+	}
+}
+
 func TestGraphClauseManipulation(t *testing.T) {
 	st := &Statement{}
 	if st.WorkingClause() != nil {
@@ -209,3 +237,37 @@ func TestInputOutputBindings(t *testing.T) {
 		t.Errorf("s.OutputBindings return the wrong input binding; got %v, want %v", got, want)
 	}
 }
+
+func TestSubqueries(t *testing.T) {
+	st := &Statement{}
+	st.BindType(Query)
+	st.AddGraph("?foo")
+	if err := st.EndSubquery(); err == nil {
+		t.Errorf("semantic.EndSubquery should have failed without a started subquery")
+	}
+	st.StartSubquery()
+	st.AddGraph("?bar")
+	st.projection = []*Projection{{Binding: "?s", Alias: "?a"}}
+	if got, want := st.Graphs(), []string{"?bar"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("semantic.StartSubquery should have reset the graphs; got %v, want %v", got, want)
+	}
+	if err := st.EndSubquery(); err != nil {
+		t.Fatalf("semantic.EndSubquery failed with error %v", err)
+	}
+	if got, want := st.Graphs(), []string{"?foo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("semantic.EndSubquery should have restored the graphs; got %v, want %v", got, want)
+	}
+	subs := st.Subqueries()
+	if len(subs) != 1 {
+		t.Fatalf("semantic.Subqueries returned %d subqueries; want 1", len(subs))
+	}
+	if got, want := subs[0].Graphs(), []string{"?bar"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("subquery returned the wrong graphs; got %v, want %v", got, want)
+	}
+	if got, want := subs[0].Type(), Query; got != want {
+		t.Errorf("subquery returned the wrong type; got %v, want %v", got, want)
+	}
+	if got, want := st.BindingsMap(), map[string]int{"?a": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("semantic.BindingsMap should only include the subquery output bindings; got %v, want %v", got, want)
+	}
+}
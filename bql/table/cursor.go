@@ -0,0 +1,135 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Cursor marks the position of the last row returned from a list of rows
+// sorted using a sort configuration that totally orders them. Rows equal to
+// the last one can only be told apart by their number, so the cursor also
+// keeps track of how many of them have already been returned.
+type Cursor struct {
+	Config SortConfig
+	Keys   []string
+	Skip   int64
+}
+
+// cursorToken is the serialized form of a cursor.
+type cursorToken struct {
+	Bindings []string `json:"b"`
+	Desc     []bool   `json:"d"`
+	Keys     []string `json:"k"`
+	Skip     int64    `json:"s"`
+}
+
+// NewCursor returns a cursor pointing to the provided row.
+func NewCursor(r Row, cfg SortConfig, skip int64) (*Cursor, error) {
+	c := &Cursor{
+		Config: cfg,
+		Skip:   skip,
+	}
+	for _, sc := range cfg {
+		v, ok := r[sc.Binding]
+		if !ok {
+			return nil, fmt.Errorf("cannot create a cursor; row %v has no binding %q", r, sc.Binding)
+		}
+		c.Keys = append(c.Keys, sortKey(v))
+	}
+	return c, nil
+}
+
+// ParseCursor returns the cursor serialized in the provided token.
+func ParseCursor(tkn string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(tkn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid continuation token %q", tkn)
+	}
+	ct := &cursorToken{}
+	if err := json.Unmarshal(b, ct); err != nil {
+		return nil, fmt.Errorf("invalid continuation token %q", tkn)
+	}
+	if len(ct.Bindings) == 0 || len(ct.Bindings) != len(ct.Desc) || len(ct.Bindings) != len(ct.Keys) || ct.Skip < 0 {
+		return nil, fmt.Errorf("invalid continuation token %q", tkn)
+	}
+	c := &Cursor{
+		Keys: ct.Keys,
+		Skip: ct.Skip,
+	}
+	for i, b := range ct.Bindings {
+		c.Config = append(c.Config, SortConfig{{Binding: b, Desc: ct.Desc[i]}}...)
+	}
+	return c, nil
+}
+
+// Token returns the opaque serialized form of the cursor.
+func (c *Cursor) Token() string {
+	ct := &cursorToken{
+		Keys: c.Keys,
+		Skip: c.Skip,
+	}
+	for _, sc := range c.Config {
+		ct.Bindings = append(ct.Bindings, sc.Binding)
+		ct.Desc = append(ct.Desc, sc.Desc)
+	}
+	b, err := json.Marshal(ct)
+	if err != nil {
+		// Marshaling strings, booleans, and integers cannot fail.
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Matches returns true if the cursor was created for the provided sort
+// configuration.
+func (c *Cursor) Matches(cfg SortConfig) bool {
+	return reflect.DeepEqual(c.Config, cfg)
+}
+
+// Compare returns a negative value if the row sorts before the cursor
+// position, zero if it is equal to the row the cursor points to, or a positive
+// value if it sorts after it.
+func (c *Cursor) Compare(r Row) int {
+	for i, sc := range c.Config {
+		if l := stringLess(sortKey(r[sc.Binding]), c.Keys[i], sc.Desc); l != 0 {
+			return l
+		}
+	}
+	return 0
+}
+
+// After removes the rows of the table that were already returned up to the
+// cursor position. The table does not need to be sorted.
+func (t *Table) After(c *Cursor) {
+	var (
+		data []Row
+		skip = c.Skip
+	)
+	for _, r := range t.data {
+		switch cmp := c.Compare(r); {
+		case cmp < 0:
+			continue
+		case cmp == 0 && skip > 0:
+			skip--
+			continue
+		}
+		data = append(data, r)
+	}
+	t.data = data
+}
@@ -0,0 +1,109 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testCursorRow(a, b string) Row {
+	return Row{
+		"?a": &Cell{S: CellString(a)},
+		"?b": &Cell{S: CellString(b)},
+	}
+}
+
+func TestCursorToken(t *testing.T) {
+	cfg := SortConfig{{Binding: "?a", Desc: true}, {Binding: "?b"}}
+	c, err := NewCursor(testCursorRow("x", "y"), cfg, 2)
+	if err != nil {
+		t.Fatalf("NewCursor failed with error %v", err)
+	}
+	got, err := ParseCursor(c.Token())
+	if err != nil {
+		t.Fatalf("ParseCursor(%q) failed with error %v", c.Token(), err)
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Errorf("ParseCursor(%q) returned the wrong cursor; got %v, want %v", c.Token(), got, c)
+	}
+	if !got.Matches(cfg) {
+		t.Errorf("cursor %v should match sort configuration %v", got, cfg)
+	}
+	if got.Matches(SortConfig{{Binding: "?a"}, {Binding: "?b"}}) {
+		t.Errorf("cursor %v should not match a different sort configuration", got)
+	}
+	if _, err := NewCursor(Row{}, cfg, 0); err == nil {
+		t.Errorf("NewCursor should have failed for a row without the sorting bindings")
+	}
+}
+
+func TestParseCursorErrors(t *testing.T) {
+	for _, tkn := range []string{"", "not a token", "e30", "eyJiIjpbIj9hIl19"} {
+		if _, err := ParseCursor(tkn); err == nil {
+			t.Errorf("ParseCursor(%q) should have failed", tkn)
+		}
+	}
+}
+
+func TestCursorCompare(t *testing.T) {
+	c, err := NewCursor(testCursorRow("b", "b"), SortConfig{{Binding: "?a"}, {Binding: "?b", Desc: true}}, 0)
+	if err != nil {
+		t.Fatalf("NewCursor failed with error %v", err)
+	}
+	testTable := []struct {
+		r    Row
+		want int
+	}{
+		{testCursorRow("a", "a"), -1},
+		{testCursorRow("b", "c"), -1},
+		{testCursorRow("b", "b"), 0},
+		{testCursorRow("b", "a"), 1},
+		{testCursorRow("c", "c"), 1},
+	}
+	for _, entry := range testTable {
+		if got := c.Compare(entry.r); got != entry.want {
+			t.Errorf("cursor.Compare(%v) = %d, want %d", entry.r, got, entry.want)
+		}
+	}
+}
+
+func TestTableAfter(t *testing.T) {
+	tbl, err := New([]string{"?a", "?b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []Row{
+		testCursorRow("a", "a"),
+		testCursorRow("b", "b"),
+		testCursorRow("b", "b"),
+		testCursorRow("b", "b"),
+		testCursorRow("c", "a"),
+	} {
+		tbl.AddRow(r)
+	}
+	c, err := NewCursor(testCursorRow("b", "b"), SortConfig{{Binding: "?a"}, {Binding: "?b"}}, 2)
+	if err != nil {
+		t.Fatalf("NewCursor failed with error %v", err)
+	}
+	tbl.After(c)
+	var got []string
+	for _, r := range tbl.Rows() {
+		got = append(got, r["?a"].String()+r["?b"].String())
+	}
+	if want := []string{"bb", "ca"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tbl.After(%v) returned the wrong rows; got %v, want %v", c, got, want)
+	}
+}
@@ -0,0 +1,604 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+const (
+	// maxMergeFanIn is the maximum number of runs merged at once. It bounds
+	// the files kept open, and the buffers held in memory, while merging.
+	maxMergeFanIn = 64
+	// rowOverhead is the estimated size of an empty row.
+	rowOverhead = 48
+	// cellOverhead is the estimated size of a row entry and its cell, without
+	// the value it holds.
+	cellOverhead = 112
+)
+
+// RowSize returns an estimate of the bytes needed to hold the row in memory.
+// Cells shared with other rows are accounted for in each of them.
+func RowSize(r Row) int64 {
+	n := int64(rowOverhead)
+	for b, c := range r {
+		n += cellOverhead + int64(len(b))
+		if c != nil {
+			n += int64(len(c.String()))
+		}
+	}
+	return n
+}
+
+// Size returns an estimate of the bytes needed to hold the rows of the table
+// in memory.
+func (t *Table) Size() int64 {
+	var n int64
+	for _, r := range t.data {
+		n += RowSize(r)
+	}
+	return n
+}
+
+// Sorter sorts rows that may not fit in memory using an external merge sort.
+// Rows are held in memory until their estimated size exceeds the budget. Then,
+// they are sorted and spilled to a temporary file as a sorted run. Merging the
+// runs returns all the rows in order. Sorters are not safe for concurrent use.
+type Sorter struct {
+	cfg    SortConfig
+	budget int64
+	dir    string
+	rows   []Row
+	size   int64
+	runs   []string
+}
+
+// NewSorter returns a sorter for the provided sort configuration that holds
+// at most budget bytes of rows in memory. A budget of 0 holds all the rows in
+// memory. Spilled rows are written to temporary files in dir, or in the
+// default directory for temporary files if dir is empty.
+func NewSorter(cfg SortConfig, budget int64, dir string) *Sorter {
+	return &Sorter{
+		cfg:    cfg,
+		budget: budget,
+		dir:    dir,
+	}
+}
+
+// Add adds a row to the sorter. The rows held in memory are spilled first if
+// adding the row would exceed the budget.
+func (s *Sorter) Add(r Row) error {
+	rs := RowSize(r)
+	if s.budget > 0 && len(s.rows) > 0 && s.size+rs > s.budget {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+	s.rows = append(s.rows, r)
+	s.size += rs
+	return nil
+}
+
+// Runs returns the number of sorted runs spilled to temporary files.
+func (s *Sorter) Runs() int {
+	return len(s.runs)
+}
+
+// spill sorts the rows held in memory and writes them to a new run.
+func (s *Sorter) spill() error {
+	sort.Stable(bySortConfig{s.rows, s.cfg})
+	run, err := s.writeRun(&sliceIterator{rows: s.rows})
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, run)
+	s.rows, s.size = nil, 0
+	return nil
+}
+
+// writeRun writes the rows of the iterator to a new temporary file and
+// returns its path. The file is removed if the rows cannot be written.
+func (s *Sorter) writeRun(it rowIterator) (string, error) {
+	f, err := ioutil.TempFile(s.dir, "badwolf-sort-")
+	if err != nil {
+		return "", fmt.Errorf("table.Sorter failed to create a temporary file to spill rows with error %v", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := gob.NewEncoder(w)
+	for {
+		r, ok, err := it.next()
+		if err == nil && ok {
+			var sr spilledRow
+			if sr, err = newSpilledRow(r); err == nil {
+				err = enc.Encode(sr)
+			}
+		}
+		if err == nil && !ok {
+			err = w.Flush()
+		}
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return "", fmt.Errorf("table.Sorter failed to spill rows to %q with error %v", f.Name(), err)
+		}
+		if !ok {
+			break
+		}
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// openRuns returns the iterators for the provided runs, and the function to
+// call to close them.
+func openRuns(runs []string) ([]rowIterator, func(), error) {
+	var ris []*runIterator
+	closeAll := func() {
+		for _, ri := range ris {
+			ri.close()
+		}
+	}
+	its := make([]rowIterator, 0, len(runs))
+	for _, run := range runs {
+		ri, err := newRunIterator(run)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		ris = append(ris, ri)
+		its = append(its, ri)
+	}
+	return its, closeAll, nil
+}
+
+// reduceRuns merges the oldest runs into a single one until the remaining
+// runs and the rows held in memory can be merged at once without opening
+// more than maxMergeFanIn runs. Merging consecutive runs keeps the order of
+// equal rows.
+func (s *Sorter) reduceRuns() error {
+	for len(s.runs) > maxMergeFanIn {
+		its, closeAll, err := openRuns(s.runs[:maxMergeFanIn])
+		if err != nil {
+			return err
+		}
+		run, err := s.writeRun(newMergeIterator(s.cfg, its))
+		closeAll()
+		if err != nil {
+			return err
+		}
+		for _, old := range s.runs[:maxMergeFanIn] {
+			os.Remove(old)
+		}
+		s.runs = append([]string{run}, s.runs[maxMergeFanIn:]...)
+	}
+	return nil
+}
+
+// Merge calls f for each row added to the sorter in order. Rows with the same
+// values for the sorted bindings keep the order they were added in. It stops
+// at the first error returned by f. If there are more than maxMergeFanIn
+// runs, they are first merged in several passes.
+func (s *Sorter) Merge(f func(Row) error) error {
+	sort.Stable(bySortConfig{s.rows, s.cfg})
+	if len(s.runs) == 0 {
+		for _, r := range s.rows {
+			if err := f(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := s.reduceRuns(); err != nil {
+		return err
+	}
+	its, closeAll, err := openRuns(s.runs)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+	// Rows still in memory were added after the spilled ones.
+	mi := newMergeIterator(s.cfg, append(its, &sliceIterator{rows: s.rows}))
+	for {
+		r, ok, err := mi.next()
+		if err != nil || !ok {
+			return err
+		}
+		if err := f(r); err != nil {
+			return err
+		}
+	}
+}
+
+// Close removes the runs spilled by the sorter.
+func (s *Sorter) Close() error {
+	var rErr error
+	for _, run := range s.runs {
+		if err := os.Remove(run); err != nil && rErr == nil {
+			rErr = err
+		}
+	}
+	s.rows, s.size, s.runs = nil, 0, nil
+	return rErr
+}
+
+// rowIterator returns rows in order.
+type rowIterator interface {
+	// next returns the next row, or false if there are no more rows.
+	next() (Row, bool, error)
+}
+
+// sliceIterator returns the rows of a slice.
+type sliceIterator struct {
+	rows []Row
+}
+
+func (it *sliceIterator) next() (Row, bool, error) {
+	if len(it.rows) == 0 {
+		return nil, false, nil
+	}
+	r := it.rows[0]
+	it.rows = it.rows[1:]
+	return r, true, nil
+}
+
+// runIterator returns the rows of a spilled run.
+type runIterator struct {
+	f   *os.File
+	dec *gob.Decoder
+}
+
+// newRunIterator opens the provided run.
+func newRunIterator(path string) (*runIterator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("table.Sorter failed to open spilled rows in %q with error %v", path, err)
+	}
+	return &runIterator{
+		f:   f,
+		dec: gob.NewDecoder(bufio.NewReader(f)),
+	}, nil
+}
+
+func (it *runIterator) next() (Row, bool, error) {
+	var sr spilledRow
+	if err := it.dec.Decode(&sr); err != nil {
+		if err == io.EOF {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("table.Sorter failed to read spilled rows from %q with error %v", it.f.Name(), err)
+	}
+	r, err := sr.row()
+	if err != nil {
+		return nil, false, err
+	}
+	return r, true, nil
+}
+
+func (it *runIterator) close() error {
+	return it.f.Close()
+}
+
+// mergeItem is the current row of one of the iterators being merged.
+type mergeItem struct {
+	row Row
+	it  int
+}
+
+// mergeHeap sorts the current rows of the iterators being merged. Equal rows
+// are sorted by iterator.
+type mergeHeap struct {
+	cfg   SortConfig
+	items []mergeItem
+}
+
+func (h *mergeHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *mergeHeap) Less(i, j int) bool {
+	ri, rj := h.items[i].row, h.items[j].row
+	if rowLess(ri, rj, h.cfg) {
+		return true
+	}
+	if rowLess(rj, ri, h.cfg) {
+		return false
+	}
+	return h.items[i].it < h.items[j].it
+}
+
+func (h *mergeHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *mergeHeap) Push(x interface{}) {
+	h.items = append(h.items, x.(mergeItem))
+}
+
+func (h *mergeHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// mergeIterator returns the rows of several sorted iterators in order. Equal
+// rows are returned in the order of the iterators.
+type mergeIterator struct {
+	its     []rowIterator
+	h       *mergeHeap
+	started bool
+}
+
+// newMergeIterator returns an iterator merging the provided ones.
+func newMergeIterator(cfg SortConfig, its []rowIterator) *mergeIterator {
+	return &mergeIterator{
+		its: its,
+		h:   &mergeHeap{cfg: cfg},
+	}
+}
+
+func (it *mergeIterator) next() (Row, bool, error) {
+	if !it.started {
+		it.started = true
+		for i, ri := range it.its {
+			r, ok, err := ri.next()
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				it.h.items = append(it.h.items, mergeItem{row: r, it: i})
+			}
+		}
+		heap.Init(it.h)
+	}
+	if it.h.Len() == 0 {
+		return nil, false, nil
+	}
+	top := it.h.items[0]
+	r, ok, err := it.its[top.it].next()
+	if err != nil {
+		return nil, false, err
+	}
+	if ok {
+		it.h.items[0].row = r
+		heap.Fix(it.h, 0)
+	} else {
+		heap.Pop(it.h)
+	}
+	return top.row, true, nil
+}
+
+// spilledRow is the serializable version of a row.
+type spilledRow map[string]spilledCell
+
+// spilledCell is the serializable version of a cell. Objects of the triples
+// in a path are stored as cells holding a node, predicate, or literal.
+type spilledCell struct {
+	Nil     bool
+	S       *string
+	N       *spilledNode
+	P       *spilledPredicate
+	L       *spilledLiteral
+	T       *time.Time
+	Path    []spilledTriple
+	HasPath bool
+}
+
+type spilledNode struct {
+	Type string
+	ID   string
+}
+
+type spilledPredicate struct {
+	ID     string
+	Anchor *time.Time
+}
+
+type spilledLiteral struct {
+	Type  literal.Type
+	Value interface{}
+}
+
+type spilledTriple struct {
+	S *spilledNode
+	P *spilledPredicate
+	O *spilledCell
+}
+
+// newSpilledRow returns the serializable version of the row.
+func newSpilledRow(r Row) (spilledRow, error) {
+	sr := make(spilledRow, len(r))
+	for b, c := range r {
+		sc, err := newSpilledCell(c)
+		if err != nil {
+			return nil, err
+		}
+		if sc == nil {
+			sc = &spilledCell{Nil: true}
+		}
+		sr[b] = *sc
+	}
+	return sr, nil
+}
+
+// row returns the row that was spilled.
+func (sr spilledRow) row() (Row, error) {
+	r := make(Row, len(sr))
+	for b, sc := range sr {
+		if sc.Nil {
+			r[b] = nil
+			continue
+		}
+		c, err := sc.cell()
+		if err != nil {
+			return nil, err
+		}
+		r[b] = c
+	}
+	return r, nil
+}
+
+func newSpilledNode(n *node.Node) *spilledNode {
+	return &spilledNode{
+		Type: n.Type().String(),
+		ID:   n.ID().String(),
+	}
+}
+
+func newSpilledPredicate(p *predicate.Predicate) *spilledPredicate {
+	sp := &spilledPredicate{ID: string(p.ID())}
+	if ta, err := p.TimeAnchor(); err == nil {
+		sp.Anchor = ta
+	}
+	return sp
+}
+
+func newSpilledCell(c *Cell) (*spilledCell, error) {
+	if c == nil {
+		return nil, nil
+	}
+	sc := &spilledCell{
+		S: c.S,
+		T: c.T,
+	}
+	if c.N != nil {
+		sc.N = newSpilledNode(c.N)
+	}
+	if c.P != nil {
+		sc.P = newSpilledPredicate(c.P)
+	}
+	if c.L != nil {
+		sc.L = &spilledLiteral{
+			Type:  c.L.Type(),
+			Value: c.L.Interface(),
+		}
+	}
+	if c.Path != nil {
+		sc.HasPath = true
+		for _, t := range c.Path {
+			o, err := objectCell(t.Object())
+			if err != nil {
+				return nil, err
+			}
+			so, err := newSpilledCell(o)
+			if err != nil {
+				return nil, err
+			}
+			sc.Path = append(sc.Path, spilledTriple{
+				S: newSpilledNode(t.Subject()),
+				P: newSpilledPredicate(t.Predicate()),
+				O: so,
+			})
+		}
+	}
+	return sc, nil
+}
+
+// objectCell returns a cell holding the provided object.
+func objectCell(o *triple.Object) (*Cell, error) {
+	if n, err := o.Node(); err == nil {
+		return &Cell{N: n}, nil
+	}
+	if p, err := o.Predicate(); err == nil {
+		return &Cell{P: p}, nil
+	}
+	if l, err := o.Literal(); err == nil {
+		return &Cell{L: l}, nil
+	}
+	return nil, fmt.Errorf("table.Sorter cannot spill unknown object %v", o)
+}
+
+func (sn *spilledNode) node() *node.Node {
+	t, id := node.Type(sn.Type), node.ID(sn.ID)
+	return node.NewNode(&t, &id)
+}
+
+func (sp *spilledPredicate) predicate() (*predicate.Predicate, error) {
+	if sp.Anchor == nil {
+		return predicate.NewImmutable(sp.ID)
+	}
+	return predicate.NewTemporal(sp.ID, *sp.Anchor)
+}
+
+func (sc *spilledCell) cell() (*Cell, error) {
+	if sc == nil {
+		return nil, nil
+	}
+	c := &Cell{
+		S: sc.S,
+		T: sc.T,
+	}
+	if sc.N != nil {
+		c.N = sc.N.node()
+	}
+	if sc.P != nil {
+		p, err := sc.P.predicate()
+		if err != nil {
+			return nil, err
+		}
+		c.P = p
+	}
+	if sc.L != nil {
+		l, err := literal.DefaultBuilder().Build(sc.L.Type, sc.L.Value)
+		if err != nil {
+			return nil, err
+		}
+		c.L = l
+	}
+	if sc.HasPath {
+		c.Path = []*triple.Triple{}
+		for _, st := range sc.Path {
+			s := st.S.node()
+			p, err := st.P.predicate()
+			if err != nil {
+				return nil, err
+			}
+			oc, err := st.O.cell()
+			if err != nil {
+				return nil, err
+			}
+			var o *triple.Object
+			switch {
+			case oc.N != nil:
+				o = triple.NewNodeObject(oc.N)
+			case oc.P != nil:
+				o = triple.NewPredicateObject(oc.P)
+			default:
+				o = triple.NewLiteralObject(oc.L)
+			}
+			t, err := triple.New(s, p, o)
+			if err != nil {
+				return nil, err
+			}
+			c.Path = append(c.Path, t)
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,274 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+func TestSpilledRowRoundTrip(t *testing.T) {
+	n, err := node.Parse("/u<joe>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip, err := predicate.NewImmutable("parent_of")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	tp, err := predicate.NewTemporal("bought", tm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := literal.DefaultBuilder()
+	lit := func(tp literal.Type, v interface{}) *literal.Literal {
+		l, err := b.Build(tp, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	t1, err := triple.New(n, ip, triple.NewNodeObject(n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, err := triple.New(n, tp, triple.NewLiteralObject(lit(literal.Text, "mini")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t3, err := triple.New(n, ip, triple.NewPredicateObject(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Row{
+		"?s":     &Cell{S: CellString("foo")},
+		"?n":     &Cell{N: n},
+		"?ip":    &Cell{P: ip},
+		"?tp":    &Cell{P: tp},
+		"?bool":  &Cell{L: lit(literal.Bool, true)},
+		"?int":   &Cell{L: lit(literal.Int64, int64(42))},
+		"?float": &Cell{L: lit(literal.Float64, 0.1)},
+		"?text":  &Cell{L: lit(literal.Text, "bar")},
+		"?blob":  &Cell{L: lit(literal.Blob, []byte("baz"))},
+		"?t":     &Cell{T: &tm},
+		"?path":  &Cell{Path: []*triple.Triple{t1, t2, t3}},
+		"?hop":   &Cell{Path: []*triple.Triple{}},
+		"?empty": &Cell{},
+		"?nil":   nil,
+	}
+	s := NewSorter(SortConfig{{Binding: "?s"}}, 1, "")
+	defer s.Close()
+	for i := 0; i < 2; i++ {
+		if err := s.Add(r); err != nil {
+			t.Fatalf("Sorter.Add failed with error %v", err)
+		}
+	}
+	if got, want := s.Runs(), 1; got != want {
+		t.Fatalf("Sorter.Add spilled the wrong number of runs; got %d, want %d", got, want)
+	}
+	var got []Row
+	if err := s.Merge(func(r Row) error {
+		got = append(got, r)
+		return nil
+	}); err != nil {
+		t.Fatalf("Sorter.Merge failed with error %v", err)
+	}
+	if want := []Row{r, r}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sorter.Merge failed to restore the spilled rows; got\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestSorterMerge(t *testing.T) {
+	var rows []Row
+	for i := 0; i < 50; i++ {
+		n, err := literal.DefaultBuilder().Build(literal.Int64, int64((i*7)%10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx, err := literal.DefaultBuilder().Build(literal.Int64, int64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, Row{
+			"?n": &Cell{L: n},
+			"?i": &Cell{L: idx},
+		})
+	}
+	cfg := SortConfig{{Binding: "?n", Desc: true}}
+	testTable := []struct {
+		budget int64
+		spills bool
+	}{
+		{budget: 0, spills: false},
+		{budget: 1 << 20, spills: false},
+		{budget: 1000, spills: true},
+		{budget: 1, spills: true},
+	}
+	for _, entry := range testTable {
+		s := NewSorter(cfg, entry.budget, "")
+		for _, r := range rows {
+			if err := s.Add(r); err != nil {
+				t.Fatalf("Sorter.Add failed with error %v", err)
+			}
+		}
+		if got, want := s.Runs() > 0, entry.spills; got != want {
+			t.Errorf("Sorter with budget %d spilled %d runs; want spills %v", entry.budget, s.Runs(), want)
+		}
+		var got []Row
+		if err := s.Merge(func(r Row) error {
+			got = append(got, r)
+			return nil
+		}); err != nil {
+			t.Fatalf("Sorter.Merge failed with error %v", err)
+		}
+		if len(got) != len(rows) {
+			t.Fatalf("Sorter.Merge with budget %d returned %d rows; want %d", entry.budget, len(got), len(rows))
+		}
+		for i := 1; i < len(got); i++ {
+			pn, _ := got[i-1]["?n"].L.Int64()
+			pi, _ := got[i-1]["?i"].L.Int64()
+			cn, _ := got[i]["?n"].L.Int64()
+			ci, _ := got[i]["?i"].L.Int64()
+			if pn < cn || pn == cn && pi > ci {
+				t.Errorf("Sorter.Merge with budget %d returned row (%d, %d) before (%d, %d)", entry.budget, pn, pi, cn, ci)
+			}
+		}
+		runs := s.runs
+		if err := s.Close(); err != nil {
+			t.Errorf("Sorter.Close failed with error %v", err)
+		}
+		for _, run := range runs {
+			if _, err := os.Stat(run); !os.IsNotExist(err) {
+				t.Errorf("Sorter.Close should have removed run %q; got %v", run, err)
+			}
+		}
+	}
+}
+
+func TestSorterMergeManyRuns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badwolf_sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	n := 3*maxMergeFanIn + 10
+	s := NewSorter(SortConfig{{Binding: "?n"}}, 1, dir)
+	for i := 0; i < n; i++ {
+		v, err := literal.DefaultBuilder().Build(literal.Int64, int64(n-i)%10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx, err := literal.DefaultBuilder().Build(literal.Int64, int64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Add(Row{"?n": &Cell{L: v}, "?i": &Cell{L: idx}}); err != nil {
+			t.Fatalf("Sorter.Add failed with error %v", err)
+		}
+	}
+	if got, want := s.Runs(), n-1; got != want {
+		t.Fatalf("Sorter.Add spilled the wrong number of runs; got %d, want %d", got, want)
+	}
+	var got []Row
+	if err := s.Merge(func(r Row) error {
+		got = append(got, r)
+		return nil
+	}); err != nil {
+		t.Fatalf("Sorter.Merge failed with error %v", err)
+	}
+	if s.Runs() > maxMergeFanIn {
+		t.Errorf("Sorter.Merge merged %d runs at once; want at most %d", s.Runs(), maxMergeFanIn)
+	}
+	if len(got) != n {
+		t.Fatalf("Sorter.Merge returned %d rows; want %d", len(got), n)
+	}
+	for i := 1; i < len(got); i++ {
+		pn, _ := got[i-1]["?n"].L.Int64()
+		pi, _ := got[i-1]["?i"].L.Int64()
+		cn, _ := got[i]["?n"].L.Int64()
+		ci, _ := got[i]["?i"].L.Int64()
+		if pn > cn || pn == cn && pi > ci {
+			t.Fatalf("Sorter.Merge returned row (%d, %d) before (%d, %d)", pn, pi, cn, ci)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Sorter.Close failed with error %v", err)
+	}
+	fs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) != 0 {
+		t.Errorf("Sorter.Close should have removed all the runs; found %d files", len(fs))
+	}
+}
+
+func TestTableReduceSorted(t *testing.T) {
+	newTable := func() *Table {
+		tbl, err := New([]string{"?foo", "?bar"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 20; i++ {
+			l, err := literal.DefaultBuilder().Build(literal.Int64, int64(i))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tbl.AddRow(Row{
+				"?foo": &Cell{S: CellString(string('a' + rune(i%3)))},
+				"?bar": &Cell{L: l},
+			})
+		}
+		return tbl
+	}
+	cfg := SortConfig{{Binding: "?foo"}}
+	aaps := func() []AliasAccPair {
+		return []AliasAccPair{
+			{InAlias: "?foo", OutAlias: "?foo"},
+			{InAlias: "?bar", OutAlias: "?sum", Acc: NewSumInt64LiteralAccumulator(0)},
+		}
+	}
+	want := newTable()
+	if err := want.Reduce(cfg, aaps()); err != nil {
+		t.Fatalf("table.Reduce failed with error %v", err)
+	}
+	for _, budget := range []int64{0, 500} {
+		in, got := newTable(), newTable()
+		got.Truncate()
+		s := NewSorter(cfg, budget, "")
+		for _, r := range in.Rows() {
+			if err := s.Add(r); err != nil {
+				t.Fatalf("Sorter.Add failed with error %v", err)
+			}
+		}
+		if err := got.ReduceSorted(s, aaps()); err != nil {
+			t.Fatalf("table.ReduceSorted failed with error %v", err)
+		}
+		s.Close()
+		if !reflect.DeepEqual(got.Bindings(), want.Bindings()) || !reflect.DeepEqual(got.Rows(), want.Rows()) {
+			t.Errorf("table.ReduceSorted with budget %d returned the wrong table; got\n%s\nwant\n%s", budget, got, want)
+		}
+	}
+}
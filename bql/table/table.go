@@ -24,6 +24,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/badwolf/triple"
 	"github.com/google/badwolf/triple/literal"
 	"github.com/google/badwolf/triple/node"
 	"github.com/google/badwolf/triple/predicate"
@@ -36,6 +37,7 @@ type Table struct {
 	bs   []string
 	mbs  map[string]bool
 	data []Row
+	tkn  string
 }
 
 // New returns a new table that can hold data for the the given bindings. The,
@@ -61,6 +63,9 @@ type Cell struct {
 	P *predicate.Predicate
 	L *literal.Literal
 	T *time.Time
+	// Path contains the ordered list of triples traversed by a property path.
+	// A path of zero hops is an empty, but not nil, list.
+	Path []*triple.Triple
 }
 
 // String returns a readable representation of a cell.
@@ -80,9 +85,22 @@ func (c *Cell) String() string {
 	if c.T != nil {
 		return c.T.Format(time.RFC3339Nano)
 	}
+	if c.Path != nil {
+		return pathString(c.Path)
+	}
 	return "<NULL>"
 }
 
+// pathString returns a readable representation of a path. Triples are
+// rendered using spaces as separators to keep the text output tab separated.
+func pathString(ts []*triple.Triple) string {
+	var ss []string
+	for _, t := range ts {
+		ss = append(ss, fmt.Sprintf("%s %s %s", t.Subject(), t.Predicate(), t.Object()))
+	}
+	return "[" + strings.Join(ss, ", ") + "]"
+}
+
 // Row represents a collection of cells.
 type Row map[string]*Cell
 
@@ -290,6 +308,150 @@ func (t *Table) DotProduct(t2 *Table) error {
 	return nil
 }
 
+// IsNull returns true if the cell does not hold any value.
+func (c *Cell) IsNull() bool {
+	return c == nil || (c.S == nil && c.N == nil && c.P == nil && c.L == nil && c.T == nil && c.Path == nil)
+}
+
+// compatibleRows returns true if the rows do not hold different values for
+// any of the provided bindings. Null cells are compatible with any value.
+func compatibleRows(r1, r2 Row, bs []string) bool {
+	for _, b := range bs {
+		c1, c2 := r1[b], r2[b]
+		if c1.IsNull() || c2.IsNull() {
+			continue
+		}
+		if c1.String() != c2.String() {
+			return false
+		}
+	}
+	return true
+}
+
+// joinKey returns the key identifying the values of the provided bindings in a
+// row. It returns false if any of them is null.
+func joinKey(r Row, bs []string) (string, bool) {
+	var k bytes.Buffer
+	for _, b := range bs {
+		c := r[b]
+		if c.IsNull() {
+			return "", false
+		}
+		k.WriteString(c.String())
+		k.WriteByte(0)
+	}
+	return k.String(), true
+}
+
+// mergeIndexes merges two sorted lists of row indexes.
+func mergeIndexes(a, b []int) []int {
+	if len(b) == 0 {
+		return a
+	}
+	res := make([]int, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if a[0] < b[0] {
+			res, a = append(res, a[0]), a[1:]
+		} else {
+			res, b = append(res, b[0]), b[1:]
+		}
+	}
+	return append(append(res, a...), b...)
+}
+
+// LeftOptionalJoin joins the provided table using the bindings both tables
+// share. Rows without a compatible row in the provided table are kept, and
+// null cells are added for the bindings only available in the provided table.
+func (t *Table) LeftOptionalJoin(t2 *Table) {
+	t.join(t2, true)
+}
+
+// Join hash joins the provided table using the bindings both tables share.
+// Only rows with a compatible row in the provided table are kept. Null cells
+// are compatible with any value.
+func (t *Table) Join(t2 *Table) {
+	t.join(t2, false)
+}
+
+// join joins the provided table using the bindings both tables share. If
+// optional is true, rows without a compatible row in the provided table are
+// kept. It is a hash join: the rows of the provided table are indexed by the
+// values of the shared bindings, so each row of the table is only compared
+// with the rows holding the same values. Rows with null values are compatible
+// with any value, so they are compared with all the rows. Joined rows keep the
+// order of both tables.
+func (t *Table) join(t2 *Table, optional bool) {
+	var shared, extra []string
+	for _, b := range t2.bs {
+		if t.mbs[b] {
+			shared = append(shared, b)
+		} else {
+			extra = append(extra, b)
+		}
+	}
+	var (
+		idx   = make(map[string][]int)
+		nulls []int
+		all   []int
+	)
+	for i, r2 := range t2.data {
+		all = append(all, i)
+		if k, ok := joinKey(r2, shared); ok {
+			idx[k] = append(idx[k], i)
+		} else {
+			nulls = append(nulls, i)
+		}
+	}
+	// candidates returns the indexes of the rows of the provided table that
+	// may be compatible with the row.
+	candidates := func(r1 Row) []int {
+		if k, ok := joinKey(r1, shared); ok {
+			return mergeIndexes(idx[k], nulls)
+		}
+		return all
+	}
+	var data []Row
+	for _, r1 := range t.data {
+		matched := false
+		for _, i := range candidates(r1) {
+			r2 := t2.data[i]
+			if !compatibleRows(r1, r2, shared) {
+				continue
+			}
+			matched = true
+			r := MergeRows([]Row{r2, r1})
+			for _, b := range shared {
+				if r1[b].IsNull() {
+					r[b] = r2[b]
+				}
+			}
+			data = append(data, r)
+		}
+		if !matched && optional {
+			r := MergeRows([]Row{r1})
+			for _, b := range extra {
+				r[b] = &Cell{}
+			}
+			data = append(data, r)
+		}
+	}
+	t.AddBindings(extra)
+	t.data = data
+}
+
+// PadBindings adds the provided bindings to the table. Rows that do not have
+// a value for any of them get a null cell.
+func (t *Table) PadBindings(bs []string) {
+	t.AddBindings(bs)
+	for _, r := range t.data {
+		for _, b := range bs {
+			if _, ok := r[b]; !ok {
+				r[b] = &Cell{}
+			}
+		}
+	}
+}
+
 // DeleteRow removes the row at position i from the table.
 func (t *Table) DeleteRow(i int) error {
 	if i < 0 || i >= len(t.data) {
@@ -311,6 +473,26 @@ func (t *Table) Limit(i int64) {
 	}
 }
 
+// Offset drops the initial ith rows.
+func (t *Table) Offset(i int64) {
+	if int64(len(t.data)) > i {
+		t.data = t.data[i:]
+		return
+	}
+	t.data = nil
+}
+
+// ContinuationToken returns the opaque token that allows fetching the rows
+// following the ones in the table. It is empty if there are no more rows.
+func (t *Table) ContinuationToken() string {
+	return t.tkn
+}
+
+// SetContinuationToken sets the continuation token of the table.
+func (t *Table) SetContinuationToken(tkn string) {
+	t.tkn = tkn
+}
+
 // SortConfig contains the sorting information. Contains the binding order
 // to use while sorting as well as the direction for each of them to use.
 type SortConfig []struct {
@@ -352,6 +534,28 @@ func stringLess(rsi, rsj string, desc bool) int {
 func CellString(s string) *string {
 	return &s
 }
+
+// sortKey returns the string used to sort the provided cell.
+func sortKey(c *Cell) string {
+	switch {
+	case c == nil:
+		return ""
+	case c.S != nil:
+		return *c.S
+	case c.N != nil:
+		return c.N.String()
+	case c.P != nil:
+		return c.P.String()
+	case c.L != nil:
+		return c.L.ToComparableString()
+	case c.T != nil:
+		return c.T.Format(time.RFC3339Nano)
+	case c.Path != nil:
+		return pathString(c.Path)
+	}
+	return ""
+}
+
 func rowLess(ri, rj Row, c SortConfig) bool {
 	if c == nil {
 		return false
@@ -365,27 +569,7 @@ func rowLess(ri, rj Row, c SortConfig) bool {
 	if !ok {
 		log.Fatalf("Could not retrieve binding %q! %v %v", cfg.Binding, ri, rj)
 	}
-	si, sj := "", ""
-	// Check if it has a string.
-	if ci.S != nil && cj.S != nil {
-		si, sj = *ci.S, *cj.S
-	}
-	// Check if it has a nodes.
-	if ci.N != nil && cj.N != nil {
-		si, sj = ci.N.String(), cj.N.String()
-	}
-	// Check if it has a predicates.
-	if ci.P != nil && cj.P != nil {
-		si, sj = ci.P.String(), cj.P.String()
-	}
-	// Check if it has a literal.
-	if ci.L != nil && cj.L != nil {
-		si, sj = ci.L.ToComparableString(), cj.L.ToComparableString()
-	}
-	// Check if it has a time anchor.
-	if ci.T != nil && cj.T != nil {
-		si, sj = ci.T.Format(time.RFC3339Nano), cj.T.Format(time.RFC3339Nano)
-	}
+	si, sj := sortKey(ci), sortKey(cj)
 	l := stringLess(si, sj, cfg.Desc)
 	if l < 0 {
 		return true
@@ -412,8 +596,7 @@ func (t *Table) Sort(cfg SortConfig) {
 
 // Accumulator type represents a generic accumulator for independent values
 // expressed as the element of the array slice. Returns the values after being
-// accumulated. If the wrong type is passed in, it will crash casting the
-// interface.
+// accumulated. If the wrong type is passed in, it returns an error.
 type Accumulator interface {
 	// Accumulate takes the given value and accumulates it to the current state.
 	Accumulate(interface{}) (interface{}, error)
@@ -422,6 +605,22 @@ type Accumulator interface {
 	Reset()
 }
 
+// literalValue returns the literal held by the provided value. Accumulators
+// can be fed literals or the cells containing them.
+func literalValue(v interface{}) (*literal.Literal, error) {
+	switch lv := v.(type) {
+	case *literal.Literal:
+		if lv != nil {
+			return lv, nil
+		}
+	case *Cell:
+		if lv != nil && lv.L != nil {
+			return lv.L, nil
+		}
+	}
+	return nil, fmt.Errorf("accumulator requires a literal value; found %v instead", v)
+}
+
 // sumInt64 implements an accumulator that sum int64 values.
 type sumInt64 struct {
 	initialState int64
@@ -430,7 +629,10 @@ type sumInt64 struct {
 
 // Accumulate takes the given value and accumulates it to the current state.
 func (s *sumInt64) Accumulate(v interface{}) (interface{}, error) {
-	l := v.(*literal.Literal)
+	l, err := literalValue(v)
+	if err != nil {
+		return s.state, err
+	}
 	iv, err := l.Int64()
 	if err != nil {
 		return s.state, err
@@ -457,7 +659,10 @@ type sumFloat64 struct {
 
 // Accumulate takes the given value and accumulates it to the current state.
 func (s *sumFloat64) Accumulate(v interface{}) (interface{}, error) {
-	l := v.(*literal.Literal)
+	l, err := literalValue(v)
+	if err != nil {
+		return s.state, err
+	}
 	iv, err := l.Float64()
 	if err != nil {
 		return s.state, err
@@ -520,6 +725,222 @@ func NewCountDistinctAccumulator() Accumulator {
 	return &countDistinctAcc{make(map[string]int64)}
 }
 
+// avgAcc implements an accumulator that averages int64 and float64 values.
+type avgAcc struct {
+	sum float64
+	cnt int64
+}
+
+// Accumulate takes the given value and accumulates it to the current state.
+func (a *avgAcc) Accumulate(v interface{}) (interface{}, error) {
+	l, err := literalValue(v)
+	if err != nil {
+		return nil, err
+	}
+	switch l.Type() {
+	case literal.Int64:
+		iv, err := l.Int64()
+		if err != nil {
+			return nil, err
+		}
+		a.sum += float64(iv)
+	case literal.Float64:
+		fv, err := l.Float64()
+		if err != nil {
+			return nil, err
+		}
+		a.sum += fv
+	default:
+		return nil, fmt.Errorf("can only average int64 and float64 literals; found literal type %s instead", l.Type())
+	}
+	a.cnt++
+	return a.sum / float64(a.cnt), nil
+}
+
+// Resets the current state back to the original one.
+func (a *avgAcc) Reset() {
+	a.sum, a.cnt = 0, 0
+}
+
+// NewAvgAccumulator averages the int64 and float64 values of literals.
+func NewAvgAccumulator() Accumulator {
+	return &avgAcc{}
+}
+
+// toCell returns the cell for the provided accumulated value.
+func toCell(v interface{}) (*Cell, error) {
+	switch cv := v.(type) {
+	case *Cell:
+		if cv != nil {
+			return cv, nil
+		}
+	case *literal.Literal:
+		if cv != nil {
+			return &Cell{L: cv}, nil
+		}
+	case *time.Time:
+		if cv != nil {
+			return &Cell{T: cv}, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot accumulate value %v", v)
+}
+
+// compareCells returns a negative number if c1 is smaller than c2, zero if
+// they are equal, and a positive number otherwise. Only literals of the same
+// type and time anchors can be compared.
+func compareCells(c1, c2 *Cell) (int, error) {
+	switch {
+	case c1.L != nil && c2.L != nil:
+		l1, l2 := c1.L, c2.L
+		if l1.Type() != l2.Type() {
+			return 0, fmt.Errorf("cannot compare literals of types %s and %s", l1.Type(), l2.Type())
+		}
+		switch l1.Type() {
+		case literal.Int64:
+			i1, _ := l1.Int64()
+			i2, _ := l2.Int64()
+			switch {
+			case i1 < i2:
+				return -1, nil
+			case i1 > i2:
+				return 1, nil
+			}
+			return 0, nil
+		case literal.Float64:
+			f1, _ := l1.Float64()
+			f2, _ := l2.Float64()
+			switch {
+			case f1 < f2:
+				return -1, nil
+			case f1 > f2:
+				return 1, nil
+			}
+			return 0, nil
+		default:
+			return strings.Compare(l1.ToComparableString(), l2.ToComparableString()), nil
+		}
+	case c1.T != nil && c2.T != nil:
+		switch {
+		case c1.T.Before(*c2.T):
+			return -1, nil
+		case c1.T.After(*c2.T):
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("can only compare literals and time anchors; found %s and %s instead", c1, c2)
+	}
+}
+
+// minMaxAcc implements an accumulator that keeps the smallest or the largest
+// literal or time anchor.
+type minMaxAcc struct {
+	max   bool
+	state *Cell
+}
+
+// Accumulate takes the given value and accumulates it to the current state.
+func (m *minMaxAcc) Accumulate(v interface{}) (interface{}, error) {
+	c, err := toCell(v)
+	if err != nil {
+		return nil, err
+	}
+	if m.state == nil {
+		if c.L == nil && c.T == nil {
+			return nil, fmt.Errorf("can only compare literals and time anchors; found %s instead", c)
+		}
+		m.state = c
+		return m.state, nil
+	}
+	cmp, err := compareCells(c, m.state)
+	if err != nil {
+		return nil, err
+	}
+	if (m.max && cmp > 0) || (!m.max && cmp < 0) {
+		m.state = c
+	}
+	return m.state, nil
+}
+
+// Resets the current state back to the original one.
+func (m *minMaxAcc) Reset() {
+	m.state = nil
+}
+
+// NewMinAccumulator keeps the smallest of the accumulated literals or time
+// anchors.
+func NewMinAccumulator() Accumulator {
+	return &minMaxAcc{}
+}
+
+// NewMaxAccumulator keeps the largest of the accumulated literals or time
+// anchors.
+func NewMaxAccumulator() Accumulator {
+	return &minMaxAcc{max: true}
+}
+
+// groupConcatAcc implements an accumulator that concatenates the text of the
+// accumulated values.
+type groupConcatAcc struct {
+	sep   string
+	state []string
+}
+
+// Accumulate takes the given value and accumulates it to the current state.
+func (g *groupConcatAcc) Accumulate(v interface{}) (interface{}, error) {
+	c, err := toCell(v)
+	if err != nil {
+		return nil, err
+	}
+	s := c.String()
+	if c.L != nil {
+		s = fmt.Sprintf("%v", c.L.Interface())
+	}
+	g.state = append(g.state, s)
+	return strings.Join(g.state, g.sep), nil
+}
+
+// Resets the current state back to the original one.
+func (g *groupConcatAcc) Reset() {
+	g.state = nil
+}
+
+// NewGroupConcatAccumulator concatenates the text of the accumulated values
+// using the provided separator.
+func NewGroupConcatAccumulator(sep string) Accumulator {
+	return &groupConcatAcc{sep: sep}
+}
+
+// accumulatedCell returns the cell holding the value returned by an
+// accumulator.
+func accumulatedCell(v interface{}) (*Cell, error) {
+	switch av := v.(type) {
+	case int64:
+		l, err := literal.DefaultBuilder().Build(literal.Int64, av)
+		if err != nil {
+			return nil, err
+		}
+		return &Cell{L: l}, nil
+	case float64:
+		l, err := literal.DefaultBuilder().Build(literal.Float64, av)
+		if err != nil {
+			return nil, err
+		}
+		return &Cell{L: l}, nil
+	case string:
+		l, err := literal.DefaultBuilder().Build(literal.Text, av)
+		if err != nil {
+			return nil, err
+		}
+		return &Cell{L: l}, nil
+	case *Cell:
+		return av, nil
+	default:
+		return nil, fmt.Errorf("unknown accumulated value %v", v)
+	}
+}
+
 // groupRangeReduce takes a sorted range and generates a new row containing
 // the aggregated columns and the non aggregated ones.
 func (t *Table) groupRangeReduce(i, j int, alias map[string]string, acc map[string]Accumulator) (Row, error) {
@@ -559,23 +980,11 @@ func (t *Table) groupRangeReduce(i, j int, alias map[string]string, acc map[stri
 			if !ok {
 				return nil, fmt.Errorf("aggregated bindings require and alias; binding %s missing alias", b)
 			}
-			// Accumulators currently only can return numeric literals.
-			switch acc.(type) {
-			case int64:
-				l, err := literal.DefaultBuilder().Build(literal.Int64, acc)
-				if err != nil {
-					return nil, err
-				}
-				newRow[a] = &Cell{L: l}
-			case float64:
-				l, err := literal.DefaultBuilder().Build(literal.Float64, acc)
-				if err != nil {
-					return nil, err
-				}
-				newRow[a] = &Cell{L: l}
-			default:
+			c, err := accumulatedCell(acc)
+			if err != nil {
 				return nil, fmt.Errorf("aggregation of binding %s returned unknown value %v or type", b, acc)
 			}
+			newRow[a] = c
 		}
 	}
 	return newRow, nil
@@ -594,8 +1003,15 @@ func (t *Table) fullGroupRangeReduce(i, j int, acc map[string]map[string]AliasAc
 	if i > j {
 		return nil, fmt.Errorf("cannot aggregate empty ranges [%d, %d)", i, j)
 	}
-	// Initialize the range and accumulator results.
-	rng := t.data[i:j]
+	return reduceRange(t.data[i:j], acc)
+}
+
+// reduceRange generates a new row containing the aggregated columns and the
+// non aggregated ones of a group of rows.
+func reduceRange(rng []Row, acc map[string]map[string]AliasAccPair) (Row, error) {
+	if len(rng) == 0 {
+		return nil, errors.New("cannot aggregate an empty group of rows")
+	}
 	// Reset the accumulators.
 	for _, aap := range acc {
 		for _, a := range aap {
@@ -631,23 +1047,11 @@ func (t *Table) fullGroupRangeReduce(i, j int, acc map[string]map[string]AliasAc
 			if app.Acc == nil {
 				newRow[app.OutAlias] = v
 			} else {
-				// Accumulators currently only can return numeric literals.
-				switch vaccs[app.InAlias][app.OutAlias].(type) {
-				case int64:
-					l, err := literal.DefaultBuilder().Build(literal.Int64, vaccs[app.InAlias][app.OutAlias])
-					if err != nil {
-						return nil, err
-					}
-					newRow[app.OutAlias] = &Cell{L: l}
-				case float64:
-					l, err := literal.DefaultBuilder().Build(literal.Float64, vaccs[app.InAlias][app.OutAlias])
-					if err != nil {
-						return nil, err
-					}
-					newRow[app.OutAlias] = &Cell{L: l}
-				default:
+				c, err := accumulatedCell(vaccs[app.InAlias][app.OutAlias])
+				if err != nil {
 					return nil, fmt.Errorf("aggregation of binding %s returned unknown value %v or type", b, acc)
 				}
+				newRow[app.OutAlias] = c
 			}
 		}
 	}
@@ -677,33 +1081,74 @@ func toMap(aaps []AliasAccPair) map[string]map[string]AliasAccPair {
 // accumulator functions to each group. Finally, the table metadata gets
 // updated to reflect the reduce operation.
 func (t *Table) Reduce(cfg SortConfig, aaps []AliasAccPair) error {
+	maaps, err := t.reduceConfig(cfg, aaps)
+	if err != nil {
+		return err
+	}
+	// Valid reduce configuration. Reduce sorts the table and then reduces
+	// contiguous groups row groups.
+	if t.NumRows() == 0 {
+		return nil
+	}
+	t.Sort(cfg)
+	rows := t.data
+	return t.reduce(cfg, aaps, maaps, func(f func(Row) error) error {
+		for _, r := range rows {
+			if err := f(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReduceSorted alters the table by range grouping the rows merged by the
+// provided sorter, instead of the ones in the table. Only the rows of the
+// group being reduced are held in memory. The table must contain the bindings
+// of the rows added to the sorter.
+func (t *Table) ReduceSorted(s *Sorter, aaps []AliasAccPair) error {
+	maaps, err := t.reduceConfig(s.cfg, aaps)
+	if err != nil {
+		return err
+	}
+	return t.reduce(s.cfg, aaps, maaps, s.Merge)
+}
+
+// reduceConfig validates the reduce configuration for the table and returns
+// the accumulators to use indexed by input and output binding.
+func (t *Table) reduceConfig(cfg SortConfig, aaps []AliasAccPair) (map[string]map[string]AliasAccPair, error) {
 	maaps := toMap(aaps)
 	// Input validation tests.
 	if len(t.bs) != len(maaps) {
-		return fmt.Errorf("table.Reduce cannot project bindings; current %v, requested %v", t.bs, aaps)
+		return nil, fmt.Errorf("table.Reduce cannot project bindings; current %v, requested %v", t.bs, aaps)
 	}
 	for _, b := range t.bs {
 		if _, ok := maaps[b]; !ok {
-			return fmt.Errorf("table.Reduce missing binding alias for %q", b)
+			return nil, fmt.Errorf("table.Reduce missing binding alias for %q", b)
 		}
 	}
 	cnt := 0
 	for b := range maaps {
 		if _, ok := t.mbs[b]; !ok {
-			return fmt.Errorf("table.Reduce unknown reducer binding %q; available bindings %v", b, t.bs)
+			return nil, fmt.Errorf("table.Reduce unknown reducer binding %q; available bindings %v", b, t.bs)
 		}
 		cnt++
 	}
 	if cnt != len(t.bs) {
-		return fmt.Errorf("table.Reduce invalid reduce configuration in cfg=%v, aap=%v for table with binding %v", cfg, aaps, t.bs)
+		return nil, fmt.Errorf("table.Reduce invalid reduce configuration in cfg=%v, aap=%v for table with binding %v", cfg, aaps, t.bs)
 	}
-	// Valid reduce configuration. Reduce sorts the table and then reduces
-	// contiguous groups row groups.
-	if t.NumRows() == 0 {
-		return nil
-	}
-	t.Sort(cfg)
-	last, lastIdx, current, newData := "", 0, "", []Row{}
+	return maaps, nil
+}
+
+// reduce replaces the data of the table with the reduced groups of contiguous
+// rows provided by each, which must be sorted by the sort configuration.
+func (t *Table) reduce(cfg SortConfig, aaps []AliasAccPair, maaps map[string]map[string]AliasAccPair, each func(func(Row) error) error) error {
+	var (
+		first   = true
+		last    string
+		rng     []Row
+		newData []Row
+	)
 	id := func(r Row) string {
 		res := ""
 		for _, c := range cfg {
@@ -711,26 +1156,27 @@ func (t *Table) Reduce(cfg SortConfig, aaps []AliasAccPair) error {
 		}
 		return res
 	}
-	for idx, r := range t.data {
-		current = id(r)
-		// First time.
-		if last == "" {
-			last, lastIdx = current, idx
-			continue
-		}
-		// Still in the same group.
-		if last == current {
-			continue
-		}
-		// A group reduce operation is needed.
-		nr, err := t.fullGroupRangeReduce(lastIdx, idx, maaps)
-		if err != nil {
-			return err
+	if err := each(func(r Row) error {
+		current := id(r)
+		if !first && last != current {
+			// A group reduce operation is needed.
+			nr, err := reduceRange(rng, maaps)
+			if err != nil {
+				return err
+			}
+			newData = append(newData, nr)
+			rng = rng[:0]
 		}
-		newData = append(newData, nr)
-		last, lastIdx = current, idx
+		first, last = false, current
+		rng = append(rng, r)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if first {
+		return nil
 	}
-	nr, err := t.fullGroupRangeReduce(lastIdx, len(t.data), maaps)
+	nr, err := reduceRange(rng, maaps)
 	if err != nil {
 		return err
 	}
@@ -23,6 +23,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/badwolf/triple"
 	"github.com/google/badwolf/triple/literal"
 	"github.com/google/badwolf/triple/node"
 	"github.com/google/badwolf/triple/predicate"
@@ -57,6 +58,14 @@ func TestCellString(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to create literal with error %v", err)
 	}
+	t1, err := triple.Parse(`/u<a>	"foo"@[]	/u<b>`, literal.DefaultBuilder())
+	if err != nil {
+		t.Fatalf("failed to create triple with error %v", err)
+	}
+	t2, err := triple.Parse(`/u<b>	"foo"@[]	/u<c>`, literal.DefaultBuilder())
+	if err != nil {
+		t.Fatalf("failed to create triple with error %v", err)
+	}
 	testTable := []struct {
 		c    *Cell
 		want string
@@ -66,6 +75,8 @@ func TestCellString(t *testing.T) {
 		{c: &Cell{P: p}, want: p.String()},
 		{c: &Cell{L: l}, want: l.String()},
 		{c: &Cell{T: &now}, want: now.Format(time.RFC3339Nano)},
+		{c: &Cell{Path: []*triple.Triple{}}, want: `[]`},
+		{c: &Cell{Path: []*triple.Triple{t1, t2}}, want: `[/u<a> "foo"@[] /u<b>, /u<b> "foo"@[] /u<c>]`},
 	}
 	for _, entry := range testTable {
 		if got := entry.c.String(); got != entry.want {
@@ -455,6 +466,100 @@ func TestDotProductContent(t *testing.T) {
 	}
 }
 
+func TestLeftOptionalJoin(t *testing.T) {
+	cell := func(s string) *Cell {
+		return &Cell{S: CellString(s)}
+	}
+	t1, err := New([]string{"?s", "?o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t1.AddRow(Row{"?s": cell("a"), "?o": cell("x")})
+	t1.AddRow(Row{"?s": cell("b"), "?o": cell("y")})
+	t1.AddRow(Row{"?s": cell("c"), "?o": &Cell{}})
+	t2, err := New([]string{"?o", "?v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2.AddRow(Row{"?o": cell("x"), "?v": cell("1")})
+	t2.AddRow(Row{"?o": cell("x"), "?v": cell("2")})
+	t2.AddRow(Row{"?o": cell("z"), "?v": cell("3")})
+	t1.LeftOptionalJoin(t2)
+
+	if got, want := len(t1.Bindings()), 3; got != want {
+		t.Errorf("LeftOptionalJoin returned the wrong number of bindings; got %d, want %d", got, want)
+	}
+	var got []string
+	for _, r := range t1.Rows() {
+		got = append(got, fmt.Sprintf("%s %s %s", r["?s"], r["?o"], r["?v"]))
+	}
+	want := []string{
+		"a x 1", "a x 2",
+		"b y <NULL>",
+		"c x 1", "c x 2", "c z 3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LeftOptionalJoin returned the wrong rows; got %v, want %v", got, want)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	cell := func(s string) *Cell {
+		return &Cell{S: CellString(s)}
+	}
+	t1, err := New([]string{"?s", "?o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t1.AddRow(Row{"?s": cell("a"), "?o": cell("x")})
+	t1.AddRow(Row{"?s": cell("b"), "?o": cell("y")})
+	t2, err := New([]string{"?o", "?v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2.AddRow(Row{"?o": cell("x"), "?v": cell("1")})
+	t2.AddRow(Row{"?o": &Cell{}, "?v": cell("2")})
+	t1.Join(t2)
+
+	var got []string
+	for _, r := range t1.Rows() {
+		got = append(got, fmt.Sprintf("%s %s %s", r["?s"], r["?o"], r["?v"]))
+	}
+	want := []string{"a x 1", "a x 2", "b y 2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Join returned the wrong rows; got %v, want %v", got, want)
+	}
+}
+
+func TestPadBindings(t *testing.T) {
+	tbl, err := New([]string{"?s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl.AddRow(Row{"?s": &Cell{S: CellString("a")}})
+	tbl.PadBindings([]string{"?s", "?o"})
+	if got, want := tbl.Bindings(), []string{"?s", "?o"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PadBindings returned the wrong bindings; got %v, want %v", got, want)
+	}
+	r, _ := tbl.Row(0)
+	if r["?s"].IsNull() || !r["?o"].IsNull() {
+		t.Errorf("PadBindings should only add null cells for missing bindings; got %v", r)
+	}
+}
+
+func TestCellIsNull(t *testing.T) {
+	var c *Cell
+	if !c.IsNull() || !(&Cell{}).IsNull() {
+		t.Errorf("nil and empty cells should be null")
+	}
+	if (&Cell{S: CellString("foo")}).IsNull() {
+		t.Errorf("cells with values should not be null")
+	}
+	if (&Cell{Path: []*triple.Triple{}}).IsNull() {
+		t.Errorf("cells with empty paths should not be null")
+	}
+}
+
 func TestDeleteRow(t *testing.T) {
 	testTable := []struct {
 		t   *Table
@@ -534,6 +639,30 @@ func Limit(t *testing.T) {
 	}
 }
 
+func TestOffset(t *testing.T) {
+	testTable := []struct {
+		in   int64
+		want []string
+	}{
+		{0, []string{"?foo_0", "?foo_1", "?foo_2"}},
+		{1, []string{"?foo_1", "?foo_2"}},
+		{2, []string{"?foo_2"}},
+		{3, nil},
+		{100, nil},
+	}
+	for _, entry := range testTable {
+		tbl := testDotTable(t, []string{"?foo"}, 3)
+		tbl.Offset(entry.in)
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?foo"].String())
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("tbl.Offset(%d) returned the wrong rows; got %v, want %v", entry.in, got, entry.want)
+		}
+	}
+}
+
 func TestStringLess(t *testing.T) {
 	testTable := []struct {
 		i    string
@@ -661,6 +790,88 @@ func TestSumAccumulators(t *testing.T) {
 	}
 }
 
+func TestSumAccumulatorsOnCells(t *testing.T) {
+	ia := NewSumInt64LiteralAccumulator(0)
+	var (
+		iv  interface{}
+		err error
+	)
+	for i := int64(0); i < 5; i++ {
+		l, _ := literal.DefaultBuilder().Build(literal.Int64, i)
+		if iv, err = ia.Accumulate(&Cell{L: l}); err != nil {
+			t.Fatalf("Int64 sum accumulator failed to accumulate a cell with error %v", err)
+		}
+	}
+	if got, want := iv.(int64), int64(10); got != want {
+		t.Errorf("Int64 sum accumulator failed; got %d, want %d", got, want)
+	}
+	if _, err := ia.Accumulate(&Cell{S: CellString("foo")}); err == nil {
+		t.Errorf("Int64 sum accumulator should have failed to accumulate a non literal cell")
+	}
+}
+
+func TestAvgAccumulator(t *testing.T) {
+	a := NewAvgAccumulator()
+	var v interface{}
+	for i := int64(1); i <= 4; i++ {
+		l, _ := literal.DefaultBuilder().Build(literal.Int64, i)
+		v, _ = a.Accumulate(&Cell{L: l})
+	}
+	if got, want := v.(float64), 2.5; got != want {
+		t.Errorf("Avg accumulator failed; got %f, want %f", got, want)
+	}
+	l, _ := literal.DefaultBuilder().Build(literal.Text, "foo")
+	if _, err := a.Accumulate(&Cell{L: l}); err == nil {
+		t.Errorf("Avg accumulator should have failed to accumulate a text literal")
+	}
+}
+
+func TestMinMaxAccumulators(t *testing.T) {
+	mn, mx := NewMinAccumulator(), NewMaxAccumulator()
+	var vmn, vmx interface{}
+	for _, f := range []float64{3.5, -1, 7, 2} {
+		l, _ := literal.DefaultBuilder().Build(literal.Float64, f)
+		vmn, _ = mn.Accumulate(&Cell{L: l})
+		vmx, _ = mx.Accumulate(&Cell{L: l})
+	}
+	if got, _ := vmn.(*Cell).L.Float64(); got != -1 {
+		t.Errorf("Min accumulator failed; got %f, want -1", got)
+	}
+	if got, _ := vmx.(*Cell).L.Float64(); got != 7 {
+		t.Errorf("Max accumulator failed; got %f, want 7", got)
+	}
+	mn.Reset()
+	now := time.Now()
+	before := now.Add(-time.Hour)
+	mn.Accumulate(&Cell{T: &now})
+	vmn, _ = mn.Accumulate(&Cell{T: &before})
+	if got := vmn.(*Cell).T; !got.Equal(before) {
+		t.Errorf("Min accumulator failed; got %v, want %v", got, before)
+	}
+	l, _ := literal.DefaultBuilder().Build(literal.Int64, int64(1))
+	if _, err := mn.Accumulate(&Cell{L: l}); err == nil {
+		t.Errorf("Min accumulator should have failed to compare a literal with a time anchor")
+	}
+}
+
+func TestGroupConcatAccumulator(t *testing.T) {
+	g := NewGroupConcatAccumulator(", ")
+	var v interface{}
+	for _, s := range []string{"foo", "bar"} {
+		l, _ := literal.DefaultBuilder().Build(literal.Text, s)
+		v, _ = g.Accumulate(&Cell{L: l})
+	}
+	n, _ := node.Parse("/u<joe>")
+	v, _ = g.Accumulate(&Cell{N: n})
+	if got, want := v.(string), "foo, bar, /u<joe>"; got != want {
+		t.Errorf("GroupConcat accumulator failed; got %q, want %q", got, want)
+	}
+	g.Reset()
+	if v, _ = g.Accumulate(&Cell{N: n}); v.(string) != "/u<joe>" {
+		t.Errorf("GroupConcat accumulator failed to reset; got %q", v)
+	}
+}
+
 func TestCountAccumulators(t *testing.T) {
 	// Count accumulator.
 	var (
@@ -1135,3 +1346,77 @@ func TestFilter(t *testing.T) {
 		}
 	}
 }
+
+func TestJoinIndexesRows(t *testing.T) {
+	cell := func(s string) *Cell {
+		return &Cell{S: CellString(s)}
+	}
+	t1, err := New([]string{"?s", "?o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t1.AddRow(Row{"?s": cell("a"), "?o": cell("x")})
+	t1.AddRow(Row{"?s": cell("b"), "?o": &Cell{}})
+	t1.AddRow(Row{"?s": cell("c"), "?o": cell("y")})
+	t1.AddRow(Row{"?s": cell("a"), "?o": cell("z")})
+	t2, err := New([]string{"?s", "?o", "?v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2.AddRow(Row{"?s": cell("a"), "?o": cell("x"), "?v": cell("1")})
+	t2.AddRow(Row{"?s": cell("c"), "?o": &Cell{}, "?v": cell("2")})
+	t2.AddRow(Row{"?s": cell("a"), "?o": cell("x"), "?v": cell("3")})
+	t2.AddRow(Row{"?s": cell("b"), "?o": cell("w"), "?v": cell("4")})
+	t2.AddRow(Row{"?s": cell("a"), "?o": cell("y"), "?v": cell("5")})
+	t1.Join(t2)
+
+	var got []string
+	for _, r := range t1.Rows() {
+		got = append(got, fmt.Sprintf("%s %s %s", r["?s"], r["?o"], r["?v"]))
+	}
+	want := []string{"a x 1", "a x 3", "b w 4", "c y 2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Join returned the wrong rows; got %v, want %v", got, want)
+	}
+}
+
+func TestMergeIndexes(t *testing.T) {
+	testTable := []struct {
+		a, b, want []int
+	}{
+		{nil, nil, nil},
+		{[]int{1, 3}, nil, []int{1, 3}},
+		{nil, []int{2}, []int{2}},
+		{[]int{0, 3, 4}, []int{1, 2, 5}, []int{0, 1, 2, 3, 4, 5}},
+	}
+	for _, entry := range testTable {
+		if got := mergeIndexes(entry.a, entry.b); len(got) != len(entry.want) || len(got) > 0 && !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("mergeIndexes(%v, %v) returned %v; want %v", entry.a, entry.b, got, entry.want)
+		}
+	}
+}
+
+func BenchmarkJoin(b *testing.B) {
+	t1, err := New([]string{"?s", "?o"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	t2, err := New([]string{"?o", "?v"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		s, o := fmt.Sprintf("s%d", i), fmt.Sprintf("o%d", i)
+		t1.AddRow(Row{"?s": &Cell{S: CellString(s)}, "?o": &Cell{S: CellString(o)}})
+		t2.AddRow(Row{"?o": &Cell{S: CellString(o)}, "?v": &Cell{S: CellString(s)}})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		t, err := New([]string{"?s", "?o"})
+		if err != nil {
+			b.Fatal(err)
+		}
+		t.data = t1.data
+		t.Join(t2)
+	}
+}
@@ -29,9 +29,19 @@ import (
 	"github.com/google/badwolf/triple/literal"
 )
 
+var (
+	// escaper escapes the backslashes and new lines of a serialized triple, so
+	// it takes a single line.
+	escaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	// unescaper reverts the escaping applied by escaper. Backslashes not
+	// followed by another backslash or an n are kept as they are.
+	unescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+)
+
 // ReadIntoGraph reads a graph out of the provided reader. The data on the
 // reader is interpret as text. Each line represents one triple using the
-// standard serialized format. ReadIntoGraph will stop if fails to Parse
+// standard serialized format, with backslashes and new lines escaped as
+// "\\" and "\n". ReadIntoGraph will stop if fails to Parse
 // a triple on the stream. The triples read till then would have also been
 // added to the graph. The int value returns the number of triples added.
 func ReadIntoGraph(ctx context.Context, g storage.Graph, r io.Reader, b literal.Builder) (int, error) {
@@ -42,7 +52,7 @@ func ReadIntoGraph(ctx context.Context, g storage.Graph, r io.Reader, b literal.
 		if text == "" {
 			continue
 		}
-		t, err := triple.Parse(text, b)
+		t, err := ParseTriple(text, b)
 		if err != nil {
 			return cnt, err
 		}
@@ -52,11 +62,32 @@ func ReadIntoGraph(ctx context.Context, g storage.Graph, r io.Reader, b literal.
 	return cnt, nil
 }
 
+// ParseTriple parses a triple serialized on a single line, reverting the
+// escaping applied by WriteGraph.
+func ParseTriple(line string, b literal.Builder) (*triple.Triple, error) {
+	return triple.Parse(unescaper.Replace(line), b)
+}
+
+// TripleSource is implemented by anything able to stream triples, like graphs
+// or the plans of construct statements.
+type TripleSource interface {
+	// Triples sends all the available triples to the provided channel, and
+	// closes it when done.
+	Triples(ctx context.Context, trpls chan<- *triple.Triple) error
+}
+
 // WriteGraph serializes the graph into the writer where each triple is
-// marshaled into a separate line. If there is an error writing the
-// serialization will stop. It returns the number of triples serialized
-// regardless if it succeeded or failed partially.
+// marshaled into a separate line. Backslashes and new lines in the triples are
+// escaped, so literals spanning several lines can be read back. If there is an
+// error writing the serialization will stop. It returns the number of triples
+// serialized regardless if it succeeded or failed partially.
 func WriteGraph(ctx context.Context, w io.Writer, g storage.Graph) (int, error) {
+	return WriteTriples(ctx, w, g)
+}
+
+// WriteTriples serializes the triples of the source into the writer using the
+// same format as WriteGraph.
+func WriteTriples(ctx context.Context, w io.Writer, src TripleSource) (int, error) {
 	var (
 		wg   sync.WaitGroup
 		tErr error
@@ -66,13 +97,13 @@ func WriteGraph(ctx context.Context, w io.Writer, g storage.Graph) (int, error)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		tErr = g.Triples(ctx, ts)
+		tErr = src.Triples(ctx, ts)
 	}()
 	for t := range ts {
 		if wErr != nil {
 			continue
 		}
-		if _, err := io.WriteString(w, fmt.Sprintf("%s\n", t.String())); err != nil {
+		if _, err := io.WriteString(w, fmt.Sprintf("%s\n", escaper.Replace(t.String()))); err != nil {
 			wErr = err
 			continue
 		}
@@ -74,6 +74,38 @@ func TestWriteIntoGraph(t *testing.T) {
 	}
 }
 
+// sliceSource is a triple source backed by a list of triples.
+type sliceSource []*triple.Triple
+
+func (s sliceSource) Triples(ctx context.Context, trpls chan<- *triple.Triple) error {
+	defer close(trpls)
+	for _, t := range s {
+		trpls <- t
+	}
+	return nil
+}
+
+func TestWriteTriples(t *testing.T) {
+	var (
+		buffer bytes.Buffer
+		want   string
+	)
+	ts, ctx := getTestTriples(t), context.Background()
+	for _, trpl := range ts {
+		want += trpl.String() + "\n"
+	}
+	cnt, err := WriteTriples(ctx, &buffer, sliceSource(ts))
+	if err != nil {
+		t.Errorf("io.WriteTriples failed to write %v with error %v", ts, err)
+	}
+	if cnt != 6 {
+		t.Errorf("io.WriteTriples should have been able to write 6 triples not %d", cnt)
+	}
+	if got := buffer.String(); got != want {
+		t.Errorf("io.WriteTriples wrote the wrong serialization; got %q, want %q", got, want)
+	}
+}
+
 func TestSerializationContents(t *testing.T) {
 	var buffer bytes.Buffer
 	ts, ctx := getTestTriples(t), context.Background()
@@ -135,3 +167,45 @@ func TestSerializationContents(t *testing.T) {
 		t.Errorf("Failed to unmarshal marshaled the right number of triples, %d != %d != 6", gs, gos)
 	}
 }
+
+func TestSerializeMultilineLiterals(t *testing.T) {
+	var buffer bytes.Buffer
+	ctx := context.Background()
+	var ts []*triple.Triple
+	for _, s := range []string{
+		"/u<john>\t\"says\"@[]\t\"a\nb\"^^type:text",
+		"/u<john>\t\"says\"@[]\t\"a\\nb\"^^type:text",
+		"/u<john>\t\"says\"@[]\t\"\\\n\\\"^^type:text",
+	} {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse failed to parse valid triple %q with error %v", s, err)
+		}
+		ts = append(ts, trpl)
+	}
+	g, err := memory.NewStore().NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("memory.NewStore().NewGraph should have never failed to create a graph")
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("storage.AddTriples should have not fail to add triples %v with error %v", ts, err)
+	}
+	if _, err := WriteGraph(ctx, &buffer, g); err != nil {
+		t.Errorf("io.WriteGraph failed with error %v", err)
+	}
+	if got, want := bytes.Count(buffer.Bytes(), []byte("\n")), len(ts); got != want {
+		t.Errorf("io.WriteGraph should write one line per triple; got %d lines, want %d", got, want)
+	}
+	g2, err := memory.NewStore().NewGraph(ctx, "test2")
+	if err != nil {
+		t.Fatalf("memory.NewStore().NewGraph should have never failed to create a graph")
+	}
+	if _, err := ReadIntoGraph(ctx, g2, &buffer, literal.DefaultBuilder()); err != nil {
+		t.Errorf("io.ReadIntoGraph failed with error %v", err)
+	}
+	for _, trpl := range ts {
+		if b, err := g2.Exist(ctx, trpl); err != nil || !b {
+			t.Errorf("g2.Exist(%q) = %v, %v; want true, nil", trpl, b, err)
+		}
+	}
+}
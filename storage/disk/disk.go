@@ -0,0 +1,444 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package disk provides a persistent file-based implementation of the
+// storage.Store and storage.Graph interfaces.
+//
+// Each graph is kept in its own append only journal file inside the store
+// directory. Every batch of additions or removals is appended to the journal
+// and synced before it becomes visible. Each journal record takes a single
+// line; backslashes and new lines in the triples are escaped. When a store is
+// opened the journals are replayed to rebuild the same six indexes (S, P, O,
+// SP, PO, SO) used by the memory driver, which then serve all the lookups.
+package disk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+const (
+	// graphExt is the extension used by the graph journal files.
+	graphExt = ".graph"
+	// addOp marks a journal record that adds a triple.
+	addOp = '+'
+	// removeOp marks a journal record that removes a triple.
+	removeOp = '-'
+)
+
+type diskStore struct {
+	dir    string
+	graphs map[string]*graph
+	rwmu   sync.RWMutex
+}
+
+// NewStore opens the store kept in the provided directory. The directory is
+// created if it does not exist yet. All the graphs found in the directory are
+// loaded back.
+func NewStore(dir string) (storage.Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("disk.NewStore(%q): failed to create store directory with error %v", dir, err)
+	}
+	s := &diskStore{
+		dir:    dir,
+		graphs: make(map[string]*graph),
+	}
+	fs, err := filepath.Glob(filepath.Join(dir, "*"+graphExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fs {
+		id, err := hex.DecodeString(strings.TrimSuffix(filepath.Base(f), graphExt))
+		if err != nil {
+			return nil, fmt.Errorf("disk.NewStore(%q): invalid graph file name %q", dir, f)
+		}
+		g, err := openGraph(string(id), f)
+		if err != nil {
+			return nil, err
+		}
+		s.graphs[string(id)] = g
+	}
+	return s, nil
+}
+
+// Name returns the ID of the backend being used.
+func (s *diskStore) Name(ctx context.Context) string {
+	return "DISK"
+}
+
+// Version returns the version of the driver implementation.
+func (s *diskStore) Version(ctx context.Context) string {
+	return "0.1.vcli"
+}
+
+// graphPath returns the path of the journal file for the provided graph id.
+func (s *diskStore) graphPath(id string) string {
+	return filepath.Join(s.dir, hex.EncodeToString([]byte(id))+graphExt)
+}
+
+// NewGraph creates a new graph.
+func (s *diskStore) NewGraph(ctx context.Context, id string) (storage.Graph, error) {
+	s.rwmu.Lock()
+	defer s.rwmu.Unlock()
+	if _, ok := s.graphs[id]; ok {
+		return nil, fmt.Errorf("disk.NewGraph(%q): graph already exists", id)
+	}
+	g, err := openGraph(id, s.graphPath(id))
+	if err != nil {
+		return nil, err
+	}
+	// Make sure the new journal survives a crash.
+	if err := syncDir(s.dir); err != nil {
+		g.f.Close()
+		return nil, err
+	}
+	s.graphs[id] = g
+	return g, nil
+}
+
+// Graph returns an existing graph if available. Getting a non existing
+// graph should return an error.
+func (s *diskStore) Graph(ctx context.Context, id string) (storage.Graph, error) {
+	s.rwmu.RLock()
+	defer s.rwmu.RUnlock()
+	if g, ok := s.graphs[id]; ok {
+		return g, nil
+	}
+	return nil, fmt.Errorf("disk.Graph(%q): graph does not exist", id)
+}
+
+// DeleteGraph deletes an existing graph. Deleting a non existing graph
+// should return an error.
+func (s *diskStore) DeleteGraph(ctx context.Context, id string) error {
+	s.rwmu.Lock()
+	defer s.rwmu.Unlock()
+	g, ok := s.graphs[id]
+	if !ok {
+		return fmt.Errorf("disk.DeleteGraph(%q): graph does not exist", id)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.f.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(g.path); err != nil {
+		return err
+	}
+	delete(s.graphs, id)
+	return syncDir(s.dir)
+}
+
+// GraphNames returns the current available graph names in the store.
+func (s *diskStore) GraphNames(ctx context.Context, names chan<- string) error {
+	if names == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	s.rwmu.RLock()
+	defer s.rwmu.RUnlock()
+	for k := range s.graphs {
+		names <- k
+	}
+	close(names)
+	return nil
+}
+
+// graph provides a persistent implementation of the graph API. All lookups are
+// served by the embedded memory graph, while mutations are first appended to
+// the journal file.
+type graph struct {
+	storage.Graph
+	path string
+	mu   sync.Mutex
+	f    *os.File
+}
+
+// openGraph opens the journal at the provided path, creating it if needed, and
+// replays it to rebuild the graph indexes.
+func openGraph(id, path string) (*graph, error) {
+	ctx := context.Background()
+	g := &graph{
+		Graph: memory.NewGraph(id),
+		path:  path,
+	}
+	records, err := g.replay(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Rewrite the journal if it mostly contains superseded records.
+	live, err := g.size(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if records > 2*live {
+		if err := g.compact(ctx); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("disk.openGraph(%q): failed to open journal with error %v", id, err)
+	}
+	g.f = f
+	return g, nil
+}
+
+// replay reads the journal and applies all its records to the indexes. A
+// truncated trailing record, left behind by an interrupted write, is dropped
+// from the journal. It returns the number of records replayed.
+func (g *graph) replay(ctx context.Context) (int, error) {
+	f, err := os.OpenFile(g.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("disk.replay(%q): failed to open journal with error %v", g.path, err)
+	}
+	defer f.Close()
+	var (
+		records int
+		offset  int64
+		b       = literal.DefaultBuilder()
+		r       = bufio.NewReader(f)
+	)
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF {
+			if line != "" {
+				// The last record was never completely written.
+				if err := f.Truncate(offset); err != nil {
+					return 0, err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		offset += int64(len(line))
+		line = strings.TrimSuffix(line, "\n")
+		if len(line) < 2 {
+			return 0, fmt.Errorf("disk.replay(%q): invalid journal record %q", g.path, line)
+		}
+		raw, err := unescape(line[2:])
+		if err != nil {
+			return 0, fmt.Errorf("disk.replay(%q): invalid journal record %q with error %v", g.path, line, err)
+		}
+		t, err := triple.Parse(raw, b)
+		if err != nil {
+			return 0, fmt.Errorf("disk.replay(%q): invalid journal record %q with error %v", g.path, line, err)
+		}
+		ts := []*triple.Triple{t}
+		switch line[0] {
+		case addOp:
+			err = g.Graph.AddTriples(ctx, ts)
+		case removeOp:
+			err = g.Graph.RemoveTriples(ctx, ts)
+		default:
+			err = fmt.Errorf("disk.replay(%q): unknown operation in journal record %q", g.path, line)
+		}
+		if err != nil {
+			return 0, err
+		}
+		records++
+	}
+	return records, nil
+}
+
+// size returns the number of triples currently available in the graph.
+func (g *graph) size(ctx context.Context) (int, error) {
+	var (
+		cnt  int
+		tErr error
+		wg   sync.WaitGroup
+	)
+	ts := make(chan *triple.Triple)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tErr = g.Graph.Triples(ctx, ts)
+	}()
+	for _ = range ts {
+		cnt++
+	}
+	wg.Wait()
+	return cnt, tErr
+}
+
+// compact rewrites the journal so it only contains the triples currently
+// available on the graph. The new journal replaces the old one atomically.
+func (g *graph) compact(ctx context.Context) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(g.path), filepath.Base(g.path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	var (
+		tErr error
+		wErr error
+		wg   sync.WaitGroup
+	)
+	w := bufio.NewWriter(tmp)
+	ts := make(chan *triple.Triple)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tErr = g.Graph.Triples(ctx, ts)
+	}()
+	for t := range ts {
+		if wErr != nil {
+			continue
+		}
+		_, wErr = w.WriteString(record(addOp, t))
+	}
+	wg.Wait()
+	if tErr != nil {
+		tmp.Close()
+		return tErr
+	}
+	if wErr == nil {
+		wErr = w.Flush()
+	}
+	if wErr == nil {
+		wErr = tmp.Sync()
+	}
+	if err := tmp.Close(); wErr == nil {
+		wErr = err
+	}
+	if wErr != nil {
+		return fmt.Errorf("disk.compact(%q): failed to write journal with error %v", g.path, wErr)
+	}
+	if err := os.Rename(tmp.Name(), g.path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(g.path))
+}
+
+// syncDir syncs the provided directory to disk, so the files created, renamed,
+// or removed in it are not lost after a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cErr := d.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return fmt.Errorf("disk.syncDir(%q): failed to sync directory with error %v", dir, err)
+	}
+	return nil
+}
+
+// escaper escapes the backslashes and new lines of a triple so its journal
+// record takes a single line.
+var escaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// record returns the journal record for the provided operation and triple.
+func record(op byte, t *triple.Triple) string {
+	return fmt.Sprintf("%c\t%s\n", op, escaper.Replace(t.String()))
+}
+
+// unescape reverts the escaping applied by record to a triple.
+func unescape(s string) (string, error) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			buf.WriteByte(s[i])
+			continue
+		}
+		if i++; i == len(s) {
+			return "", fmt.Errorf("unterminated escape sequence")
+		}
+		switch s[i] {
+		case '\\':
+			buf.WriteByte('\\')
+		case 'n':
+			buf.WriteByte('\n')
+		default:
+			return "", fmt.Errorf("invalid escape sequence \"\\%c\"", s[i])
+		}
+	}
+	return buf.String(), nil
+}
+
+// append writes the records for the provided triples to the journal and syncs
+// it to disk.
+func (g *graph) append(op byte, ts []*triple.Triple) error {
+	var buf bytes.Buffer
+	for _, t := range ts {
+		buf.WriteString(record(op, t))
+	}
+	if _, err := g.f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("disk.append(%q): failed to write journal with error %v", g.path, err)
+	}
+	return g.f.Sync()
+}
+
+// AddTriples adds the triples to the storage.
+func (g *graph) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.append(addOp, ts); err != nil {
+		return err
+	}
+	return g.Graph.AddTriples(ctx, ts)
+}
+
+// RemoveTriples removes the triples from the storage.
+func (g *graph) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.append(removeOp, ts); err != nil {
+		return err
+	}
+	return g.Graph.RemoveTriples(ctx, ts)
+}
+
+// Snapshot returns a read-only view of the graph as it is now.
+func (g *graph) Snapshot(ctx context.Context) (storage.Graph, error) {
+	return g.Graph.(storage.Snapshotter).Snapshot(ctx)
+}
+
+// Stats returns the statistics of the graph kept by the memory indexes.
+func (g *graph) Stats(ctx context.Context) (*storage.Stats, error) {
+	return g.Graph.(storage.StatsProvider).Stats(ctx)
+}
+
+// PredicateCardinality returns the number of triples whose predicate has the
+// provided ID using the memory indexes.
+func (g *graph) PredicateCardinality(ctx context.Context, id string) (int64, error) {
+	return g.Graph.(storage.CardinalityProvider).PredicateCardinality(ctx, id)
+}
+
+// ObjectCardinality returns the number of triples with the provided object
+// using the memory indexes.
+func (g *graph) ObjectCardinality(ctx context.Context, o *triple.Object) (int64, error) {
+	return g.Graph.(storage.CardinalityProvider).ObjectCardinality(ctx, o)
+}
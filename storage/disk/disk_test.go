@@ -0,0 +1,722 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package disk
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// newTestStore returns a store on a new temporary directory and the function
+// to call to remove it.
+func newTestStore(t *testing.T) (storage.Store, func()) {
+	dir, err := ioutil.TempDir("", "badwolf_disk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("disk.NewStore(%q) failed with error %v", dir, err)
+	}
+	return s, func() { os.RemoveAll(dir) }
+}
+
+func TestDiskStore(t *testing.T) {
+	s, done := newTestStore(t)
+	defer done()
+	ctx := context.Background()
+	// Create a new graph.
+	if _, err := s.NewGraph(ctx, "test"); err != nil {
+		t.Errorf("diskStore.NewGraph: should never fail to crate a graph; %s", err)
+	}
+	// Create an existing graph.
+	if _, err := s.NewGraph(ctx, "test"); err == nil {
+		t.Errorf("diskStore.NewGraph: should never succeed to create an existing graph")
+	}
+	// Get an existing graph.
+	if _, err := s.Graph(ctx, "test"); err != nil {
+		t.Errorf("diskStore.Graph: should never fail to get an existing graph; %s", err)
+	}
+	// Delete an existing graph.
+	if err := s.DeleteGraph(ctx, "test"); err != nil {
+		t.Errorf("diskStore.DeleteGraph: should never fail to delete an existing graph; %s", err)
+	}
+	// Get a non existing graph.
+	if _, err := s.Graph(ctx, "test"); err == nil {
+		t.Errorf("diskStore.Graph: should never succeed to get a non existing graph; %s", err)
+	}
+	// Delete an existing graph.
+	if err := s.DeleteGraph(ctx, "test"); err == nil {
+		t.Errorf("diskStore.DeleteGraph: should never succed to delete a non existing graph; %s", err)
+	}
+}
+
+func TestGraphNames(t *testing.T) {
+	gs, ctx := []string{"?foo", "?bar", "?test"}, context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	for _, g := range gs {
+		if _, err := s.NewGraph(ctx, g); err != nil {
+			t.Errorf("diskStore.NewGraph: should never fail to crate a graph %s; %s", g, err)
+		}
+	}
+	// To avoid blocking on the test. On a real usage of the driver you would like
+	// to call the graph operation on a separated goroutine using a sync.WaitGroup
+	// to collect the error code eventually.
+	gns := make(chan string, len(gs))
+	if err := s.GraphNames(ctx, gns); err != nil {
+		t.Errorf("diskStore.GraphNames: failed with error %v", err)
+	}
+	cnt := 0
+	for g := range gns {
+		found := false
+		for _, gn := range gs {
+			if g == gn {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("diskStore.GraphNames: failed to return the expected graph names; got %v", g)
+		}
+		cnt++
+	}
+	if got, want := cnt, len(gs); got != want {
+		t.Errorf("diskStore.GraphNames: failed to return the expected number of graph names; got %d, want %d", got, want)
+	}
+}
+
+func createTriples(t *testing.T, ss []string) []*triple.Triple {
+	ts := []*triple.Triple{}
+	for _, s := range ss {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Errorf("triple.Parse failed to parse valid triple %s with error %v", s, err)
+			continue
+		}
+		ts = append(ts, trpl)
+	}
+	return ts
+}
+
+func getTestTriples(t *testing.T) []*triple.Triple {
+	return createTriples(t, []string{
+		"/u<john>\t\"knows\"@[]\t/u<mary>",
+		"/u<john>\t\"knows\"@[]\t/u<peter>",
+		"/u<john>\t\"knows\"@[]\t/u<alice>",
+		"/u<mary>\t\"knows\"@[]\t/u<andrew>",
+		"/u<mary>\t\"knows\"@[]\t/u<kim>",
+		"/u<mary>\t\"knows\"@[]\t/u<alice>",
+	})
+}
+
+func TestAddRemoveTriples(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	if err := g.RemoveTriples(ctx, ts); err != nil {
+		t.Errorf("g.RemoveTriples(_) failed failed to remove test triples with error %v", err)
+	}
+}
+
+func TestObjects(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	// To avoid blocking on the test. On a real usage of the driver you would like
+	// to call the graph operation on a separated goroutine using a sync.WaitGroup
+	// to collect the error code eventually.
+	os := make(chan *triple.Object, 100)
+	if err := g.Objects(ctx, ts[0].Subject(), ts[0].Predicate(), storage.DefaultLookup, os); err != nil {
+		t.Errorf("g.Objects(%s, %s) failed with error %v", ts[0].Subject(), ts[0].Predicate(), err)
+	}
+	cnt := 0
+	for o := range os {
+		cnt++
+		n, _ := o.Node()
+		ty, id := n.Type().String(), n.ID().String()
+		if ty != "/u" || (id != "mary" && id != "peter" && id != "alice") {
+			t.Errorf("g.Objects(%s, %s) failed to return a valid object; returned %s instead", ts[0].Subject(), ts[0].Predicate(), n)
+		}
+	}
+	if cnt != 3 {
+		t.Errorf("g.Objects(%s, %s) failed to retrieve 3 objects, got %d instead", ts[0].Subject(), ts[0].Predicate(), cnt)
+	}
+}
+
+func TestSubjects(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	// To avoid blocking on the test. On a real usage of the driver you would like
+	// to call the graph operation on a separated goroutine using a sync.WaitGroup
+	// to collect the error code eventually.
+	ss := make(chan *node.Node, 100)
+	if err := g.Subjects(ctx, ts[0].Predicate(), ts[0].Object(), storage.DefaultLookup, ss); err != nil {
+		t.Errorf("g.Subjects(%s, %s) failed with error %v", ts[0].Predicate(), ts[0].Object(), err)
+	}
+	cnt := 0
+	for s := range ss {
+		cnt++
+		ty, id := s.Type().String(), s.ID().String()
+		if ty != "/u" || id != "john" {
+			t.Errorf("g.Subjects(%s, %s) failed to return a valid subject; returned %s instead", ts[0].Predicate(), ts[0].Object(), s)
+		}
+	}
+	if cnt != 1 {
+		t.Errorf("g.Objects(%s, %s) failed to retrieve 1 objects, got %d instead", ts[0].Subject(), ts[0].Predicate(), cnt)
+	}
+}
+
+func TestPredicatesForSubjectAndObject(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	// To avoid blocking on the test. On a real usage of the driver you would like
+	// to call the graph operation on a separated goroutine using a sync.WaitGroup
+	// to collect the error code eventually.
+	ps := make(chan *predicate.Predicate, 100)
+	if err := g.PredicatesForSubjectAndObject(ctx, ts[0].Subject(), ts[0].Object(), storage.DefaultLookup, ps); err != nil {
+		t.Errorf("g.PredicatesForSubjectAndObject(%s, %s) failed with error %v", ts[0].Subject(), ts[0].Object(), err)
+	}
+	cnt := 0
+	for p := range ps {
+		cnt++
+		if p.Type() != predicate.Immutable || p.ID() != "knows" {
+			t.Errorf("g.PredicatesForSubjectAndObject(%s, %s) failed to return a valid subject; returned %s instead", ts[0].Subject(), ts[0].Object(), p)
+		}
+	}
+	if cnt != 1 {
+		t.Errorf("g.PredicatesForSubjectAndObject(%s, %s) failed to retrieve 1 predicate, got %d instead", ts[0].Subject(), ts[0].Object(), cnt)
+	}
+}
+
+func TestPredicatesForSubject(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	// To avoid blocking on the test. On a real usage of the driver you would like
+	// to call the graph operation on a separated goroutine using a sync.WaitGroup
+	// to collect the error code eventually.
+	ps := make(chan *predicate.Predicate, 100)
+	if err := g.PredicatesForSubject(ctx, ts[0].Subject(), storage.DefaultLookup, ps); err != nil {
+		t.Errorf("g.PredicatesForSubject(%s) failed with error %v", ts[0].Subject(), err)
+	}
+	cnt := 0
+	for p := range ps {
+		cnt++
+		if p.Type() != predicate.Immutable || p.ID() != "knows" {
+			t.Errorf("g.PredicatesForSubject(%s) failed to return a valid predicate; returned %s instead", ts[0].Subject(), p)
+		}
+	}
+	if cnt != 3 {
+		t.Errorf("g.PredicatesForSubjectAndObject(%s) failed to retrieve 3 predicates, got %d instead", ts[0].Subject(), cnt)
+	}
+}
+
+func TestPredicatesForObject(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	// To avoid blocking on the test. On a real usage of the driver you would like
+	// to call the graph operation on a separated goroutine using a sync.WaitGroup
+	// to collect the error code eventually.
+	ps := make(chan *predicate.Predicate, 100)
+	if err := g.PredicatesForObject(ctx, ts[0].Object(), storage.DefaultLookup, ps); err != nil {
+		t.Errorf("g.PredicatesForObject(%s) failed with error %v", ts[0].Object(), err)
+	}
+	cnt := 0
+	for p := range ps {
+		cnt++
+		if p.Type() != predicate.Immutable || p.ID() != "knows" {
+			t.Errorf("g.PredicatesForObject(%s) failed to return a valid predicate; returned %s instead", ts[0].Object(), p)
+		}
+	}
+	if cnt != 1 {
+		t.Errorf("g.PredicatesForObject(%s) failed to retrieve 1 predicate, got %d instead", ts[0].Object(), cnt)
+	}
+}
+
+func TestTriplesForSubject(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	// To avoid blocking on the test. On a real usage of the driver you would like
+	// to call the graph operation on a separated goroutine using a sync.WaitGroup
+	// to collect the error code eventually.
+	trpls := make(chan *triple.Triple, 100)
+	if err := g.TriplesForSubject(ctx, ts[0].Subject(), storage.DefaultLookup, trpls); err != nil {
+		t.Errorf("g.TriplesForSubject(%s) failed with error %v", ts[0].Subject(), err)
+	}
+	cnt := 0
+	for _ = range trpls {
+		cnt++
+	}
+	if cnt != 3 {
+		t.Errorf("g.triplesForSubject(%s) failed to retrieve 3 predicates, got %d instead", ts[0].Subject(), cnt)
+	}
+}
+
+func TestTriplesForPredicate(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	// To avoid blocking on the test. On a real usage of the driver you would like
+	// to call the graph operation on a separated goroutine using a sync.WaitGroup
+	// to collect the error code eventually.
+	trpls := make(chan *triple.Triple, 100)
+	if err := g.TriplesForPredicate(ctx, ts[0].Predicate(), storage.DefaultLookup, trpls); err != nil {
+		t.Errorf("g.TriplesForPredicate(%s) failed with error %v", ts[0].Subject(), err)
+	}
+	cnt := 0
+	for _ = range trpls {
+		cnt++
+	}
+	if cnt != 6 {
+		t.Errorf("g.triplesForPredicate(%s) failed to retrieve 3 predicates, got %d instead", ts[0].Predicate(), cnt)
+	}
+}
+
+func TestTriplesForObject(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	// To avoid blocking on the test. On a real usage of the driver you would like
+	// to call the graph operation on a separated goroutine using a sync.WaitGroup
+	// to collect the error code eventually.
+	trpls := make(chan *triple.Triple, 100)
+	if err := g.TriplesForObject(ctx, ts[0].Object(), storage.DefaultLookup, trpls); err != nil {
+		t.Errorf("g.TriplesForObject(%s) failed with error %v", ts[0].Object(), err)
+	}
+	cnt := 0
+	for _ = range trpls {
+		cnt++
+	}
+	if cnt != 1 {
+		t.Errorf("g.TriplesForObject(%s) failed to retrieve 1 predicates, got %d instead", ts[0].Object(), cnt)
+	}
+}
+
+func mustParse(t string) *time.Time {
+	r, err := time.Parse(time.RFC3339Nano, t)
+	if err != nil {
+		panic(err)
+	}
+	return &r
+}
+
+func TestTriplesForObjectWithLimit(t *testing.T) {
+	ts := createTriples(t, []string{
+		"/u<bob>\t\"kissed\"@[2015-01-01T00:00:00-09:00]\t/u<mary>",
+		"/u<bob>\t\"kissed\"@[2015-02-01T00:00:00-09:00]\t/u<mary>",
+		"/u<bob>\t\"kissed\"@[2015-03-01T00:00:00-09:00]\t/u<mary>",
+		"/u<bob>\t\"kissed\"@[2015-04-01T00:00:00-09:00]\t/u<mary>",
+		"/u<bob>\t\"kissed\"@[2015-05-01T00:00:00-09:00]\t/u<mary>",
+		"/u<bob>\t\"kissed\"@[2015-06-01T00:00:00-09:00]\t/u<mary>",
+	})
+	ctx := context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	// To avoid blocking on the test. On a real usage of the driver you would like
+	// to call the graph operation on a separated goroutine using a sync.WaitGroup
+	// to collect the error code eventually.
+	trpls := make(chan *triple.Triple, 100)
+	lo := &storage.LookupOptions{
+		MaxElements: 2,
+		LowerAnchor: mustParse("2015-04-01T00:00:00-08:00"),
+		UpperAnchor: mustParse("2015-06-01T00:00:00-10:00"),
+	}
+	if err := g.TriplesForObject(ctx, ts[0].Object(), lo, trpls); err != nil {
+		t.Errorf("g.TriplesForObject(%s) failed with error %v", ts[0].Object(), err)
+	}
+	cnt := 0
+	for tr := range trpls {
+		ta, err := tr.Predicate().TimeAnchor()
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if ta.Before(*lo.LowerAnchor) || ta.After(*lo.UpperAnchor) {
+			t.Errorf("g.TriplesForObject(%s) unexpected triple receved: %s", ts[0].Object(), tr)
+		}
+		cnt++
+	}
+	if cnt != lo.MaxElements {
+		t.Errorf("g.TriplesForObject(%s) failed to retrieve 2 triples, got %d instead", ts[0].Object(), cnt)
+	}
+}
+
+func TestTriplesForSubjectAndPredicate(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	// To avoid blocking on the test. On a real usage of the driver you would like
+	// to call the graph operation on a separated goroutine using a sync.WaitGroup
+	// to collect the error code eventually.
+	trpls := make(chan *triple.Triple, 100)
+	if err := g.TriplesForSubjectAndPredicate(ctx, ts[0].Subject(), ts[0].Predicate(), storage.DefaultLookup, trpls); err != nil {
+		t.Errorf("g.TriplesForSubjectAndPredicate(%s, %s) failed with error %v", ts[0].Subject(), ts[0].Predicate(), err)
+	}
+	cnt := 0
+	for _ = range trpls {
+		cnt++
+	}
+	if cnt != 3 {
+		t.Errorf("g.TriplesForSubjectAndPredicate(%s, %s) failed to retrieve 3 predicates, got %d instead", ts[0].Subject(), ts[0].Predicate(), cnt)
+	}
+}
+
+func TestTriplesForPredicateAndObject(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	// To avoid blocking on the test. On a real usage of the driver you would like
+	// to call the graph operation on a separated goroutine using a sync.WaitGroup
+	// to collect the error code eventually.
+	trpls := make(chan *triple.Triple, 100)
+	if err := g.TriplesForPredicateAndObject(ctx, ts[0].Predicate(), ts[0].Object(), storage.DefaultLookup, trpls); err != nil {
+		t.Errorf("g.TriplesForPredicateAndObject(%s, %s) failed with error %v", ts[0].Predicate(), ts[0].Object(), err)
+	}
+	cnt := 0
+	for _ = range trpls {
+		cnt++
+	}
+	if cnt != 1 {
+		t.Errorf("g.TriplesForPredicateAndObject(%s, %s) failed to retrieve 1 predicates, got %d instead", ts[0].Predicate(), ts[0].Object(), cnt)
+	}
+}
+
+func TestExists(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	for _, trpl := range ts {
+		b, err := g.Exist(ctx, trpl)
+		if err != nil {
+			t.Errorf("g.Exist should have not failed for triple %s with error %s", trpl, err)
+		}
+		if !b {
+			t.Errorf("g.Exist should have not failed for triple %s", trpl)
+		}
+	}
+}
+
+func TestTriples(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	// To avoid blocking on the test. On a real usage of the driver you would like
+	// to call the graph operation on a separated goroutine using a sync.WaitGroup
+	// to collect the error code eventually.
+	trpls := make(chan *triple.Triple, 100)
+	if err := g.Triples(ctx, trpls); err != nil {
+		t.Fatal(err)
+	}
+	cnt := 0
+	for _ = range trpls {
+		cnt++
+	}
+	if cnt != 6 {
+		t.Errorf("g.TriplesForPredicateAndObject(%s, %s) failed to retrieve 1 predicates, got %d instead", ts[0].Predicate(), ts[0].Object(), cnt)
+	}
+}
+
+// countTriples returns the number of triples available in the provided graph.
+func countTriples(t *testing.T, g storage.Graph) int {
+	trpls := make(chan *triple.Triple, 100)
+	if err := g.Triples(context.Background(), trpls); err != nil {
+		t.Fatal(err)
+	}
+	cnt := 0
+	for _ = range trpls {
+		cnt++
+	}
+	return cnt
+}
+
+func TestReopenStore(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	dir, err := ioutil.TempDir("", "badwolf_disk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("disk.NewStore(%q) failed with error %v", dir, err)
+	}
+	for _, id := range []string{"?kept", "?deleted"} {
+		g, err := s.NewGraph(ctx, id)
+		if err != nil {
+			t.Fatalf("diskStore.NewGraph(%q) failed with error %v", id, err)
+		}
+		if err := g.AddTriples(ctx, ts); err != nil {
+			t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+		}
+	}
+	g, _ := s.Graph(ctx, "?kept")
+	if err := g.RemoveTriples(ctx, ts[:2]); err != nil {
+		t.Errorf("g.RemoveTriples(_) failed failed to remove test triples with error %v", err)
+	}
+	if err := s.DeleteGraph(ctx, "?deleted"); err != nil {
+		t.Errorf("diskStore.DeleteGraph(%q) failed with error %v", "?deleted", err)
+	}
+
+	// Open the same directory again.
+	s, err = NewStore(dir)
+	if err != nil {
+		t.Fatalf("disk.NewStore(%q) failed to reopen the store with error %v", dir, err)
+	}
+	if _, err := s.Graph(ctx, "?deleted"); err == nil {
+		t.Errorf("diskStore.Graph(%q) should have not returned a deleted graph", "?deleted")
+	}
+	g, err = s.Graph(ctx, "?kept")
+	if err != nil {
+		t.Fatalf("diskStore.Graph(%q) failed to return a persisted graph with error %v", "?kept", err)
+	}
+	if got, want := countTriples(t, g), len(ts)-2; got != want {
+		t.Errorf("g.Triples returned the wrong number of persisted triples; got %d, want %d", got, want)
+	}
+	for i, trpl := range ts {
+		b, err := g.Exist(ctx, trpl)
+		if err != nil {
+			t.Errorf("g.Exist should have not failed for triple %s with error %s", trpl, err)
+		}
+		if want := i >= 2; b != want {
+			t.Errorf("g.Exist(%s) returned %v after reopening the store; want %v", trpl, b, want)
+		}
+	}
+}
+
+func TestReopenTruncatedJournal(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	dir, err := ioutil.TempDir("", "badwolf_disk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("disk.NewStore(%q) failed with error %v", dir, err)
+	}
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatalf("diskStore.NewGraph failed with error %v", err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	// Simulate a write interrupted in the middle of a record.
+	path := s.(*diskStore).graphPath("?test")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("+\t/u<john>\t\"kno"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	s, err = NewStore(dir)
+	if err != nil {
+		t.Fatalf("disk.NewStore(%q) failed to recover a truncated journal with error %v", dir, err)
+	}
+	g, err = s.Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := countTriples(t, g), len(ts); got != want {
+		t.Errorf("g.Triples returned the wrong number of recovered triples; got %d, want %d", got, want)
+	}
+	// New records should be appended after the last valid one.
+	extra := createTriples(t, []string{"/u<kim>\t\"knows\"@[]\t/u<john>"})
+	if err := g.AddTriples(ctx, extra); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	s, err = NewStore(dir)
+	if err != nil {
+		t.Fatalf("disk.NewStore(%q) failed with error %v", dir, err)
+	}
+	g, _ = s.Graph(ctx, "?test")
+	if got, want := countTriples(t, g), len(ts)+1; got != want {
+		t.Errorf("g.Triples returned the wrong number of persisted triples; got %d, want %d", got, want)
+	}
+}
+
+func TestReopenMultilineLiterals(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "badwolf_disk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("disk.NewStore(%q) failed with error %v", dir, err)
+	}
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatalf("diskStore.NewGraph failed with error %v", err)
+	}
+	ts := createTriples(t, []string{
+		"/u<john>\t\"says\"@[]\t\"a\nb\"^^type:text",
+		"/u<john>\t\"says\"@[]\t\"a\\nb\"^^type:text",
+		"/u<john>\t\"says\"@[]\t\"\\\n\\\"^^type:text",
+		"/u<john>\t\"says\"@[]\t\"\n\"^^type:text",
+	})
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	if err := g.RemoveTriples(ctx, ts[3:]); err != nil {
+		t.Errorf("g.RemoveTriples(_) failed failed to remove test triples with error %v", err)
+	}
+
+	// Open the same directory again.
+	s, err = NewStore(dir)
+	if err != nil {
+		t.Fatalf("disk.NewStore(%q) failed to reopen the store with error %v", dir, err)
+	}
+	g, err = s.Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := countTriples(t, g), 3; got != want {
+		t.Errorf("g.Triples returned the wrong number of persisted triples; got %d, want %d", got, want)
+	}
+	for i, trpl := range ts {
+		b, err := g.Exist(ctx, trpl)
+		if err != nil {
+			t.Errorf("g.Exist should have not failed for triple %q with error %s", trpl, err)
+		}
+		if want := i < 3; b != want {
+			t.Errorf("g.Exist(%q) returned %v after reopening the store; want %v", trpl, b, want)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	got, err := g.(storage.StatsProvider).Stats(ctx)
+	if err != nil {
+		t.Fatalf("g.Stats failed with error %v", err)
+	}
+	want := storage.Stats{
+		Triples:             6,
+		Subjects:            2,
+		Predicates:          1,
+		Objects:             5,
+		ImmutablePredicates: 1,
+	}
+	if *got != want {
+		t.Errorf("g.Stats returned the wrong statistics; got %+v, want %+v", *got, want)
+	}
+}
+
+func TestCardinality(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s, done := newTestStore(t)
+	defer done()
+	g, _ := s.NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	cp := g.(storage.CardinalityProvider)
+	got, err := cp.PredicateCardinality(ctx, "knows")
+	if err != nil {
+		t.Fatalf("g.PredicateCardinality failed with error %v", err)
+	}
+	if want := int64(6); got != want {
+		t.Errorf("g.PredicateCardinality returned the wrong cardinality; got %d, want %d", got, want)
+	}
+	got, err = cp.ObjectCardinality(ctx, ts[2].Object())
+	if err != nil {
+		t.Fatalf("g.ObjectCardinality failed with error %v", err)
+	}
+	if want := int64(2); got != want {
+		t.Errorf("g.ObjectCardinality returned the wrong cardinality; got %d, want %d", got, want)
+	}
+}
@@ -37,14 +37,14 @@ func init() {
 }
 
 type memoryStore struct {
-	graphs map[string]storage.Graph
+	graphs map[string]*memory
 	rwmu   sync.RWMutex
 }
 
 // NewStore creates a new memory store.
 func NewStore() storage.Store {
 	return &memoryStore{
-		graphs: make(map[string]storage.Graph),
+		graphs: make(map[string]*memory),
 	}
 }
 
@@ -58,9 +58,9 @@ func (s *memoryStore) Version(ctx context.Context) string {
 	return "0.1.vcli"
 }
 
-// NewGraph creates a new graph.
-func (s *memoryStore) NewGraph(ctx context.Context, id string) (storage.Graph, error) {
-	g := &memory{
+// newMemory returns a new empty memory graph for the provided id.
+func newMemory(id string) *memory {
+	return &memory{
 		id:    id,
 		idx:   make(map[string]*triple.Triple),
 		idxS:  make(map[string]map[string]*triple.Triple),
@@ -69,7 +69,20 @@ func (s *memoryStore) NewGraph(ctx context.Context, id string) (storage.Graph, e
 		idxSP: make(map[string]map[string]*triple.Triple),
 		idxPO: make(map[string]map[string]*triple.Triple),
 		idxSO: make(map[string]map[string]*triple.Triple),
+		// Only used to report the cardinality of predicate IDs.
+		idxPID: make(map[string]map[string]*triple.Triple),
 	}
+}
+
+// NewGraph returns a new volatile graph that does not belong to any store.
+// It allows other drivers to reuse the memory indexes.
+func NewGraph(id string) storage.Graph {
+	return newMemory(id)
+}
+
+// NewGraph creates a new graph.
+func (s *memoryStore) NewGraph(ctx context.Context, id string) (storage.Graph, error) {
+	g := newMemory(id)
 
 	s.rwmu.Lock()
 	defer s.rwmu.Unlock()
@@ -128,6 +141,16 @@ type memory struct {
 	idxSP map[string]map[string]*triple.Triple
 	idxPO map[string]map[string]*triple.Triple
 	idxSO map[string]map[string]*triple.Triple
+	// idxPID indexes the triples by the ID of their predicate.
+	idxPID map[string]map[string]*triple.Triple
+	// version is increased on every mutation of the graph.
+	version uint64
+	// shared is true while the indexes are also referenced by a snapshot or a
+	// transaction copy of the graph.
+	shared bool
+	// owned contains the inner index maps copied since the indexes were last
+	// shared. It is nil if the indexes were never shared.
+	owned map[string]bool
 }
 
 // ID returns the id for this graph.
@@ -135,10 +158,92 @@ func (m *memory) ID(ctx context.Context) string {
 	return m.id
 }
 
+// copyIndex returns a copy of the provided index. The inner maps are not
+// copied.
+func copyIndex(idx map[string]map[string]*triple.Triple) map[string]map[string]*triple.Triple {
+	c := make(map[string]map[string]*triple.Triple, len(idx))
+	for k, ts := range idx {
+		c[k] = ts
+	}
+	return c
+}
+
+// unshare makes the indexes safe to modify if they are shared. Only the outer
+// maps are copied; the inner maps are copied on their first modification. It
+// must be called holding the write lock.
+func (m *memory) unshare() {
+	if !m.shared {
+		return
+	}
+	idx := make(map[string]*triple.Triple, len(m.idx))
+	for k, t := range m.idx {
+		idx[k] = t
+	}
+	m.idx = idx
+	m.idxS, m.idxP, m.idxO = copyIndex(m.idxS), copyIndex(m.idxP), copyIndex(m.idxO)
+	m.idxSP, m.idxPO, m.idxSO = copyIndex(m.idxSP), copyIndex(m.idxPO), copyIndex(m.idxSO)
+	m.idxPID = copyIndex(m.idxPID)
+	m.owned = make(map[string]bool)
+	m.shared = false
+}
+
+// add adds the triple to the inner map of the index for the provided key. The
+// inner map is copied first if it may be shared. It must be called holding
+// the write lock after unshare.
+func (m *memory) add(name string, idx map[string]map[string]*triple.Triple, key, tuuid string, t *triple.Triple) {
+	ts, ok := idx[key]
+	switch {
+	case !ok:
+		ts = make(map[string]*triple.Triple)
+		idx[key] = ts
+		if m.owned != nil {
+			m.owned[name+key] = true
+		}
+	case m.owned != nil && !m.owned[name+key]:
+		c := make(map[string]*triple.Triple, len(ts)+1)
+		for k, v := range ts {
+			c[k] = v
+		}
+		ts = c
+		idx[key] = ts
+		m.owned[name+key] = true
+	}
+	ts[tuuid] = t
+}
+
+// remove removes the triple from the inner map of the index for the provided
+// key, dropping the inner map once empty. The inner map is copied first if it
+// may be shared. It must be called holding the write lock after unshare.
+func (m *memory) remove(name string, idx map[string]map[string]*triple.Triple, key, tuuid string) {
+	ts, ok := idx[key]
+	if !ok {
+		return
+	}
+	if _, ok := ts[tuuid]; !ok {
+		return
+	}
+	if len(ts) == 1 {
+		delete(idx, key)
+		return
+	}
+	if m.owned != nil && !m.owned[name+key] {
+		c := make(map[string]*triple.Triple, len(ts))
+		for k, v := range ts {
+			c[k] = v
+		}
+		ts = c
+		idx[key] = ts
+		m.owned[name+key] = true
+	}
+	delete(ts, tuuid)
+}
+
 // AddTriples adds the triples to the storage.
 func (m *memory) AddTriples(ctx context.Context, ts []*triple.Triple) error {
 	m.rwmu.Lock()
 	defer m.rwmu.Unlock()
+	m.version++
+	m.unshare()
 	for _, t := range ts {
 		suuid := t.UUID().String()
 		sUUID := t.Subject().UUID().String()
@@ -147,38 +252,13 @@ func (m *memory) AddTriples(ctx context.Context, ts []*triple.Triple) error {
 		// Update master index
 		m.idx[suuid] = t
 
-		if _, ok := m.idxS[sUUID]; !ok {
-			m.idxS[sUUID] = make(map[string]*triple.Triple)
-		}
-		m.idxS[sUUID][suuid] = t
-
-		if _, ok := m.idxP[pUUID]; !ok {
-			m.idxP[pUUID] = make(map[string]*triple.Triple)
-		}
-		m.idxP[pUUID][suuid] = t
-
-		if _, ok := m.idxO[oUUID]; !ok {
-			m.idxO[oUUID] = make(map[string]*triple.Triple)
-		}
-		m.idxO[oUUID][suuid] = t
-
-		key := strings.Join([]string{sUUID, pUUID}, ":")
-		if _, ok := m.idxSP[key]; !ok {
-			m.idxSP[key] = make(map[string]*triple.Triple)
-		}
-		m.idxSP[key][suuid] = t
-
-		key = strings.Join([]string{pUUID, oUUID}, ":")
-		if _, ok := m.idxPO[key]; !ok {
-			m.idxPO[key] = make(map[string]*triple.Triple)
-		}
-		m.idxPO[key][suuid] = t
-
-		key = strings.Join([]string{sUUID, oUUID}, ":")
-		if _, ok := m.idxSO[key]; !ok {
-			m.idxSO[key] = make(map[string]*triple.Triple)
-		}
-		m.idxSO[key][suuid] = t
+		m.add("s/", m.idxS, sUUID, suuid, t)
+		m.add("p/", m.idxP, pUUID, suuid, t)
+		m.add("o/", m.idxO, oUUID, suuid, t)
+		m.add("sp/", m.idxSP, strings.Join([]string{sUUID, pUUID}, ":"), suuid, t)
+		m.add("po/", m.idxPO, strings.Join([]string{pUUID, oUUID}, ":"), suuid, t)
+		m.add("so/", m.idxSO, strings.Join([]string{sUUID, oUUID}, ":"), suuid, t)
+		m.add("pid/", m.idxPID, string(t.Predicate().ID()), suuid, t)
 	}
 	return nil
 }
@@ -192,32 +272,65 @@ func (m *memory) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
 		oUUID := t.Object().UUID().String()
 		// Update master index
 		m.rwmu.Lock()
+		m.version++
+		m.unshare()
 		delete(m.idx, suuid)
-		delete(m.idxS[sUUID], suuid)
-		delete(m.idxP[pUUID], suuid)
-		delete(m.idxO[oUUID], suuid)
-
-		key := strings.Join([]string{sUUID, pUUID}, ":")
-		delete(m.idxSP[key], suuid)
-		if len(m.idxSP[key]) == 0 {
-			delete(m.idxSP, key)
-		}
+		m.remove("s/", m.idxS, sUUID, suuid)
+		m.remove("p/", m.idxP, pUUID, suuid)
+		m.remove("o/", m.idxO, oUUID, suuid)
+		m.remove("sp/", m.idxSP, strings.Join([]string{sUUID, pUUID}, ":"), suuid)
+		m.remove("po/", m.idxPO, strings.Join([]string{pUUID, oUUID}, ":"), suuid)
+		m.remove("so/", m.idxSO, strings.Join([]string{sUUID, oUUID}, ":"), suuid)
+		m.remove("pid/", m.idxPID, string(t.Predicate().ID()), suuid)
+		m.rwmu.Unlock()
+	}
+	return nil
+}
 
-		key = strings.Join([]string{pUUID, oUUID}, ":")
-		delete(m.idxPO[key], suuid)
-		if len(m.idxPO[key]) == 0 {
-			delete(m.idxPO, key)
+// Stats returns the statistics of the graph computed from the sizes of its
+// indexes.
+func (m *memory) Stats(ctx context.Context) (*storage.Stats, error) {
+	m.rwmu.RLock()
+	defer m.rwmu.RUnlock()
+	st := &storage.Stats{
+		Triples:    int64(len(m.idx)),
+		Subjects:   int64(len(m.idxS)),
+		Predicates: int64(len(m.idxPID)),
+		Objects:    int64(len(m.idxO)),
+	}
+	// Predicates with different time anchors are indexed separately, but they
+	// share the same ID.
+	tps, ips := make(map[string]bool), make(map[string]bool)
+	for _, ts := range m.idxP {
+		// All the triples indexed under a predicate share it, so looking at
+		// any of them is enough.
+		for _, t := range ts {
+			p := t.Predicate()
+			if p.Type() == predicate.Temporal {
+				tps[string(p.ID())] = true
+			} else {
+				ips[string(p.ID())] = true
+			}
+			break
 		}
+	}
+	st.TemporalPredicates, st.ImmutablePredicates = int64(len(tps)), int64(len(ips))
+	return st, nil
+}
 
-		key = strings.Join([]string{sUUID, oUUID}, ":")
-		delete(m.idxSO[key], suuid)
-		if len(m.idxSO[key]) == 0 {
-			delete(m.idxSO, key)
-		}
+// PredicateCardinality returns the number of triples whose predicate has the
+// provided ID.
+func (m *memory) PredicateCardinality(ctx context.Context, id string) (int64, error) {
+	m.rwmu.RLock()
+	defer m.rwmu.RUnlock()
+	return int64(len(m.idxPID[id])), nil
+}
 
-		m.rwmu.Unlock()
-	}
-	return nil
+// ObjectCardinality returns the number of triples with the provided object.
+func (m *memory) ObjectCardinality(ctx context.Context, o *triple.Object) (int64, error) {
+	m.rwmu.RLock()
+	defer m.rwmu.RUnlock()
+	return int64(len(m.idxO[o.UUID().String()])), nil
 }
 
 // checker provides the mechanics to check if a predicate/triple should be
@@ -524,3 +524,118 @@ func TestTriples(t *testing.T) {
 		t.Errorf("g.TriplesForPredicateAndObject(%s, %s) failed to retrieve 1 predicates, got %d instead", ts[0].Predicate(), ts[0].Object(), cnt)
 	}
 }
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	ts := append(getTestTriples(t), createTriples(t, []string{
+		"/u<john>\t\"met\"@[2016-01-01T00:00:00-08:00]\t/u<mary>",
+		"/u<john>\t\"met\"@[2016-02-01T00:00:00-08:00]\t/u<mary>",
+		"/u<mary>\t\"knows\"@[2016-03-01T00:00:00-08:00]\t/u<john>",
+	})...)
+	g, _ := NewStore().NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	testTable := []struct {
+		remove []*triple.Triple
+		want   storage.Stats
+	}{
+		{
+			want: storage.Stats{
+				Triples:             9,
+				Subjects:            2,
+				Predicates:          2,
+				Objects:             6,
+				TemporalPredicates:  2,
+				ImmutablePredicates: 1,
+			},
+		},
+		{
+			remove: ts[3:7],
+			want: storage.Stats{
+				Triples:             5,
+				Subjects:            2,
+				Predicates:          2,
+				Objects:             4,
+				TemporalPredicates:  2,
+				ImmutablePredicates: 1,
+			},
+		},
+		{
+			remove: ts[8:],
+			want: storage.Stats{
+				Triples:             4,
+				Subjects:            1,
+				Predicates:          2,
+				Objects:             3,
+				TemporalPredicates:  1,
+				ImmutablePredicates: 1,
+			},
+		},
+	}
+	for _, entry := range testTable {
+		if err := g.RemoveTriples(ctx, entry.remove); err != nil {
+			t.Fatalf("g.RemoveTriples(_) failed to remove test triples with error %v", err)
+		}
+		got, err := g.(storage.StatsProvider).Stats(ctx)
+		if err != nil {
+			t.Fatalf("g.Stats failed with error %v", err)
+		}
+		if *got != entry.want {
+			t.Errorf("g.Stats returned the wrong statistics; got %+v, want %+v", *got, entry.want)
+		}
+	}
+}
+
+func TestCardinality(t *testing.T) {
+	ctx := context.Background()
+	ts := append(getTestTriples(t), createTriples(t, []string{
+		"/u<john>\t\"met\"@[2016-01-01T00:00:00-08:00]\t/u<mary>",
+		"/u<john>\t\"met\"@[2016-02-01T00:00:00-08:00]\t/u<mary>",
+	})...)
+	g, _ := NewStore().NewGraph(ctx, "test")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	s, err := g.(storage.Snapshotter).Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("g.Snapshot failed with error %v", err)
+	}
+	if err := g.RemoveTriples(ctx, ts[:1]); err != nil {
+		t.Fatalf("g.RemoveTriples(_) failed to remove test triples with error %v", err)
+	}
+	alice, mary := ts[2].Object(), ts[6].Object()
+	testTable := []struct {
+		g          storage.Graph
+		knows, met int64
+		alice      int64
+		mary       int64
+	}{
+		{g: s, knows: 6, met: 2, alice: 2, mary: 3},
+		{g: g, knows: 5, met: 2, alice: 2, mary: 2},
+	}
+	for _, entry := range testTable {
+		cp := entry.g.(storage.CardinalityProvider)
+		for id, want := range map[string]int64{"knows": entry.knows, "met": entry.met, "missing": 0} {
+			got, err := cp.PredicateCardinality(ctx, id)
+			if err != nil {
+				t.Fatalf("PredicateCardinality(_, %q) failed with error %v", id, err)
+			}
+			if got != want {
+				t.Errorf("PredicateCardinality(_, %q) returned the wrong cardinality; got %d, want %d", id, got, want)
+			}
+		}
+		for _, o := range []struct {
+			o    *triple.Object
+			want int64
+		}{{alice, entry.alice}, {mary, entry.mary}} {
+			got, err := cp.ObjectCardinality(ctx, o.o)
+			if err != nil {
+				t.Fatalf("ObjectCardinality(_, %v) failed with error %v", o.o, err)
+			}
+			if got != o.want {
+				t.Errorf("ObjectCardinality(_, %v) returned the wrong cardinality; got %d, want %d", o.o, got, o.want)
+			}
+		}
+	}
+}
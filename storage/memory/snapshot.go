@@ -0,0 +1,74 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+)
+
+// clone returns a copy of the graph that can be modified without affecting the
+// original graph. It also returns the version of the graph copied. The copy
+// shares the indexes with the original graph, and each of them copies the
+// parts of the indexes it modifies.
+func (m *memory) clone() (*memory, uint64) {
+	m.rwmu.Lock()
+	defer m.rwmu.Unlock()
+	m.shared = true
+	return &memory{
+		id:      m.id,
+		idx:     m.idx,
+		idxS:    m.idxS,
+		idxP:    m.idxP,
+		idxO:    m.idxO,
+		idxSP:   m.idxSP,
+		idxPO:   m.idxPO,
+		idxSO:   m.idxSO,
+		idxPID:  m.idxPID,
+		version: m.version,
+		shared:  true,
+	}, m.version
+}
+
+// Snapshot returns a read-only view of the graph as it is now. Creating it
+// does not copy the indexes.
+func (m *memory) Snapshot(ctx context.Context) (storage.Graph, error) {
+	c, _ := m.clone()
+	return &snapshot{c}, nil
+}
+
+// snapshot is a read-only graph frozen at the time it was created.
+type snapshot struct {
+	*memory
+}
+
+// AddTriples always fails since snapshots cannot be modified.
+func (s *snapshot) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	return fmt.Errorf("memory.AddTriples(%q): snapshots are read-only", s.id)
+}
+
+// RemoveTriples always fails since snapshots cannot be modified.
+func (s *snapshot) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	return fmt.Errorf("memory.RemoveTriples(%q): snapshots are read-only", s.id)
+}
+
+// Snapshot returns the snapshot itself since it never changes.
+func (s *snapshot) Snapshot(ctx context.Context) (storage.Graph, error) {
+	return s, nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+)
+
+// countSubjectTriples returns the number of triples in the provided graph for
+// the subject of the provided triple.
+func countSubjectTriples(t *testing.T, g storage.Graph, trpl *triple.Triple) int {
+	trpls := make(chan *triple.Triple, 100)
+	if err := g.TriplesForSubject(context.Background(), trpl.Subject(), storage.DefaultLookup, trpls); err != nil {
+		t.Fatal(err)
+	}
+	cnt := 0
+	for _ = range trpls {
+		cnt++
+	}
+	return cnt
+}
+
+func snapshotGraph(t *testing.T, g storage.Graph) storage.Graph {
+	s, err := g.(storage.Snapshotter).Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("g.Snapshot failed with error %v", err)
+	}
+	return s
+}
+
+func TestSnapshotIsFrozen(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	g, _ := NewStore().NewGraph(ctx, "?a")
+	if err := g.AddTriples(ctx, ts[:3]); err != nil {
+		t.Fatal(err)
+	}
+	s := snapshotGraph(t, g)
+	if err := g.AddTriples(ctx, ts[3:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.RemoveTriples(ctx, ts[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := countTriples(t, g), len(ts)-1; got != want {
+		t.Errorf("graph returned the wrong number of triples; got %d, want %d", got, want)
+	}
+	if got, want := countSubjectTriples(t, g, ts[0]), 2; got != want {
+		t.Errorf("graph returned the wrong number of triples for %v; got %d, want %d", ts[0].Subject(), got, want)
+	}
+	if got, want := countTriples(t, s), 3; got != want {
+		t.Errorf("snapshot returned the wrong number of triples; got %d, want %d", got, want)
+	}
+	if got, want := countSubjectTriples(t, s, ts[0]), 3; got != want {
+		t.Errorf("snapshot returned the wrong number of triples for %v; got %d, want %d", ts[0].Subject(), got, want)
+	}
+	if got, want := countSubjectTriples(t, s, ts[3]), 0; got != want {
+		t.Errorf("snapshot returned the wrong number of triples for %v; got %d, want %d", ts[3].Subject(), got, want)
+	}
+	if b, err := s.Exist(ctx, ts[0]); err != nil || !b {
+		t.Errorf("s.Exist(%v) = %v, %v; want true, nil", ts[0], b, err)
+	}
+}
+
+func TestSnapshotIsReadOnly(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	g, _ := NewStore().NewGraph(ctx, "?a")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	s := snapshotGraph(t, g)
+	if err := s.AddTriples(ctx, ts); err == nil {
+		t.Errorf("s.AddTriples(_) should have failed on a snapshot")
+	}
+	if err := s.RemoveTriples(ctx, ts); err == nil {
+		t.Errorf("s.RemoveTriples(_) should have failed on a snapshot")
+	}
+	if got, want := countTriples(t, s), len(ts); got != want {
+		t.Errorf("snapshot returned the wrong number of triples; got %d, want %d", got, want)
+	}
+}
+
+func TestSnapshotSharesIndexes(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	g, _ := NewStore().NewGraph(ctx, "?a")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	m, s := g.(*memory), snapshotGraph(t, g).(*snapshot)
+	ptr := func(v interface{}) uintptr {
+		return reflect.ValueOf(v).Pointer()
+	}
+	if ptr(m.idx) != ptr(s.idx) || ptr(m.idxS) != ptr(s.idxS) || ptr(m.idxSO) != ptr(s.idxSO) {
+		t.Fatalf("snapshot should share the indexes with the graph")
+	}
+	// Removing a triple of /u<john> should only copy the inner maps it touches.
+	if err := g.RemoveTriples(ctx, ts[:1]); err != nil {
+		t.Fatal(err)
+	}
+	john, mary := ts[0].Subject().UUID().String(), ts[3].Subject().UUID().String()
+	if ptr(m.idxS[john]) == ptr(s.idxS[john]) {
+		t.Errorf("modified inner index for %v should have been copied", ts[0].Subject())
+	}
+	if ptr(m.idxS[mary]) != ptr(s.idxS[mary]) {
+		t.Errorf("untouched inner index for %v should still be shared", ts[3].Subject())
+	}
+	if got, want := len(s.idxS[john]), 3; got != want {
+		t.Errorf("snapshot index for %v was modified; got %d triples, want %d", ts[0].Subject(), got, want)
+	}
+}
+
+func TestTxCommitKeepsSnapshots(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	st := NewStore()
+	g, _ := st.NewGraph(ctx, "?a")
+	if err := g.AddTriples(ctx, ts[:3]); err != nil {
+		t.Fatal(err)
+	}
+	s := snapshotGraph(t, g)
+	tx := beginTx(t, st)
+	ta, err := tx.Graph(ctx, "?a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ta.AddTriples(ctx, ts[3:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.RemoveTriples(ctx, ts[3:4]); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := countTriples(t, g), len(ts)-1; got != want {
+		t.Errorf("graph returned the wrong number of triples; got %d, want %d", got, want)
+	}
+	if got, want := countTriples(t, s), 3; got != want {
+		t.Errorf("snapshot returned the wrong number of triples; got %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,417 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// BeginTx starts a new transaction on the store.
+func (s *memoryStore) BeginTx(ctx context.Context) (storage.Tx, error) {
+	return &memoryTx{
+		s:       s,
+		graphs:  make(map[string]*txGraph),
+		deleted: make(map[string]*memory),
+	}, nil
+}
+
+// memoryTx implements storage.Tx for the memory store. Graphs are copied the
+// first time they are modified inside the transaction, and the copies replace
+// the contents of the original graphs on commit. Copies share the indexes with
+// the original graphs until they are modified.
+type memoryTx struct {
+	s       *memoryStore
+	mu      sync.Mutex
+	done    bool
+	graphs  map[string]*txGraph
+	deleted map[string]*memory
+}
+
+// Name returns the ID of the backend being used.
+func (t *memoryTx) Name(ctx context.Context) string {
+	return t.s.Name(ctx)
+}
+
+// Version returns the version of the driver implementation.
+func (t *memoryTx) Version(ctx context.Context) string {
+	return t.s.Version(ctx)
+}
+
+// check returns an error if the transaction is already finished.
+func (t *memoryTx) check() error {
+	if t.done {
+		return fmt.Errorf("memory.Tx: transaction already committed or rolled back")
+	}
+	return nil
+}
+
+// NewGraph creates a new graph inside the transaction.
+func (t *memoryTx) NewGraph(ctx context.Context, id string) (storage.Graph, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	if _, ok := t.graphs[id]; ok {
+		return nil, fmt.Errorf("memory.NewGraph(%q): graph already exists", id)
+	}
+	if _, ok := t.deleted[id]; !ok {
+		if _, err := t.s.Graph(ctx, id); err == nil {
+			return nil, fmt.Errorf("memory.NewGraph(%q): graph already exists", id)
+		}
+	}
+	g := &txGraph{
+		id:   id,
+		tx:   t,
+		work: newMemory(id),
+	}
+	t.graphs[id] = g
+	return g, nil
+}
+
+// Graph returns an existing graph as seen by the transaction.
+func (t *memoryTx) Graph(ctx context.Context, id string) (storage.Graph, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	if g, ok := t.graphs[id]; ok {
+		return g, nil
+	}
+	if _, ok := t.deleted[id]; ok {
+		return nil, fmt.Errorf("memory.Graph(%q): graph does not exist", id)
+	}
+	t.s.rwmu.RLock()
+	m, ok := t.s.graphs[id]
+	t.s.rwmu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("memory.Graph(%q): graph does not exist", id)
+	}
+	g := &txGraph{
+		id:   id,
+		tx:   t,
+		base: m,
+	}
+	t.graphs[id] = g
+	return g, nil
+}
+
+// DeleteGraph deletes an existing graph inside the transaction.
+func (t *memoryTx) DeleteGraph(ctx context.Context, id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.check(); err != nil {
+		return err
+	}
+	g, ok := t.graphs[id]
+	if ok {
+		delete(t.graphs, id)
+		if g.base != nil {
+			t.deleted[id] = g.base
+		}
+		return nil
+	}
+	if _, ok := t.deleted[id]; ok {
+		return fmt.Errorf("memory.DeleteGraph(%q): graph does not exist", id)
+	}
+	t.s.rwmu.RLock()
+	m, ok := t.s.graphs[id]
+	t.s.rwmu.RUnlock()
+	if !ok {
+		return fmt.Errorf("memory.DeleteGraph(%q): graph does not exist", id)
+	}
+	t.deleted[id] = m
+	return nil
+}
+
+// GraphNames returns the graph names available in the transaction.
+func (t *memoryTx) GraphNames(ctx context.Context, names chan<- string) error {
+	if names == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.check(); err != nil {
+		close(names)
+		return err
+	}
+	t.s.rwmu.RLock()
+	defer t.s.rwmu.RUnlock()
+	for k := range t.s.graphs {
+		_, staged := t.graphs[k]
+		if _, ok := t.deleted[k]; !ok && !staged {
+			names <- k
+		}
+	}
+	for k := range t.graphs {
+		names <- k
+	}
+	close(names)
+	return nil
+}
+
+// Commit applies all the changes staged in the transaction.
+func (t *memoryTx) Commit(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.check(); err != nil {
+		return err
+	}
+	t.done = true
+
+	s := t.s
+	s.rwmu.Lock()
+	defer s.rwmu.Unlock()
+	// Lock all the modified graphs in a stable order to avoid deadlocks.
+	var (
+		ids      []string
+		modified []*txGraph
+	)
+	for id := range t.graphs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		g := t.graphs[id]
+		if g.base != nil && g.work != nil {
+			modified = append(modified, g)
+			g.base.rwmu.Lock()
+			defer g.base.rwmu.Unlock()
+		}
+	}
+	// Validate that no conflicting changes were committed meanwhile.
+	for id, m := range t.deleted {
+		if s.graphs[id] != m {
+			return fmt.Errorf("memory.Commit: graph %q was modified by another transaction", id)
+		}
+	}
+	for _, id := range ids {
+		g := t.graphs[id]
+		cur, ok := s.graphs[id]
+		if g.base == nil {
+			if _, deleted := t.deleted[id]; ok && !deleted {
+				return fmt.Errorf("memory.Commit: graph %q was created by another transaction", id)
+			}
+			continue
+		}
+		if !ok || cur != g.base {
+			return fmt.Errorf("memory.Commit: graph %q was deleted by another transaction", id)
+		}
+		if g.work != nil && g.base.version != g.version {
+			return fmt.Errorf("memory.Commit: graph %q was modified by another transaction", id)
+		}
+	}
+	// Apply the changes.
+	for id := range t.deleted {
+		delete(s.graphs, id)
+	}
+	for _, id := range ids {
+		if g := t.graphs[id]; g.base == nil {
+			s.graphs[id] = g.work
+		}
+	}
+	for _, g := range modified {
+		w, m := g.work, g.base
+		m.idx, m.idxS, m.idxP, m.idxO = w.idx, w.idxS, w.idxP, w.idxO
+		m.idxSP, m.idxPO, m.idxSO, m.idxPID = w.idxSP, w.idxPO, w.idxSO, w.idxPID
+		m.shared, m.owned = w.shared, w.owned
+		m.version++
+	}
+	return nil
+}
+
+// Rollback discards all the changes staged in the transaction.
+func (t *memoryTx) Rollback(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.check(); err != nil {
+		return err
+	}
+	t.done = true
+	t.graphs, t.deleted = nil, nil
+	return nil
+}
+
+// txGraph is a graph inside a transaction. Lookups are served by the original
+// graph until the first mutation. From then on, they are served by a private
+// copy that contains the changes made inside the transaction. Once the
+// transaction is finished the graph can no longer be modified.
+type txGraph struct {
+	id      string
+	tx      *memoryTx
+	mu      sync.Mutex
+	base    *memory
+	work    *memory
+	version uint64
+}
+
+// current returns the graph that should serve lookups. Once the transaction is
+// finished, lookups are served by the original graph, which holds the
+// committed changes, so they are guarded by its lock.
+func (g *txGraph) current() *memory {
+	g.tx.mu.Lock()
+	done := g.tx.done
+	g.tx.mu.Unlock()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.work != nil && (!done || g.base == nil) {
+		return g.work
+	}
+	return g.base
+}
+
+// writable returns the private copy of the graph, creating it if needed.
+func (g *txGraph) writable() *memory {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.work == nil {
+		g.work, g.version = g.base.clone()
+	}
+	return g.work
+}
+
+// mutate applies the provided function to the private copy of the graph. The
+// transaction is kept locked meanwhile, so it cannot be finished while the
+// copy is being modified.
+func (g *txGraph) mutate(f func(m *memory) error) error {
+	g.tx.mu.Lock()
+	defer g.tx.mu.Unlock()
+	if err := g.tx.check(); err != nil {
+		return err
+	}
+	return f(g.writable())
+}
+
+// ID returns the id for this graph.
+func (g *txGraph) ID(ctx context.Context) string {
+	return g.id
+}
+
+// AddTriples adds the triples to the transaction copy of the graph.
+func (g *txGraph) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	return g.mutate(func(m *memory) error {
+		return m.AddTriples(ctx, ts)
+	})
+}
+
+// RemoveTriples removes the triples from the transaction copy of the graph.
+func (g *txGraph) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	return g.mutate(func(m *memory) error {
+		return m.RemoveTriples(ctx, ts)
+	})
+}
+
+// Objects published the objects for the give object and predicate to the
+// provided channel.
+func (g *txGraph) Objects(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, objs chan<- *triple.Object) error {
+	return g.current().Objects(ctx, s, p, lo, objs)
+}
+
+// Subject publishes the subjects for the give predicate and object to the
+// provided channel.
+func (g *txGraph) Subjects(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, subjs chan<- *node.Node) error {
+	return g.current().Subjects(ctx, p, o, lo, subjs)
+}
+
+// PredicatesForSubjectAndObject publishes all the predicates available for
+// the given subject and object to the provided channel.
+func (g *txGraph) PredicatesForSubjectAndObject(ctx context.Context, s *node.Node, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	return g.current().PredicatesForSubjectAndObject(ctx, s, o, lo, prds)
+}
+
+// PredicatesForSubject publishes all the predicates available for the given
+// subject to the provided channel.
+func (g *txGraph) PredicatesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	return g.current().PredicatesForSubject(ctx, s, lo, prds)
+}
+
+// PredicatesForObject publishes all the predicates available for the given
+// object to the provided channel.
+func (g *txGraph) PredicatesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, prds chan<- *predicate.Predicate) error {
+	return g.current().PredicatesForObject(ctx, o, lo, prds)
+}
+
+// TriplesForSubject publishes all triples available for the given subject to
+// the provided channel.
+func (g *txGraph) TriplesForSubject(ctx context.Context, s *node.Node, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	return g.current().TriplesForSubject(ctx, s, lo, trpls)
+}
+
+// TriplesForPredicate publishes all triples available for the given predicate
+// to the provided channel.
+func (g *txGraph) TriplesForPredicate(ctx context.Context, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	return g.current().TriplesForPredicate(ctx, p, lo, trpls)
+}
+
+// TriplesForObject publishes all triples available for the given object to the
+// provided channel.
+func (g *txGraph) TriplesForObject(ctx context.Context, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	return g.current().TriplesForObject(ctx, o, lo, trpls)
+}
+
+// TriplesForSubjectAndPredicate publishes all triples available for the given
+// subject and predicate to the provided channel.
+func (g *txGraph) TriplesForSubjectAndPredicate(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	return g.current().TriplesForSubjectAndPredicate(ctx, s, p, lo, trpls)
+}
+
+// TriplesForPredicateAndObject publishes all triples available for the given
+// predicate and object to the provided channel.
+func (g *txGraph) TriplesForPredicateAndObject(ctx context.Context, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	return g.current().TriplesForPredicateAndObject(ctx, p, o, lo, trpls)
+}
+
+// Exist checks if the provided triple exists on the store.
+func (g *txGraph) Exist(ctx context.Context, t *triple.Triple) (bool, error) {
+	return g.current().Exist(ctx, t)
+}
+
+// Triples allows to iterate over all available triples by pushing them to the
+// provided channel.
+func (g *txGraph) Triples(ctx context.Context, trpls chan<- *triple.Triple) error {
+	return g.current().Triples(ctx, trpls)
+}
+
+// Stats returns the statistics of the graph as seen by the transaction.
+func (g *txGraph) Stats(ctx context.Context) (*storage.Stats, error) {
+	return g.current().Stats(ctx)
+}
+
+// PredicateCardinality returns the number of triples whose predicate has the
+// provided ID as seen by the transaction.
+func (g *txGraph) PredicateCardinality(ctx context.Context, id string) (int64, error) {
+	return g.current().PredicateCardinality(ctx, id)
+}
+
+// ObjectCardinality returns the number of triples with the provided object as
+// seen by the transaction.
+func (g *txGraph) ObjectCardinality(ctx context.Context, o *triple.Object) (int64, error) {
+	return g.current().ObjectCardinality(ctx, o)
+}
+
+// Snapshot returns a read-only view of the graph as seen by the transaction.
+func (g *txGraph) Snapshot(ctx context.Context) (storage.Graph, error) {
+	return g.current().Snapshot(ctx)
+}
@@ -0,0 +1,253 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+)
+
+// countTriples returns the number of triples in the provided graph.
+func countTriples(t *testing.T, g storage.Graph) int {
+	trpls := make(chan *triple.Triple, 100)
+	if err := g.Triples(context.Background(), trpls); err != nil {
+		t.Fatal(err)
+	}
+	cnt := 0
+	for _ = range trpls {
+		cnt++
+	}
+	return cnt
+}
+
+func beginTx(t *testing.T, s storage.Store) storage.Tx {
+	tx, err := s.(storage.Transactional).BeginTx(context.Background())
+	if err != nil {
+		t.Fatalf("memoryStore.BeginTx failed with error %v", err)
+	}
+	return tx
+}
+
+func TestTxCommit(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s := NewStore()
+	g, _ := s.NewGraph(ctx, "?a")
+	if err := g.AddTriples(ctx, ts[:3]); err != nil {
+		t.Fatal(err)
+	}
+	tx := beginTx(t, s)
+	ta, err := tx.Graph(ctx, "?a")
+	if err != nil {
+		t.Fatalf("tx.Graph(%q) failed with error %v", "?a", err)
+	}
+	if err := ta.AddTriples(ctx, ts[3:]); err != nil {
+		t.Errorf("ta.AddTriples(_) failed with error %v", err)
+	}
+	tb, err := tx.NewGraph(ctx, "?b")
+	if err != nil {
+		t.Fatalf("tx.NewGraph(%q) failed with error %v", "?b", err)
+	}
+	if err := tb.AddTriples(ctx, ts); err != nil {
+		t.Errorf("tb.AddTriples(_) failed with error %v", err)
+	}
+	// Changes are visible inside the transaction, but not outside.
+	if got, want := countTriples(t, ta), len(ts); got != want {
+		t.Errorf("transaction graph returned the wrong number of triples; got %d, want %d", got, want)
+	}
+	if got, want := countTriples(t, g), 3; got != want {
+		t.Errorf("uncommitted triples should not be visible; got %d triples, want %d", got, want)
+	}
+	if _, err := s.Graph(ctx, "?b"); err == nil {
+		t.Errorf("uncommitted graph %q should not be visible", "?b")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("tx.Commit failed with error %v", err)
+	}
+	if got, want := countTriples(t, g), len(ts); got != want {
+		t.Errorf("committed triples should be visible; got %d triples, want %d", got, want)
+	}
+	gb, err := s.Graph(ctx, "?b")
+	if err != nil {
+		t.Fatalf("committed graph %q should be visible; %v", "?b", err)
+	}
+	if got, want := countTriples(t, gb), len(ts); got != want {
+		t.Errorf("committed triples should be visible; got %d triples, want %d", got, want)
+	}
+	if err := tx.Commit(ctx); err == nil {
+		t.Errorf("tx.Commit should fail on a finished transaction")
+	}
+}
+
+func TestTxRollback(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s := NewStore()
+	g, _ := s.NewGraph(ctx, "?a")
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatal(err)
+	}
+	tx := beginTx(t, s)
+	ta, err := tx.Graph(ctx, "?a")
+	if err != nil {
+		t.Fatalf("tx.Graph(%q) failed with error %v", "?a", err)
+	}
+	if err := ta.RemoveTriples(ctx, ts); err != nil {
+		t.Errorf("ta.RemoveTriples(_) failed with error %v", err)
+	}
+	if err := tx.DeleteGraph(ctx, "?a"); err != nil {
+		t.Errorf("tx.DeleteGraph(%q) failed with error %v", "?a", err)
+	}
+	if _, err := tx.Graph(ctx, "?a"); err == nil {
+		t.Errorf("tx.Graph(%q) should fail for a graph deleted in the transaction", "?a")
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("tx.Rollback failed with error %v", err)
+	}
+	if _, err := tx.Graph(ctx, "?a"); err == nil {
+		t.Errorf("tx.Graph should fail on a finished transaction")
+	}
+	g, err = s.Graph(ctx, "?a")
+	if err != nil {
+		t.Fatalf("rolled back graph deletion should not be applied; %v", err)
+	}
+	if got, want := countTriples(t, g), len(ts); got != want {
+		t.Errorf("rolled back changes should not be applied; got %d triples, want %d", got, want)
+	}
+}
+
+func TestTxFinishedGraphs(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s := NewStore()
+	g, _ := s.NewGraph(ctx, "?a")
+	if err := g.AddTriples(ctx, ts[:3]); err != nil {
+		t.Fatal(err)
+	}
+	tx := beginTx(t, s)
+	ta, err := tx.Graph(ctx, "?a")
+	if err != nil {
+		t.Fatalf("tx.Graph(%q) failed with error %v", "?a", err)
+	}
+	if err := ta.AddTriples(ctx, ts[3:4]); err != nil {
+		t.Errorf("ta.AddTriples(_) failed with error %v", err)
+	}
+	tb, err := tx.NewGraph(ctx, "?b")
+	if err != nil {
+		t.Fatalf("tx.NewGraph(%q) failed with error %v", "?b", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("tx.Commit failed with error %v", err)
+	}
+	// Graphs kept from a committed transaction cannot modify the store.
+	if err := ta.AddTriples(ctx, ts[4:]); err == nil {
+		t.Errorf("ta.AddTriples(_) should fail on a committed transaction")
+	}
+	if err := ta.RemoveTriples(ctx, ts[:1]); err == nil {
+		t.Errorf("ta.RemoveTriples(_) should fail on a committed transaction")
+	}
+	if err := tb.AddTriples(ctx, ts); err == nil {
+		t.Errorf("tb.AddTriples(_) should fail on a committed transaction")
+	}
+	if got, want := countTriples(t, g), 4; got != want {
+		t.Errorf("committed graph returned the wrong number of triples; got %d, want %d", got, want)
+	}
+	if got, want := countTriples(t, ta), 4; got != want {
+		t.Errorf("committed transaction graph returned the wrong number of triples; got %d, want %d", got, want)
+	}
+	gb, err := s.Graph(ctx, "?b")
+	if err != nil {
+		t.Fatalf("committed graph %q should be visible; %v", "?b", err)
+	}
+	if got, want := countTriples(t, gb), 0; got != want {
+		t.Errorf("committed graph %q returned the wrong number of triples; got %d, want %d", "?b", got, want)
+	}
+
+	// Graphs kept from a rolled back transaction cannot modify the store.
+	tx = beginTx(t, s)
+	ta, err = tx.Graph(ctx, "?a")
+	if err != nil {
+		t.Fatalf("tx.Graph(%q) failed with error %v", "?a", err)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("tx.Rollback failed with error %v", err)
+	}
+	if err := ta.AddTriples(ctx, ts); err == nil {
+		t.Errorf("ta.AddTriples(_) should fail on a rolled back transaction")
+	}
+	if got, want := countTriples(t, g), 4; got != want {
+		t.Errorf("rolled back graph returned the wrong number of triples; got %d, want %d", got, want)
+	}
+}
+
+func TestTxConflict(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s := NewStore()
+	ga, _ := s.NewGraph(ctx, "?a")
+	gb, _ := s.NewGraph(ctx, "?b")
+	tx := beginTx(t, s)
+	for _, id := range []string{"?a", "?b"} {
+		g, err := tx.Graph(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, ts[:3]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A concurrent change to one of the graphs.
+	if err := gb.AddTriples(ctx, ts[3:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx); err == nil {
+		t.Errorf("tx.Commit should fail when a graph was modified concurrently")
+	}
+	// None of the changes should have been applied.
+	if got, want := countTriples(t, ga), 0; got != want {
+		t.Errorf("failed commit should not modify any graph; got %d triples, want %d", got, want)
+	}
+	if got, want := countTriples(t, gb), 3; got != want {
+		t.Errorf("failed commit should not modify any graph; got %d triples, want %d", got, want)
+	}
+}
+
+func TestTxGraphNames(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	s.NewGraph(ctx, "?a")
+	s.NewGraph(ctx, "?b")
+	tx := beginTx(t, s)
+	if err := tx.DeleteGraph(ctx, "?a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.NewGraph(ctx, "?c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.NewGraph(ctx, "?b"); err == nil {
+		t.Errorf("tx.NewGraph(%q) should fail for an existing graph", "?b")
+	}
+	gns := make(chan string, 10)
+	if err := tx.GraphNames(ctx, gns); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool)
+	for n := range gns {
+		got[n] = true
+	}
+	if len(got) != 2 || !got["?b"] || !got["?c"] {
+		t.Errorf("tx.GraphNames returned %v; want [?b ?c]", got)
+	}
+}
@@ -221,3 +221,73 @@ type Graph interface {
 	// elements in the channel.
 	Triples(ctx context.Context, trpls chan<- *triple.Triple) error
 }
+
+// Transactional is an optional interface that stores can implement to allow
+// applying a set of changes atomically across graphs.
+type Transactional interface {
+	// BeginTx starts a new transaction on the store.
+	BeginTx(ctx context.Context) (Tx, error)
+}
+
+// Tx interface describes a transaction on a store. A transaction is a store
+// on its own. All the graphs returned by it stage the changes done to them
+// until the transaction is committed. Lookups on those graphs also see the
+// changes made inside the transaction. Once Commit or Rollback is called the
+// transaction can no longer be used.
+type Tx interface {
+	Store
+
+	// Commit atomically applies all the changes staged in the transaction. If
+	// the changes conflict with changes committed after the transaction
+	// started, none of them is applied and an error is returned.
+	Commit(ctx context.Context) error
+
+	// Rollback discards all the changes staged in the transaction.
+	Rollback(ctx context.Context) error
+}
+
+// Snapshotter is an optional interface that graphs can implement to provide
+// point-in-time views of their contents.
+type Snapshotter interface {
+	// Snapshot returns a read-only graph frozen with the current contents of
+	// the graph. Later changes to the graph are not visible through the
+	// returned graph, and any attempt to modify it returns an error.
+	Snapshot(ctx context.Context) (Graph, error)
+}
+
+// Stats contains statistics about the contents of a graph.
+type Stats struct {
+	// Triples is the number of triples in the graph.
+	Triples int64
+	// Subjects, Predicates, and Objects contain the number of distinct
+	// subjects, predicates, and objects of the triples in the graph. Predicates
+	// are counted by ID, regardless of their time anchors.
+	Subjects   int64
+	Predicates int64
+	Objects    int64
+	// TemporalPredicates and ImmutablePredicates split the distinct predicate
+	// IDs by their type. An ID used by both temporal and immutable predicates
+	// is counted in both.
+	TemporalPredicates  int64
+	ImmutablePredicates int64
+}
+
+// StatsProvider is an optional interface that graphs can implement to report
+// statistics about their contents without having to scan all their triples.
+type StatsProvider interface {
+	// Stats returns the statistics of the current contents of the graph.
+	Stats(ctx context.Context) (*Stats, error)
+}
+
+// CardinalityProvider is an optional interface that graphs can implement to
+// report how many triples use a given predicate or object without having to
+// retrieve them. Together with StatsProvider, it allows the query planner to
+// resolve the most selective clauses first.
+type CardinalityProvider interface {
+	// PredicateCardinality returns the number of triples whose predicate has
+	// the provided ID, regardless of its type or time anchor.
+	PredicateCardinality(ctx context.Context, id string) (int64, error)
+
+	// ObjectCardinality returns the number of triples with the provided object.
+	ObjectCardinality(ctx context.Context, o *triple.Object) (int64, error)
+}
@@ -0,0 +1,394 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package wal provides a write-ahead log that can wrap any storage.Graph to
+// make its mutations durable.
+//
+// Every call to AddTriples or RemoveTriples is appended to the log as a single
+// checksummed batch and synced before being applied to the wrapped graph. On
+// startup the last checkpoint snapshot is loaded and the log replayed on top of
+// it. Batches that were only partially written when the process died are
+// discarded as a whole, so the recovered graph always reflects a prefix of the
+// batches issued.
+package wal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	bwio "github.com/google/badwolf/io"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+const (
+	// logFile is the name of the log file inside the WAL directory.
+	logFile = "log"
+	// snapshotFile is the name of the checkpoint snapshot file inside the WAL
+	// directory.
+	snapshotFile = "snapshot"
+	// headerSize is the size in bytes of a batch header. The header contains
+	// the payload length followed by its CRC32 checksum.
+	headerSize = 8
+	// maxBatchSize is the upper bound used to detect corrupted headers.
+	maxBatchSize = 1 << 30
+)
+
+// op identifies the operation recorded in a log batch.
+type op byte
+
+const (
+	addOp    op = '+'
+	removeOp op = '-'
+)
+
+// Graph wraps a storage.Graph logging all its mutations before applying them.
+// Lookups are served directly by the wrapped graph.
+type Graph struct {
+	storage.Graph
+	dir string
+	mu  sync.Mutex
+	log *os.File
+}
+
+// New wraps the provided graph with a write-ahead log kept in dir. The
+// directory is created if it does not exist. If dir already contains a
+// snapshot or a log, they are loaded into g before returning. The provided
+// graph is expected to be empty.
+func New(ctx context.Context, g storage.Graph, dir string) (*Graph, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("wal.New(%q): failed to create log directory with error %v", dir, err)
+	}
+	w := &Graph{
+		Graph: g,
+		dir:   dir,
+	}
+	if err := w.loadSnapshot(ctx); err != nil {
+		return nil, err
+	}
+	if err := w.replay(ctx); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, logFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("wal.New(%q): failed to open log with error %v", dir, err)
+	}
+	// Make sure the log survives a crash if it was just created.
+	if err := syncDir(dir); err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.log = f
+	return w, nil
+}
+
+// loadSnapshot adds the triples of the last checkpoint, if any, to the
+// wrapped graph.
+func (w *Graph) loadSnapshot(ctx context.Context) error {
+	f, err := os.Open(filepath.Join(w.dir, snapshotFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := bwio.ReadIntoGraph(ctx, w.Graph, f, literal.DefaultBuilder()); err != nil {
+		return fmt.Errorf("wal.New(%q): failed to load snapshot with error %v", w.dir, err)
+	}
+	return nil
+}
+
+// replay applies all the complete batches found in the log to the wrapped
+// graph. A last batch left incomplete by an interrupted write is dropped by
+// truncating the log after the previous one. Any other invalid batch is
+// reported as an error, so the batches logged after it are never lost.
+func (w *Graph) replay(ctx context.Context) error {
+	f, err := os.OpenFile(filepath.Join(w.dir, logFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("wal.New(%q): failed to open log with error %v", w.dir, err)
+	}
+	defer f.Close()
+	var (
+		offset int64
+		r      = bufio.NewReader(f)
+	)
+	for {
+		payload, err := readPayload(r)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err == errChecksum {
+			if _, pErr := r.Peek(1); pErr == io.EOF {
+				// Only the last batch can be partially written.
+				break
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("wal.New(%q): invalid batch at offset %d with error %v", w.dir, offset, err)
+		}
+		o, ts, err := decode(payload)
+		if err != nil {
+			return fmt.Errorf("wal.New(%q): invalid batch at offset %d with error %v", w.dir, offset, err)
+		}
+		if err := w.apply(ctx, o, ts); err != nil {
+			return err
+		}
+		offset += int64(headerSize + len(payload))
+	}
+	return f.Truncate(offset)
+}
+
+// errChecksum is returned when the payload of a batch does not match the
+// checksum in its header.
+var errChecksum = errors.New("batch checksum mismatch")
+
+// readPayload reads the next batch and returns its payload once its checksum
+// has been verified. It returns io.EOF if there are no more batches, and
+// io.ErrUnexpectedEOF if the batch is incomplete.
+func readPayload(r io.Reader) ([]byte, error) {
+	var hdr [headerSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	size, sum := binary.BigEndian.Uint32(hdr[:4]), binary.BigEndian.Uint32(hdr[4:])
+	if size == 0 || size > maxBatchSize {
+		return nil, fmt.Errorf("invalid batch size %d", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(payload) != sum {
+		return nil, errChecksum
+	}
+	return payload, nil
+}
+
+// batch returns the header and payload of the batch for the provided
+// operation and triples. Batches larger than maxBatchSize are rejected, since
+// they could not be told apart from a corrupted header when read back.
+func batch(o op, ts []*triple.Triple) ([]byte, error) {
+	payload := encode(o, ts)
+	if len(payload) > maxBatchSize {
+		return nil, fmt.Errorf("batch of %d bytes exceeds the maximum size of %d bytes", len(payload), maxBatchSize)
+	}
+	buf := make([]byte, headerSize, headerSize+len(payload))
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:], crc32.ChecksumIEEE(payload))
+	return append(buf, payload...), nil
+}
+
+// encode returns the log payload for the provided operation and triples. Each
+// triple is prefixed by the length of its text representation, so literals
+// spanning several lines are stored unchanged.
+func encode(o op, ts []*triple.Triple) []byte {
+	var (
+		buf bytes.Buffer
+		n   [binary.MaxVarintLen64]byte
+	)
+	buf.WriteByte(byte(o))
+	for _, t := range ts {
+		s := t.String()
+		buf.Write(n[:binary.PutUvarint(n[:], uint64(len(s)))])
+		buf.WriteString(s)
+	}
+	return buf.Bytes()
+}
+
+// decode returns the operation and triples stored in a log payload.
+func decode(payload []byte) (op, []*triple.Triple, error) {
+	if len(payload) == 0 {
+		return 0, nil, fmt.Errorf("empty payload")
+	}
+	o := op(payload[0])
+	if o != addOp && o != removeOp {
+		return 0, nil, fmt.Errorf("unknown operation %q", payload[0])
+	}
+	var (
+		ts []*triple.Triple
+		b  = literal.DefaultBuilder()
+		r  = bytes.NewReader(payload[1:])
+	)
+	for r.Len() > 0 {
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid triple length with error %v", err)
+		}
+		if size > uint64(r.Len()) {
+			return 0, nil, fmt.Errorf("triple of %d bytes exceeds the remaining %d bytes of the payload", size, r.Len())
+		}
+		s := make([]byte, size)
+		r.Read(s)
+		t, err := triple.Parse(string(s), b)
+		if err != nil {
+			return 0, nil, err
+		}
+		ts = append(ts, t)
+	}
+	return o, ts, nil
+}
+
+// apply runs the provided operation against the wrapped graph.
+func (w *Graph) apply(ctx context.Context, o op, ts []*triple.Triple) error {
+	if o == addOp {
+		return w.Graph.AddTriples(ctx, ts)
+	}
+	return w.Graph.RemoveTriples(ctx, ts)
+}
+
+// write appends a batch to the log and syncs it. If the batch cannot be
+// completely written, the log is rolled back to its previous size. It returns
+// the size of the log before the batch was appended.
+func (w *Graph) write(o op, ts []*triple.Triple) (int64, error) {
+	buf, err := batch(o, ts)
+	if err != nil {
+		return 0, fmt.Errorf("wal.write(%q): %v", w.dir, err)
+	}
+	fi, err := w.log.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.log.Write(buf); err != nil {
+		w.log.Truncate(fi.Size())
+		return 0, fmt.Errorf("wal.write(%q): failed to append batch with error %v", w.dir, err)
+	}
+	return fi.Size(), w.log.Sync()
+}
+
+// mutate logs the provided operation and then applies it. If it cannot be
+// applied, the batch is removed from the log so it is not replayed.
+func (w *Graph) mutate(ctx context.Context, o op, ts []*triple.Triple) error {
+	if len(ts) == 0 {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.log == nil {
+		return fmt.Errorf("wal.Graph(%q): log already closed", w.dir)
+	}
+	size, err := w.write(o, ts)
+	if err != nil {
+		return err
+	}
+	if err := w.apply(ctx, o, ts); err != nil {
+		lErr := w.log.Truncate(size)
+		if lErr == nil {
+			lErr = w.log.Sync()
+		}
+		if lErr != nil {
+			return fmt.Errorf("wal.Graph(%q): failed to apply batch with error %v, and to remove it from the log with error %v", w.dir, err, lErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// AddTriples adds the triples to the storage.
+func (w *Graph) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	return w.mutate(ctx, addOp, ts)
+}
+
+// RemoveTriples removes the triples from the storage.
+func (w *Graph) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	return w.mutate(ctx, removeOp, ts)
+}
+
+// Checkpoint writes a full snapshot of the wrapped graph and truncates the
+// log. Mutations are blocked while the checkpoint is in progress.
+func (w *Graph) Checkpoint(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.log == nil {
+		return fmt.Errorf("wal.Graph(%q): log already closed", w.dir)
+	}
+	path := filepath.Join(w.dir, snapshotFile)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	bw := bufio.NewWriter(f)
+	_, wErr := bwio.WriteGraph(ctx, bw, w.Graph)
+	if wErr == nil {
+		wErr = bw.Flush()
+	}
+	if wErr == nil {
+		wErr = f.Sync()
+	}
+	if err := f.Close(); wErr == nil {
+		wErr = err
+	}
+	if wErr != nil {
+		return fmt.Errorf("wal.Checkpoint(%q): failed to write snapshot with error %v", w.dir, wErr)
+	}
+	// Once the snapshot is in place, replaying the log on top of it yields the
+	// same graph. Hence, a crash before the log is truncated is harmless.
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	// The rename must be durable before the log is truncated.
+	if err := syncDir(w.dir); err != nil {
+		return err
+	}
+	if err := w.log.Truncate(0); err != nil {
+		return err
+	}
+	return w.log.Sync()
+}
+
+// syncDir syncs the provided directory to disk, so the files created or
+// renamed in it are not lost after a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cErr := d.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return fmt.Errorf("wal.syncDir(%q): failed to sync directory with error %v", dir, err)
+	}
+	return nil
+}
+
+// Close closes the log. The wrapped graph remains usable, but further
+// mutations through the log will fail.
+func (w *Graph) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.log == nil {
+		return nil
+	}
+	err := w.log.Close()
+	w.log = nil
+	return err
+}
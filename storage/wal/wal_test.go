@@ -0,0 +1,350 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+func createTriples(t *testing.T, ss []string) []*triple.Triple {
+	ts := []*triple.Triple{}
+	for _, s := range ss {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Errorf("triple.Parse failed to parse valid triple %s with error %v", s, err)
+			continue
+		}
+		ts = append(ts, trpl)
+	}
+	return ts
+}
+
+func getTestTriples(t *testing.T) []*triple.Triple {
+	return createTriples(t, []string{
+		"/u<john>\t\"knows\"@[]\t/u<mary>",
+		"/u<john>\t\"knows\"@[]\t/u<peter>",
+		"/u<john>\t\"knows\"@[]\t/u<alice>",
+		"/u<mary>\t\"knows\"@[]\t/u<andrew>",
+		"/u<mary>\t\"knows\"@[]\t/u<kim>",
+		"/u<mary>\t\"knows\"@[2016-04-10T04:25:00.000000000Z]\t/u<alice>",
+	})
+}
+
+// failingGraph is a graph whose mutations fail while fail is set.
+type failingGraph struct {
+	storage.Graph
+	fail bool
+}
+
+// AddTriples adds the triples to the graph unless fail is set.
+func (g *failingGraph) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	if g.fail {
+		return fmt.Errorf("failingGraph.AddTriples: failed to add triples")
+	}
+	return g.Graph.AddTriples(ctx, ts)
+}
+
+// open wraps a new empty memory graph with the log kept in dir.
+func open(t *testing.T, dir string) *Graph {
+	g, err := New(context.Background(), memory.NewGraph("?test"), dir)
+	if err != nil {
+		t.Fatalf("wal.New(_, _, %q) failed with error %v", dir, err)
+	}
+	return g
+}
+
+// checkTriples checks that the graph contains exactly the provided triples.
+func checkTriples(t *testing.T, g storage.Graph, ts []*triple.Triple) {
+	ctx := context.Background()
+	trpls := make(chan *triple.Triple, 100)
+	if err := g.Triples(ctx, trpls); err != nil {
+		t.Fatal(err)
+	}
+	cnt := 0
+	for _ = range trpls {
+		cnt++
+	}
+	if got, want := cnt, len(ts); got != want {
+		t.Errorf("g.Triples returned the wrong number of triples; got %d, want %d", got, want)
+	}
+	for _, trpl := range ts {
+		if b, err := g.Exist(ctx, trpl); err != nil || !b {
+			t.Errorf("g.Exist(%s) = %v, %v; want true, nil", trpl, b, err)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	dir, err := ioutil.TempDir("", "badwolf_wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := open(t, dir)
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	if err := g.RemoveTriples(ctx, ts[:2]); err != nil {
+		t.Errorf("g.RemoveTriples(_) failed to remove test triples with error %v", err)
+	}
+	checkTriples(t, g, ts[2:])
+	if err := g.Close(); err != nil {
+		t.Errorf("g.Close() failed with error %v", err)
+	}
+	if err := g.AddTriples(ctx, ts); err == nil {
+		t.Errorf("g.AddTriples(_) should have failed on a closed log")
+	}
+
+	g = open(t, dir)
+	defer g.Close()
+	checkTriples(t, g, ts[2:])
+}
+
+func TestCheckpoint(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	dir, err := ioutil.TempDir("", "badwolf_wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := open(t, dir)
+	if err := g.AddTriples(ctx, ts[:4]); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	if err := g.Checkpoint(ctx); err != nil {
+		t.Fatalf("g.Checkpoint() failed with error %v", err)
+	}
+	fi, err := os.Stat(filepath.Join(dir, logFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 0 {
+		t.Errorf("g.Checkpoint() should have truncated the log; got size %d", fi.Size())
+	}
+	if err := g.AddTriples(ctx, ts[4:]); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	if err := g.RemoveTriples(ctx, ts[:1]); err != nil {
+		t.Errorf("g.RemoveTriples(_) failed to remove test triples with error %v", err)
+	}
+	g.Close()
+
+	g = open(t, dir)
+	defer g.Close()
+	checkTriples(t, g, ts[1:])
+}
+
+func TestInterruptedBatch(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	dir, err := ioutil.TempDir("", "badwolf_wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := open(t, dir)
+	if err := g.AddTriples(ctx, ts[:3]); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	g.Close()
+	path := filepath.Join(dir, logFile)
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := fi.Size()
+
+	// Simulate a process killed while writing the second batch.
+	g = open(t, dir)
+	if err := g.AddTriples(ctx, ts[3:]); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	g.Close()
+	fi, err = os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, valid+(fi.Size()-valid)/2); err != nil {
+		t.Fatal(err)
+	}
+	g = open(t, dir)
+	checkTriples(t, g, ts[:3])
+	// The partial batch must be gone so new batches can be replayed.
+	if err := g.AddTriples(ctx, ts[5:]); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	g.Close()
+	g = open(t, dir)
+	defer g.Close()
+	checkTriples(t, g, append(ts[:3:3], ts[5]))
+}
+
+func TestCorruptedBatch(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	dir, err := ioutil.TempDir("", "badwolf_wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := open(t, dir)
+	if err := g.AddTriples(ctx, ts[:2]); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	if err := g.AddTriples(ctx, ts[2:]); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	g.Close()
+	// Flip the last byte of the second batch.
+	path := filepath.Join(dir, logFile)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[len(b)-2] ^= 0xff
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	g = open(t, dir)
+	defer g.Close()
+	checkTriples(t, g, ts[:2])
+}
+
+func TestMultilineLiterals(t *testing.T) {
+	ctx := context.Background()
+	ts := createTriples(t, []string{
+		"/u<john>\t\"says\"@[]\t\"a\nb\"^^type:text",
+		"/u<john>\t\"says\"@[]\t\"\n\"^^type:text",
+		"/u<mary>\t\"says\"@[]\t\"c\r\nd\"^^type:text",
+	})
+	dir, err := ioutil.TempDir("", "badwolf_wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := open(t, dir)
+	if err := g.AddTriples(ctx, ts[:2]); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	g.Close()
+	g = open(t, dir)
+	checkTriples(t, g, ts[:2])
+	if err := g.Checkpoint(ctx); err != nil {
+		t.Fatalf("g.Checkpoint() failed with error %v", err)
+	}
+	if err := g.AddTriples(ctx, ts[2:]); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	g.Close()
+
+	g = open(t, dir)
+	defer g.Close()
+	checkTriples(t, g, ts)
+}
+
+func TestCorruptedMiddleBatch(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	dir, err := ioutil.TempDir("", "badwolf_wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := open(t, dir)
+	if err := g.AddTriples(ctx, ts[:2]); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	g.Close()
+	path := filepath.Join(dir, logFile)
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := fi.Size()
+	g = open(t, dir)
+	if err := g.AddTriples(ctx, ts[2:4]); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	if err := g.AddTriples(ctx, ts[4:]); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	g.Close()
+	// Flip a byte of the payload of the second batch.
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[first+headerSize+1] ^= 0xff
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(ctx, memory.NewGraph("?test"), dir); err == nil {
+		t.Errorf("wal.New(_, _, %q) should fail on a corrupted batch followed by valid ones", dir)
+	}
+	// The batches after the corrupted one must be kept.
+	fi, err = os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fi.Size(), int64(len(b)); got != want {
+		t.Errorf("wal.New(_, _, %q) should not have truncated the log; got size %d, want %d", dir, got, want)
+	}
+}
+
+func TestFailedBatchIsNotLogged(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	dir, err := ioutil.TempDir("", "badwolf_wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fg := &failingGraph{Graph: memory.NewGraph("?test")}
+	g, err := New(ctx, fg, dir)
+	if err != nil {
+		t.Fatalf("wal.New(_, _, %q) failed with error %v", dir, err)
+	}
+	if err := g.AddTriples(ctx, ts[:3]); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	fg.fail = true
+	if err := g.AddTriples(ctx, ts[3:]); err == nil {
+		t.Errorf("g.AddTriples(_) should have failed to add triples to a failing graph")
+	}
+	fg.fail = false
+	if err := g.AddTriples(ctx, ts[5:]); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	g.Close()
+
+	g = open(t, dir)
+	defer g.Close()
+	checkTriples(t, g, append(ts[:3:3], ts[5]))
+}
@@ -141,6 +141,21 @@ var treeGraphWalkingBQL = []string{
       ?c3 "parent_of"@[] ?c4
    }
    GROUP BY ?c0, ?c1, ?c2, ?c3, ?c4;`,
+	`SELECT ?c
+   FROM ?%s
+   WHERE {
+      /tn<0> "parent_of"@[]{"1"^^type:int64, "5"^^type:int64} ?c
+   };`,
+	`SELECT ?c
+   FROM ?%s
+   WHERE {
+      /tn<0> "parent_of"@[]+ ?c
+   };`,
+	`SELECT ?c, ?h, ?p
+   FROM ?%s
+   WHERE {
+      /tn<0> "parent_of"@[]{"1"^^type:int64, "3"^^type:int64} shortest path ?p hops ?h ?c
+   };`,
 }
 
 var randomGraphWalkingBQL = []string{
@@ -259,6 +274,21 @@ var randomGraphWalkingBQL = []string{
       ?c3 "follow"@[] ?c4
    }
    GROUP BY ?c0, ?c1, ?c2, ?c3, ?c4;`,
+	`SELECT ?c
+   FROM ?%s
+   WHERE {
+      /tn<0> "follow"@[]{"1"^^type:int64, "5"^^type:int64} ?c
+   };`,
+	`SELECT ?c
+   FROM ?%s
+   WHERE {
+      /tn<0> "follow"@[]+ ?c
+   };`,
+	`SELECT ?c, ?h, ?p
+   FROM ?%s
+   WHERE {
+      /tn<0> "follow"@[]{"1"^^type:int64, "3"^^type:int64} shortest path ?p hops ?h ?c
+   };`,
 }
 
 // BQLTreeGraphWalking creates the benchmark.
@@ -0,0 +1,76 @@
+// Copyright 2016 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/google/badwolf/bql/planner"
+	"github.com/google/badwolf/bql/table"
+)
+
+// PrintRows runs the statement and writes its rows to w as they arrive,
+// using the same layout as table.Table.String. The bindings are written
+// before the first row, and an empty line after the last one. It returns the
+// number of rows written.
+func PrintRows(ctx context.Context, s planner.Streamer, w io.Writer) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	rows, errc := make(chan table.Row), make(chan error, 1)
+	go func() {
+		errc <- s.Stream(ctx, rows)
+	}()
+	var (
+		bs  = s.Bindings()
+		buf bytes.Buffer
+		n   int
+		err error
+	)
+	for r := range rows {
+		if err != nil {
+			// Drain the rows sent before the statement noticed the cancellation.
+			continue
+		}
+		buf.Reset()
+		if n == 0 {
+			buf.WriteString(strings.Join(bs, "\t"))
+			buf.WriteString("\n")
+		}
+		if err = r.ToTextLine(&buf, bs, "\t"); err == nil {
+			buf.WriteString("\n")
+			_, err = w.Write(buf.Bytes())
+		}
+		if err != nil {
+			cancel()
+			continue
+		}
+		n++
+	}
+	if sErr := <-errc; err == nil {
+		err = sErr
+	}
+	if err != nil {
+		return n, err
+	}
+	if n > 0 {
+		_, err = fmt.Fprintln(w)
+	}
+	return n, err
+}
@@ -23,6 +23,7 @@ import (
 
 	"golang.org/x/net/context"
 
+	bwio "github.com/google/badwolf/io"
 	"github.com/google/badwolf/storage"
 	"github.com/google/badwolf/tools/vcli/bw/command"
 	"github.com/google/badwolf/tools/vcli/bw/io"
@@ -37,7 +38,8 @@ func New(store storage.Store, bulkSize, builderSize int) *command.Command {
 		Short:     "load triples in bulk stored in a file.",
 		Long: `Loads all the triples stored in a file into the provided graphs.
 Graph names need to be separated by commans with no whitespaces. Each triple
-needs to placed in a single line. Each triple needs to be formated so it can be
+needs to placed in a single line, with backslashes and new lines escaped as \\
+and \n. Each triple needs to be formated so it can be
 parsed as indicated in the documetation (see https://github.com/google/badwolf).
 All data in the file will be treated as triples. A line starting with # will
 be treated as a commented line. If the load fails you may end up with partially
@@ -61,7 +63,7 @@ func Eval(ctx context.Context, usage string, args []string, store storage.Store,
 	path := args[len(args)-2]
 	go storeTriple(ctx, store, graphs, bulkSize, trplsChan, errChan, doneChan)
 	cnt, err := io.ProcessLines(path, func(line string) error {
-		t, err := triple.Parse(line, lb)
+		t, err := bwio.ParseTriple(line, lb)
 		if err != nil {
 			return err
 		}
@@ -23,7 +23,9 @@ import (
 	"flag"
 	"os"
 
+	"github.com/google/badwolf/bql/planner"
 	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/disk"
 	"github.com/google/badwolf/storage/memory"
 	"github.com/google/badwolf/tools/vcli/bw/common"
 	"github.com/google/badwolf/tools/vcli/bw/repl"
@@ -33,11 +35,15 @@ var (
 	// drivers contains the registered drivers available for this command line tool.
 	registeredDrivers map[string]common.StoreGenerator
 	// Available flags.
-	driver                = flag.String("driver", "VOLATILE", "The storage driver to use {VOLATILE}.")
+	driver                = flag.String("driver", "VOLATILE", "The storage driver to use {VOLATILE|DISK}.")
 	bqlChannelSize        = flag.Int("bql_channel_size", 0, "Internal channel size to use on BQL queries.")
+	bqlWorkers            = flag.Int("bql_workers", planner.DefaultWorkers, "Maximum number of goroutines used to run independent lookups of a BQL query concurrently.")
+	bqlMemoryBudget       = flag.Int64("bql_memory_budget", 0, "Estimated bytes a BQL query can use to hold rows in memory; rows sorted or grouped beyond it spill to temporary files. 0 means no limit.")
+	bqlSpillDir           = flag.String("bql_spill_dir", "", "Directory where BQL queries spill the rows they sort or group. Defaults to the temporary directory.")
 	bulkTripleOpSize      = flag.Int("bulk_triple_op_size", 1000, "Number of triples to use in bulk load operations.")
 	bulkTripleBuilderSize = flag.Int("bulk_triple_builder_size_in_bytes", 1000, "Maximum size of literals when parsing a triple.")
 	// Add your driver flags below.
+	diskDir = flag.String("disk_dir", "badwolf_data", "Directory where the DISK driver stores its graphs.")
 )
 
 // Registers the available drivers.
@@ -47,11 +53,15 @@ func registerDrivers() {
 		"VOLATILE": func() (storage.Store, error) {
 			return memory.NewStore(), nil
 		},
+		// Persistent file based storage driver.
+		"DISK": func() (storage.Store, error) {
+			return disk.NewStore(*diskDir)
+		},
 	}
 }
 
 func main() {
 	flag.Parse()
 	registerDrivers()
-	os.Exit(common.Run(*driver, registeredDrivers, *bqlChannelSize, *bulkTripleOpSize, *bulkTripleBuilderSize, repl.SimpleReadLine))
+	os.Exit(common.Run(*driver, registeredDrivers, *bqlChannelSize, *bqlWorkers, *bqlMemoryBudget, *bqlSpillDir, *bulkTripleOpSize, *bulkTripleBuilderSize, repl.SimpleReadLine))
 }
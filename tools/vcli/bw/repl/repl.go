@@ -40,10 +40,10 @@ import (
 const prompt = "bql> "
 
 // New create the version command.
-func New(driver storage.Store, chanSize, bulkSize, builderSize int, rl ReadLiner) *command.Command {
+func New(driver storage.Store, chanSize, workers int, memoryBudget int64, spillDir string, bulkSize, builderSize int, rl ReadLiner) *command.Command {
 	return &command.Command{
 		Run: func(ctx context.Context, args []string) int {
-			REPL(driver, os.Stdin, rl, chanSize, bulkSize, builderSize)
+			REPL(driver, os.Stdin, rl, chanSize, workers, memoryBudget, spillDir, bulkSize, builderSize)
 			return 0
 		},
 		UsageLine: "bql",
@@ -73,7 +73,7 @@ func SimpleReadLine(f *os.File) <-chan string {
 }
 
 // REPL starts a read-evaluation-print-loop to run BQL commands.
-func REPL(driver storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize, builderSize int) int {
+func REPL(driver storage.Store, input *os.File, rl ReadLiner, chanSize, workers int, memoryBudget int64, spillDir string, bulkSize, builderSize int) int {
 	ctx := context.Background()
 	fmt.Printf("Welcome to BadWolf vCli (%d.%d.%d-%s)\n", version.Major, version.Minor, version.Patch, version.Release)
 	fmt.Printf("Using driver %q. Type quit; to exit\n", driver.Name(ctx))
@@ -82,7 +82,10 @@ func REPL(driver storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize
 		fmt.Printf("\n\nThanks for all those BQL queries!\n\n")
 	}()
 	fmt.Print(prompt)
-	l := ""
+	l, session := "", planner.NewSession(driver)
+	session.SetWorkers(workers)
+	session.SetMemoryBudget(memoryBudget)
+	session.SetSpillDir(spillDir)
 	for line := range rl(input) {
 		nl := strings.TrimSpace(line)
 		if nl == "" {
@@ -124,7 +127,7 @@ func REPL(driver storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize
 			continue
 		}
 		if strings.HasPrefix(l, "run") {
-			path, cmds, err := runBQLFromFile(ctx, driver, chanSize, strings.TrimSpace(l[:len(l)-1]))
+			path, cmds, err := runBQLFromFile(ctx, session, chanSize, strings.TrimSpace(l[:len(l)-1]))
 			if err != nil {
 				fmt.Printf("[ERROR] %s\n\n", err)
 			} else {
@@ -135,14 +138,14 @@ func REPL(driver storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize
 			continue
 		}
 
-		table, err := runBQL(ctx, l, driver, chanSize)
+		pln, err := planBQL(ctx, l, session, chanSize)
 		l = ""
+		if err == nil {
+			err = printResult(ctx, pln)
+		}
 		if err != nil {
 			fmt.Printf("[ERROR] %s\n\n", err)
 		} else {
-			if len(table.Bindings()) > 0 {
-				fmt.Println(table.String())
-			}
 			fmt.Println("[OK]")
 		}
 		fmt.Print(prompt)
@@ -161,7 +164,7 @@ func printHelp() {
 }
 
 // runBQLFromFile loads all the statements in the file and runs them.
-func runBQLFromFile(ctx context.Context, driver storage.Store, chanSize int, line string) (string, int, error) {
+func runBQLFromFile(ctx context.Context, session *planner.Session, chanSize int, line string) (string, int, error) {
 	ss := strings.Split(strings.TrimSpace(line), " ")
 	if len(ss) != 2 {
 		return "", 0, fmt.Errorf("wrong syntax: run <file_with_bql_statements>")
@@ -173,7 +176,7 @@ func runBQLFromFile(ctx context.Context, driver storage.Store, chanSize int, lin
 	}
 	for idx, stm := range lines {
 		fmt.Printf("Processing statement (%d/%d)\n", idx+1, len(lines))
-		_, err := runBQL(ctx, stm, driver, chanSize)
+		_, err := runBQL(ctx, stm, session, chanSize)
 		if err != nil {
 			return "", 0, fmt.Errorf("%v on\n%s\n", err, stm)
 		}
@@ -182,8 +185,18 @@ func runBQLFromFile(ctx context.Context, driver storage.Store, chanSize int, lin
 	return path, len(lines), nil
 }
 
-// runBQL attempts to execute the provided query against the given store.
-func runBQL(ctx context.Context, bql string, s storage.Store, chanSize int) (*table.Table, error) {
+// runBQL attempts to execute the provided query as part of the given session.
+func runBQL(ctx context.Context, bql string, s *planner.Session, chanSize int) (*table.Table, error) {
+	pln, err := planBQL(ctx, bql, s, chanSize)
+	if err != nil {
+		return nil, err
+	}
+	return executeBQL(ctx, pln)
+}
+
+// planBQL parses the provided query and creates its plan as part of the given
+// session.
+func planBQL(ctx context.Context, bql string, s *planner.Session, chanSize int) (planner.Executor, error) {
 	p, err := grammar.NewParser(grammar.SemanticBQL())
 	if err != nil {
 		return nil, fmt.Errorf("failed to initilize a valid BQL parser")
@@ -192,13 +205,44 @@ func runBQL(ctx context.Context, bql string, s storage.Store, chanSize int) (*ta
 	if err := p.Parse(grammar.NewLLk(bql, 1), stm); err != nil {
 		return nil, fmt.Errorf("failed to parse BQL statement with error %v", err)
 	}
-	pln, err := planner.New(ctx, s, stm, chanSize)
+	pln, err := s.New(ctx, stm, chanSize)
 	if err != nil {
 		return nil, fmt.Errorf("should have not failed to create a plan using memory.DefaultStorage for statement %v with error %v", stm, err)
 	}
+	return pln, nil
+}
+
+// executeBQL executes the provided plan.
+func executeBQL(ctx context.Context, pln planner.Executor) (*table.Table, error) {
 	res, err := pln.Execute(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("planner.Execute: failed to execute insert plan with error %v", err)
 	}
 	return res, nil
 }
+
+// printResult executes the provided plan and prints the resulting table. The
+// rows of queries that can be streamed are printed as they arrive.
+func printResult(ctx context.Context, pln planner.Executor) error {
+	if sp, ok := pln.(planner.Streamer); ok && sp.Streamable() {
+		n, err := io.PrintRows(ctx, sp, os.Stdout)
+		if err != nil {
+			return fmt.Errorf("planner.Stream: failed to stream query plan with error %v", err)
+		}
+		if bs := sp.Bindings(); n == 0 && len(bs) > 0 {
+			fmt.Printf("%s\n\n", strings.Join(bs, "\t"))
+		}
+		return nil
+	}
+	table, err := executeBQL(ctx, pln)
+	if err != nil {
+		return err
+	}
+	if len(table.Bindings()) > 0 {
+		fmt.Println(table.String())
+	}
+	if tkn := table.ContinuationToken(); tkn != "" {
+		fmt.Printf("Continuation token: %q\n", tkn)
+	}
+	return nil
+}
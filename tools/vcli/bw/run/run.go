@@ -33,7 +33,7 @@ import (
 )
 
 // New creates the help command.
-func New(store storage.Store, chanSize int) *command.Command {
+func New(store storage.Store, chanSize, workers int, memoryBudget int64, spillDir string) *command.Command {
 	cmd := &command.Command{
 		UsageLine: "run file_path",
 		Short:     "runs BQL statements.",
@@ -43,13 +43,13 @@ sequentially.
 `,
 	}
 	cmd.Run = func(ctx context.Context, args []string) int {
-		return runCommand(ctx, cmd, args, store, chanSize)
+		return runCommand(ctx, cmd, args, store, chanSize, workers, memoryBudget, spillDir)
 	}
 	return cmd
 }
 
 // runCommand runs all the BQL statements available in the file.
-func runCommand(ctx context.Context, cmd *command.Command, args []string, store storage.Store, chanSize int) int {
+func runCommand(ctx context.Context, cmd *command.Command, args []string, store storage.Store, chanSize, workers int, memoryBudget int64, spillDir string) int {
 	if len(args) < 3 {
 		fmt.Fprintf(os.Stderr, "[ERROR] Missing required file path. ")
 		cmd.Usage()
@@ -62,9 +62,28 @@ func runCommand(ctx context.Context, cmd *command.Command, args []string, store
 		return 2
 	}
 	fmt.Printf("Processing file %s\n\n", args[len(args)-1])
+	session := planner.NewSession(store)
+	session.SetWorkers(workers)
+	session.SetMemoryBudget(memoryBudget)
+	session.SetSpillDir(spillDir)
 	for idx, stm := range lines {
 		fmt.Printf("Processing statement (%d/%d):\n%s\n\n", idx+1, len(lines), stm)
-		tbl, err := BQL(ctx, stm, store, chanSize)
+		pln, err := planBQL(ctx, stm, session, chanSize)
+		if err != nil {
+			fmt.Printf("[FAIL] %v\n\n", err)
+			continue
+		}
+		if sp, ok := pln.(planner.Streamer); ok && sp.Streamable() {
+			// Print the rows as they arrive.
+			fmt.Println("Result:")
+			if _, err := io.PrintRows(ctx, sp, os.Stdout); err != nil {
+				fmt.Printf("[FAIL] [ERROR] Failed to execute BQL statement with error %v\n\n", err)
+				continue
+			}
+			fmt.Printf("OK\n\n")
+			continue
+		}
+		tbl, err := executeBQL(ctx, pln)
 		if err != nil {
 			fmt.Printf("[FAIL] %v\n\n", err)
 			continue
@@ -73,6 +92,9 @@ func runCommand(ctx context.Context, cmd *command.Command, args []string, store
 		if tbl.NumRows() > 0 {
 			fmt.Println(tbl)
 		}
+		if tkn := tbl.ContinuationToken(); tkn != "" {
+			fmt.Printf("Continuation token: %q\n", tkn)
+		}
 		fmt.Printf("OK\n\n")
 	}
 	return 0
@@ -80,6 +102,22 @@ func runCommand(ctx context.Context, cmd *command.Command, args []string, store
 
 // BQL attempts to execute the provided query against the given store.
 func BQL(ctx context.Context, bql string, s storage.Store, chanSize int) (*table.Table, error) {
+	return sessionBQL(ctx, bql, planner.NewSession(s), chanSize)
+}
+
+// sessionBQL attempts to execute the provided query as part of the given
+// session.
+func sessionBQL(ctx context.Context, bql string, s *planner.Session, chanSize int) (*table.Table, error) {
+	pln, err := planBQL(ctx, bql, s, chanSize)
+	if err != nil {
+		return nil, err
+	}
+	return executeBQL(ctx, pln)
+}
+
+// planBQL parses the provided query and creates its plan as part of the given
+// session.
+func planBQL(ctx context.Context, bql string, s *planner.Session, chanSize int) (planner.Executor, error) {
 	p, err := grammar.NewParser(grammar.SemanticBQL())
 	if err != nil {
 		return nil, fmt.Errorf("[ERROR] Failed to initilize a valid BQL parser")
@@ -88,10 +126,15 @@ func BQL(ctx context.Context, bql string, s storage.Store, chanSize int) (*table
 	if err := p.Parse(grammar.NewLLk(bql, 1), stm); err != nil {
 		return nil, fmt.Errorf("[ERROR] Failed to parse BQL statement with error %v", err)
 	}
-	pln, err := planner.New(ctx, s, stm, chanSize)
+	pln, err := s.New(ctx, stm, chanSize)
 	if err != nil {
 		return nil, fmt.Errorf("[ERROR] Should have not failed to create a plan using memory.DefaultStorage for statement %v with error %v", stm, err)
 	}
+	return pln, nil
+}
+
+// executeBQL executes the provided plan.
+func executeBQL(ctx context.Context, pln planner.Executor) (*table.Table, error) {
 	res, err := pln.Execute(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("[ERROR] Failed to execute BQL statement with error %v", err)